@@ -42,14 +42,14 @@ func TestBloomFilterIntegration(t *testing.T) {
 		activityID := uint64(1001)
 
 		// 1. 添加商品到布隆过滤器
-		inventory.AddToBloomFilter(activityID)
+		inventory.AddToBloomFilter(ctx, activityID)
 
 		// 2. 检查商品存在且未售罄
 		available := inventory.LocalCheck(ctx, activityID)
 		assert.True(t, available, "存在的商品且未售罄应该返回true")
 
 		// 3. 标记商品为售罄
-		err = inventory.MarkSoldOut(activityID)
+		err = inventory.MarkSoldOut(ctx, activityID)
 		require.NoError(t, err)
 
 		// 4. 再次检查，应该返回false（已售罄）
@@ -82,7 +82,7 @@ func TestBloomFilterIntegration(t *testing.T) {
 		assert.Equal(t, 8, result.RemainStock, "剩余库存应该正确")
 
 		// 4. 模拟库存耗尽，标记售罄
-		err = inventory.MarkSoldOut(activityID)
+		err = inventory.MarkSoldOut(ctx, activityID)
 		require.NoError(t, err)
 
 		// 5. 再次尝试扣减，应该被售罄检查拒绝
@@ -103,7 +103,7 @@ func TestBloomFilterIntegration(t *testing.T) {
 		// 添加大量商品到布隆过滤器
 		start := time.Now()
 		for i := 2000; i < 3000; i++ {
-			inventory.AddToBloomFilter(uint64(i))
+			inventory.AddToBloomFilter(ctx, uint64(i))
 		}
 		addDuration := time.Since(start)
 		t.Logf("添加1000个商品到布隆过滤器耗时: %v", addDuration)
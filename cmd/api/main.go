@@ -14,16 +14,24 @@ import (
 	"seckill/internal/database"
 	"seckill/internal/handler"
 	"seckill/internal/middleware"
+	"seckill/internal/model"
 	"seckill/internal/redis"
 	"seckill/internal/repository"
 	"seckill/internal/service/auth"
+	"seckill/internal/service/blacklist"
+	"seckill/internal/service/gray"
+	"seckill/internal/service/lifecycle"
 	"seckill/internal/service/order"
+	"seckill/internal/service/risk"
 	"seckill/internal/service/seckill"
 	"seckill/internal/service/stock"
+	"seckill/internal/service/vip"
 	"seckill/internal/utils"
+	"seckill/pkg/bloom"
 	"seckill/pkg/breaker"
 	"seckill/pkg/degrade"
 	"seckill/pkg/limiter"
+	"seckill/pkg/lock"
 	"seckill/pkg/log"
 	"seckill/pkg/queue"
 	"seckill/pkg/snowflake"
@@ -78,16 +86,35 @@ func main() {
 	// Initialize dependencies
 	db := database.GetDB()
 
-	// Create Redis v9 client for services
-	redisV9Client := redisv9.NewClient(&redisv9.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	})
+	// Services share the client from redis.Init, single node or cluster as configured
+	redisV9Client := redis.GetClient()
+
+	// Preload Lua scripts, EVALSHA reloads any the server loses later
+	redis.Scripts.SetDebug(cfg.Redis.ScriptDebug)
+	limiter.UseSlidingWindowScript(redis.Scripts.Register("limiter:sliding_window", limiter.SlidingWindowScript))
+	limiter.UseSemaphoreScript(redis.Scripts.Register("limiter:semaphore_acquire", limiter.SemaphoreAcquireScript))
+	bloom.UseCountingScripts(
+		redis.Scripts.Register("bloom:counting_add", bloom.CountingAddScript),
+		redis.Scripts.Register("bloom:counting_remove", bloom.CountingRemoveScript),
+		redis.Scripts.Register("bloom:counting_test", bloom.CountingTestScript),
+	)
+	lock.UseLockScripts(
+		redis.Scripts.Register("lock:unlock", lock.UnlockScript),
+		redis.Scripts.Register("lock:extend", lock.ExtendScript),
+	)
+	if err := redis.Scripts.Load(context.Background(), redisV9Client); err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Warn("Failed to preload Lua scripts")
+	}
 
 	// Create repositories
 	goodsRepo := repository.NewGoodsRepository(db)
 	orderRepo := repository.NewOrderRepository(db)
+	if cfg.Seckill.Outbox.Enabled {
+		// Order changes are announced only while the outbox relay runs to drain them
+		orderRepo = repository.NewOrderRepositoryWithOutbox(db)
+	}
 	// activityRepo := repository.NewActivityRepository(db) // For stock sync service (disabled for now)
 
 	// Create ID generator
@@ -113,13 +140,96 @@ func main() {
 			"error": err.Error(),
 		}).Fatal("Failed to create inventory manager")
 	}
+	if cfg.Seckill.StockLease.Enabled {
+		inventory.EnableStockLease(seckill.LeaseConfig{
+			Batch:         cfg.Seckill.StockLease.Batch,
+			TTL:           cfg.Seckill.StockLease.TTL,
+			FlushInterval: cfg.Seckill.StockLease.FlushInterval,
+		})
+	}
+
+	// Record every stock movement in stock_logs, written in batches off the request path
+	var stockLogWriter *seckill.StockLogWriter
+	if cfg.Seckill.StockLog.Enabled {
+		stockLogWriter = seckill.NewStockLogWriter(repository.NewStockLogRepository(db), repository.NewActivityRepository(db), cfg.Seckill.StockLog.BufferSize, cfg.Seckill.StockLog.BatchSize)
+		inventory.EnableStockLog(stockLogWriter)
+	}
+
+	// Create circuit breaker manager, one breaker per seckill dependency
+	circuitBreakerManager := breaker.NewManager(breaker.Config{
+		MaxRequests: 5,
+		Interval:    time.Minute,
+		Timeout:     30 * time.Second,
+		ReadyToTrip: nil, // Use default
+		OnStateChange: func(name string, from, to breaker.State) {
+			log.WithFields(map[string]interface{}{
+				"breaker": name,
+				"from":    from.String(),
+				"to":      to.String(),
+			}).Warn("Circuit breaker state changed")
+		},
+	})
+
+	// Deduct from MySQL while the Redis breaker is open
+	var fallbackInventory seckill.Inventory
+	if cfg.Seckill.MySQLFallback.Enabled {
+		mysqlInventory := seckill.NewMySQLInventory(
+			repository.NewPurchaseRepository(db),
+			cfg.Seckill.MySQLFallback.QPS,
+			cfg.Seckill.MySQLFallback.MaxConcurrent,
+		)
+		if stockLogWriter != nil {
+			mysqlInventory.EnableStockLog(stockLogWriter)
+		}
+		fallbackInventory = mysqlInventory
+	}
+	stockInventory := seckill.NewInventoryFailover(inventory, fallbackInventory, circuitBreakerManager)
+
+	// Log tries persistently so those stranded without an order can be recovered
+	var tccSweeper *seckill.TCCSweeper
+	if cfg.Seckill.TCCRecovery.Enabled {
+		tccLogRepo := repository.NewTCCLogRepository(db)
+		stockInventory.EnableTCCLog(tccLogRepo)
+		tccSweeper = seckill.NewTCCSweeper(stockInventory, tccLogRepo, cfg.Seckill.TCCRecovery.Deadline, cfg.Seckill.TCCRecovery.BatchSize)
+	}
+
+	if cfg.Seckill.MySQLFallback.Enabled && !cfg.Seckill.TCCRecovery.Enabled {
+		log.Warn("MySQL fallback runs without TCC logs, Redis tries whose order is not written yet can be oversold")
+	}
+
+	// Retry confirms and cancels that failed after the order was written
+	var compensator *seckill.TCCCompensator
+	if cfg.Seckill.Compensation.Enabled {
+		compensator = seckill.NewTCCCompensator(stockInventory, repository.NewCompensationRepository(db), seckill.CompensationConfig{
+			MaxAttempts: cfg.Seckill.Compensation.MaxAttempts,
+			BaseBackoff: cfg.Seckill.Compensation.BaseBackoff,
+			MaxBackoff:  cfg.Seckill.Compensation.MaxBackoff,
+			BatchSize:   cfg.Seckill.Compensation.BatchSize,
+		})
+	}
+
+	// Create result notifier (pushes result transitions across instances)
+	resultNotifier := seckill.NewResultNotifier(redisV9Client)
+
+	// Create blacklist service (MySQL backed, cached in Redis for the seckill fast path)
+	blacklistService := blacklist.NewBlacklistService(repository.NewBlacklistRepository(db), redisV9Client)
+
+	// Create degrade manager and the controller degrading activities from live signals
+	degradeManager := degrade.NewDegradeManager(redisV9Client)
+	autoDegrade := degrade.NewAutoController(degradeManager, seckill.OrderBacklog(messageQueue))
+
+	// Create stock service and reconciler shared by the workers and the admin API
+	activityRepo := repository.NewActivityRepository(db)
+	stockService := stock.NewStockService(activityRepo, goodsRepo, inventory, redisV9Client)
+	stockLogRepo := repository.NewStockLogRepository(db)
+	reconciler := seckill.NewReconciler(inventory, activityRepo, orderRepo, stockLogRepo, cfg.Seckill.Reconcile.InFlight)
 
-	router := setupRouter(redisV9Client, goodsRepo, orderRepo, idGenerator, messageQueue, inventory)
+	router, seckillService := setupRouter(redisV9Client, goodsRepo, orderRepo, idGenerator, messageQueue, inventory, stockInventory, stockService, reconciler, stockLogRepo, circuitBreakerManager, tccSweeper, compensator, resultNotifier, blacklistService, degradeManager, autoDegrade)
 
 	// Start VIP priority order consumer
 	// 3 VIP workers + 10 normal workers
 	vipConsumer := consumer.NewVIPPriorityConsumer(
-		order.NewOrderService(orderRepo, goodsRepo, inventory, idGenerator),
+		order.NewOrderService(orderRepo, goodsRepo, stockInventory, idGenerator, resultNotifier, compensator),
 		messageQueue,
 		3,  // VIP workers
 		10, // Normal workers
@@ -127,16 +237,45 @@ func main() {
 	vipConsumer.Start(context.Background())
 
 	// Create services for workers
-	activityRepo := repository.NewActivityRepository(db)
-	orderService := order.NewOrderService(orderRepo, goodsRepo, inventory, idGenerator)
-	stockService := stock.NewStockService(activityRepo, goodsRepo, inventory, redisV9Client)
+	orderService := order.NewOrderService(orderRepo, goodsRepo, stockInventory, idGenerator, resultNotifier, compensator)
+	lifecycleService := lifecycle.NewLifecycleService(activityRepo, seckillService, inventory, redisV9Client)
+
+	// Rebuild the shared bloom filter from activities holding stock in Redis
+	if err := inventory.RebuildBloomFilter(context.Background(), getStockedActivityIDs(context.Background(), activityRepo)); err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Error("Failed to rebuild bloom filter")
+	}
 
 	// Create context for workers
 	workerCtx, workerCancel := context.WithCancel(context.Background())
 	defer workerCancel()
 
 	// Start all background workers
-	startWorkers(workerCtx, orderService, stockService, activityRepo)
+	startWorkers(workerCtx, orderService, stockService, lifecycleService, blacklistService, activityRepo)
+	go resultNotifier.Run(workerCtx)
+	go seckill.NewStockBroadcaster(inventory, redisV9Client).Run(workerCtx)
+	go inventory.RunLeaseKeeper(workerCtx)
+	go seckill.NewAutoDegrader(autoDegrade, activityRepo).Run(workerCtx, 5*time.Second)
+	if stockLogWriter != nil {
+		go stockLogWriter.Run(workerCtx, cfg.Seckill.StockLog.FlushInterval)
+	}
+	if tccSweeper != nil {
+		go tccSweeper.Run(workerCtx, cfg.Seckill.TCCRecovery.Interval)
+	}
+	if compensator != nil {
+		go compensator.Run(workerCtx, cfg.Seckill.Compensation.Interval)
+	}
+	if cfg.Seckill.Outbox.Enabled {
+		outboxRelay := order.NewOutboxRelay(repository.NewOutboxRepository(db), messageQueue, cfg.Seckill.Outbox.BatchSize, cfg.Seckill.Outbox.Retention)
+		go outboxRelay.Run(workerCtx, cfg.Seckill.Outbox.Interval)
+	}
+	if cfg.Seckill.Reconcile.Enabled {
+		go seckill.NewReconcileJob(reconciler, redisV9Client, cfg.Seckill.Reconcile.Delay, cfg.Seckill.Reconcile.ReportDir).Run(workerCtx, cfg.Seckill.Reconcile.Interval)
+	}
+	if cfg.Redis.Sentinel.Enabled {
+		go seckill.NewFailoverMonitor(inventory, redis.Sentinels, cfg.Redis.Sentinel.MasterName, stockService.CheckActiveActivities).Run(workerCtx)
+	}
 
 	server := &http.Server{
 		Addr:           fmt.Sprintf(":%d", cfg.Server.Port),
@@ -178,13 +317,25 @@ func main() {
 		}).Fatal("Server forced to shutdown")
 	}
 
+	// Hand unsold leased stock back to the other instances
+	if err := inventory.ReleaseLeases(ctx); err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Error("Failed to release stock leases")
+	}
+
+	// Write the stock logs of requests finished during shutdown
+	if stockLogWriter != nil {
+		stockLogWriter.Flush(ctx)
+	}
+
 	log.Info("Server exited")
 }
 
 // ========== Worker Functions ==========
 
 // startWorkers starts all background workers
-func startWorkers(ctx context.Context, orderService order.OrderService, stockService stock.StockService, activityRepo repository.ActivityRepository) {
+func startWorkers(ctx context.Context, orderService order.OrderService, stockService stock.StockService, lifecycleService lifecycle.LifecycleService, blacklistService blacklist.BlacklistService, activityRepo repository.ActivityRepository) {
 	// Worker 1: Handle expired orders (every 1 minute)
 	go expiredOrderWorker(ctx, orderService, 1*time.Minute)
 
@@ -202,6 +353,12 @@ func startWorkers(ctx context.Context, orderService order.OrderService, stockSer
 		stockService.StartPeriodicSync(ctx, 2*time.Minute)
 	}()
 
+	// Worker 6: Drive activity prewarm/start/end (every 10 seconds)
+	go lifecycleService.StartScheduler(ctx, 10*time.Second)
+
+	// Worker 7: Rebuild blacklist cache from MySQL (on start, then every 5 minutes)
+	go blacklistService.StartPeriodicSync(ctx, 5*time.Minute)
+
 	log.Info("All workers started successfully")
 }
 
@@ -364,14 +521,34 @@ func getActiveActivityIDs(ctx context.Context, activityRepo repository.ActivityR
 	return activityIDs
 }
 
-func setupRouter(redisV9Client *redisv9.Client, goodsRepo repository.GoodsRepository, orderRepo repository.OrderRepository, idGenerator *snowflake.IDGenerator, messageQueue *queue.MemoryQueue, inventory *seckill.MultiLevelInventory) *gin.Engine {
+// getStockedActivityIDs returns running activities plus prewarmed upcoming ones, i.e. those with stock in Redis
+func getStockedActivityIDs(ctx context.Context, activityRepo repository.ActivityRepository) []uint64 {
+	activityIDs := getActiveActivityIDs(ctx, activityRepo)
+
+	upcoming, err := activityRepo.ListByStatus(ctx, model.ActivityStatusNotStarted, 1000)
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Error("Failed to query upcoming activities")
+		return activityIDs
+	}
+	for _, activity := range upcoming {
+		if activity.PrewarmStatus == model.PrewarmStatusDone {
+			activityIDs = append(activityIDs, activity.ID)
+		}
+	}
+
+	return activityIDs
+}
+
+func setupRouter(redisV9Client redisv9.UniversalClient, goodsRepo repository.GoodsRepository, orderRepo repository.OrderRepository, idGenerator *snowflake.IDGenerator, messageQueue *queue.MemoryQueue, inventory *seckill.MultiLevelInventory, stockInventory *seckill.InventoryFailover, stockService stock.StockService, reconciler *seckill.Reconciler, stockLogRepo repository.StockLogRepository, circuitBreakerManager *breaker.Manager, tccSweeper *seckill.TCCSweeper, compensator *seckill.TCCCompensator, resultNotifier *seckill.ResultNotifier, blacklistService blacklist.BlacklistService, degradeManager *degrade.DegradeManager, autoDegrade *degrade.AutoController) (*gin.Engine, seckill.SeckillService) {
 	router := gin.New()
 
 	router.Use(middleware.Logger())
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 
-	router.GET("/health", healthCheck)
+	router.GET("/health", healthCheck(circuitBreakerManager))
 	router.GET("/ping", ping)
 
 	// Initialize services
@@ -393,17 +570,14 @@ func setupRouter(redisV9Client *redisv9.Client, goodsRepo repository.GoodsReposi
 	// Create multi-dimension rate limiter
 	rateLimiter := limiter.NewMultiDimensionLimiter(redisV9Client)
 
-	// Create circuit breaker manager
-	circuitBreakerManager := breaker.NewManager(breaker.Config{
-		MaxRequests:   5,
-		Interval:      time.Minute,
-		Timeout:       30 * time.Second,
-		ReadyToTrip:   nil, // Use default
-		OnStateChange: nil,
-	})
+	// Create risk engine
+	riskEngine := risk.NewEngine(redisV9Client, userRepo)
 
-	// Create degrade manager
-	degradeManager := degrade.NewDegradeManager(redisV9Client)
+	// Create VIP tier service
+	vipService := vip.NewVIPService(userRepo, repository.NewVIPGrantRepository(db), redisV9Client)
+
+	// Create gray rollout controller
+	grayController := gray.NewController(redisV9Client, userRepo, activityRepo)
 
 	// Create services
 	authService := auth.NewAuthService(userRepo, jwtManager, redisV9Client)
@@ -413,21 +587,36 @@ func setupRouter(redisV9Client *redisv9.Client, goodsRepo repository.GoodsReposi
 		rateLimiter,
 		circuitBreakerManager,
 		degradeManager,
+		riskEngine,
+		vipService,
+		grayController,
 		messageQueue,
 		redisV9Client,
+		redis.GetReadClient(),
+		stockInventory,
 	)
 
 	// Create handlers
 	authHandler := handler.NewAuthHandler(authService)
 	activityHandler := handler.NewActivityHandler(activityRepo)
 	seckillHandler := handler.NewSeckillHandler(seckillService)
+	resultStreamHandler := handler.NewResultStreamHandler(seckillService, resultNotifier)
+	blacklistHandler := handler.NewBlacklistHandler(blacklistService)
+	vipHandler := handler.NewVIPHandler(vipService)
+	grayHandler := handler.NewGrayHandler(grayController)
+	degradeHandler := handler.NewDegradeHandler(degradeManager, autoDegrade)
+	scriptHandler := handler.NewScriptHandler(redis.Scripts)
+	tccHandler := handler.NewTCCHandler(tccSweeper, compensator)
+	stockHandler := handler.NewStockHandler(stockService)
+	reconcileHandler := handler.NewReconcileHandler(reconciler)
+	stockLogHandler := handler.NewStockLogHandler(stockLogRepo)
 
 	// Setup routes
 	api := router.Group("/api")
 	{
 		v1 := api.Group("/v1")
 		{
-			v1.GET("/health", healthCheck)
+			v1.GET("/health", healthCheck(circuitBreakerManager))
 			v1.GET("/ping", ping)
 
 			// Public auth routes
@@ -466,37 +655,85 @@ func setupRouter(redisV9Client *redisv9.Client, goodsRepo repository.GoodsReposi
 				{
 					seckillGroup.POST("/execute", seckillHandler.DoSeckill)
 					seckillGroup.GET("/result/:request_id", seckillHandler.QueryResult)
+					seckillGroup.GET("/result/stream", resultStreamHandler.StreamResults)
+					seckillGroup.GET("/queue/:activity_id", seckillHandler.QueryQueueStatus)
 					seckillGroup.POST("/prewarm/:activity_id", seckillHandler.PrewarmActivity)
 				}
 			}
+
+			// Admin routes
+			admin := v1.Group("/admin")
+			admin.Use(middleware.RequireRole(tokenValidator, "admin"))
+			{
+				admin.GET("/blacklist", blacklistHandler.List)
+				admin.POST("/blacklist", blacklistHandler.Add)
+				admin.DELETE("/blacklist/:user_id", blacklistHandler.Remove)
+
+				admin.GET("/vip/:user_id", vipHandler.GetUserTier)
+				admin.POST("/vip/grants", vipHandler.Grant)
+				admin.DELETE("/vip/grants/:user_id", vipHandler.Revoke)
+
+				admin.PUT("/activities/:id/gray", grayHandler.UpdateConfig)
+				admin.PUT("/activities/:id/limits", seckillHandler.UpdateActivityLimits)
+				admin.GET("/activities/:id/reconciliation", reconcileHandler.Reconcile)
+				admin.POST("/activities/:id/stock/sync", stockHandler.SyncToRedis)
+				admin.GET("/activities/:id/stock/consistency", stockHandler.CheckConsistency)
+				admin.POST("/activities/:id/stock/repair", stockHandler.RepairInconsistency)
+				admin.GET("/activities/:id/stock-logs", stockLogHandler.History)
+				admin.GET("/activities/:id/stock-logs/summary", stockLogHandler.Summary)
+
+				admin.GET("/degrade", degradeHandler.Status)
+				admin.GET("/degrade/:id/transitions", degradeHandler.Transitions)
+				admin.GET("/degrade/:id/lottery", degradeHandler.Lottery)
+
+				admin.GET("/scripts", scriptHandler.Stats)
+
+				admin.GET("/tcc/recovery", tccHandler.Recovery)
+				admin.GET("/tcc/dead-letters", tccHandler.ListDeadLetters)
+				admin.POST("/tcc/dead-letters/:id/retry", tccHandler.RetryDeadLetter)
+				admin.POST("/tcc/dead-letters/:id/resolve", tccHandler.ResolveDeadLetter)
+			}
 		}
 	}
 
-	return router
+	return router, seckillService
 }
 
-func healthCheck(c *gin.Context) {
-	dbHealth := checkDatabase()
+func healthCheck(breakers *breaker.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dbHealth := checkDatabase()
 
-	redisHealth := checkRedis()
+		redisHealth := checkRedis()
 
-	health := map[string]interface{}{
-		"status":    "ok",
-		"timestamp": time.Now().Unix(),
-		"version":   "1.0.0",
-		"services": map[string]interface{}{
-			"database": dbHealth,
-			"redis":    redisHealth,
-		},
-	}
+		circuitBreakers := breakers.Snapshots()
 
-	if !dbHealth["healthy"].(bool) || !redisHealth["healthy"].(bool) {
-		health["status"] = "error"
-		c.JSON(http.StatusServiceUnavailable, health)
-		return
-	}
+		health := map[string]interface{}{
+			"status":    "ok",
+			"timestamp": time.Now().Unix(),
+			"version":   "1.0.0",
+			"services": map[string]interface{}{
+				"database": dbHealth,
+				"redis":    redisHealth,
+			},
+			"circuit_breakers": circuitBreakers,
+		}
+
+		if !dbHealth["healthy"].(bool) || !redisHealth["healthy"].(bool) {
+			health["status"] = "error"
+			c.JSON(http.StatusServiceUnavailable, health)
+			return
+		}
 
-	c.JSON(http.StatusOK, health)
+		// Dependencies answer pings but the seckill path is shedding their calls
+		for _, cb := range circuitBreakers {
+			if cb.State != breaker.StateClosed.String() {
+				health["status"] = "degraded"
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, health)
+	}
 }
 
 func ping(c *gin.Context) {
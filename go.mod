@@ -6,6 +6,7 @@ toolchain go1.24.7
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/alicebob/miniredis/v2 v2.35.0
 	github.com/allegro/bigcache/v3 v3.1.0
 	github.com/bits-and-blooms/bloom/v3 v3.7.0
 	github.com/fsnotify/fsnotify v1.7.0
@@ -25,11 +26,9 @@ require (
 )
 
 require (
-	github.com/alicebob/miniredis/v2 v2.35.0 // indirect
 	github.com/go-logr/logr v1.3.0 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/pmylund/go-bitset v0.0.0-20120712110920-d72c4b165e1a // indirect
-	github.com/pmylund/go-bloom v0.0.0-20120528014648-4ab62f5a40bf // indirect
 	github.com/yuin/gopher-lua v1.1.1 // indirect
 	go.opentelemetry.io/otel/metric v1.21.0 // indirect
 )
@@ -65,9 +65,23 @@ type RedisConfig struct {
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	PoolTimeout  time.Duration `mapstructure:"pool_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	ScriptDebug  bool          `mapstructure:"script_debug"` // 开启Lua脚本中的redis.log调试日志
+	StrictSlots  bool          `mapstructure:"strict_slots"` // 单节点下也拒绝跨slot的多key命令，用于测试环境提前暴露集群问题
 	
 	// 集群模式配置
 	Cluster RedisClusterConfig `mapstructure:"cluster"`
+
+	// 哨兵模式配置
+	Sentinel RedisSentinelConfig `mapstructure:"sentinel"`
+}
+
+// RedisSentinelConfig represents Redis Sentinel configuration
+type RedisSentinelConfig struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	MasterName       string   `mapstructure:"master_name"`
+	Addrs            []string `mapstructure:"addrs"`
+	SentinelPassword string   `mapstructure:"sentinel_password"`
+	ReplicaReads     bool     `mapstructure:"replica_reads"` // 活动配置、秒杀结果等只读查询走从节点
 }
 
 // RedisClusterConfig represents Redis cluster configuration
@@ -209,6 +223,50 @@ type SeckillConfig struct {
 		RetryTimes    int           `mapstructure:"retry_times"`    
 		RetryInterval time.Duration `mapstructure:"retry_interval"` 
 	} `mapstructure:"order"`
+	StockLease struct {
+		Enabled bool          `mapstructure:"enabled"`
+		Batch         int           `mapstructure:"batch"`          // Units each instance leases at a time
+		TTL           time.Duration `mapstructure:"ttl"`            // Unused units are returned after this long
+		FlushInterval time.Duration `mapstructure:"flush_interval"` // Deduct log entries of leased deductions are written to Redis this often
+	} `mapstructure:"stock_lease"`
+	MySQLFallback struct {
+		Enabled       bool `mapstructure:"enabled"`
+		QPS           int  `mapstructure:"qps"`            // Deductions per second sent to MySQL while Redis is tripped
+		MaxConcurrent int  `mapstructure:"max_concurrent"` // Deductions in flight against MySQL at once
+	} `mapstructure:"mysql_fallback"`
+	TCCRecovery struct {
+		Enabled   bool          `mapstructure:"enabled"`
+		Interval  time.Duration `mapstructure:"interval"`
+		Deadline  time.Duration `mapstructure:"deadline"`   // Tries without an order this old are cancelled
+		BatchSize int           `mapstructure:"batch_size"` // Tries recovered per sweep
+	} `mapstructure:"tcc_recovery"`
+	Compensation struct {
+		Enabled     bool          `mapstructure:"enabled"`
+		Interval    time.Duration `mapstructure:"interval"`
+		MaxAttempts int           `mapstructure:"max_attempts"` // Attempts before a compensation is dead-lettered
+		BaseBackoff time.Duration `mapstructure:"base_backoff"`
+		MaxBackoff  time.Duration `mapstructure:"max_backoff"`
+		BatchSize   int           `mapstructure:"batch_size"`
+	} `mapstructure:"compensation"`
+	Outbox struct {
+		Enabled   bool          `mapstructure:"enabled"`
+		Interval  time.Duration `mapstructure:"interval"`
+		BatchSize int           `mapstructure:"batch_size"` // Events published per run
+		Retention time.Duration `mapstructure:"retention"`  // Delivered events are deleted after this
+	} `mapstructure:"outbox"`
+	Reconcile struct {
+		Enabled   bool          `mapstructure:"enabled"`
+		Interval  time.Duration `mapstructure:"interval"`
+		Delay     time.Duration `mapstructure:"delay"`     // Wait after an activity ends, under the 15m deduct log lifetime
+		InFlight  time.Duration `mapstructure:"in_flight"` // Tries younger than this are not judged
+		ReportDir string        `mapstructure:"report_dir"`
+	} `mapstructure:"reconcile"`
+	StockLog struct {
+		Enabled       bool          `mapstructure:"enabled"`
+		BufferSize    int           `mapstructure:"buffer_size"` // Entries queued before new ones are dropped
+		BatchSize     int           `mapstructure:"batch_size"`  // Entries per INSERT
+		FlushInterval time.Duration `mapstructure:"flush_interval"`
+	} `mapstructure:"stock_log"`
 	Activity struct {
 		PreloadTime time.Duration `mapstructure:"preload_time"` 
 		CacheTime   time.Duration `mapstructure:"cache_time"`  
@@ -270,6 +328,15 @@ func (c *Config) Validate() error {
 	if c.Redis.Host == "" {
 		return fmt.Errorf("redis host is required")
 	}
+
+	if c.Redis.Sentinel.Enabled {
+		if c.Redis.Cluster.Enabled {
+			return fmt.Errorf("redis sentinel and cluster modes are mutually exclusive")
+		}
+		if c.Redis.Sentinel.MasterName == "" || len(c.Redis.Sentinel.Addrs) == 0 {
+			return fmt.Errorf("redis sentinel master name and addrs are required")
+		}
+	}
 	
 	if c.Security.JWT.Secret == "" {
 		return fmt.Errorf("JWT secret is required")
@@ -399,6 +466,75 @@ func (c *Config) SetDefaults() {
 	if c.Seckill.Order.RetryInterval == 0 {
 		c.Seckill.Order.RetryInterval = time.Second
 	}
+	if c.Seckill.StockLease.Batch == 0 {
+		c.Seckill.StockLease.Batch = 50
+	}
+	if c.Seckill.StockLease.TTL == 0 {
+		c.Seckill.StockLease.TTL = 10 * time.Second
+	}
+	if c.Seckill.StockLease.FlushInterval == 0 {
+		c.Seckill.StockLease.FlushInterval = 100 * time.Millisecond
+	}
+	if c.Seckill.MySQLFallback.QPS == 0 {
+		c.Seckill.MySQLFallback.QPS = 200
+	}
+	if c.Seckill.MySQLFallback.MaxConcurrent == 0 {
+		c.Seckill.MySQLFallback.MaxConcurrent = 20
+	}
+	if c.Seckill.TCCRecovery.Interval == 0 {
+		c.Seckill.TCCRecovery.Interval = time.Minute
+	}
+	if c.Seckill.TCCRecovery.Deadline == 0 {
+		c.Seckill.TCCRecovery.Deadline = 10 * time.Minute
+	}
+	if c.Seckill.TCCRecovery.BatchSize == 0 {
+		c.Seckill.TCCRecovery.BatchSize = 100
+	}
+	if c.Seckill.Compensation.Interval == 0 {
+		c.Seckill.Compensation.Interval = 5 * time.Second
+	}
+	if c.Seckill.Compensation.MaxAttempts == 0 {
+		c.Seckill.Compensation.MaxAttempts = 8
+	}
+	if c.Seckill.Compensation.BaseBackoff == 0 {
+		c.Seckill.Compensation.BaseBackoff = 5 * time.Second
+	}
+	if c.Seckill.Compensation.MaxBackoff == 0 {
+		c.Seckill.Compensation.MaxBackoff = 10 * time.Minute
+	}
+	if c.Seckill.Compensation.BatchSize == 0 {
+		c.Seckill.Compensation.BatchSize = 100
+	}
+	if c.Seckill.Outbox.Interval == 0 {
+		c.Seckill.Outbox.Interval = time.Second
+	}
+	if c.Seckill.Outbox.BatchSize == 0 {
+		c.Seckill.Outbox.BatchSize = 100
+	}
+	if c.Seckill.Outbox.Retention == 0 {
+		c.Seckill.Outbox.Retention = 72 * time.Hour
+	}
+	if c.Seckill.Reconcile.Interval == 0 {
+		c.Seckill.Reconcile.Interval = 30 * time.Second
+	}
+	if c.Seckill.Reconcile.Delay == 0 {
+		c.Seckill.Reconcile.Delay = 2 * time.Minute
+	}
+	if c.Seckill.Reconcile.InFlight == 0 {
+		c.Seckill.Reconcile.InFlight = time.Minute
+	}
+	if c.Seckill.Reconcile.ReportDir == "" {
+		c.Seckill.Reconcile.ReportDir = "reports/reconcile"
+	}
+	if c.Seckill.StockLog.BufferSize == 0 {
+		c.Seckill.StockLog.BufferSize = 10000
+	}
+	if c.Seckill.StockLog.BatchSize == 0 {
+		c.Seckill.StockLog.BatchSize = 200
+	}
+	if c.Seckill.StockLog.FlushInterval == 0 {
+		c.Seckill.StockLog.FlushInterval = time.Second
+	}
 	if c.Seckill.Activity.PreloadTime == 0 {
 		c.Seckill.Activity.PreloadTime = 10 * time.Minute
 	}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"time"
 
+	"seckill/internal/model"
 	"seckill/internal/service/order"
 	"seckill/pkg/log"
 	"seckill/pkg/queue"
 )
 
 // VIPPriorityConsumer VIP priority order consumer
-// Consumes from VIP queue first, then normal queue
+// Consumes from VIP queues highest tier first, then normal queue
 type VIPPriorityConsumer struct {
 	orderService order.OrderService
 	messageQueue queue.MessageQueue
@@ -60,7 +61,10 @@ func (c *VIPPriorityConsumer) Start(ctx context.Context) {
 	}
 }
 
-// consumeVIP consumes only from VIP queue
+// vipPollTimeout total time a worker waits on the VIP queues before moving on
+const vipPollTimeout = 100 * time.Millisecond
+
+// consumeVIP consumes only from VIP queues
 func (c *VIPPriorityConsumer) consumeVIP(ctx context.Context, workerID int) {
 	log.WithFields(map[string]interface{}{
 		"worker_id": workerID,
@@ -80,7 +84,7 @@ func (c *VIPPriorityConsumer) consumeVIP(ctx context.Context, workerID int) {
 			}).Info("VIP worker context cancelled")
 			return
 		default:
-			c.processMessage(ctx, "seckill_orders_vip", workerID, "VIP")
+			c.tryProcessVIP(ctx, workerID)
 		}
 	}
 }
@@ -105,17 +109,29 @@ func (c *VIPPriorityConsumer) consumeWithPriority(ctx context.Context, workerID
 			}).Info("Priority worker context cancelled")
 			return
 		default:
-			// Try VIP queue first (with short timeout)
-			vipProcessed := c.tryProcessMessage(ctx, "seckill_orders_vip", workerID, "VIP", 100*time.Millisecond)
+			// Try VIP queues first (with short timeout)
+			vipProcessed := c.tryProcessVIP(ctx, workerID)
 			
 			if !vipProcessed {
 				// If no VIP message, try normal queue
-				c.processMessage(ctx, "seckill_orders", workerID, "Normal")
+				c.processMessage(ctx, model.OrderTopicNormal, workerID, "Normal")
 			}
 		}
 	}
 }
 
+// tryProcessVIP tries the VIP queues from the highest tier down, returns whether a message was processed
+func (c *VIPPriorityConsumer) tryProcessVIP(ctx context.Context, workerID int) bool {
+	topics := model.VIPOrderTopics()
+	timeout := vipPollTimeout / time.Duration(len(topics))
+	for _, topic := range topics {
+		if c.tryProcessMessage(ctx, topic, workerID, "VIP", timeout) {
+			return true
+		}
+	}
+	return false
+}
+
 // tryProcessMessage tries to process a message with timeout
 func (c *VIPPriorityConsumer) tryProcessMessage(ctx context.Context, topic string, workerID int, queueType string, timeout time.Duration) bool {
 	consumeCtx, cancel := context.WithTimeout(ctx, timeout)
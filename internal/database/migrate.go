@@ -21,6 +21,13 @@ func AutoMigrate(db *gorm.DB) error {
 		&model.Order{},
 		&model.OrderDetail{},
 		&model.StockLog{},
+		&model.Blacklist{},
+		&model.VIPGrant{},
+		&model.Purchase{},
+		&model.TCCLog{},
+		&model.TCCCompensation{},
+		&model.TCCDeadLetter{},
+		&model.OutboxEvent{},
 	}
 
 	for _, model := range models {
@@ -77,6 +84,8 @@ func DropTables(db *gorm.DB) error {
 	log.Warn("Dropping all tables...")
 
 	tables := []string{
+		"vip_grants",
+		"user_blacklists",
 		"stock_logs",
 		"order_details",
 		"orders",
@@ -108,6 +117,8 @@ func CheckTables(db *gorm.DB) error {
 		"orders",
 		"order_details",
 		"stock_logs",
+		"user_blacklists",
+		"vip_grants",
 	}
 
 	for _, table := range tables {
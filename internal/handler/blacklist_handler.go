@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seckill/internal/repository"
+	"seckill/internal/service/blacklist"
+	"seckill/pkg/utils"
+)
+
+// BlacklistHandler admin blacklist handler
+type BlacklistHandler struct {
+	blacklistService blacklist.BlacklistService
+}
+
+// NewBlacklistHandler creates a blacklist handler
+func NewBlacklistHandler(blacklistService blacklist.BlacklistService) *BlacklistHandler {
+	return &BlacklistHandler{
+		blacklistService: blacklistService,
+	}
+}
+
+// AddBlacklistRequest add blacklist entry request
+type AddBlacklistRequest struct {
+	UserID     uint64     `json:"user_id" binding:"required"`
+	ActivityID uint64     `json:"activity_id"` // 0 or omitted for a global entry
+	Reason     string     `json:"reason" binding:"required,max=255"`
+	ExpireAt   *time.Time `json:"expire_at"` // RFC3339, omitted never expires
+}
+
+// Add adds or replaces a blacklist entry
+func (h *BlacklistHandler) Add(c *gin.Context) {
+	var req AddBlacklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request parameters")
+		return
+	}
+
+	operatorID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	entry, err := h.blacklistService.Add(c.Request.Context(), &blacklist.AddRequest{
+		UserID:     req.UserID,
+		ActivityID: req.ActivityID,
+		Reason:     req.Reason,
+		Operator:   strconv.FormatInt(operatorID.(int64), 10),
+		ExpireAt:   req.ExpireAt,
+	})
+	if err != nil {
+		if errors.Is(err, blacklist.ErrInvalidEntry) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, entry)
+}
+
+// Remove removes a blacklist entry, ?activity_id= selects a per-activity entry
+func (h *BlacklistHandler) Remove(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	activityID, err := strconv.ParseUint(c.DefaultQuery("activity_id", "0"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return
+	}
+
+	if err := h.blacklistService.Remove(c.Request.Context(), userID, activityID); err != nil {
+		if errors.Is(err, blacklist.ErrEntryNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Blacklist entry removed successfully"})
+}
+
+// List lists and searches blacklist entries
+//
+// Query: user_id, activity_id, scope (global|activity), keyword, include_expired, page, page_size
+func (h *BlacklistHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := repository.BlacklistFilter{
+		Scope:          c.Query("scope"),
+		Keyword:        c.Query("keyword"),
+		IncludeExpired: c.Query("include_expired") == "true",
+	}
+	if filter.Scope != "" && filter.Scope != repository.BlacklistScopeGlobal && filter.Scope != repository.BlacklistScopeActivity {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid scope")
+		return
+	}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		filter.UserID = userID
+	}
+	if activityIDStr := c.Query("activity_id"); activityIDStr != "" {
+		activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+			return
+		}
+		filter.ActivityID = activityID
+	}
+
+	entries, total, err := h.blacklistService.List(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessPageResponse(c, entries, total, page, pageSize)
+}
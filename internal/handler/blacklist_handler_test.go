@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/internal/service/blacklist"
+)
+
+// MockBlacklistService mock blacklist service
+type MockBlacklistService struct {
+	mock.Mock
+}
+
+func (m *MockBlacklistService) Add(ctx context.Context, req *blacklist.AddRequest) (*model.Blacklist, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Blacklist), args.Error(1)
+}
+
+func (m *MockBlacklistService) Remove(ctx context.Context, userID, activityID uint64) error {
+	args := m.Called(ctx, userID, activityID)
+	return args.Error(0)
+}
+
+func (m *MockBlacklistService) List(ctx context.Context, filter repository.BlacklistFilter, page, pageSize int) ([]*model.Blacklist, int64, error) {
+	args := m.Called(ctx, filter, page, pageSize)
+	return args.Get(0).([]*model.Blacklist), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockBlacklistService) SyncToRedis(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBlacklistService) StartPeriodicSync(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+func setupBlacklistRouter(handler *BlacklistHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+		c.Next()
+	})
+	router.GET("/admin/blacklist", handler.List)
+	router.POST("/admin/blacklist", handler.Add)
+	router.DELETE("/admin/blacklist/:user_id", handler.Remove)
+	return router
+}
+
+func TestBlacklistHandler_Add(t *testing.T) {
+	t.Run("successful add", func(t *testing.T) {
+		mockService := new(MockBlacklistService)
+		router := setupBlacklistRouter(NewBlacklistHandler(mockService))
+
+		mockService.On("Add", mock.Anything, mock.MatchedBy(func(req *blacklist.AddRequest) bool {
+			return req.UserID == 1001 && req.ActivityID == 5 && req.Reason == "scalper" && req.Operator == "1"
+		})).Return(&model.Blacklist{ID: 1, UserID: 1001, ActivityID: 5}, nil)
+
+		body, _ := json.Marshal(map[string]interface{}{"user_id": 1001, "activity_id": 5, "reason": "scalper"})
+		req, _ := http.NewRequest("POST", "/admin/blacklist", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing reason", func(t *testing.T) {
+		mockService := new(MockBlacklistService)
+		router := setupBlacklistRouter(NewBlacklistHandler(mockService))
+
+		body, _ := json.Marshal(map[string]interface{}{"user_id": 1001})
+		req, _ := http.NewRequest("POST", "/admin/blacklist", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired entry rejected", func(t *testing.T) {
+		mockService := new(MockBlacklistService)
+		router := setupBlacklistRouter(NewBlacklistHandler(mockService))
+
+		mockService.On("Add", mock.Anything, mock.Anything).Return(nil, blacklist.ErrInvalidEntry)
+
+		body, _ := json.Marshal(map[string]interface{}{"user_id": 1001, "reason": "bot", "expire_at": "2000-01-01T00:00:00Z"})
+		req, _ := http.NewRequest("POST", "/admin/blacklist", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestBlacklistHandler_Remove(t *testing.T) {
+	t.Run("successful remove", func(t *testing.T) {
+		mockService := new(MockBlacklistService)
+		router := setupBlacklistRouter(NewBlacklistHandler(mockService))
+
+		mockService.On("Remove", mock.Anything, uint64(1001), uint64(5)).Return(nil)
+
+		req, _ := http.NewRequest("DELETE", "/admin/blacklist/1001?activity_id=5", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("entry not found", func(t *testing.T) {
+		mockService := new(MockBlacklistService)
+		router := setupBlacklistRouter(NewBlacklistHandler(mockService))
+
+		mockService.On("Remove", mock.Anything, uint64(1001), uint64(0)).Return(blacklist.ErrEntryNotFound)
+
+		req, _ := http.NewRequest("DELETE", "/admin/blacklist/1001", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestBlacklistHandler_List(t *testing.T) {
+	t.Run("search with filters", func(t *testing.T) {
+		mockService := new(MockBlacklistService)
+		router := setupBlacklistRouter(NewBlacklistHandler(mockService))
+
+		filter := repository.BlacklistFilter{UserID: 1001, Scope: repository.BlacklistScopeActivity, Keyword: "bot"}
+		mockService.On("List", mock.Anything, filter, 2, 10).
+			Return([]*model.Blacklist{{ID: 1, UserID: 1001, ActivityID: 5}}, int64(11), nil)
+
+		req, _ := http.NewRequest("GET", "/admin/blacklist?user_id=1001&scope=activity&keyword=bot&page=2&page_size=10", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		data := response["data"].(map[string]interface{})
+		assert.Equal(t, float64(11), data["total"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		mockService := new(MockBlacklistService)
+		router := setupBlacklistRouter(NewBlacklistHandler(mockService))
+
+		req, _ := http.NewRequest("GET", "/admin/blacklist?scope=ip", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
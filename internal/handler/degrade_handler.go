@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seckill/pkg/degrade"
+	"seckill/pkg/utils"
+)
+
+// DegradeHandler admin degradation handler
+type DegradeHandler struct {
+	manager    *degrade.DegradeManager
+	controller *degrade.AutoController
+}
+
+// NewDegradeHandler creates a degrade handler
+func NewDegradeHandler(manager *degrade.DegradeManager, controller *degrade.AutoController) *DegradeHandler {
+	return &DegradeHandler{
+		manager:    manager,
+		controller: controller,
+	}
+}
+
+// Status lists degraded activities, the lottery counters of those drawing lots and the auto
+// degrade state on this instance
+func (h *DegradeHandler) Status(c *gin.Context) {
+	degraded, err := h.manager.GetDegradeStatus(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	lottery := make(map[uint64]*degrade.LotteryStats)
+	for activityID, strategy := range degraded {
+		if strategy.Type != "lottery" {
+			continue
+		}
+		stats, err := h.manager.GetLotteryStats(c.Request.Context(), activityID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		lottery[activityID] = stats
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"degraded": degraded,
+		"lottery":  lottery,
+		"auto":     h.controller.States(),
+	})
+}
+
+// Lottery gets the lottery counters of an activity, kept a week after its last draw
+func (h *DegradeHandler) Lottery(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return
+	}
+
+	stats, err := h.manager.GetLotteryStats(c.Request.Context(), activityID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, stats)
+}
+
+// Transitions lists the recent auto degrade transitions of an activity
+func (h *DegradeHandler) Transitions(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	transitions, err := h.controller.Transitions(c.Request.Context(), activityID, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, transitions)
+}
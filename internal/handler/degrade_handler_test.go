@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/pkg/degrade"
+)
+
+func TestDegradeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	manager := degrade.NewDegradeManager(client)
+	controller := degrade.NewAutoController(manager, nil)
+	for i := 0; i < 20; i++ {
+		manager.RecordRequest(1, time.Millisecond, true)
+	}
+	_, err = controller.Evaluate(context.Background(), 1, &degrade.AutoPolicy{
+		ErrorRate:    0.5,
+		RecoverRatio: 0.5,
+		RecoverAfter: 1,
+		TTL:          time.Minute,
+		Strategy:     &degrade.DegradeStrategy{Type: "return_error"},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, manager.EnableDegrade(ctx, 2, &degrade.DegradeStrategy{Type: "lottery", Ratio: 0.5}))
+	for userID := uint64(1); userID <= 10; userID++ {
+		manager.DrawLottery(ctx, 2, userID, 0.5)
+	}
+
+	handler := NewDegradeHandler(manager, controller)
+	router := gin.New()
+	router.GET("/admin/degrade", handler.Status)
+	router.GET("/admin/degrade/:id/transitions", handler.Transitions)
+	router.GET("/admin/degrade/:id/lottery", handler.Lottery)
+
+	t.Run("status", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/degrade", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				Degraded map[string]interface{}          `json:"degraded"`
+				Lottery  map[string]degrade.LotteryStats `json:"lottery"`
+				Auto     []degrade.AutoState             `json:"auto"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Contains(t, response.Data.Degraded, "1")
+		// Only activities drawing lots report counters
+		require.Len(t, response.Data.Lottery, 1)
+		stats := response.Data.Lottery["2"]
+		assert.Equal(t, int64(10), stats.Admitted+stats.Rejected)
+		require.Len(t, response.Data.Auto, 1)
+		assert.Equal(t, degrade.AutoStateDegraded, response.Data.Auto[0].State)
+	})
+
+	t.Run("transitions", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/degrade/1/transitions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []degrade.Transition `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Data, 1)
+		assert.Equal(t, degrade.AutoStateDegraded, response.Data[0].To)
+	})
+
+	t.Run("lottery", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/degrade/2/lottery", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data degrade.LotteryStats `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(10), response.Data.Admitted+response.Data.Rejected)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/degrade/abc/transitions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seckill/internal/service/gray"
+	"seckill/pkg/utils"
+)
+
+// GrayHandler admin gray rollout handler
+type GrayHandler struct {
+	controller *gray.Controller
+}
+
+// NewGrayHandler creates a gray handler
+func NewGrayHandler(controller *gray.Controller) *GrayHandler {
+	return &GrayHandler{
+		controller: controller,
+	}
+}
+
+// UpdateConfig changes the gray strategy, ratio, whitelist and params of an activity
+func (h *GrayHandler) UpdateConfig(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return
+	}
+
+	var req gray.UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request parameters")
+		return
+	}
+
+	activity, err := h.controller.UpdateConfig(c.Request.Context(), activityID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gray.ErrInvalidConfig):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, gray.ErrActivityNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "Activity not found")
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"activity_id":    activity.ID,
+		"gray_strategy":  activity.GrayStrategy,
+		"gray_ratio":     activity.GrayRatio,
+		"gray_whitelist": activity.GrayWhitelist,
+		"ext_config":     activity.ExtConfig,
+	})
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/internal/service/gray"
+)
+
+// fakeGrayActivityRepo stores one activity
+type fakeGrayActivityRepo struct {
+	repository.ActivityRepository
+	activity *model.SeckillActivity
+}
+
+func (r *fakeGrayActivityRepo) GetByID(ctx context.Context, id int64) (*model.SeckillActivity, error) {
+	if int64(r.activity.ID) != id {
+		return nil, repository.ErrActivityNotFound
+	}
+	copied := *r.activity
+	return &copied, nil
+}
+
+func (r *fakeGrayActivityRepo) UpdateGray(ctx context.Context, id int64, strategy *string, ratio float64, whitelist, extConfig model.JSONObject) error {
+	r.activity.GrayStrategy = strategy
+	r.activity.GrayRatio = ratio
+	r.activity.GrayWhitelist = whitelist
+	r.activity.ExtConfig = extConfig
+	return nil
+}
+
+func setupGrayRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	activityRepo := &fakeGrayActivityRepo{activity: &model.SeckillActivity{ID: 1}}
+	handler := NewGrayHandler(gray.NewController(client, nil, activityRepo))
+
+	router := gin.New()
+	router.PUT("/admin/activities/:id/gray", handler.UpdateConfig)
+	return router
+}
+
+func TestGrayHandler_UpdateConfig(t *testing.T) {
+	router := setupGrayRouter(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"success", "/admin/activities/1/gray", `{"strategy":"hash","ratio":0.2}`, http.StatusOK},
+		{"invalid id", "/admin/activities/abc/gray", `{"strategy":"hash","ratio":0.2}`, http.StatusBadRequest},
+		{"invalid body", "/admin/activities/1/gray", `{`, http.StatusBadRequest},
+		{"invalid config", "/admin/activities/1/gray", `{"strategy":"unknown"}`, http.StatusBadRequest},
+		{"activity not found", "/admin/activities/2/gray", `{"strategy":"hash","ratio":0.2}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PUT", tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seckill/internal/repository"
+	"seckill/internal/service/seckill"
+	"seckill/pkg/utils"
+)
+
+// ReconcileHandler admin reconciliation handler
+type ReconcileHandler struct {
+	reconciler *seckill.Reconciler
+}
+
+// NewReconcileHandler creates a reconciliation handler
+func NewReconcileHandler(reconciler *seckill.Reconciler) *ReconcileHandler {
+	return &ReconcileHandler{
+		reconciler: reconciler,
+	}
+}
+
+// Reconcile reconciles an activity on demand
+//
+// Query: format (json, the default, or csv listing the issues only)
+func (h *ReconcileHandler) Reconcile(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid format")
+		return
+	}
+
+	report, err := h.reconciler.Reconcile(c.Request.Context(), activityID)
+	if err != nil {
+		if errors.Is(err, repository.ErrActivityNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=reconcile_%d.csv", activityID))
+		c.Status(http.StatusOK)
+		if err := report.WriteCSV(c.Writer); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+	utils.SuccessResponse(c, report)
+}
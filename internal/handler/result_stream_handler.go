@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seckill/internal/service/seckill"
+	"seckill/pkg/utils"
+)
+
+const (
+	// maxStreamRequestIDs request IDs a single stream may watch
+	maxStreamRequestIDs = 20
+	// resultStreamTimeout stream is closed after this long, clients reconnect
+	resultStreamTimeout = 5 * time.Minute
+	// streamHeartbeat keeps proxies from closing idle streams
+	streamHeartbeat = 15 * time.Second
+)
+
+// ResultStreamHandler pushes seckill result transitions over Server-Sent Events
+type ResultStreamHandler struct {
+	seckillService seckill.SeckillService
+	notifier       *seckill.ResultNotifier
+}
+
+// NewResultStreamHandler creates a result stream handler
+func NewResultStreamHandler(seckillService seckill.SeckillService, notifier *seckill.ResultNotifier) *ResultStreamHandler {
+	return &ResultStreamHandler{
+		seckillService: seckillService,
+		notifier:       notifier,
+	}
+}
+
+// StreamResults streams result transitions for ?request_ids=a,b,c
+func (h *ResultStreamHandler) StreamResults(c *gin.Context) {
+	requestIDs := parseRequestIDs(c.Query("request_ids"))
+	if len(requestIDs) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Missing request_ids parameter")
+		return
+	}
+	if len(requestIDs) > maxStreamRequestIDs {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Too many request_ids")
+		return
+	}
+
+	// Get user ID from JWT middleware
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	userID := uint64(userIDValue.(int64))
+
+	// Subscribe before reading current state so no transition is missed in between
+	sub := h.notifier.Subscribe(userID, requestIDs)
+	defer h.notifier.Unsubscribe(sub)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	pending := make(map[string]bool, len(requestIDs))
+	for _, requestID := range requestIDs {
+		pending[requestID] = true
+		result, err := h.seckillService.QuerySeckillResult(c.Request.Context(), requestID, userID)
+		if err != nil || result.Status == "" {
+			continue
+		}
+		event := &seckill.ResultEvent{
+			RequestID: requestID,
+			UserID:    userID,
+			Status:    result.Status,
+			OrderID:   result.OrderID,
+			Message:   result.Message,
+			Timestamp: time.Now().Unix(),
+		}
+		c.SSEvent("result", event)
+		if event.Final() {
+			delete(pending, requestID)
+		}
+	}
+	c.Writer.Flush()
+
+	timeout := time.NewTimer(resultStreamTimeout)
+	defer timeout.Stop()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for len(pending) > 0 {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-timeout.C:
+			return
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+		case event := <-sub.Events():
+			c.SSEvent("result", event)
+			if event.Final() {
+				delete(pending, event.RequestID)
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// parseRequestIDs splits a comma separated list, dropping blanks and duplicates
+func parseRequestIDs(raw string) []string {
+	seen := make(map[string]bool)
+	var requestIDs []string
+	for _, requestID := range strings.Split(raw, ",") {
+		requestID = strings.TrimSpace(requestID)
+		if requestID == "" || seen[requestID] {
+			continue
+		}
+		seen[requestID] = true
+		requestIDs = append(requestIDs, requestID)
+	}
+	return requestIDs
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/service/seckill"
+)
+
+func setupResultStream(t *testing.T, mockService *MockSeckillService) (*gin.Engine, *seckill.ResultNotifier) {
+	gin.SetMode(gin.TestMode)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	notifier := seckill.NewResultNotifier(client)
+	handler := NewResultStreamHandler(mockService, notifier)
+	seckillHandler := NewSeckillHandler(mockService)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(123))
+		c.Next()
+	})
+	router.GET("/seckill/result/:request_id", seckillHandler.QueryResult)
+	router.GET("/seckill/result/stream", handler.StreamResults)
+	return router, notifier
+}
+
+func TestResultStreamHandler_StreamResults(t *testing.T) {
+	t.Run("final result closes stream", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		router, _ := setupResultStream(t, mockService)
+
+		mockService.On("QuerySeckillResult", mock.Anything, "req1", uint64(123)).Return(&seckill.SeckillResult{
+			Success:   true,
+			RequestID: "req1",
+			OrderID:   "SK1",
+			Status:    seckill.ResultStatusOrderCreated,
+		}, nil)
+
+		req, _ := http.NewRequest("GET", "/seckill/result/stream?request_ids=req1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+		assert.Contains(t, w.Body.String(), "event:result")
+		assert.Contains(t, w.Body.String(), `"order_id":"SK1"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("pushed transition", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		router, notifier := setupResultStream(t, mockService)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go notifier.Run(ctx)
+
+		mockService.On("QuerySeckillResult", mock.Anything, "req2", uint64(123)).
+			Return((*seckill.SeckillResult)(nil), errors.New("seckill result not found"))
+
+		// Keep publishing until the stream has subscribed and closed
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(20 * time.Millisecond):
+					notifier.Publish(ctx, 123, seckill.ResultStatusFailed, &seckill.SeckillResult{
+						RequestID: "req2",
+						Message:   "Order creation failed",
+					})
+				}
+			}
+		}()
+
+		req, _ := http.NewRequest("GET", "/seckill/result/stream?request_ids=req2", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		close(done)
+
+		assert.Contains(t, w.Body.String(), `"status":"failed"`)
+	})
+
+	t.Run("missing request_ids", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		router, _ := setupResultStream(t, mockService)
+
+		req, _ := http.NewRequest("GET", "/seckill/result/stream", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestParseRequestIDs(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, parseRequestIDs(" a,b,,a "))
+	assert.Empty(t, parseRequestIDs(""))
+}
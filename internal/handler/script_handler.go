@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	redisx "seckill/internal/redis"
+	"seckill/pkg/utils"
+)
+
+// ScriptHandler admin Lua script handler
+type ScriptHandler struct {
+	registry *redisx.ScriptRegistry
+}
+
+// NewScriptHandler creates a script handler
+func NewScriptHandler(registry *redisx.ScriptRegistry) *ScriptHandler {
+	return &ScriptHandler{
+		registry: registry,
+	}
+}
+
+// Stats lists the registered scripts with their version and call metrics on this instance
+func (h *ScriptHandler) Stats(c *gin.Context) {
+	utils.SuccessResponse(c, gin.H{
+		"scripts": h.registry.Stats(),
+	})
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	redisx "seckill/internal/redis"
+)
+
+func TestScriptHandler_Stats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	registry := redisx.NewScriptRegistry()
+	script := registry.Register("ping", `return 1`)
+	require.NoError(t, script.Run(context.Background(), client, nil).Err())
+
+	router := gin.New()
+	router.GET("/admin/scripts", NewScriptHandler(registry).Stats)
+
+	req, _ := http.NewRequest("GET", "/admin/scripts", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data struct {
+			Scripts []redisx.ScriptStats `json:"scripts"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data.Scripts, 1)
+	assert.Equal(t, "ping", response.Data.Scripts[0].Name)
+	assert.Equal(t, script.Hash(), response.Data.Scripts[0].SHA)
+	assert.Equal(t, int64(1), response.Data.Scripts[0].Calls)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -110,3 +111,64 @@ func (h *SeckillHandler) PrewarmActivity(c *gin.Context) {
 	utils.SuccessResponse(c, gin.H{"message": "Activity prewarmed successfully"})
 }
 
+// ActivityLimitsRequest admin request to change activity limits
+type ActivityLimitsRequest struct {
+	MaxQPS        *int `json:"max_qps" binding:"required,min=0"`
+	MaxConcurrent *int `json:"max_concurrent" binding:"required,min=0"`
+}
+
+// UpdateActivityLimits updates activity MaxQPS and MaxConcurrent
+func (h *SeckillHandler) UpdateActivityLimits(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return
+	}
+
+	var req ActivityLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid parameters: "+err.Error())
+		return
+	}
+
+	activity, err := h.seckillService.UpdateActivityLimits(c.Request.Context(), activityID, *req.MaxQPS, *req.MaxConcurrent)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Update failed: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"activity_id":    activity.ID,
+		"max_qps":        activity.MaxQPS,
+		"max_concurrent": activity.MaxConcurrent,
+	})
+}
+
+// QueryQueueStatus queries waiting room position
+func (h *SeckillHandler) QueryQueueStatus(c *gin.Context) {
+	activityIDStr := c.Param("activity_id")
+	activityID, err := strconv.ParseUint(activityIDStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return
+	}
+
+	// Get user ID from JWT middleware
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ticket, err := h.seckillService.QueryQueueStatus(c.Request.Context(), activityID, uint64(userID.(int64)))
+	if err != nil {
+		if errors.Is(err, seckill.ErrNotInWaitingRoom) {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Query failed: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, ticket)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"seckill/internal/model"
 	"seckill/internal/service/seckill"
 )
 
@@ -34,6 +36,22 @@ func (m *MockSeckillService) QuerySeckillResult(ctx context.Context, requestID s
 	return args.Get(0).(*seckill.SeckillResult), args.Error(1)
 }
 
+func (m *MockSeckillService) QueryQueueStatus(ctx context.Context, activityID, userID uint64) (*seckill.WaitingTicket, error) {
+	args := m.Called(ctx, activityID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*seckill.WaitingTicket), args.Error(1)
+}
+
+func (m *MockSeckillService) UpdateActivityLimits(ctx context.Context, activityID uint64, maxQPS, maxConcurrent int) (*model.SeckillActivity, error) {
+	args := m.Called(ctx, activityID, maxQPS, maxConcurrent)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.SeckillActivity), args.Error(1)
+}
+
 func TestSeckillHandler_DoSeckill(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -279,6 +297,57 @@ func TestSeckillHandler_QueryResult(t *testing.T) {
 	})
 }
 
+func TestSeckillHandler_QueryQueueStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(mockService *MockSeckillService) *httptest.ResponseRecorder {
+		handler := NewSeckillHandler(mockService)
+		router := gin.New()
+		router.GET("/seckill/queue/:activity_id", func(c *gin.Context) {
+			c.Set("user_id", int64(123))
+			handler.QueryQueueStatus(c)
+		})
+
+		req, _ := http.NewRequest("GET", "/seckill/queue/1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("waiting", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		mockService.On("QueryQueueStatus", mock.Anything, uint64(1), uint64(123)).
+			Return(&seckill.WaitingTicket{ActivityID: 1, UserID: 123, Position: 7, QueuePos: 3}, nil)
+
+		w := serve(mockService)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		data := response["data"].(map[string]interface{})
+		assert.Equal(t, float64(3), data["queue_pos"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not in waiting room", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		mockService.On("QueryQueueStatus", mock.Anything, uint64(1), uint64(123)).Return(nil, seckill.ErrNotInWaitingRoom)
+
+		w := serve(mockService)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("redis failure", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		mockService.On("QueryQueueStatus", mock.Anything, uint64(1), uint64(123)).Return(nil, errors.New("redis: connection refused"))
+
+		w := serve(mockService)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
 func TestSeckillHandler_PrewarmActivity(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -328,4 +397,44 @@ func TestSeckillHandler_PrewarmActivity(t *testing.T) {
 
 		mockService.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+func TestSeckillHandler_UpdateActivityLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("successful update", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		handler := NewSeckillHandler(mockService)
+
+		router := gin.New()
+		router.PUT("/admin/activities/:id/limits", handler.UpdateActivityLimits)
+
+		mockService.On("UpdateActivityLimits", mock.Anything, uint64(1), 200, 0).
+			Return(&model.SeckillActivity{ID: 1, MaxQPS: 200}, nil)
+
+		req, _ := http.NewRequest("PUT", "/admin/activities/1/limits", bytes.NewBufferString(`{"max_qps":200,"max_concurrent":0}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		mockService := new(MockSeckillService)
+		handler := NewSeckillHandler(mockService)
+
+		router := gin.New()
+		router.PUT("/admin/activities/:id/limits", handler.UpdateActivityLimits)
+
+		req, _ := http.NewRequest("PUT", "/admin/activities/1/limits", bytes.NewBufferString(`{"max_qps":-1,"max_concurrent":10}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "UpdateActivityLimits")
+	})
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"seckill/internal/service/seckill"
 	"seckill/internal/service/stock"
 	"seckill/pkg/utils"
 )
@@ -30,7 +33,7 @@ func (h *StockHandler) SyncToRedis(c *gin.Context) {
 		return
 	}
 
-	if err := h.stockService.SyncStockToRedis(c.Request.Context(), activityID); err != nil {
+	if err := h.stockService.SyncStockToRedis(stockContext(c), activityID); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -82,7 +85,7 @@ func (h *StockHandler) RepairInconsistency(c *gin.Context) {
 		return
 	}
 
-	if err := h.stockService.RepairStockInconsistency(c.Request.Context(), activityID); err != nil {
+	if err := h.stockService.RepairStockInconsistency(stockContext(c), activityID); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -90,3 +93,12 @@ func (h *StockHandler) RepairInconsistency(c *gin.Context) {
 	utils.SuccessResponse(c, gin.H{"message": "Stock inconsistency repaired successfully"})
 }
 
+
+// stockContext the request context, recording the admin as the operator of the stock it moves
+func stockContext(c *gin.Context) context.Context {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return c.Request.Context()
+	}
+	return seckill.WithStockMeta(c.Request.Context(), seckill.StockMeta{Operator: fmt.Sprint(userID)})
+}
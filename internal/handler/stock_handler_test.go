@@ -39,6 +39,11 @@ func (m *MockStockService) RepairStockInconsistency(ctx context.Context, activit
 	return args.Error(0)
 }
 
+func (m *MockStockService) CheckActiveActivities(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func (m *MockStockService) StartPeriodicSync(ctx context.Context, interval time.Duration) {
 	m.Called(ctx, interval)
 }
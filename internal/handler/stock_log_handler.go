@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/utils"
+)
+
+// StockLogHandler admin stock audit trail handler
+type StockLogHandler struct {
+	repo repository.StockLogRepository
+}
+
+// NewStockLogHandler creates a stock log handler
+func NewStockLogHandler(repo repository.StockLogRepository) *StockLogHandler {
+	return &StockLogHandler{
+		repo: repo,
+	}
+}
+
+// History lists an activity's stock logs, newest first
+//
+// Query: the filters of stockLogQuery, cursor (next_cursor of the previous page), limit (default 50, max 200)
+func (h *StockLogHandler) History(c *gin.Context) {
+	activityID, filter, ok := stockLogQuery(c)
+	if !ok {
+		return
+	}
+
+	var cursor uint64
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		var err error
+		cursor, err = strconv.ParseUint(cursorStr, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	// One more than asked tells whether another page follows
+	logs, err := h.repo.ListHistory(c.Request.Context(), activityID, filter, cursor, limit+1)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hasMore := len(logs) > limit
+	nextCursor := ""
+	if hasMore {
+		logs = logs[:limit]
+		nextCursor = strconv.FormatUint(logs[limit-1].ID, 10)
+	}
+
+	utils.SuccessCursorResponse(c, logs, nextCursor, hasMore)
+}
+
+// Summary totals an activity's deducted, reverted and synced stock per minute, to line stock
+// drops up with traffic
+//
+// Query: the filters of stockLogQuery
+func (h *StockLogHandler) Summary(c *gin.Context) {
+	activityID, filter, ok := stockLogQuery(c)
+	if !ok {
+		return
+	}
+
+	minutes, err := h.repo.SummarizeByMinute(c.Request.Context(), activityID, filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var deducted, reverted, synced int
+	for _, minute := range minutes {
+		deducted += minute.Deducted
+		reverted += minute.Reverted
+		synced += minute.Synced
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"activity_id": activityID,
+		"minutes":     minutes,
+		"deducted":    deducted,
+		"reverted":    reverted,
+		"synced":      synced,
+	})
+}
+
+// stockLogQuery parses the :id path parameter and the stock log filters
+//
+// Query: operation_type (1 deduct, 2 revert, 3 sync, 4 confirm, 5 repair), start_time and
+// end_time (RFC3339, end exclusive), request_id, order_no, operator
+func stockLogQuery(c *gin.Context) (uint64, repository.StockLogFilter, bool) {
+	var filter repository.StockLogFilter
+
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid activity ID")
+		return 0, filter, false
+	}
+
+	if operationStr := c.Query("operation_type"); operationStr != "" {
+		operation, err := strconv.Atoi(operationStr)
+		if err != nil || operation < model.OperationTypeDeduct || operation > model.OperationTypeRepair {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operation type")
+			return 0, filter, false
+		}
+		filter.OperationType = int8(operation)
+	}
+
+	if startStr := c.Query("start_time"); startStr != "" {
+		if filter.StartTime, err = time.Parse(time.RFC3339, startStr); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid start time")
+			return 0, filter, false
+		}
+	}
+	if endStr := c.Query("end_time"); endStr != "" {
+		if filter.EndTime, err = time.Parse(time.RFC3339, endStr); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid end time")
+			return 0, filter, false
+		}
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && !filter.EndTime.After(filter.StartTime) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "End time must be after start time")
+		return 0, filter, false
+	}
+
+	filter.RequestID = c.Query("request_id")
+	filter.OrderNo = c.Query("order_no")
+	filter.Operator = c.Query("operator")
+	return activityID, filter, true
+}
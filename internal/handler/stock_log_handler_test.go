@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// fakeStockLogRepo serves stock logs newest first, remembering the last query
+type fakeStockLogRepo struct {
+	repository.StockLogRepository
+	logs    []model.StockLog // Newest first
+	minutes []repository.StockLogMinute
+
+	filter   repository.StockLogFilter
+	beforeID uint64
+	limit    int
+}
+
+func (r *fakeStockLogRepo) ListHistory(ctx context.Context, activityID uint64, filter repository.StockLogFilter, beforeID uint64, limit int) ([]model.StockLog, error) {
+	r.filter, r.beforeID, r.limit = filter, beforeID, limit
+	var logs []model.StockLog
+	for _, entry := range r.logs {
+		if beforeID > 0 && entry.ID >= beforeID {
+			continue
+		}
+		if len(logs) == limit {
+			break
+		}
+		logs = append(logs, entry)
+	}
+	return logs, nil
+}
+
+func (r *fakeStockLogRepo) SummarizeByMinute(ctx context.Context, activityID uint64, filter repository.StockLogFilter) ([]repository.StockLogMinute, error) {
+	r.filter = filter
+	return r.minutes, nil
+}
+
+func setupStockLogRouter(handler *StockLogHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/activities/:id/stock-logs", handler.History)
+	router.GET("/admin/activities/:id/stock-logs/summary", handler.Summary)
+	return router
+}
+
+func TestStockLogHandler_History(t *testing.T) {
+	repo := &fakeStockLogRepo{}
+	for id := uint64(5); id > 0; id-- {
+		repo.logs = append(repo.logs, model.StockLog{ID: id, ActivityID: 1, OperationType: model.OperationTypeDeduct, Quantity: -1})
+	}
+	router := setupStockLogRouter(NewStockLogHandler(repo))
+
+	type historyResponse struct {
+		Data struct {
+			List       []model.StockLog `json:"list"`
+			NextCursor string           `json:"next_cursor"`
+			HasMore    bool             `json:"has_more"`
+		} `json:"data"`
+	}
+	serve := func(query string) historyResponse {
+		req, _ := http.NewRequest("GET", "/admin/activities/1/stock-logs?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response historyResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	first := serve("limit=2&operation_type=1&start_time=2026-01-01T10:00:00Z&end_time=2026-01-01T11:00:00Z&order_no=ORDER1&operator=42")
+	require.Len(t, first.Data.List, 2)
+	assert.Equal(t, uint64(5), first.Data.List[0].ID)
+	assert.Equal(t, "4", first.Data.NextCursor)
+	assert.True(t, first.Data.HasMore)
+	assert.Equal(t, 3, repo.limit)
+	assert.Equal(t, repository.StockLogFilter{
+		OperationType: model.OperationTypeDeduct,
+		StartTime:     time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+		EndTime:       time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC),
+		OrderNo:       "ORDER1",
+		Operator:      "42",
+	}, repo.filter)
+
+	// The last page carries no cursor
+	last := serve("limit=2&cursor=2")
+	assert.Equal(t, uint64(2), repo.beforeID)
+	require.Len(t, last.Data.List, 1)
+	assert.Equal(t, uint64(1), last.Data.List[0].ID)
+	assert.Empty(t, last.Data.NextCursor)
+	assert.False(t, last.Data.HasMore)
+}
+
+func TestStockLogHandler_InvalidQuery(t *testing.T) {
+	router := setupStockLogRouter(NewStockLogHandler(&fakeStockLogRepo{}))
+
+	for _, path := range []string{
+		"/admin/activities/abc/stock-logs",
+		"/admin/activities/1/stock-logs?operation_type=9",
+		"/admin/activities/1/stock-logs?start_time=yesterday",
+		"/admin/activities/1/stock-logs?start_time=2026-01-01T11:00:00Z&end_time=2026-01-01T10:00:00Z",
+		"/admin/activities/1/stock-logs?cursor=-1",
+		"/admin/activities/1/stock-logs/summary?end_time=now",
+	} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestStockLogHandler_Summary(t *testing.T) {
+	minute := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo := &fakeStockLogRepo{minutes: []repository.StockLogMinute{
+		{Minute: minute, Deducted: 120, Reverted: 3},
+		{Minute: minute.Add(time.Minute), Deducted: 80, Synced: 50},
+	}}
+
+	req, _ := http.NewRequest("GET", "/admin/activities/1/stock-logs/summary?request_id=r1", nil)
+	w := httptest.NewRecorder()
+	setupStockLogRouter(NewStockLogHandler(repo)).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Minutes  []repository.StockLogMinute `json:"minutes"`
+			Deducted int                         `json:"deducted"`
+			Reverted int                         `json:"reverted"`
+			Synced   int                         `json:"synced"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Data.Minutes, 2)
+	assert.Equal(t, 200, response.Data.Deducted)
+	assert.Equal(t, 3, response.Data.Reverted)
+	assert.Equal(t, 50, response.Data.Synced)
+	assert.Equal(t, "r1", repo.filter.RequestID)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"seckill/internal/repository"
+	"seckill/internal/service/seckill"
+	"seckill/pkg/utils"
+)
+
+// TCCHandler admin TCC recovery and compensation handler
+type TCCHandler struct {
+	sweeper     *seckill.TCCSweeper
+	compensator *seckill.TCCCompensator
+}
+
+// NewTCCHandler creates a TCC handler, nil dependencies report their feature as disabled
+func NewTCCHandler(sweeper *seckill.TCCSweeper, compensator *seckill.TCCCompensator) *TCCHandler {
+	return &TCCHandler{
+		sweeper:     sweeper,
+		compensator: compensator,
+	}
+}
+
+// ResolveDeadLetterRequest resolve dead letter request
+type ResolveDeadLetterRequest struct {
+	Note string `json:"note" binding:"required,max=255"`
+}
+
+// Recovery reports the dangling tries recovered by this instance's sweeper
+func (h *TCCHandler) Recovery(c *gin.Context) {
+	if h.sweeper == nil {
+		utils.SuccessResponse(c, gin.H{
+			"enabled": false,
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"enabled": true,
+		"stats":   h.sweeper.Stats(),
+	})
+}
+
+// ListDeadLetters lists compensations that need manual attention
+//
+// Query: status (0 open, 1 retried, 2 resolved, omitted for all), page, page_size
+func (h *TCCHandler) ListDeadLetters(c *gin.Context) {
+	if !h.compensationEnabled(c) {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	status := -1
+	if statusStr := c.Query("status"); statusStr != "" {
+		var err error
+		status, err = strconv.Atoi(statusStr)
+		if err != nil || status < 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status")
+			return
+		}
+	}
+
+	letters, total, err := h.compensator.ListDeadLetters(c.Request.Context(), status, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessPageResponse(c, letters, total, page, pageSize)
+}
+
+// RetryDeadLetter attempts a dead letter's confirm or cancel once more
+func (h *TCCHandler) RetryDeadLetter(c *gin.Context) {
+	if !h.compensationEnabled(c) {
+		return
+	}
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+	operator, ok := operatorID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.compensator.RetryDeadLetter(c.Request.Context(), id, operator)
+	if err != nil {
+		writeDeadLetterError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"result": result})
+}
+
+// ResolveDeadLetter closes a dead letter handled by hand
+func (h *TCCHandler) ResolveDeadLetter(c *gin.Context) {
+	if !h.compensationEnabled(c) {
+		return
+	}
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+	var req ResolveDeadLetterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request parameters")
+		return
+	}
+	operator, ok := operatorID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.compensator.ResolveDeadLetter(c.Request.Context(), id, operator, req.Note); err != nil {
+		writeDeadLetterError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Dead letter resolved successfully"})
+}
+
+// compensationEnabled answers 503 when compensation is disabled
+func (h *TCCHandler) compensationEnabled(c *gin.Context) bool {
+	if h.compensator == nil {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "TCC compensation is disabled")
+		return false
+	}
+	return true
+}
+
+// deadLetterID parses the :id path parameter
+func deadLetterID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid dead letter ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// operatorID the authenticated admin, as recorded on what they change
+func operatorID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return "", false
+	}
+	return strconv.FormatInt(userID.(int64), 10), true
+}
+
+// writeDeadLetterError maps dead letter errors to status codes
+func writeDeadLetterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrDeadLetterNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, seckill.ErrDeadLetterClosed), errors.Is(err, seckill.ErrCompensationConflict):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
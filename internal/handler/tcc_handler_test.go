@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/internal/service/seckill"
+)
+
+// fakeDeadLetterRepo dead letter half of a compensation repository
+type fakeDeadLetterRepo struct {
+	repository.CompensationRepository
+	letters map[uint64]*model.TCCDeadLetter
+}
+
+func (r *fakeDeadLetterRepo) ListDeadLetters(ctx context.Context, status int, page, pageSize int) ([]*model.TCCDeadLetter, int64, error) {
+	var letters []*model.TCCDeadLetter
+	for _, letter := range r.letters {
+		if status < 0 || int(letter.Status) == status {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, int64(len(letters)), nil
+}
+
+func (r *fakeDeadLetterRepo) GetDeadLetter(ctx context.Context, id uint64) (*model.TCCDeadLetter, error) {
+	letter, ok := r.letters[id]
+	if !ok {
+		return nil, repository.ErrDeadLetterNotFound
+	}
+	return letter, nil
+}
+
+func (r *fakeDeadLetterRepo) ResolveDeadLetter(ctx context.Context, id uint64, status int8, resolvedBy, note string) (bool, error) {
+	letter, ok := r.letters[id]
+	if !ok || letter.Status != model.DeadLetterStatusOpen {
+		return false, nil
+	}
+	letter.Status, letter.ResolvedBy, letter.ResolveNote = status, &resolvedBy, &note
+	return true, nil
+}
+
+func setupTCCRouter(handler *TCCHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+		c.Next()
+	})
+	router.GET("/admin/tcc/recovery", handler.Recovery)
+	router.GET("/admin/tcc/dead-letters", handler.ListDeadLetters)
+	router.POST("/admin/tcc/dead-letters/:id/resolve", handler.ResolveDeadLetter)
+	return router
+}
+
+func TestTCCHandler_Recovery(t *testing.T) {
+	type recoveryResponse struct {
+		Data struct {
+			Enabled bool                     `json:"enabled"`
+			Stats   seckill.TCCRecoveryStats `json:"stats"`
+		} `json:"data"`
+	}
+
+	serve := func(sweeper *seckill.TCCSweeper) recoveryResponse {
+		req, _ := http.NewRequest("GET", "/admin/tcc/recovery", nil)
+		w := httptest.NewRecorder()
+		setupTCCRouter(NewTCCHandler(sweeper, nil)).ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response recoveryResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	assert.False(t, serve(nil).Data.Enabled)
+
+	response := serve(seckill.NewTCCSweeper(nil, nil, 10*time.Minute, 100))
+	assert.True(t, response.Data.Enabled)
+	assert.Equal(t, int64(0), response.Data.Stats.RecoveredUnits)
+}
+
+func TestTCCHandler_DeadLetters(t *testing.T) {
+	repo := &fakeDeadLetterRepo{letters: map[uint64]*model.TCCDeadLetter{
+		1: {ID: 1, DeductID: "deduct:r1:1", Operation: model.TCCOperationConfirm, LastError: "already_cancelled"},
+	}}
+	compensator := seckill.NewTCCCompensator(nil, repo, seckill.CompensationConfig{})
+	router := setupTCCRouter(NewTCCHandler(nil, compensator))
+
+	t.Run("list open", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/tcc/dead-letters?status=0", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "already_cancelled")
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/tcc/dead-letters?status=x", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	resolve := func(id string) int {
+		body, _ := json.Marshal(ResolveDeadLetterRequest{Note: "order refunded"})
+		req, _ := http.NewRequest("POST", "/admin/tcc/dead-letters/"+id+"/resolve", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("resolve", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, resolve("1"))
+		assert.Equal(t, int8(model.DeadLetterStatusResolved), repo.letters[1].Status)
+		assert.Equal(t, "1", *repo.letters[1].ResolvedBy)
+
+		assert.Equal(t, http.StatusConflict, resolve("1"))
+		assert.Equal(t, http.StatusNotFound, resolve("2"))
+		assert.Equal(t, http.StatusBadRequest, resolve("x"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/tcc/dead-letters", nil)
+		w := httptest.NewRecorder()
+		setupTCCRouter(NewTCCHandler(nil, nil)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"seckill/internal/model"
+	"seckill/internal/service/vip"
+	"seckill/pkg/utils"
+)
+
+// VIPHandler admin VIP tier handler
+type VIPHandler struct {
+	vipService vip.VIPService
+}
+
+// NewVIPHandler creates a VIP handler
+func NewVIPHandler(vipService vip.VIPService) *VIPHandler {
+	return &VIPHandler{
+		vipService: vipService,
+	}
+}
+
+// GrantVIPRequest grant VIP tier request
+type GrantVIPRequest struct {
+	UserID   uint64     `json:"user_id" binding:"required"`
+	Tier     int8       `json:"tier" binding:"required,min=1,max=3"` // 1-silver, 2-gold, 3-platinum
+	Reason   string     `json:"reason" binding:"required,max=255"`
+	ExpireAt *time.Time `json:"expire_at"` // RFC3339, omitted never expires
+}
+
+// Grant grants a VIP tier
+func (h *VIPHandler) Grant(c *gin.Context) {
+	var req GrantVIPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request parameters")
+		return
+	}
+
+	operatorID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	grant, err := h.vipService.Grant(c.Request.Context(), &vip.GrantRequest{
+		UserID:   req.UserID,
+		Tier:     model.VIPTier(req.Tier),
+		Reason:   req.Reason,
+		Operator: strconv.FormatInt(operatorID.(int64), 10),
+		ExpireAt: req.ExpireAt,
+	})
+	if err != nil {
+		if errors.Is(err, vip.ErrInvalidGrant) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, grant)
+}
+
+// Revoke revokes all active VIP grants of a user
+func (h *VIPHandler) Revoke(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	operatorID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	revoked, err := h.vipService.Revoke(c.Request.Context(), userID, strconv.FormatInt(operatorID.(int64), 10))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"revoked": revoked})
+}
+
+// GetUserTier gets a user's effective tier and grant history
+func (h *VIPHandler) GetUserTier(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	userTier, err := h.vipService.GetUserTier(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	utils.SuccessResponse(c, userTier)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"seckill/internal/model"
+	"seckill/internal/service/vip"
+)
+
+// MockVIPService mock VIP service
+type MockVIPService struct {
+	mock.Mock
+}
+
+func (m *MockVIPService) GetTier(ctx context.Context, userID uint64) (model.VIPTier, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(model.VIPTier), args.Error(1)
+}
+
+func (m *MockVIPService) GetUserTier(ctx context.Context, userID uint64) (*vip.UserTier, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*vip.UserTier), args.Error(1)
+}
+
+func (m *MockVIPService) Grant(ctx context.Context, req *vip.GrantRequest) (*model.VIPGrant, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.VIPGrant), args.Error(1)
+}
+
+func (m *MockVIPService) Revoke(ctx context.Context, userID uint64, operator string) (int64, error) {
+	args := m.Called(ctx, userID, operator)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockVIPService) Invalidate(ctx context.Context, userID uint64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func setupVIPRouter(handler *VIPHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(9))
+		c.Next()
+	})
+	router.GET("/admin/vip/:user_id", handler.GetUserTier)
+	router.POST("/admin/vip/grants", handler.Grant)
+	router.DELETE("/admin/vip/grants/:user_id", handler.Revoke)
+	return router
+}
+
+func TestVIPHandler_Grant(t *testing.T) {
+	t.Run("successful grant", func(t *testing.T) {
+		mockService := new(MockVIPService)
+		router := setupVIPRouter(NewVIPHandler(mockService))
+
+		mockService.On("Grant", mock.Anything, mock.MatchedBy(func(req *vip.GrantRequest) bool {
+			return req.UserID == 1001 && req.Tier == model.VIPTierGold && req.Operator == "9"
+		})).Return(&model.VIPGrant{ID: 1, UserID: 1001, Tier: model.VIPTierGold}, nil)
+
+		body, _ := json.Marshal(map[string]interface{}{"user_id": 1001, "tier": 2, "reason": "campaign"})
+		req, _ := http.NewRequest("POST", "/admin/vip/grants", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid tier", func(t *testing.T) {
+		mockService := new(MockVIPService)
+		router := setupVIPRouter(NewVIPHandler(mockService))
+
+		body, _ := json.Marshal(map[string]interface{}{"user_id": 1001, "tier": 5, "reason": "campaign"})
+		req, _ := http.NewRequest("POST", "/admin/vip/grants", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Grant", mock.Anything, mock.Anything)
+	})
+}
+
+func TestVIPHandler_Revoke(t *testing.T) {
+	mockService := new(MockVIPService)
+	router := setupVIPRouter(NewVIPHandler(mockService))
+
+	mockService.On("Revoke", mock.Anything, uint64(1001), "9").Return(int64(2), nil)
+
+	req, _ := http.NewRequest("DELETE", "/admin/vip/grants/1001", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, float64(2), data["revoked"])
+}
+
+func TestVIPHandler_GetUserTier(t *testing.T) {
+	t.Run("user found", func(t *testing.T) {
+		mockService := new(MockVIPService)
+		router := setupVIPRouter(NewVIPHandler(mockService))
+
+		mockService.On("GetUserTier", mock.Anything, uint64(1001)).
+			Return(&vip.UserTier{UserID: 1001, Tier: model.VIPTierGold, TierName: "gold"}, nil)
+
+		req, _ := http.NewRequest("GET", "/admin/vip/1001", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockService := new(MockVIPService)
+		router := setupVIPRouter(NewVIPHandler(mockService))
+
+		mockService.On("GetUserTier", mock.Anything, uint64(1002)).Return(nil, errors.New("record not found"))
+
+		req, _ := http.NewRequest("GET", "/admin/vip/1002", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -61,6 +61,12 @@ const (
 	PrewarmStatusDone   = 1 // 已预热
 )
 
+// ShardStrategy stock shard strategy const
+const (
+	ShardStrategyHash       = "hash"        // 按用户哈希
+	ShardStrategyRoundRobin = "round_robin" // 轮询
+)
+
 // JSONObject custom json object type
 type JSONObject map[string]interface{}
 
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Blacklist user blacklist entry model, ActivityID 0 means the entry applies to all activities
+type Blacklist struct {
+	ID         uint64     `gorm:"primaryKey;autoIncrement;comment:黑名单ID" json:"id"`
+	UserID     uint64     `gorm:"type:bigint unsigned;not null;uniqueIndex:uk_user_activity;comment:用户ID" json:"user_id"`
+	ActivityID uint64     `gorm:"type:bigint unsigned;not null;default:0;uniqueIndex:uk_user_activity;index;comment:活动ID，0-全局" json:"activity_id"`
+	Reason     string     `gorm:"type:varchar(255);not null;comment:原因" json:"reason"`
+	Operator   string     `gorm:"type:varchar(50);not null;comment:操作人" json:"operator"`
+	ExpireAt   *time.Time `gorm:"type:timestamp;index;comment:过期时间，空为永久" json:"expire_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+}
+
+// TableName set name
+func (Blacklist) TableName() string {
+	return "user_blacklists"
+}
+
+// IsGlobal check if entry applies to all activities
+func (b *Blacklist) IsGlobal() bool {
+	return b.ActivityID == 0
+}
+
+// IsExpired check if entry has expired
+func (b *Blacklist) IsExpired(now time.Time) bool {
+	return b.ExpireAt != nil && !now.Before(*b.ExpireAt)
+}
+
+// CacheKey Redis key checked on the seckill fast path
+func (b *Blacklist) CacheKey() string {
+	return BlacklistCacheKey(b.UserID, b.ActivityID)
+}
+
+// BlacklistCacheKey Redis key of a user's entry, activityID 0 is the global entry
+func BlacklistCacheKey(userID, activityID uint64) string {
+	if activityID == 0 {
+		return fmt.Sprintf("blacklist:user:%d", userID)
+	}
+	return fmt.Sprintf("blacklist:activity:%d:user:%d", activityID, userID)
+}
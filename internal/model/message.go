@@ -10,6 +10,7 @@ type OrderMessage struct {
 	Quantity   int     `json:"quantity"`    // Quantity
 	Price      float64 `json:"price"`       // Unit price
 	IsVIP      bool    `json:"is_vip"`      // Is VIP user
+	VIPTier    VIPTier `json:"vip_tier"`    // VIP tier
 	IP         string  `json:"ip"`          // User IP
 	DeviceID   string  `json:"device_id"`   // Device ID
 	Timestamp  int64   `json:"timestamp"`   // Timestamp
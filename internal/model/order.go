@@ -9,22 +9,22 @@ type Order struct {
 	ID             uint64     `gorm:"primaryKey;autoIncrement;comment:订单ID" json:"id"`
 	OrderNo        string     `gorm:"type:varchar(32);uniqueIndex;not null;comment:订单号" json:"order_no"`
 	RequestID      string     `gorm:"type:varchar(32);uniqueIndex;not null;comment:请求ID（幂等）" json:"request_id"`
-	UserID         uint64     `gorm:"type:bigint unsigned;not null;index;comment:用户ID" json:"user_id"`
-	ActivityID     uint64     `gorm:"type:bigint unsigned;not null;index;comment:活动ID" json:"activity_id"`
+	UserID         uint64     `gorm:"type:bigint unsigned;not null;index;index:idx_activity_user_status,priority:2;comment:用户ID" json:"user_id"`
+	ActivityID     uint64     `gorm:"type:bigint unsigned;not null;index;index:idx_activity_user_status,priority:1;comment:活动ID" json:"activity_id"`
 	GoodsID        uint64     `gorm:"type:bigint unsigned;not null;comment:商品ID" json:"goods_id"`
-	Quantity       int        `gorm:"type:int;not null;comment:购买数量" json:"quantity"`
+	Quantity       int        `gorm:"type:int;not null;index:idx_activity_user_status,priority:4;comment:购买数量" json:"quantity"`
 	Price          int64      `gorm:"type:bigint;not null;comment:单价（分）" json:"price"`
 	TotalAmount    int64      `gorm:"type:bigint;not null;comment:总金额（分）" json:"total_amount"`
 	DiscountAmount int64      `gorm:"type:bigint;default:0;comment:优惠金额（分）" json:"discount_amount"`
 	PaymentAmount  int64      `gorm:"type:bigint;not null;comment:实付金额（分）" json:"payment_amount"`
-	Status         int8       `gorm:"type:tinyint;not null;default:1;index;comment:状态：1-待支付，2-已支付，3-已取消，4-已退款，5-已完成" json:"status"`
+	Status         int8       `gorm:"type:tinyint;not null;default:1;index;index:idx_activity_user_status,priority:3;comment:状态：1-待支付，2-已支付，3-已取消，4-已退款，5-已完成" json:"status"`
 	PaymentMethod  *string    `gorm:"type:varchar(20);comment:支付方式" json:"payment_method,omitempty"`
 	PaymentNo      *string    `gorm:"type:varchar(64);comment:支付流水号" json:"payment_no,omitempty"`
 	PaidAt         *time.Time `gorm:"type:timestamp;comment:支付时间" json:"paid_at,omitempty"`
 	ExpireAt       time.Time  `gorm:"type:timestamp;not null;index;comment:过期时间" json:"expire_at"`
 	CancelReason   *string    `gorm:"type:varchar(255);comment:取消原因" json:"cancel_reason,omitempty"`
 	Remark         *string    `gorm:"type:varchar(500);comment:备注" json:"remark,omitempty"`
-	DeductID       string     `gorm:"type:varchar(100);index;comment:库存扣减ID（TCC）" json:"deduct_id,omitempty"`
+	DeductID       string     `gorm:"type:varchar(100);index;index:idx_activity_user_status,priority:5;comment:库存扣减ID（TCC）" json:"deduct_id,omitempty"`
 	CreatedAt      time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;index;comment:创建时间" json:"created_at"`
 	UpdatedAt      time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
 	
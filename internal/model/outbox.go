@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+)
+
+// OutboxEvent event written in the same transaction as the state change it announces,
+// published to the queue by the outbox relay
+type OutboxEvent struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement;comment:事件ID" json:"id"`
+	Topic       string     `gorm:"type:varchar(64);not null;comment:主题/事件类型" json:"topic"`
+	AggregateID string     `gorm:"type:varchar(64);not null;index;comment:聚合ID（订单号）" json:"aggregate_id"`
+	Payload     string     `gorm:"type:text;not null;comment:事件内容（JSON）" json:"payload"`
+	Status      int8       `gorm:"type:tinyint;not null;default:0;index;comment:状态：0-待投递，1-已投递" json:"status"`
+	Attempts    int        `gorm:"type:int;not null;default:0;comment:投递失败次数" json:"attempts"`
+	LastError   string     `gorm:"type:varchar(255);not null;default:'';comment:最近一次错误" json:"last_error"`
+	DeliveredAt *time.Time `gorm:"type:timestamp;comment:投递时间" json:"delivered_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+}
+
+// TableName set name
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+// OutboxStatus outbox event status const
+const (
+	OutboxStatusPending   = 0 // 待投递
+	OutboxStatusDelivered = 1 // 已投递
+)
+
+// Order event topics, also the event type carried in the payload
+const (
+	EventOrderCreated   = "OrderCreated"
+	EventOrderPaid      = "OrderPaid"
+	EventOrderCancelled = "OrderCancelled"
+)
+
+// OrderEvent order event payload. EventID is stable across redeliveries,
+// consumers deduplicate on it since the relay delivers at least once.
+type OrderEvent struct {
+	EventID       string    `json:"event_id"`   // Order number and event type
+	EventType     string    `json:"event_type"` // OrderCreated/OrderPaid/OrderCancelled
+	OrderID       uint64    `json:"order_id"`
+	OrderNo       string    `json:"order_no"`
+	RequestID     string    `json:"request_id"`
+	UserID        uint64    `json:"user_id"`
+	ActivityID    uint64    `json:"activity_id"`
+	GoodsID       uint64    `json:"goods_id"`
+	Quantity      int       `json:"quantity"`
+	PaymentAmount int64     `json:"payment_amount"` // In cents
+	Status        int8      `json:"status"`
+	DeductID      string    `json:"deduct_id,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// Purchase per-user purchase row written when stock is deducted from MySQL instead of Redis
+// One row per deduction, the rows of a user count against the activity's purchase limit
+type Purchase struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement;comment:购买ID" json:"id"`
+	DeductID   string    `gorm:"type:varchar(128);not null;uniqueIndex;comment:扣减ID" json:"deduct_id"`
+	ActivityID uint64    `gorm:"type:bigint unsigned;not null;index:idx_activity_user;comment:活动ID" json:"activity_id"`
+	UserID     uint64    `gorm:"type:bigint unsigned;not null;index:idx_activity_user;comment:用户ID" json:"user_id"`
+	Quantity   int       `gorm:"type:int;not null;comment:购买数量" json:"quantity"`
+	Status     int8      `gorm:"type:tinyint;not null;default:0;comment:状态：0-预扣，1-已确认，2-已取消" json:"status"`
+	CreatedAt  time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+}
+
+// TableName set name
+func (Purchase) TableName() string {
+	return "seckill_purchases"
+}
+
+// PurchaseStatus purchase status const
+const (
+	PurchaseStatusTry       = 0 // 预扣
+	PurchaseStatusConfirmed = 1 // 已确认
+	PurchaseStatusCancelled = 2 // 已取消
+)
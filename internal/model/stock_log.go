@@ -9,7 +9,7 @@ type StockLog struct {
 	ID            uint64    `gorm:"primaryKey;autoIncrement;comment:日志ID" json:"id"`
 	ActivityID    uint64    `gorm:"type:bigint unsigned;not null;index;comment:活动ID" json:"activity_id"`
 	GoodsID       uint64    `gorm:"type:bigint unsigned;not null;index;comment:商品ID" json:"goods_id"`
-	OperationType int8      `gorm:"type:tinyint;not null;comment:操作类型：1-扣减，2-回补，3-同步" json:"operation_type"`
+	OperationType int8      `gorm:"type:tinyint;not null;comment:操作类型：1-扣减，2-回补，3-同步，4-确认，5-修复" json:"operation_type"`
 	Quantity      int       `gorm:"type:int;not null;comment:数量（正数为增加，负数为减少）" json:"quantity"`
 	BeforeStock   int       `gorm:"type:int;not null;comment:操作前库存" json:"before_stock"`
 	AfterStock    int       `gorm:"type:int;not null;comment:操作后库存" json:"after_stock"`
@@ -31,9 +31,11 @@ func (StockLog) TableName() string {
 
 // OperationType operation type const
 const (
-	OperationTypeDeduct = 1 // 扣减
-	OperationTypeRevert = 2 // 回补
-	OperationTypeSync   = 3 // 同步
+	OperationTypeDeduct  = 1 // 扣减
+	OperationTypeRevert  = 2 // 回补
+	OperationTypeSync    = 3 // 同步
+	OperationTypeConfirm = 4 // 确认（预扣转为已售，变动的是预扣库存）
+	OperationTypeRepair  = 5 // 修复
 )
 
 // IsDeduct check if operation is deduct	
@@ -60,6 +62,10 @@ func (sl *StockLog) GetOperationTypeName() string {
 		return "回补"
 	case OperationTypeSync:
 		return "同步"
+	case OperationTypeConfirm:
+		return "确认"
+	case OperationTypeRepair:
+		return "修复"
 	default:
 		return "未知"
 	}
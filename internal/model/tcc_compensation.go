@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+)
+
+// TCCCompensation pending retry of a confirm or cancel that failed after the order side committed
+type TCCCompensation struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement;comment:补偿ID" json:"id"`
+	DeductID    string    `gorm:"type:varchar(128);not null;uniqueIndex:uk_deduct_operation;comment:扣减ID" json:"deduct_id"`
+	Operation   string    `gorm:"type:varchar(16);not null;uniqueIndex:uk_deduct_operation;comment:操作：confirm/cancel" json:"operation"`
+	ActivityID  uint64    `gorm:"type:bigint unsigned;not null;comment:活动ID" json:"activity_id"`
+	Quantity    int       `gorm:"type:int;not null;comment:扣减数量" json:"quantity"`
+	Attempts    int       `gorm:"type:int;not null;default:0;comment:已尝试次数" json:"attempts"`
+	NextRetryAt time.Time `gorm:"type:timestamp;not null;index;comment:下次重试时间" json:"next_retry_at"`
+	LastError   string    `gorm:"type:varchar(255);not null;default:'';comment:最近一次错误" json:"last_error"`
+	CreatedAt   time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+}
+
+// TableName set name
+func (TCCCompensation) TableName() string {
+	return "tcc_compensations"
+}
+
+// TCCDeadLetter compensation that ran out of attempts or conflicts with the deduction's state
+type TCCDeadLetter struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement;comment:死信ID" json:"id"`
+	DeductID    string     `gorm:"type:varchar(128);not null;index;comment:扣减ID" json:"deduct_id"`
+	Operation   string     `gorm:"type:varchar(16);not null;comment:操作：confirm/cancel" json:"operation"`
+	ActivityID  uint64     `gorm:"type:bigint unsigned;not null;comment:活动ID" json:"activity_id"`
+	Quantity    int        `gorm:"type:int;not null;comment:扣减数量" json:"quantity"`
+	Attempts    int        `gorm:"type:int;not null;comment:已尝试次数" json:"attempts"`
+	LastError   string     `gorm:"type:varchar(255);not null;default:'';comment:最近一次错误" json:"last_error"`
+	Status      int8       `gorm:"type:tinyint;not null;default:0;index;comment:状态：0-待处理，1-重试成功，2-人工处理" json:"status"`
+	ResolvedBy  *string    `gorm:"type:varchar(50);comment:处理人" json:"resolved_by,omitempty"`
+	ResolveNote *string    `gorm:"type:varchar(255);comment:处理说明" json:"resolve_note,omitempty"`
+	ResolvedAt  *time.Time `gorm:"type:timestamp;comment:处理时间" json:"resolved_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+}
+
+// TableName set name
+func (TCCDeadLetter) TableName() string {
+	return "tcc_dead_letters"
+}
+
+// TCC compensation operation const
+const (
+	TCCOperationConfirm = "confirm"
+	TCCOperationCancel  = "cancel"
+)
+
+// DeadLetterStatus dead letter status const
+const (
+	DeadLetterStatusOpen     = 0 // 待处理
+	DeadLetterStatusRetried  = 1 // 重试成功
+	DeadLetterStatusResolved = 2 // 人工处理
+)
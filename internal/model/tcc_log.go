@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+// TCCLog persistent record of a successful stock try and how it was settled
+// Redis deduction records expire, this row outlives them so stranded tries can be found and cancelled
+type TCCLog struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement;comment:日志ID" json:"id"`
+	DeductID   string    `gorm:"type:varchar(128);not null;uniqueIndex;index:idx_activity_status_user,priority:5;comment:扣减ID" json:"deduct_id"`
+	RequestID  string    `gorm:"type:varchar(64);not null;index;comment:请求ID" json:"request_id"`
+	ActivityID uint64    `gorm:"type:bigint unsigned;not null;index:idx_activity_status_user,priority:1;comment:活动ID" json:"activity_id"`
+	UserID     uint64    `gorm:"type:bigint unsigned;not null;index:idx_activity_status_user,priority:3;comment:用户ID" json:"user_id"`
+	Quantity   int       `gorm:"type:int;not null;index:idx_activity_status_user,priority:4;comment:扣减数量" json:"quantity"`
+	Status     int8      `gorm:"type:tinyint;not null;default:0;index:idx_status_created;index:idx_activity_status_user,priority:2;comment:状态：0-预扣，1-已确认，2-已取消" json:"status"`
+	Result     string    `gorm:"type:varchar(255);not null;default:'';comment:最近一次确认/取消结果" json:"result"`
+	Recovered  bool      `gorm:"not null;default:false;comment:是否由恢复任务取消" json:"recovered"`
+	CreatedAt  time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;index:idx_status_created;comment:创建时间" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+}
+
+// TableName set name
+func (TCCLog) TableName() string {
+	return "tcc_logs"
+}
+
+// TCCStatus TCC log status const
+const (
+	TCCStatusTry       = 0 // 预扣
+	TCCStatusConfirmed = 1 // 已确认
+	TCCStatusCancelled = 2 // 已取消
+)
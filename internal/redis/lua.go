@@ -3,7 +3,10 @@ package redis
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,18 +15,19 @@ import (
 // Lua脚本常量
 const (
 	// 库存扣减脚本
+	// 所有key通过KEYS传入并以{activity_id}为hash tag，集群下落在同一slot
 	StockDeductScript = `
-		local activity_id = KEYS[1]
-		local goods_id = KEYS[2]
-		local user_id = KEYS[3]
-		local request_id = KEYS[4]
+		local stock_key = KEYS[1]
+		local user_buy_key = KEYS[2]
+		local request_key = KEYS[3]
+		local log_key = KEYS[4]
 		
 		local quantity = tonumber(ARGV[1])
 		local limit_per_user = tonumber(ARGV[2])
 		local expire_time = tonumber(ARGV[3])
+		local user_id = ARGV[4]
 		
 		-- 检查库存
-		local stock_key = "stock:" .. activity_id .. ":" .. goods_id
 		local current_stock = redis.call('GET', stock_key)
 		if not current_stock then
 			return {-1, "stock not found"}
@@ -35,7 +39,6 @@ const (
 		end
 		
 		-- 检查用户购买限制
-		local user_buy_key = "user_buy:" .. activity_id .. ":" .. user_id
 		local user_bought = redis.call('GET', user_buy_key)
 		if not user_bought then
 			user_bought = 0
@@ -48,7 +51,6 @@ const (
 		end
 		
 		-- 检查请求是否已处理（防重复）
-		local request_key = "request:" .. request_id
 		local request_exists = redis.call('EXISTS', request_key)
 		if request_exists == 1 then
 			return {-4, "duplicate request"}
@@ -70,8 +72,7 @@ const (
 		redis.call('SETEX', request_key, expire_time, 1)
 		
 		-- 记录扣减日志
-		local log_key = "stock_log:" .. activity_id .. ":" .. goods_id
-		local log_data = user_id .. ":" .. quantity .. ":" .. os.time()
+		local log_data = user_id .. ":" .. quantity .. ":" .. redis.call('TIME')[1]
 		redis.call('LPUSH', log_key, log_data)
 		redis.call('EXPIRE', log_key, expire_time)
 		
@@ -80,35 +81,32 @@ const (
 
 	// 库存回滚脚本
 	StockRevertScript = `
-		local activity_id = KEYS[1]
-		local goods_id = KEYS[2]
-		local user_id = KEYS[3]
-		local request_id = KEYS[4]
+		local stock_key = KEYS[1]
+		local user_buy_key = KEYS[2]
+		local request_key = KEYS[3]
+		local log_key = KEYS[4]
 		
 		local quantity = tonumber(ARGV[1])
 		local expire_time = tonumber(ARGV[2])
+		local user_id = ARGV[3]
 		
 		-- 检查请求是否存在
-		local request_key = "request:" .. request_id
 		local request_exists = redis.call('EXISTS', request_key)
 		if request_exists == 0 then
 			return {-1, "request not found"}
 		end
 		
 		-- 回滚库存
-		local stock_key = "stock:" .. activity_id .. ":" .. goods_id
 		redis.call('INCRBY', stock_key, quantity)
 		
 		-- 回滚用户购买数量
-		local user_buy_key = "user_buy:" .. activity_id .. ":" .. user_id
 		redis.call('DECRBY', user_buy_key, quantity)
 		
 		-- 删除请求标记
 		redis.call('DEL', request_key)
 		
 		-- 记录回滚日志
-		local log_key = "stock_log:" .. activity_id .. ":" .. goods_id
-		local log_data = user_id .. ":-" .. quantity .. ":" .. os.time()
+		local log_data = user_id .. ":-" .. quantity .. ":" .. redis.call('TIME')[1]
 		redis.call('LPUSH', log_key, log_data)
 		redis.call('EXPIRE', log_key, expire_time)
 		
@@ -168,34 +166,199 @@ const (
 	`
 )
 
+// ScriptRegistry Lua脚本注册表
+//
+// 所有组件的脚本都在此注册，启动时预加载，调用走EVALSHA，遇到NOSCRIPT自动重新加载。
+// 脚本以内容的SHA1作为版本，修改后的脚本与旧版本并存，不同版本的实例互不影响。
+type ScriptRegistry struct {
+	mu      sync.RWMutex
+	scripts map[string]*Script
+	debug   atomic.Bool
+}
+
+// Script 已注册的Lua脚本
+type Script struct {
+	registry *ScriptRegistry
+	name     string
+	release  *redis.Script
+	debug    *redis.Script
+
+	calls        atomic.Int64
+	errors       atomic.Int64
+	reloads      atomic.Int64
+	totalLatency atomic.Int64 // 纳秒
+	maxLatency   atomic.Int64 // 纳秒
+}
+
+// ScriptStats 脚本调用统计
+type ScriptStats struct {
+	Name       string        `json:"name"`
+	SHA        string        `json:"sha"`
+	Calls      int64         `json:"calls"`
+	Errors     int64         `json:"errors"`
+	Reloads    int64         `json:"reloads"`
+	AvgLatency time.Duration `json:"avg_latency"`
+	MaxLatency time.Duration `json:"max_latency"`
+}
+
+// Scripts 全局脚本注册表
+var Scripts = NewScriptRegistry()
+
+// NewScriptRegistry 创建脚本注册表
+func NewScriptRegistry() *ScriptRegistry {
+	return &ScriptRegistry{
+		scripts: make(map[string]*Script),
+	}
+}
+
+// SetDebug 开启脚本中 `if DEBUG then ... end` 包裹的调试日志，需在Load之前设置
+func (r *ScriptRegistry) SetDebug(enabled bool) {
+	r.debug.Store(enabled)
+}
+
+// Register 注册脚本，同名脚本重复注册返回已有脚本
+func (r *ScriptRegistry) Register(name, body string) *Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if script, ok := r.scripts[name]; ok {
+		return script
+	}
+	script := &Script{
+		registry: r,
+		name:     name,
+		release:  redis.NewScript("local DEBUG = false\n" + body),
+		debug:    redis.NewScript("local DEBUG = true\n" + body),
+	}
+	r.scripts[name] = script
+	return script
+}
+
+// Load 预加载所有脚本
+func (r *ScriptRegistry) Load(ctx context.Context, client redis.Scripter) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for name, script := range r.scripts {
+		if err := script.active().Load(ctx, client).Err(); err != nil {
+			return fmt.Errorf("failed to load lua script %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Stats 按名称排序的脚本统计
+func (r *ScriptRegistry) Stats() []ScriptStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make([]ScriptStats, 0, len(r.scripts))
+	for _, script := range r.scripts {
+		stats = append(stats, script.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// active 当前调试开关对应的脚本版本
+func (s *Script) active() *redis.Script {
+	if s.registry.debug.Load() {
+		return s.debug
+	}
+	return s.release
+}
+
+// Name 脚本名称
+func (s *Script) Name() string {
+	return s.name
+}
+
+// Hash 当前版本的SHA1
+func (s *Script) Hash() string {
+	return s.active().Hash()
+}
+
+// Run 通过EVALSHA执行脚本，脚本缓存被清空（重启、故障切换、SCRIPT FLUSH）时重新加载一次
+func (s *Script) Run(ctx context.Context, c redis.Scripter, keys []string, args ...interface{}) *redis.Cmd {
+	script := s.active()
+	start := time.Now()
+
+	cmd := script.EvalSha(ctx, c, keys, args...)
+	if redis.HasErrorPrefix(cmd.Err(), "NOSCRIPT") {
+		s.reloads.Add(1)
+		if err := script.Load(ctx, c).Err(); err != nil {
+			cmd = redis.NewCmd(ctx)
+			cmd.SetErr(err)
+		} else {
+			cmd = script.EvalSha(ctx, c, keys, args...)
+		}
+	}
+
+	s.observe(time.Since(start), cmd.Err())
+	return cmd
+}
+
+// observe 记录一次调用，脚本返回nil不算错误
+func (s *Script) observe(latency time.Duration, err error) {
+	s.calls.Add(1)
+	if err != nil && err != redis.Nil {
+		s.errors.Add(1)
+	}
+	s.totalLatency.Add(int64(latency))
+	for {
+		current := s.maxLatency.Load()
+		if int64(latency) <= current || s.maxLatency.CompareAndSwap(current, int64(latency)) {
+			break
+		}
+	}
+}
+
+// Stats 脚本调用统计
+func (s *Script) Stats() ScriptStats {
+	stats := ScriptStats{
+		Name:       s.name,
+		SHA:        s.Hash(),
+		Calls:      s.calls.Load(),
+		Errors:     s.errors.Load(),
+		Reloads:    s.reloads.Load(),
+		MaxLatency: time.Duration(s.maxLatency.Load()),
+	}
+	if stats.Calls > 0 {
+		stats.AvgLatency = time.Duration(s.totalLatency.Load() / stats.Calls)
+	}
+	return stats
+}
+
 // LuaScript Lua脚本管理器
 type LuaScript struct {
 	client redis.Cmdable
 	
-	stockDeductScript *redis.Script
-	stockRevertScript *redis.Script
+	stockDeductScript *Script
+	stockRevertScript *Script
 	
-	rateLimitScript *redis.Script
+	rateLimitScript *Script
 	
-	lockScript   *redis.Script
-	unlockScript *redis.Script
+	lockScript   *Script
+	unlockScript *Script
 }
 
 // NewLuaScript 创建Lua脚本管理器
 func NewLuaScript(client redis.Cmdable) *LuaScript {
 	return &LuaScript{
 		client:            client,
-		stockDeductScript: redis.NewScript(StockDeductScript),
-		stockRevertScript: redis.NewScript(StockRevertScript),
-		rateLimitScript:   redis.NewScript(RateLimitScript),
-		lockScript:        redis.NewScript(DistributedLockScript),
-		unlockScript:      redis.NewScript(ReleaseLockScript),
+		stockDeductScript: Scripts.Register("stock_deduct", StockDeductScript),
+		stockRevertScript: Scripts.Register("stock_revert", StockRevertScript),
+		rateLimitScript:   Scripts.Register("rate_limit", RateLimitScript),
+		lockScript:        Scripts.Register("lock_acquire", DistributedLockScript),
+		unlockScript:      Scripts.Register("lock_release", ReleaseLockScript),
 	}
 }
 
 // LoadScripts 预加载所有脚本
 func (ls *LuaScript) LoadScripts(ctx context.Context) error {
-	scripts := []*redis.Script{
+	scripts := []*Script{
 		ls.stockDeductScript,
 		ls.stockRevertScript,
 		ls.rateLimitScript,
@@ -204,7 +367,7 @@ func (ls *LuaScript) LoadScripts(ctx context.Context) error {
 	}
 	
 	for _, script := range scripts {
-		if err := script.Load(ctx, ls.client).Err(); err != nil {
+		if err := script.active().Load(ctx, ls.client).Err(); err != nil {
 			return fmt.Errorf("failed to load lua script: %w", err)
 		}
 	}
@@ -212,10 +375,20 @@ func (ls *LuaScript) LoadScripts(ctx context.Context) error {
 	return nil
 }
 
+// stockScriptKeys 库存脚本的key，以活动ID为hash tag
+func stockScriptKeys(activityID, goodsID, userID, requestID string) []string {
+	return []string{
+		fmt.Sprintf("stock:{%s}:%s", activityID, goodsID),
+		fmt.Sprintf("user_buy:{%s}:%s", activityID, userID),
+		fmt.Sprintf("request:{%s}:%s", activityID, requestID),
+		fmt.Sprintf("stock_log:{%s}:%s", activityID, goodsID),
+	}
+}
+
 // StockDeduct 库存扣减
 func (ls *LuaScript) StockDeduct(ctx context.Context, activityID, goodsID, userID, requestID string, quantity, limitPerUser int, expireTime time.Duration) (int, string, int, error) {
-	keys := []string{activityID, goodsID, userID, requestID}
-	args := []interface{}{quantity, limitPerUser, int(expireTime.Seconds())}
+	keys := stockScriptKeys(activityID, goodsID, userID, requestID)
+	args := []interface{}{quantity, limitPerUser, int(expireTime.Seconds()), userID}
 
 	result, err := ls.stockDeductScript.Run(ctx, ls.client, keys, args...).Result()
 	if err != nil {
@@ -242,8 +415,8 @@ func (ls *LuaScript) StockDeduct(ctx context.Context, activityID, goodsID, userI
 
 // StockRevert 库存回滚
 func (ls *LuaScript) StockRevert(ctx context.Context, activityID, goodsID, userID, requestID string, quantity int, expireTime time.Duration) (int, string, error) {
-	keys := []string{activityID, goodsID, userID, requestID}
-	args := []interface{}{quantity, int(expireTime.Seconds())}
+	keys := stockScriptKeys(activityID, goodsID, userID, requestID)
+	args := []interface{}{quantity, int(expireTime.Seconds()), userID}
 
 	result, err := ls.stockRevertScript.Run(ctx, ls.client, keys, args...).Result()
 	if err != nil {
@@ -377,22 +550,24 @@ func Unlock(ctx context.Context, key, value string) (bool, error) {
 
 // InitStock 初始化库存
 func InitStock(ctx context.Context, activityID, goodsID string, stock int) error {
-	if Client == nil {
+	client := GetClient()
+	if client == nil {
 		return fmt.Errorf("redis client not initialized")
 	}
 	
-	key := fmt.Sprintf("stock:%s:%s", activityID, goodsID)
-	return Client.Set(ctx, key, stock, 0).Err()
+	key := fmt.Sprintf("stock:{%s}:%s", activityID, goodsID)
+	return client.Set(ctx, key, stock, 0).Err()
 }
 
 // GetStock 获取当前库存
 func GetStock(ctx context.Context, activityID, goodsID string) (int, error) {
-	if Client == nil {
+	client := GetClient()
+	if client == nil {
 		return 0, fmt.Errorf("redis client not initialized")
 	}
 	
-	key := fmt.Sprintf("stock:%s:%s", activityID, goodsID)
-	result, err := Client.Get(ctx, key).Result()
+	key := fmt.Sprintf("stock:{%s}:%s", activityID, goodsID)
+	result, err := client.Get(ctx, key).Result()
 	if err != nil {
 		return 0, err
 	}
@@ -402,12 +577,13 @@ func GetStock(ctx context.Context, activityID, goodsID string) (int, error) {
 
 // GetUserBought 获取用户已购买数量
 func GetUserBought(ctx context.Context, activityID, userID string) (int, error) {
-	if Client == nil {
+	client := GetClient()
+	if client == nil {
 		return 0, fmt.Errorf("redis client not initialized")
 	}
 	
-	key := fmt.Sprintf("user_buy:%s:%s", activityID, userID)
-	result, err := Client.Get(ctx, key).Result()
+	key := fmt.Sprintf("user_buy:{%s}:%s", activityID, userID)
+	result, err := client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return 0, nil
 	}
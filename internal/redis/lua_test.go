@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRegistry(t *testing.T) (*ScriptRegistry, *redis.Client, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+	return NewScriptRegistry(), client, mr
+}
+
+func TestScriptRegistry_RunReloadsOnNoScript(t *testing.T) {
+	registry, client, _ := setupRegistry(t)
+	ctx := context.Background()
+	script := registry.Register("incr", `return redis.call('INCRBY', KEYS[1], ARGV[1])`)
+
+	// Not preloaded: the first call loads it
+	n, err := script.Run(ctx, client, []string{"counter"}, 2).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// Script cache flushed, e.g. by a restart or failover
+	require.NoError(t, client.ScriptFlush(ctx).Err())
+	n, err = script.Run(ctx, client, []string{"counter"}, 3).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	stats := script.Stats()
+	assert.Equal(t, "incr", stats.Name)
+	assert.Equal(t, int64(2), stats.Calls)
+	assert.Equal(t, int64(0), stats.Errors)
+	assert.Equal(t, int64(2), stats.Reloads)
+}
+
+func TestScriptRegistry_LoadPreloads(t *testing.T) {
+	registry, client, _ := setupRegistry(t)
+	ctx := context.Background()
+	script := registry.Register("get", `return redis.call('GET', KEYS[1])`)
+
+	require.NoError(t, registry.Load(ctx, client))
+	exists, err := client.ScriptExists(ctx, script.Hash()).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true}, exists)
+
+	// A nil reply is not an error
+	err = script.Run(ctx, client, []string{"missing"}).Err()
+	assert.Equal(t, redis.Nil, err)
+	assert.Equal(t, int64(0), script.Stats().Reloads)
+	assert.Equal(t, int64(0), script.Stats().Errors)
+}
+
+func TestScriptRegistry_DebugVersion(t *testing.T) {
+	registry, client, _ := setupRegistry(t)
+	ctx := context.Background()
+	script := registry.Register("debug", `if DEBUG then return 1 end return 0`)
+	assert.Same(t, script, registry.Register("debug", `return 2`))
+
+	release := script.Hash()
+	n, err := script.Run(ctx, client, nil).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// The debug build is a different version of the script
+	registry.SetDebug(true)
+	assert.NotEqual(t, release, script.Hash())
+	n, err = script.Run(ctx, client, nil).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestScriptRegistry_Stats(t *testing.T) {
+	registry, client, _ := setupRegistry(t)
+	ctx := context.Background()
+	registry.Register("b", `return 1`)
+	failing := registry.Register("a", `return redis.call('INCR', KEYS[1])`)
+
+	require.NoError(t, client.Set(ctx, "text", "x", 0).Err())
+	assert.Error(t, failing.Run(ctx, client, []string{"text"}).Err())
+
+	stats := registry.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, "a", stats[0].Name)
+	assert.Equal(t, int64(1), stats[0].Errors)
+	assert.Equal(t, "b", stats[1].Name)
+	assert.Equal(t, int64(0), stats[1].Calls)
+}
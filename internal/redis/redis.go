@@ -14,6 +14,10 @@ import (
 var (
 	Client        *redis.Client
 	ClusterClient *redis.ClusterClient
+	// ReplicaClient routes read-only queries to Sentinel replicas, nil unless replica reads are enabled
+	ReplicaClient *redis.Client
+	// Sentinels connections to the sentinels, used to watch for failovers
+	Sentinels []*redis.SentinelClient
 )
 
 // Init initializes the Redis client with the given configuration.
@@ -21,6 +25,9 @@ func Init(cfg *config.Config) error {
 	if cfg.Redis.Cluster.Enabled {
 		return initCluster(cfg)
 	}
+	if cfg.Redis.Sentinel.Enabled {
+		return initSentinel(cfg)
+	}
 	return initSingle(cfg)
 }
 
@@ -45,6 +52,9 @@ func initSingle(cfg *config.Config) error {
 	if err := Client.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("failed to connect redis: %w", err)
 	}
+	if cfg.Redis.StrictSlots {
+		RejectCrossSlot(Client)
+	}
 
 	log.Info("Redis single mode connected successfully")
 	return nil
@@ -82,6 +92,59 @@ func initCluster(cfg *config.Config) error {
 	return nil
 }
 
+// initSentinel initializes a Sentinel managed Redis client
+// The failover client always talks to the current primary, following Sentinel across failovers
+func initSentinel(cfg *config.Config) error {
+	sentinel := cfg.Redis.Sentinel
+	options := func(replicaOnly bool) *redis.FailoverOptions {
+		return &redis.FailoverOptions{
+			MasterName:       sentinel.MasterName,
+			SentinelAddrs:    sentinel.Addrs,
+			SentinelPassword: sentinel.SentinelPassword,
+			ReplicaOnly:      replicaOnly,
+			Password:         cfg.Redis.Password,
+			DB:               cfg.Redis.DB,
+
+			PoolSize:        cfg.Redis.PoolSize,
+			MinIdleConns:    cfg.Redis.MinIdleConns,
+			MaxRetries:      cfg.Redis.MaxRetries,
+			DialTimeout:     cfg.Redis.DialTimeout,
+			ReadTimeout:     cfg.Redis.ReadTimeout,
+			WriteTimeout:    cfg.Redis.WriteTimeout,
+			ConnMaxIdleTime: cfg.Redis.IdleTimeout,
+		}
+	}
+
+	Client = redis.NewFailoverClient(options(false))
+	if sentinel.ReplicaReads {
+		ReplicaClient = redis.NewFailoverClient(options(true))
+	}
+	for _, addr := range sentinel.Addrs {
+		Sentinels = append(Sentinels, redis.NewSentinelClient(&redis.Options{
+			Addr:        addr,
+			Password:    sentinel.SentinelPassword,
+			DialTimeout: cfg.Redis.DialTimeout,
+		}))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := Client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect redis sentinel master: %w", err)
+	}
+	if cfg.Redis.StrictSlots {
+		RejectCrossSlot(Client)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"master_name":   sentinel.MasterName,
+		"sentinels":     sentinel.Addrs,
+		"replica_reads": sentinel.ReplicaReads,
+	}).Info("Redis sentinel mode connected successfully")
+	return nil
+}
+
 // Close closes the Redis client connection.
 func Close() error {
 	if ClusterClient != nil {
@@ -89,18 +152,38 @@ func Close() error {
 			return err
 		}
 	}
+	if ReplicaClient != nil {
+		if err := ReplicaClient.Close(); err != nil {
+			return err
+		}
+	}
+	for _, sentinel := range Sentinels {
+		sentinel.Close()
+	}
 	if Client != nil {
 		return Client.Close()
 	}
 	return nil
 }
 
-// GetClient returns the Redis client instance.
-func GetClient() redis.Cmdable {
+// GetClient returns the Redis client instance, single node or cluster depending on config.
+func GetClient() redis.UniversalClient {
 	if ClusterClient != nil {
 		return ClusterClient
 	}
-	return Client
+	if Client != nil {
+		return Client
+	}
+	return nil
+}
+
+// GetReadClient returns the client for read-only queries that tolerate replication lag
+// Falls back to the primary when replica reads are not configured.
+func GetReadClient() redis.UniversalClient {
+	if ReplicaClient != nil {
+		return ReplicaClient
+	}
+	return GetClient()
 }
 
 // Health checks the health status of the Redis client.
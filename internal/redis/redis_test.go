@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 
 	"seckill/internal/config"
@@ -76,6 +77,18 @@ func TestGetClient(t *testing.T) {
 	assert.Nil(t, client)
 }
 
+func TestGetReadClient(t *testing.T) {
+	Client = redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer func() { Client = nil }()
+
+	// Reads go to the primary without replicas
+	assert.Equal(t, redis.UniversalClient(Client), GetReadClient())
+
+	ReplicaClient = redis.NewClient(&redis.Options{Addr: "localhost:6380"})
+	defer func() { ReplicaClient = nil }()
+	assert.Equal(t, redis.UniversalClient(ReplicaClient), GetReadClient())
+}
+
 func TestClose(t *testing.T) {
 	// 测试关闭连接
 	err := Close()
@@ -350,9 +363,7 @@ func TestLuaScriptIntegration(t *testing.T) {
 		assert.Fail(t, "redis client not initialized")
 		return
 	}
-	Client.Del(ctx, "stock:"+activityID+":"+goodsID)
-	Client.Del(ctx, "user_buy:"+activityID+":"+userID)
-	Client.Del(ctx, "request:"+requestID)
+	Client.Del(ctx, stockScriptKeys(activityID, goodsID, userID, requestID)...)
 	Client.Del(ctx, "test_rate_limit")
 	Client.Del(ctx, "test_lock")
 }
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClusterSlots Redis Cluster 的slot总数
+const ClusterSlots = 16384
+
+// ErrCrossSlot 多key命令的key不在同一slot，与集群返回的错误前缀一致
+var ErrCrossSlot = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+
+// Slot 计算key所在的集群slot，遵循{hash tag}规则
+func Slot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % ClusterSlots)
+}
+
+// crc16 CRC16-CCITT(XMODEM)，与Redis Cluster的key分布算法一致
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// RejectCrossSlot 给客户端挂上slot校验钩子
+// 单节点Redis和miniredis不会拒绝跨slot的多key命令，挂上后测试里就能提前发现集群下才会失败的脚本
+func RejectCrossSlot(client redis.UniversalClient) {
+	client.AddHook(crossSlotHook{})
+}
+
+type crossSlotHook struct{}
+
+func (crossSlotHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (crossSlotHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := checkSlot(commandKeys(cmd.Args())); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (crossSlotHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := checkSlot(commandKeys(cmd.Args())); err != nil {
+				cmd.SetErr(err)
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// checkSlot 所有key必须落在同一slot
+func checkSlot(keys []string) error {
+	if len(keys) < 2 {
+		return nil
+	}
+	slot := Slot(keys[0])
+	for _, key := range keys[1:] {
+		if Slot(key) != slot {
+			return fmt.Errorf("%w: %s and %s", ErrCrossSlot, keys[0], key)
+		}
+	}
+	return nil
+}
+
+// commandKeys 提取多key命令的key，单key命令返回nil
+func commandKeys(args []interface{}) []string {
+	if len(args) < 2 {
+		return nil
+	}
+
+	var keys []interface{}
+	switch strings.ToLower(fmt.Sprint(args[0])) {
+	case "eval", "evalsha", "eval_ro", "evalsha_ro":
+		if len(args) < 3 {
+			return nil
+		}
+		n, err := strconv.Atoi(fmt.Sprint(args[2]))
+		if err != nil || n < 0 || 3+n > len(args) {
+			return nil
+		}
+		keys = args[3 : 3+n]
+	case "del", "unlink", "exists", "touch", "mget", "sdiff", "sinter", "sunion":
+		keys = args[1:]
+	default:
+		return nil
+	}
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, fmt.Sprint(key))
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStrictClient(t *testing.T) *redis.Client {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	RejectCrossSlot(client)
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+	return client
+}
+
+func TestSlot(t *testing.T) {
+	// Reference value from the Redis Cluster spec
+	assert.Equal(t, 12739, Slot("123456789"))
+
+	// Only the hash tag counts
+	assert.Equal(t, Slot("user1000"), Slot("{user1000}.following"))
+	assert.Equal(t, Slot("{user1000}.following"), Slot("{user1000}.followers"))
+
+	// Empty or unterminated tags hash the whole key
+	assert.Equal(t, int(crc16("foo{}{bar}")%ClusterSlots), Slot("foo{}{bar}"))
+	assert.Equal(t, int(crc16("foo{bar")%ClusterSlots), Slot("foo{bar"))
+}
+
+func TestRejectCrossSlot(t *testing.T) {
+	client := setupStrictClient(t)
+	ctx := context.Background()
+	script := `return redis.call('MSET', KEYS[1], 1, KEYS[2], 2)`
+
+	err := client.Eval(ctx, script, []string{"stock:1", "stock:2"}).Err()
+	assert.True(t, errors.Is(err, ErrCrossSlot))
+	assert.Contains(t, err.Error(), "CROSSSLOT")
+
+	err = client.Eval(ctx, script, []string{"stock:{1}", "reserved:{1}"}).Err()
+	assert.NoError(t, err)
+
+	// Multi-key commands are checked too, in pipelines as well
+	assert.True(t, errors.Is(client.Del(ctx, "a", "b").Err(), ErrCrossSlot))
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "a")
+	pipe.Exists(ctx, "{x}a", "{y}b")
+	_, err = pipe.Exec(ctx)
+	assert.True(t, errors.Is(err, ErrCrossSlot))
+
+	// Single-key commands are untouched
+	assert.NoError(t, client.Set(ctx, "a", 1, 0).Err())
+}
+
+func TestStockScripts_SingleSlot(t *testing.T) {
+	client := setupStrictClient(t)
+	ctx := context.Background()
+	scripts := NewLuaScript(client)
+
+	require.NoError(t, client.Set(ctx, stockScriptKeys("1", "2", "3", "4")[0], 10, 0).Err())
+
+	code, msg, remain, err := scripts.StockDeduct(ctx, "1", "2", "3", "4", 2, 5, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code, msg)
+	assert.Equal(t, 8, remain)
+
+	code, msg, err = scripts.StockRevert(ctx, "1", "2", "3", "4", 2, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code, msg)
+
+	stock, err := client.Get(ctx, stockScriptKeys("1", "2", "3", "4")[0]).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 10, stock)
+}
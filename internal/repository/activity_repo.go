@@ -10,6 +10,12 @@ import (
 	"seckill/internal/model"
 )
 
+// ErrActivityNotFound activity does not exist
+var ErrActivityNotFound = errors.New("activity not found")
+
+// ErrInsufficientStock conditional stock decrement matched no row
+var ErrInsufficientStock = errors.New("insufficient stock")
+
 // ActivityRepository activity repository interface
 type ActivityRepository interface {
 	// Create activity
@@ -33,6 +39,18 @@ type ActivityRepository interface {
 	// List upcoming activities
 	ListUpcoming(ctx context.Context, limit int) ([]*model.SeckillActivity, error)
 
+	// List activities by status
+	ListByStatus(ctx context.Context, status int8, limit int) ([]*model.SeckillActivity, error)
+
+	// Update activity prewarm status
+	UpdatePrewarmStatus(ctx context.Context, id int64, prewarmStatus int8) error
+
+	// Update activity gray rollout config
+	UpdateGray(ctx context.Context, id int64, strategy *string, ratio float64, whitelist, extConfig model.JSONObject) error
+
+	// Update activity MaxQPS and MaxConcurrent
+	UpdateLimits(ctx context.Context, id int64, maxQPS, maxConcurrent int) error
+
 	// Decrement stock (atomic operation)
 	DecrStock(ctx context.Context, id int64, quantity int) error
 
@@ -61,7 +79,7 @@ func (r *activityRepository) GetByID(ctx context.Context, id int64) (*model.Seck
 	err := r.db.WithContext(ctx).Where("id = ?", id).First(&activity).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("activity not found")
+			return nil, ErrActivityNotFound
 		}
 		return nil, err
 	}
@@ -78,7 +96,7 @@ func (r *activityRepository) GetByIDWithGoods(ctx context.Context, id int64) (*m
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("activity not found")
+			return nil, ErrActivityNotFound
 		}
 		return nil, err
 	}
@@ -143,6 +161,51 @@ func (r *activityRepository) ListUpcoming(ctx context.Context, limit int) ([]*mo
 	return activities, err
 }
 
+// ListByStatus lists activities by status
+func (r *activityRepository) ListByStatus(ctx context.Context, status int8, limit int) ([]*model.SeckillActivity, error) {
+	var activities []*model.SeckillActivity
+
+	err := r.db.WithContext(ctx).
+		Where("status = ?", status).
+		Order("start_time ASC").
+		Limit(limit).
+		Find(&activities).Error
+
+	return activities, err
+}
+
+// UpdatePrewarmStatus updates activity prewarm status
+func (r *activityRepository) UpdatePrewarmStatus(ctx context.Context, id int64, prewarmStatus int8) error {
+	return r.db.WithContext(ctx).
+		Model(&model.SeckillActivity{}).
+		Where("id = ?", id).
+		Update("prewarm_status", prewarmStatus).Error
+}
+
+// UpdateGray updates activity gray rollout config
+func (r *activityRepository) UpdateGray(ctx context.Context, id int64, strategy *string, ratio float64, whitelist, extConfig model.JSONObject) error {
+	return r.db.WithContext(ctx).
+		Model(&model.SeckillActivity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"gray_strategy":  strategy,
+			"gray_ratio":     ratio,
+			"gray_whitelist": whitelist,
+			"ext_config":     extConfig,
+		}).Error
+}
+
+// UpdateLimits updates activity MaxQPS and MaxConcurrent
+func (r *activityRepository) UpdateLimits(ctx context.Context, id int64, maxQPS, maxConcurrent int) error {
+	return r.db.WithContext(ctx).
+		Model(&model.SeckillActivity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"max_qps":        maxQPS,
+			"max_concurrent": maxConcurrent,
+		}).Error
+}
+
 // DecrStock decrements stock (atomic operation)
 func (r *activityRepository) DecrStock(ctx context.Context, id int64, quantity int) error {
 	result := r.db.WithContext(ctx).
@@ -158,7 +221,7 @@ func (r *activityRepository) DecrStock(ctx context.Context, id int64, quantity i
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("insufficient stock")
+		return ErrInsufficientStock
 	}
 
 	return nil
@@ -219,6 +219,113 @@ func TestActivityRepository_UpdateStatus(t *testing.T) {
 	}
 }
 
+func TestActivityRepository_UpdatePrewarmStatus(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewActivityRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `seckill_activities` SET `prewarm_status`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs(int8(model.PrewarmStatusDone), sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := repo.UpdatePrewarmStatus(context.Background(), 1, model.PrewarmStatusDone)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestActivityRepository_UpdateGray(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewActivityRepository(db)
+	strategy := "whitelist"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `seckill_activities` SET `ext_config`=\\?,`gray_ratio`=\\?,`gray_strategy`=\\?,`gray_whitelist`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), 0.0, &strategy, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := repo.UpdateGray(context.Background(), 1, &strategy, 0, model.JSONObject{"user_ids": []uint64{1001}}, nil)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestActivityRepository_UpdateLimits(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewActivityRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `seckill_activities` SET `max_concurrent`=\\?,`max_qps`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs(50, 200, sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := repo.UpdateLimits(context.Background(), 1, 200, 50)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestActivityRepository_ListByStatus(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewActivityRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "status"}).
+		AddRow(1, "Activity 1", model.ActivityStatusRunning).
+		AddRow(2, "Activity 2", model.ActivityStatusRunning)
+
+	mock.ExpectQuery("SELECT \\* FROM `seckill_activities` WHERE status = \\? ORDER BY start_time ASC LIMIT \\?").
+		WithArgs(int8(model.ActivityStatusRunning), 100).
+		WillReturnRows(rows)
+
+	activities, err := repo.ListByStatus(context.Background(), model.ActivityStatusRunning, 100)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if len(activities) != 2 {
+		t.Errorf("Expected 2 activities, got %d", len(activities))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestActivityRepository_ListActive(t *testing.T) {
 	db, mock := setupActivityMockDB(t)
 	defer func() {
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"seckill/internal/model"
+)
+
+// Blacklist scopes for BlacklistFilter
+const (
+	BlacklistScopeGlobal   = "global"
+	BlacklistScopeActivity = "activity"
+)
+
+// BlacklistFilter blacklist search conditions, zero values are ignored
+type BlacklistFilter struct {
+	UserID         uint64
+	ActivityID     uint64
+	Scope          string // global, activity or empty for both
+	Keyword        string // Matches reason or operator
+	IncludeExpired bool
+}
+
+// BlacklistRepository blacklist repository interface
+type BlacklistRepository interface {
+	// Upsert creates an entry or replaces the one of the same user and activity
+	Upsert(ctx context.Context, entry *model.Blacklist) error
+
+	// Get gets an entry by user and activity, activityID 0 is the global entry
+	Get(ctx context.Context, userID, activityID uint64) (*model.Blacklist, error)
+
+	// Delete deletes an entry by user and activity
+	Delete(ctx context.Context, userID, activityID uint64) error
+
+	// List lists entries matching the filter with pagination
+	List(ctx context.Context, filter BlacklistFilter, page, pageSize int) ([]*model.Blacklist, int64, error)
+
+	// ListUnexpired lists all entries not expired at now
+	ListUnexpired(ctx context.Context, now time.Time) ([]*model.Blacklist, error)
+}
+
+// blacklistRepository blacklist repository implementation
+type blacklistRepository struct {
+	db *gorm.DB
+}
+
+// NewBlacklistRepository creates a blacklist repository
+func NewBlacklistRepository(db *gorm.DB) BlacklistRepository {
+	return &blacklistRepository{db: db}
+}
+
+// Upsert creates an entry or replaces the one of the same user and activity
+func (r *blacklistRepository) Upsert(ctx context.Context, entry *model.Blacklist) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "operator", "expire_at", "updated_at"}),
+		}).
+		Create(entry).Error
+}
+
+// Get gets an entry by user and activity
+func (r *blacklistRepository) Get(ctx context.Context, userID, activityID uint64) (*model.Blacklist, error) {
+	var entry model.Blacklist
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND activity_id = ?", userID, activityID).
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Delete deletes an entry by user and activity
+func (r *blacklistRepository) Delete(ctx context.Context, userID, activityID uint64) error {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND activity_id = ?", userID, activityID).
+		Delete(&model.Blacklist{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// List lists entries matching the filter with pagination
+func (r *blacklistRepository) List(ctx context.Context, filter BlacklistFilter, page, pageSize int) ([]*model.Blacklist, int64, error) {
+	var entries []*model.Blacklist
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.Blacklist{})
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	switch {
+	case filter.ActivityID > 0:
+		query = query.Where("activity_id = ?", filter.ActivityID)
+	case filter.Scope == BlacklistScopeGlobal:
+		query = query.Where("activity_id = 0")
+	case filter.Scope == BlacklistScopeActivity:
+		query = query.Where("activity_id > 0")
+	}
+	if filter.Keyword != "" {
+		like := "%" + filter.Keyword + "%"
+		query = query.Where("reason LIKE ? OR operator LIKE ?", like, like)
+	}
+	if !filter.IncludeExpired {
+		query = query.Where("expire_at IS NULL OR expire_at > ?", time.Now())
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.
+		Order("id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// ListUnexpired lists all entries not expired at now
+func (r *blacklistRepository) ListUnexpired(ctx context.Context, now time.Time) ([]*model.Blacklist, error) {
+	var entries []*model.Blacklist
+	err := r.db.WithContext(ctx).
+		Where("expire_at IS NULL OR expire_at > ?", now).
+		Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"seckill/internal/model"
+)
+
+func TestBlacklistRepository_Upsert(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewBlacklistRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_blacklists` .* ON DUPLICATE KEY UPDATE `reason`=VALUES\\(`reason`\\),`operator`=VALUES\\(`operator`\\),`expire_at`=VALUES\\(`expire_at`\\)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = repo.Upsert(context.Background(), &model.Blacklist{
+		UserID:     1001,
+		ActivityID: 1,
+		Reason:     "scalper",
+		Operator:   "1",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBlacklistRepository_Delete(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewBlacklistRepository(db)
+
+	t.Run("deleted", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `user_blacklists` WHERE user_id = \\? AND activity_id = \\?").
+			WithArgs(uint64(1001), uint64(0)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.Delete(context.Background(), 1001, 0))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `user_blacklists`").
+			WithArgs(uint64(1002), uint64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.Delete(context.Background(), 1002, 3)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBlacklistRepository_List(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewBlacklistRepository(db)
+
+	filter := BlacklistFilter{
+		UserID:  1001,
+		Scope:   BlacklistScopeGlobal,
+		Keyword: "scalp",
+	}
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `user_blacklists` WHERE user_id = \\? AND activity_id = 0 AND \\(reason LIKE \\? OR operator LIKE \\?\\) AND \\(expire_at IS NULL OR expire_at > \\?\\)").
+		WithArgs(uint64(1001), "%scalp%", "%scalp%", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "activity_id", "reason", "operator", "expire_at", "created_at", "updated_at"}).
+		AddRow(1, 1001, 0, "scalper", "1", nil, time.Now(), time.Now())
+	mock.ExpectQuery("SELECT \\* FROM `user_blacklists` WHERE .* ORDER BY id DESC LIMIT \\?").
+		WithArgs(uint64(1001), "%scalp%", "%scalp%", sqlmock.AnyArg(), 20).
+		WillReturnRows(rows)
+
+	entries, total, err := repo.List(context.Background(), filter, 1, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, entries, 1)
+	assert.True(t, entries[0].IsGlobal())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBlacklistRepository_ListUnexpired(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewBlacklistRepository(db)
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "activity_id", "reason", "operator", "expire_at"}).
+		AddRow(1, 1001, 0, "scalper", "1", nil).
+		AddRow(2, 1002, 5, "bot", "1", now.Add(time.Hour))
+	mock.ExpectQuery("SELECT \\* FROM `user_blacklists` WHERE expire_at IS NULL OR expire_at > \\?").
+		WithArgs(now).
+		WillReturnRows(rows)
+
+	entries, err := repo.ListUnexpired(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.False(t, entries[1].IsGlobal())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
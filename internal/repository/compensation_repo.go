@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"seckill/internal/model"
+)
+
+// ErrDeadLetterNotFound no dead letter with the given ID
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// CompensationRepository TCC compensation queue and dead letter repository interface
+type CompensationRepository interface {
+	// Enqueue queues a compensation, an operation already queued for the deduction is kept
+	Enqueue(ctx context.Context, item *model.TCCCompensation) error
+
+	// ClaimDue locks up to limit compensations due at now, oldest first, skipping those another
+	// instance holds, and hands them to fn with a repository bound to the claim. What fn records
+	// through it commits with the claim when fn returns nil.
+	ClaimDue(ctx context.Context, now time.Time, limit int, fn func(claim CompensationRepository, items []*model.TCCCompensation) error) error
+
+	// Reschedule records a failed attempt and the time of the next one
+	Reschedule(ctx context.Context, id uint64, attempts int, nextRetryAt time.Time, lastError string) error
+
+	// Complete removes a compensation that succeeded
+	Complete(ctx context.Context, id uint64) error
+
+	// Escalate moves a compensation to the dead letter table
+	Escalate(ctx context.Context, item *model.TCCCompensation) error
+
+	// ListDeadLetters lists dead letters, status < 0 lists all of them
+	ListDeadLetters(ctx context.Context, status int, page, pageSize int) ([]*model.TCCDeadLetter, int64, error)
+
+	// GetDeadLetter gets a dead letter by ID
+	GetDeadLetter(ctx context.Context, id uint64) (*model.TCCDeadLetter, error)
+
+	// ResolveDeadLetter closes an open dead letter, false when it is already closed
+	ResolveDeadLetter(ctx context.Context, id uint64, status int8, resolvedBy, note string) (bool, error)
+}
+
+// compensationRepository compensation repository implementation
+type compensationRepository struct {
+	db *gorm.DB
+}
+
+// NewCompensationRepository creates a compensation repository
+func NewCompensationRepository(db *gorm.DB) CompensationRepository {
+	return &compensationRepository{db: db}
+}
+
+// Enqueue queues a compensation
+func (r *compensationRepository) Enqueue(ctx context.Context, item *model.TCCCompensation) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(item).Error
+}
+
+// ClaimDue claims compensations due at now, so no two instances retry the same one
+func (r *compensationRepository) ClaimDue(ctx context.Context, now time.Time, limit int, fn func(claim CompensationRepository, items []*model.TCCCompensation) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var items []*model.TCCCompensation
+		err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("next_retry_at <= ?", now).
+			Order("next_retry_at ASC").
+			Limit(limit).
+			Find(&items).Error
+		if err != nil || len(items) == 0 {
+			return err
+		}
+		return fn(NewCompensationRepository(tx), items)
+	})
+}
+
+// Reschedule records a failed attempt
+func (r *compensationRepository) Reschedule(ctx context.Context, id uint64, attempts int, nextRetryAt time.Time, lastError string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.TCCCompensation{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":      attempts,
+			"next_retry_at": nextRetryAt,
+			"last_error":    lastError,
+		}).Error
+}
+
+// Complete removes a compensation that succeeded
+func (r *compensationRepository) Complete(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Delete(&model.TCCCompensation{}, id).Error
+}
+
+// Escalate moves a compensation to the dead letter table
+func (r *compensationRepository) Escalate(ctx context.Context, item *model.TCCCompensation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Another instance escalating the same item deletes nothing and adds nothing
+		result := tx.Delete(&model.TCCCompensation{}, item.ID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Create(&model.TCCDeadLetter{
+			DeductID:   item.DeductID,
+			Operation:  item.Operation,
+			ActivityID: item.ActivityID,
+			Quantity:   item.Quantity,
+			Attempts:   item.Attempts,
+			LastError:  item.LastError,
+			Status:     model.DeadLetterStatusOpen,
+		}).Error
+	})
+}
+
+// ListDeadLetters lists dead letters, newest first
+func (r *compensationRepository) ListDeadLetters(ctx context.Context, status int, page, pageSize int) ([]*model.TCCDeadLetter, int64, error) {
+	var letters []*model.TCCDeadLetter
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.TCCDeadLetter{})
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.
+		Order("id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&letters).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return letters, total, nil
+}
+
+// GetDeadLetter gets a dead letter by ID
+func (r *compensationRepository) GetDeadLetter(ctx context.Context, id uint64) (*model.TCCDeadLetter, error) {
+	var letter model.TCCDeadLetter
+	if err := r.db.WithContext(ctx).First(&letter, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// ResolveDeadLetter closes an open dead letter
+func (r *compensationRepository) ResolveDeadLetter(ctx context.Context, id uint64, status int8, resolvedBy, note string) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&model.TCCDeadLetter{}).
+		Where("id = ? AND status = ?", id, model.DeadLetterStatusOpen).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"resolved_by":  resolvedBy,
+			"resolve_note": note,
+			"resolved_at":  now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
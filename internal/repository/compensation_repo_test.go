@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+)
+
+func TestCompensationRepository_Enqueue(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewCompensationRepository(db)
+
+	// A second failure of the same operation keeps the queued one
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tcc_compensations` .* ON DUPLICATE KEY UPDATE `id`=`id`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := repo.Enqueue(context.Background(), &model.TCCCompensation{
+		DeductID:    "deduct:r1:1",
+		Operation:   model.TCCOperationConfirm,
+		ActivityID:  1,
+		Quantity:    1,
+		Attempts:    1,
+		NextRetryAt: time.Now(),
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCompensationRepository_ClaimDue(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewCompensationRepository(db)
+	now := time.Now()
+
+	// Compensations are moved on before the claim commits
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `tcc_compensations` WHERE next_retry_at <= \\? ORDER BY next_retry_at ASC LIMIT \\? FOR UPDATE SKIP LOCKED").
+		WithArgs(now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "deduct_id", "operation", "attempts"}).
+			AddRow(1, "deduct:r1:1", model.TCCOperationConfirm, 1).
+			AddRow(2, "deduct:r2:1", model.TCCOperationCancel, 2))
+	mock.ExpectExec("DELETE FROM `tcc_compensations` WHERE `tcc_compensations`.`id` = \\?").
+		WithArgs(uint64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `tcc_compensations` SET `attempts`=\\?,`last_error`=\\?,`next_retry_at`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs(3, "i/o timeout", sqlmock.AnyArg(), sqlmock.AnyArg(), uint64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.ClaimDue(context.Background(), now, 10, func(claim CompensationRepository, items []*model.TCCCompensation) error {
+		require.Len(t, items, 2)
+		if err := claim.Complete(context.Background(), items[0].ID); err != nil {
+			return err
+		}
+		return claim.Reschedule(context.Background(), items[1].ID, 3, now.Add(time.Minute), "i/o timeout")
+	})
+	require.NoError(t, err)
+
+	// Nothing due, or everything claimed by another instance: fn is not called
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `tcc_compensations`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+	err = repo.ClaimDue(context.Background(), now, 10, func(claim CompensationRepository, items []*model.TCCCompensation) error {
+		t.Fatal("fn called without due compensations")
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCompensationRepository_Escalate(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewCompensationRepository(db)
+	item := &model.TCCCompensation{ID: 3, DeductID: "deduct:r1:1", Operation: model.TCCOperationCancel, ActivityID: 1, Quantity: 2, Attempts: 8, LastError: "i/o timeout"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `tcc_compensations` WHERE `tcc_compensations`.`id` = \\?").
+		WithArgs(uint64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `tcc_dead_letters`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.Escalate(context.Background(), item))
+
+	// Already escalated by another instance: no second dead letter
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `tcc_compensations`").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	require.NoError(t, repo.Escalate(context.Background(), item))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCompensationRepository_ResolveDeadLetter(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewCompensationRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tcc_dead_letters` SET `resolve_note`=\\?,`resolved_at`=\\?,`resolved_by`=\\?,`status`=\\?,`updated_at`=\\? WHERE id = \\? AND status = \\?").
+		WithArgs("refunded", sqlmock.AnyArg(), "1", model.DeadLetterStatusResolved, sqlmock.AnyArg(), uint64(5), model.DeadLetterStatusOpen).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	resolved, err := repo.ResolveDeadLetter(context.Background(), 5, model.DeadLetterStatusResolved, "1", "refunded")
+	require.NoError(t, err)
+	assert.True(t, resolved)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCompensationRepository_GetDeadLetterNotFound(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewCompensationRepository(db)
+
+	mock.ExpectQuery("SELECT \\* FROM `tcc_dead_letters` WHERE `tcc_dead_letters`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.GetDeadLetter(context.Background(), 5)
+	assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -88,7 +88,7 @@ func (r *goodsRepository) DecrStock(ctx context.Context, id uint64, quantity int
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("insufficient stock")
+		return ErrInsufficientStock
 	}
 
 	return nil
@@ -31,11 +31,17 @@ type OrderRepository interface {
 
 	// List expired orders
 	ListExpiredOrders(ctx context.Context, limit int) ([]*model.Order, error)
+
+	// List activity orders with ID greater than afterID, in ID order
+	ListByActivity(ctx context.Context, activityID, afterID uint64, limit int) ([]*model.Order, error)
 }
 
 // orderRepository order repository implementation
 type orderRepository struct {
 	db *gorm.DB
+
+	// Whether order changes are announced in the outbox
+	outbox bool
 }
 
 // NewOrderRepository creates an order repository
@@ -43,6 +49,12 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
+// NewOrderRepositoryWithOutbox creates an order repository announcing order changes in the
+// outbox. Only use it while the outbox relay runs, nothing else drains or prunes the table.
+func NewOrderRepositoryWithOutbox(db *gorm.DB) OrderRepository {
+	return &orderRepository{db: db, outbox: true}
+}
+
 // Create creates an order
 func (r *orderRepository) Create(ctx context.Context, order *model.Order) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -62,7 +74,11 @@ func (r *orderRepository) Create(ctx context.Context, order *model.Order) error
 			}
 		}
 
-		return nil
+		// Announce the order in the same transaction
+		if !r.outbox {
+			return nil
+		}
+		return insertOrderEvent(tx, order, model.EventOrderCreated)
 	})
 }
 
@@ -134,10 +150,21 @@ func (r *orderRepository) UpdateStatus(ctx context.Context, id uint64, status in
 		// Keep original cancel/refund time if already set
 	}
 
-	return r.db.WithContext(ctx).
-		Model(&model.Order{}).
-		Where("id = ?", id).
-		Updates(updates).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Order{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		eventType, ok := orderStatusEvents[status]
+		if !ok || !r.outbox {
+			return nil
+		}
+		var order model.Order
+		if err := tx.Where("id = ?", id).First(&order).Error; err != nil {
+			return err
+		}
+		return insertOrderEvent(tx, &order, eventType)
+	})
 }
 
 // ListUserOrders lists user orders
@@ -179,3 +206,15 @@ func (r *orderRepository) ListExpiredOrders(ctx context.Context, limit int) ([]*
 	return orders, err
 }
 
+// ListByActivity lists activity orders after afterID, for walking every order of an activity
+func (r *orderRepository) ListByActivity(ctx context.Context, activityID, afterID uint64, limit int) ([]*model.Order, error) {
+	var orders []*model.Order
+
+	err := r.db.WithContext(ctx).
+		Where("activity_id = ? AND id > ?", activityID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&orders).Error
+
+	return orders, err
+}
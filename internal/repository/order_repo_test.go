@@ -35,7 +35,7 @@ func TestOrderRepository_Create(t *testing.T) {
 		sqlDB.Close()
 	}()
 
-	repo := NewOrderRepository(db)
+	repo := NewOrderRepositoryWithOutbox(db)
 	ctx := context.Background()
 
 	order := &model.Order{
@@ -69,6 +69,9 @@ func TestOrderRepository_Create(t *testing.T) {
 			order.Status, order.PaymentMethod, order.PaymentNo, order.PaidAt, order.ExpireAt, 
 			order.CancelReason, order.Remark, order.DeductID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO `outbox`").
+		WithArgs(model.EventOrderCreated, order.OrderNo, sqlmock.AnyArg(), model.OutboxStatusPending, 0, "", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	err := repo.Create(ctx, order)
@@ -192,7 +195,7 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 		sqlDB.Close()
 	}()
 
-	repo := NewOrderRepository(db)
+	repo := NewOrderRepositoryWithOutbox(db)
 	ctx := context.Background()
 
 	orderID := uint64(1)
@@ -202,6 +205,12 @@ func TestOrderRepository_UpdateStatus(t *testing.T) {
 	mock.ExpectExec("UPDATE `orders` SET `paid_at`=\\?,`status`=\\?,`updated_at`=\\? WHERE id = \\?").
 		WithArgs(sqlmock.AnyArg(), newStatus, sqlmock.AnyArg(), orderID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\? ORDER BY `orders`.`id` LIMIT \\?").
+		WithArgs(orderID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_no", "status"}).AddRow(orderID, "ORDER123456789", newStatus))
+	mock.ExpectExec("INSERT INTO `outbox`").
+		WithArgs(model.EventOrderPaid, "ORDER123456789", sqlmock.AnyArg(), model.OutboxStatusPending, 0, "", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	err := repo.UpdateStatus(ctx, orderID, newStatus)
@@ -296,6 +305,36 @@ func TestOrderRepository_ListExpiredOrders(t *testing.T) {
 	}
 }
 
+func TestOrderRepository_ListByActivity(t *testing.T) {
+	db, mock := setupOrderMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewOrderRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "order_no", "activity_id", "deduct_id"}).
+		AddRow(101, "ORDER101", 1, "deduct:r1:1").
+		AddRow(102, "ORDER102", 1, "deduct:r2:1")
+
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE activity_id = \\? AND id > \\? ORDER BY id ASC LIMIT \\?").
+		WithArgs(uint64(1), uint64(100), 1000).
+		WillReturnRows(rows)
+
+	orders, err := repo.ListByActivity(context.Background(), 1, 100, 1000)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(orders) != 2 || orders[1].DeductID != "deduct:r2:1" {
+		t.Errorf("Unexpected orders: %+v", orders)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestOrderRepository_Interface(t *testing.T) {
 	db, _ := setupOrderMockDB(t)
 	defer func() {
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"seckill/internal/model"
+)
+
+// orderStatusEvents events announcing an order status change
+var orderStatusEvents = map[int8]string{
+	model.OrderStatusPaid:      model.EventOrderPaid,
+	model.OrderStatusCancelled: model.EventOrderCancelled,
+}
+
+// OutboxRepository outbox repository interface, events are written by the repositories
+// changing the state they announce and read back here by the relay
+type OutboxRepository interface {
+	// ClaimPending locks the oldest undelivered event of up to limit orders, skipping those another
+	// relay holds, and hands them to fn with a repository bound to the claim. What fn records
+	// through it commits with the claim when fn returns nil.
+	ClaimPending(ctx context.Context, limit int, fn func(claim OutboxRepository, events []*model.OutboxEvent) error) error
+
+	// MarkDelivered marks an event published
+	MarkDelivered(ctx context.Context, id uint64) error
+
+	// RecordFailure records a failed publish, the event stays pending
+	RecordFailure(ctx context.Context, id uint64, lastError string) error
+
+	// DeleteDelivered removes events delivered before the given time, returning how many
+	DeleteDelivered(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// outboxRepository outbox repository implementation
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates an outbox repository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// ClaimPending claims the oldest undelivered event of each order, oldest first
+func (r *outboxRepository) ClaimPending(ctx context.Context, limit int, fn func(claim OutboxRepository, events []*model.OutboxEvent) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A later event waits while an earlier one of its order is pending, even one claimed
+		// by another relay, so no relay delivers it ahead
+		var events []*model.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("status = ?", model.OutboxStatusPending).
+			Where("NOT EXISTS (SELECT 1 FROM outbox earlier WHERE earlier.aggregate_id = outbox.aggregate_id AND earlier.status = ? AND earlier.id < outbox.id)", model.OutboxStatusPending).
+			Order("id ASC").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		return fn(NewOutboxRepository(tx), events)
+	})
+}
+
+// MarkDelivered marks an event published
+func (r *outboxRepository) MarkDelivered(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).
+		Model(&model.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       model.OutboxStatusDelivered,
+			"delivered_at": time.Now(),
+		}).Error
+}
+
+// RecordFailure records a failed publish
+func (r *outboxRepository) RecordFailure(ctx context.Context, id uint64, lastError string) error {
+	return r.db.WithContext(ctx).
+		Model(&model.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": lastError,
+		}).Error
+}
+
+// DeleteDelivered removes events delivered before the given time
+func (r *outboxRepository) DeleteDelivered(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND delivered_at < ?", model.OutboxStatusDelivered, before).
+		Limit(limit).
+		Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// insertOrderEvent writes an order event to the outbox within tx
+func insertOrderEvent(tx *gorm.DB, order *model.Order, eventType string) error {
+	payload, err := json.Marshal(&model.OrderEvent{
+		EventID:       order.OrderNo + ":" + eventType,
+		EventType:     eventType,
+		OrderID:       order.ID,
+		OrderNo:       order.OrderNo,
+		RequestID:     order.RequestID,
+		UserID:        order.UserID,
+		ActivityID:    order.ActivityID,
+		GoodsID:       order.GoodsID,
+		Quantity:      order.Quantity,
+		PaymentAmount: order.PaymentAmount,
+		Status:        order.Status,
+		DeductID:      order.DeductID,
+		OccurredAt:    time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&model.OutboxEvent{
+		Topic:       eventType,
+		AggregateID: order.OrderNo,
+		Payload:     string(payload),
+		Status:      model.OutboxStatusPending,
+	}).Error
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+)
+
+func TestOrderRepository_UpdateStatusWithoutEvent(t *testing.T) {
+	db, mock := setupOrderMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewOrderRepository(db)
+
+	// Completing an order announces nothing
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `orders` SET `status`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs(int8(model.OrderStatusCompleted), sqlmock.AnyArg(), uint64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.UpdateStatus(context.Background(), 1, model.OrderStatusCompleted))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderRepository_WithoutOutbox(t *testing.T) {
+	db, mock := setupOrderMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewOrderRepository(db)
+
+	// Without the relay nothing would drain the outbox, so paying an order writes no event
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `orders` SET `paid_at`=\\?,`status`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.UpdateStatus(context.Background(), 1, model.OrderStatusPaid))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_ClaimPending(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewOutboxRepository(db)
+
+	// Events are marked before the claim commits
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `outbox` WHERE status = \\? AND \\(NOT EXISTS \\(SELECT 1 FROM outbox earlier WHERE earlier.aggregate_id = outbox.aggregate_id AND earlier.status = \\? AND earlier.id < outbox.id\\)\\) ORDER BY id ASC LIMIT \\? FOR UPDATE SKIP LOCKED").
+		WithArgs(model.OutboxStatusPending, model.OutboxStatusPending, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "aggregate_id", "payload"}).
+			AddRow(1, model.EventOrderCreated, "ORDER1", `{"event_id":"ORDER1:OrderCreated"}`).
+			AddRow(3, model.EventOrderCreated, "ORDER2", `{"event_id":"ORDER2:OrderCreated"}`))
+	mock.ExpectExec("UPDATE `outbox` SET `delivered_at`=\\?,`status`=\\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), model.OutboxStatusDelivered, uint64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `outbox` SET `attempts`=attempts \\+ 1,`last_error`=\\? WHERE id = \\?").
+		WithArgs("publish timeout", uint64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var claimed []*model.OutboxEvent
+	err := repo.ClaimPending(context.Background(), 10, func(claim OutboxRepository, events []*model.OutboxEvent) error {
+		claimed = events
+		if err := claim.MarkDelivered(context.Background(), events[0].ID); err != nil {
+			return err
+		}
+		return claim.RecordFailure(context.Background(), events[1].ID, "publish timeout")
+	})
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, "ORDER2", claimed[1].AggregateID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_ClaimPendingRollsBack(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewOutboxRepository(db)
+
+	// A failed claim leaves its events pending for the next run
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `outbox`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "aggregate_id", "payload"}).
+			AddRow(1, model.EventOrderCreated, "ORDER1", `{}`))
+	mock.ExpectRollback()
+
+	err := repo.ClaimPending(context.Background(), 10, func(claim OutboxRepository, events []*model.OutboxEvent) error {
+		return errors.New("mark failed")
+	})
+	assert.EqualError(t, err, "mark failed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_MarkDeliveredAndFailure(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewOutboxRepository(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `outbox` SET `delivered_at`=\\?,`status`=\\? WHERE id = \\?").
+		WithArgs(sqlmock.AnyArg(), model.OutboxStatusDelivered, uint64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.MarkDelivered(ctx, 1))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `outbox` SET `attempts`=attempts \\+ 1,`last_error`=\\? WHERE id = \\?").
+		WithArgs("publish timeout", uint64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.RecordFailure(ctx, 2, "publish timeout"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `outbox` WHERE status = \\? AND delivered_at < \\? LIMIT \\?").
+		WithArgs(model.OutboxStatusDelivered, sqlmock.AnyArg(), 100).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectCommit()
+	deleted, err := repo.DeleteDelivered(ctx, time.Now(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
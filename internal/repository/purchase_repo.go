@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"seckill/internal/model"
+)
+
+// ErrPurchaseLimitExceeded the user already holds the activity's purchase limit
+var ErrPurchaseLimitExceeded = errors.New("purchase limit exceeded")
+
+// PurchaseRepository purchase repository interface, the MySQL side of stock deduction
+type PurchaseRepository interface {
+	// Try deducts activity stock and records the purchase in one transaction, returning the stock left.
+	// Units sold on Redis, as their orders and pending TCC logs show, are neither sold again nor let
+	// past the purchase limit.
+	Try(ctx context.Context, purchase *model.Purchase, limitPerUser int) (int, error)
+
+	// Confirm confirms a pending purchase, false when it is not pending
+	Confirm(ctx context.Context, deductID string) (bool, error)
+
+	// Cancel cancels a pending purchase and returns its stock, giving back the purchase and the
+	// stock left. The purchase is nil when it was not pending.
+	Cancel(ctx context.Context, deductID string) (*model.Purchase, int, error)
+
+	// GetByDeductID gets a purchase by deduction ID, nil when there is none
+	GetByDeductID(ctx context.Context, deductID string) (*model.Purchase, error)
+}
+
+// purchaseRepository purchase repository implementation
+type purchaseRepository struct {
+	db *gorm.DB
+}
+
+// NewPurchaseRepository creates a purchase repository
+func NewPurchaseRepository(db *gorm.DB) PurchaseRepository {
+	return &purchaseRepository{db: db}
+}
+
+// Try deducts activity stock and records the purchase in one transaction, returning the stock left
+func (r *purchaseRepository) Try(ctx context.Context, purchase *model.Purchase, limitPerUser int) (int, error) {
+	stock := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The conditional decrement locks the activity row, so the limit check below
+		// cannot race with another purchase of the same activity
+		if err := NewActivityRepository(tx).DecrStock(ctx, int64(purchase.ActivityID), purchase.Quantity); err != nil {
+			return err
+		}
+		var err error
+		if stock, err = stockOf(tx, purchase.ActivityID); err != nil {
+			return err
+		}
+
+		// Redis sales never reach the stock column, the units they hold are not for sale here
+		sold, err := soldOnRedis(tx, purchase.ActivityID, 0)
+		if err != nil {
+			return err
+		}
+		if stock < sold {
+			return ErrInsufficientStock
+		}
+		stock -= sold
+
+		if limitPerUser > 0 {
+			var bought int64
+			err := tx.Model(&model.Purchase{}).
+				Select("COALESCE(SUM(quantity), 0)").
+				Where("activity_id = ? AND user_id = ? AND status <> ?", purchase.ActivityID, purchase.UserID, model.PurchaseStatusCancelled).
+				Scan(&bought).Error
+			if err != nil {
+				return err
+			}
+			sold, err := soldOnRedis(tx, purchase.ActivityID, purchase.UserID)
+			if err != nil {
+				return err
+			}
+			if int(bought)+sold+purchase.Quantity > limitPerUser {
+				return ErrPurchaseLimitExceeded
+			}
+		}
+
+		purchase.Status = model.PurchaseStatusTry
+		return tx.Create(purchase).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return stock, nil
+}
+
+// Confirm confirms a pending purchase
+func (r *purchaseRepository) Confirm(ctx context.Context, deductID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.Purchase{}).
+		Where("deduct_id = ? AND status = ?", deductID, model.PurchaseStatusTry).
+		Update("status", model.PurchaseStatusConfirmed)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Cancel cancels a pending purchase and returns its stock
+func (r *purchaseRepository) Cancel(ctx context.Context, deductID string) (*model.Purchase, int, error) {
+	var cancelled *model.Purchase
+	stock := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var purchase model.Purchase
+		if err := tx.Where("deduct_id = ?", deductID).First(&purchase).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		// Only the transition out of try returns stock, repeated cancels are no-ops
+		result := tx.Model(&model.Purchase{}).
+			Where("id = ? AND status = ?", purchase.ID, model.PurchaseStatusTry).
+			Update("status", model.PurchaseStatusCancelled)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := NewActivityRepository(tx).IncrStock(ctx, int64(purchase.ActivityID), purchase.Quantity); err != nil {
+			return err
+		}
+		var err error
+		if stock, err = stockOf(tx, purchase.ActivityID); err != nil {
+			return err
+		}
+		sold, err := soldOnRedis(tx, purchase.ActivityID, 0)
+		if err != nil {
+			return err
+		}
+		stock -= sold
+		cancelled = &purchase
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return cancelled, stock, nil
+}
+
+// soldOnRedis sums the units of an activity deducted from Redis and not cancelled, those of
+// one user when userID is set. Both queries run under the activity row lock and are covered
+// by the orders and tcc_logs (activity_id, ...) indexes.
+func soldOnRedis(tx *gorm.DB, activityID, userID uint64) (int, error) {
+	ordered, err := orderedOnRedis(tx, activityID, userID)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := pendingOnRedis(tx, activityID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return ordered + pending, nil
+}
+
+// orderedOnRedis sums the units of live orders deducted from Redis. Orders of MySQL
+// deductions are left out, their purchases already count.
+func orderedOnRedis(tx *gorm.DB, activityID, userID uint64) (int, error) {
+	query := tx.Model(&model.Order{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("activity_id = ? AND status <> ?", activityID, model.OrderStatusCancelled).
+		Where("NOT EXISTS (SELECT 1 FROM seckill_purchases WHERE seckill_purchases.deduct_id = orders.deduct_id)")
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var ordered int64
+	err := query.Scan(&ordered).Error
+	return int(ordered), err
+}
+
+// pendingOnRedis sums the units of Redis tries whose order is not written yet, as their
+// TCC logs show. MySQL deduction IDs start with mysql: and are left out.
+func pendingOnRedis(tx *gorm.DB, activityID, userID uint64) (int, error) {
+	query := tx.Model(&model.TCCLog{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("activity_id = ? AND status = ? AND deduct_id NOT LIKE ?", activityID, model.TCCStatusTry, "mysql:%").
+		Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.deduct_id = tcc_logs.deduct_id)")
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var pending int64
+	err := query.Scan(&pending).Error
+	return int(pending), err
+}
+
+// stockOf reads the activity stock, inside the transaction holding its row lock
+func stockOf(tx *gorm.DB, activityID uint64) (int, error) {
+	var stock int
+	err := tx.Model(&model.SeckillActivity{}).
+		Select("stock").
+		Where("id = ?", activityID).
+		Scan(&stock).Error
+	return stock, err
+}
+
+// GetByDeductID gets a purchase by deduction ID
+func (r *purchaseRepository) GetByDeductID(ctx context.Context, deductID string) (*model.Purchase, error) {
+	var purchase model.Purchase
+	err := r.db.WithContext(ctx).
+		Where("deduct_id = ?", deductID).
+		First(&purchase).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &purchase, nil
+}
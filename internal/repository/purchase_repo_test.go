@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+)
+
+const (
+	purchaseDecrSQL  = "UPDATE `seckill_activities` SET `sold`=sold \\+ \\?,`stock`=stock - \\?,`updated_at`=\\? WHERE id = \\? AND stock >= \\?"
+	purchaseStockSQL = "SELECT stock FROM `seckill_activities` WHERE id = \\?"
+	purchaseSumSQL   = "SELECT COALESCE\\(SUM\\(quantity\\), 0\\) FROM `seckill_purchases` WHERE activity_id = \\? AND user_id = \\? AND status <> \\?"
+	orderedSQL       = "SELECT COALESCE\\(SUM\\(quantity\\), 0\\) FROM `orders` WHERE \\(activity_id = \\? AND status <> \\?\\) AND NOT EXISTS \\(SELECT 1 FROM seckill_purchases WHERE seckill_purchases.deduct_id = orders.deduct_id\\)"
+	pendingSQL       = "SELECT COALESCE\\(SUM\\(quantity\\), 0\\) FROM `tcc_logs` WHERE \\(activity_id = \\? AND status = \\? AND deduct_id NOT LIKE \\?\\) AND NOT EXISTS \\(SELECT 1 FROM orders WHERE orders.deduct_id = tcc_logs.deduct_id\\)"
+)
+
+func TestPurchaseRepository_Try(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewPurchaseRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(purchaseDecrSQL).
+		WithArgs(2, 2, sqlmock.AnyArg(), uint64(1), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(purchaseStockSQL).
+		WithArgs(uint64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(8))
+	mock.ExpectQuery(orderedSQL).
+		WithArgs(uint64(1), model.OrderStatusCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(5))
+	mock.ExpectQuery(pendingSQL).
+		WithArgs(uint64(1), model.TCCStatusTry, "mysql:%").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	mock.ExpectQuery(purchaseSumSQL).
+		WithArgs(uint64(1), uint64(7), model.PurchaseStatusCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	mock.ExpectQuery(orderedSQL+" AND user_id = \\?").
+		WithArgs(uint64(1), model.OrderStatusCancelled, uint64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+	mock.ExpectQuery(pendingSQL+" AND user_id = \\?").
+		WithArgs(uint64(1), model.TCCStatusTry, "mysql:%", uint64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO `seckill_purchases`").
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+
+	purchase := &model.Purchase{DeductID: "mysql:deduct:r1", ActivityID: 1, UserID: 7, Quantity: 2}
+	stock, err := repo.Try(context.Background(), purchase, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stock, "units sold on Redis are not left for sale")
+	assert.Equal(t, uint64(5), purchase.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseRepository_TryInsufficientStock(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewPurchaseRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(purchaseDecrSQL).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := repo.Try(context.Background(), &model.Purchase{DeductID: "mysql:deduct:r1", ActivityID: 1, UserID: 7, Quantity: 1}, 3)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseRepository_TrySoldOnRedis(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewPurchaseRepository(db)
+
+	// The column still holds 2 after the decrement, but a Redis order and a Redis try
+	// whose order is not written yet took 3 of them
+	mock.ExpectBegin()
+	mock.ExpectExec(purchaseDecrSQL).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(purchaseStockSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(2))
+	mock.ExpectQuery(orderedSQL).
+		WithArgs(uint64(1), model.OrderStatusCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	mock.ExpectQuery(pendingSQL).
+		WithArgs(uint64(1), model.TCCStatusTry, "mysql:%").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(2))
+	mock.ExpectRollback()
+
+	_, err := repo.Try(context.Background(), &model.Purchase{DeductID: "mysql:deduct:r1", ActivityID: 1, UserID: 7, Quantity: 1}, 3)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseRepository_TryLimitExceeded(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewPurchaseRepository(db)
+
+	// The stock decrement is rolled back with the rejected purchase
+	mock.ExpectBegin()
+	mock.ExpectExec(purchaseDecrSQL).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(purchaseStockSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(9))
+	mock.ExpectQuery(orderedSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+	mock.ExpectQuery(pendingSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+	mock.ExpectQuery(purchaseSumSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	// Units the user bought on Redis count against the limit too, ordered or not
+	mock.ExpectQuery(orderedSQL + " AND user_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	mock.ExpectQuery(pendingSQL + " AND user_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	mock.ExpectRollback()
+
+	_, err := repo.Try(context.Background(), &model.Purchase{DeductID: "mysql:deduct:r1", ActivityID: 1, UserID: 7, Quantity: 1}, 3)
+	assert.ErrorIs(t, err, ErrPurchaseLimitExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseRepository_Cancel(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewPurchaseRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `seckill_purchases` WHERE deduct_id = \\?").
+		WithArgs("mysql:deduct:r1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "deduct_id", "activity_id", "user_id", "quantity", "status"}).
+			AddRow(5, "mysql:deduct:r1", 1, 7, 2, model.PurchaseStatusTry))
+	mock.ExpectExec("UPDATE `seckill_purchases` SET `status`=\\?,`updated_at`=\\? WHERE id = \\? AND status = \\?").
+		WithArgs(model.PurchaseStatusCancelled, sqlmock.AnyArg(), uint64(5), model.PurchaseStatusTry).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `seckill_activities` SET `sold`=sold - \\?,`stock`=stock \\+ \\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(purchaseStockSQL).
+		WithArgs(uint64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+	mock.ExpectQuery(orderedSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(3))
+	mock.ExpectQuery(pendingSQL).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1))
+	mock.ExpectCommit()
+
+	cancelled, stock, err := repo.Cancel(context.Background(), "mysql:deduct:r1")
+	require.NoError(t, err)
+	require.NotNil(t, cancelled)
+	assert.Equal(t, 2, cancelled.Quantity)
+	assert.Equal(t, 6, stock)
+
+	// Already cancelled: no stock is returned twice
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `seckill_purchases` WHERE deduct_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "deduct_id", "activity_id", "user_id", "quantity", "status"}).
+			AddRow(5, "mysql:deduct:r1", 1, 7, 2, model.PurchaseStatusCancelled))
+	mock.ExpectExec("UPDATE `seckill_purchases` SET `status`=\\?,`updated_at`=\\? WHERE id = \\? AND status = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	cancelled, _, err = repo.Cancel(context.Background(), "mysql:deduct:r1")
+	require.NoError(t, err)
+	assert.Nil(t, cancelled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseRepository_Confirm(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewPurchaseRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `seckill_purchases` SET `status`=\\?,`updated_at`=\\? WHERE deduct_id = \\? AND status = \\?").
+		WithArgs(model.PurchaseStatusConfirmed, sqlmock.AnyArg(), "mysql:deduct:r1", model.PurchaseStatusTry).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	confirmed, err := repo.Confirm(context.Background(), "mysql:deduct:r1")
+	require.NoError(t, err)
+	assert.True(t, confirmed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
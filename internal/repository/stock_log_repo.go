@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 	"seckill/internal/model"
@@ -12,6 +13,9 @@ type StockLogRepository interface {
 	// Create creates a stock log
 	Create(ctx context.Context, log *model.StockLog) error
 
+	// CreateBatch creates stock logs in one statement
+	CreateBatch(ctx context.Context, logs []*model.StockLog) error
+
 	// GetByActivityID gets stock logs by activity ID
 	GetByActivityID(ctx context.Context, activityID uint64, limit int) ([]model.StockLog, error)
 
@@ -20,6 +24,58 @@ type StockLogRepository interface {
 
 	// List lists stock logs with pagination
 	List(ctx context.Context, page, pageSize int) ([]model.StockLog, int64, error)
+
+	// ListByActivity lists activity stock logs with ID greater than afterID, in ID order
+	ListByActivity(ctx context.Context, activityID, afterID uint64, limit int) ([]model.StockLog, error)
+
+	// ListHistory lists activity stock logs matching filter with ID below beforeID, newest first.
+	// A zero beforeID starts from the newest log.
+	ListHistory(ctx context.Context, activityID uint64, filter StockLogFilter, beforeID uint64, limit int) ([]model.StockLog, error)
+
+	// SummarizeByMinute totals the quantities of activity stock logs matching filter per minute
+	SummarizeByMinute(ctx context.Context, activityID uint64, filter StockLogFilter) ([]StockLogMinute, error)
+}
+
+// StockLogFilter narrows an activity's stock logs, empty fields match every log
+type StockLogFilter struct {
+	OperationType int8
+	StartTime     time.Time // Inclusive
+	EndTime       time.Time // Exclusive
+	RequestID     string
+	OrderNo       string
+	Operator      string
+}
+
+// scope adds the filter's conditions to a query
+func (f StockLogFilter) scope(db *gorm.DB) *gorm.DB {
+	if f.OperationType != 0 {
+		db = db.Where("operation_type = ?", f.OperationType)
+	}
+	if !f.StartTime.IsZero() {
+		db = db.Where("created_at >= ?", f.StartTime)
+	}
+	if !f.EndTime.IsZero() {
+		db = db.Where("created_at < ?", f.EndTime)
+	}
+	if f.RequestID != "" {
+		db = db.Where("request_id = ?", f.RequestID)
+	}
+	if f.OrderNo != "" {
+		db = db.Where("order_no = ?", f.OrderNo)
+	}
+	if f.Operator != "" {
+		db = db.Where("operator = ?", f.Operator)
+	}
+	return db
+}
+
+// StockLogMinute stock moved in one minute. Quantities are positive when stock left the pool
+// for deducted and returned to it for reverted; synced nets syncs and repairs.
+type StockLogMinute struct {
+	Minute   time.Time `json:"minute"`
+	Deducted int       `json:"deducted"`
+	Reverted int       `json:"reverted"`
+	Synced   int       `json:"synced"`
 }
 
 // stockLogRepository stock log repository implementation
@@ -39,6 +95,14 @@ func (r *stockLogRepository) Create(ctx context.Context, log *model.StockLog) er
 	return r.db.WithContext(ctx).Create(log).Error
 }
 
+// CreateBatch creates stock logs in one statement
+func (r *stockLogRepository) CreateBatch(ctx context.Context, logs []*model.StockLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&logs).Error
+}
+
 // GetByActivityID gets stock logs by activity ID
 func (r *stockLogRepository) GetByActivityID(ctx context.Context, activityID uint64, limit int) ([]model.StockLog, error) {
 	var logs []model.StockLog
@@ -84,3 +148,66 @@ func (r *stockLogRepository) List(ctx context.Context, page, pageSize int) ([]mo
 	return logs, total, nil
 }
 
+// ListByActivity lists activity stock logs after afterID, for walking every log of an activity
+func (r *stockLogRepository) ListByActivity(ctx context.Context, activityID, afterID uint64, limit int) ([]model.StockLog, error) {
+	var logs []model.StockLog
+	err := r.db.WithContext(ctx).
+		Where("activity_id = ? AND id > ?", activityID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// ListHistory lists activity stock logs matching filter below beforeID, the ID is the cursor
+func (r *stockLogRepository) ListHistory(ctx context.Context, activityID uint64, filter StockLogFilter, beforeID uint64, limit int) ([]model.StockLog, error) {
+	query := r.db.WithContext(ctx).
+		Where("activity_id = ?", activityID).
+		Scopes(filter.scope)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var logs []model.StockLog
+	err := query.
+		Order("id DESC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// SummarizeByMinute totals the quantities of activity stock logs matching filter per minute
+func (r *stockLogRepository) SummarizeByMinute(ctx context.Context, activityID uint64, filter StockLogFilter) ([]StockLogMinute, error) {
+	var rows []struct {
+		Minute   int64
+		Deducted int
+		Reverted int
+		Synced   int
+	}
+	err := r.db.WithContext(ctx).
+		Model(&model.StockLog{}).
+		Select("FLOOR(UNIX_TIMESTAMP(created_at) / 60) * 60 AS minute, "+
+			"SUM(CASE WHEN operation_type = ? THEN -quantity ELSE 0 END) AS deducted, "+
+			"SUM(CASE WHEN operation_type = ? THEN quantity ELSE 0 END) AS reverted, "+
+			"SUM(CASE WHEN operation_type IN ? THEN quantity ELSE 0 END) AS synced",
+			model.OperationTypeDeduct, model.OperationTypeRevert, []int8{model.OperationTypeSync, model.OperationTypeRepair}).
+		Where("activity_id = ?", activityID).
+		Scopes(filter.scope).
+		Group("minute").
+		Order("minute").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := make([]StockLogMinute, 0, len(rows))
+	for _, row := range rows {
+		minutes = append(minutes, StockLogMinute{
+			Minute:   time.Unix(row.Minute, 0),
+			Deducted: row.Deducted,
+			Reverted: row.Reverted,
+			Synced:   row.Synced,
+		})
+	}
+	return minutes, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -68,6 +69,35 @@ func TestStockLogRepository_Create(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestStockLogRepository_CreateBatch(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewStockLogRepository(db)
+
+	now := time.Now()
+	logs := []*model.StockLog{
+		{ActivityID: 1, GoodsID: 2, OperationType: model.OperationTypeDeduct, Quantity: -1, BeforeStock: 10, AfterStock: 9, CreatedAt: now},
+		{ActivityID: 1, GoodsID: 2, OperationType: model.OperationTypeRevert, Quantity: 1, BeforeStock: 9, AfterStock: 10, CreatedAt: now},
+	}
+
+	// Both rows go out in one INSERT
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `stock_logs` .* VALUES \\(.*\\),\\(.*\\)").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.CreateBatch(context.Background(), logs))
+
+	// Nothing to write: no statement at all
+	assert.NoError(t, repo.CreateBatch(context.Background(), nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestStockLogRepository_GetByActivityID(t *testing.T) {
 	db, mock, err := setupStockLogTestDB()
 	assert.NoError(t, err)
@@ -97,6 +127,101 @@ func TestStockLogRepository_GetByActivityID(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestStockLogRepository_ListByActivity(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewStockLogRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "activity_id", "operation_type", "quantity"}).
+		AddRow(11, 1, model.OperationTypeDeduct, -1).
+		AddRow(12, 1, model.OperationTypeRevert, 1)
+
+	mock.ExpectQuery("SELECT \\* FROM `stock_logs` WHERE activity_id = \\? AND id > \\? ORDER BY id ASC LIMIT \\?").
+		WithArgs(uint64(1), uint64(10), 500).
+		WillReturnRows(rows)
+
+	logs, err := repo.ListByActivity(context.Background(), 1, 10, 500)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 2)
+	assert.Equal(t, uint64(11), logs[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStockLogRepository_ListHistory(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewStockLogRepository(db)
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	filter := StockLogFilter{
+		OperationType: model.OperationTypeRevert,
+		StartTime:     start,
+		EndTime:       end,
+		RequestID:     "r1",
+		OrderNo:       "ORDER1",
+		Operator:      "42",
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `stock_logs` WHERE activity_id = \\? AND id < \\? AND operation_type = \\? AND created_at >= \\? " +
+		"AND created_at < \\? AND request_id = \\? AND order_no = \\? AND operator = \\? ORDER BY id DESC LIMIT \\?").
+		WithArgs(uint64(1), uint64(100), int8(model.OperationTypeRevert), start, end, "r1", "ORDER1", "42", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "activity_id", "operation_type", "quantity"}).
+			AddRow(99, 1, model.OperationTypeRevert, 1))
+
+	logs, err := repo.ListHistory(context.Background(), 1, filter, 100, 20)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, uint64(99), logs[0].ID)
+
+	// No cursor and no filter: the newest logs of the activity
+	mock.ExpectQuery("SELECT \\* FROM `stock_logs` WHERE activity_id = \\? ORDER BY id DESC LIMIT \\?").
+		WithArgs(uint64(1), 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	logs, err = repo.ListHistory(context.Background(), 1, StockLogFilter{}, 0, 20)
+	assert.NoError(t, err)
+	assert.Empty(t, logs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStockLogRepository_SummarizeByMinute(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewStockLogRepository(db)
+
+	start := time.Unix(1792159200, 0)
+	mock.ExpectQuery("SELECT FLOOR\\(UNIX_TIMESTAMP\\(created_at\\) / 60\\) \\* 60 AS minute, .* FROM `stock_logs` " +
+		"WHERE activity_id = \\? AND created_at >= \\? GROUP BY `minute` ORDER BY minute").
+		WithArgs(model.OperationTypeDeduct, model.OperationTypeRevert, model.OperationTypeSync, model.OperationTypeRepair, uint64(1), start).
+		WillReturnRows(sqlmock.NewRows([]string{"minute", "deducted", "reverted", "synced"}).
+			AddRow(1792159200, 120, 3, 0).
+			AddRow(1792159260, 80, 0, -5))
+
+	minutes, err := repo.SummarizeByMinute(context.Background(), 1, StockLogFilter{StartTime: start})
+	assert.NoError(t, err)
+	assert.Equal(t, []StockLogMinute{
+		{Minute: time.Unix(1792159200, 0), Deducted: 120, Reverted: 3},
+		{Minute: time.Unix(1792159260, 0), Deducted: 80, Synced: -5},
+	}, minutes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestStockLogRepository_GetByOrderNo(t *testing.T) {
 	db, mock, err := setupStockLogTestDB()
 	assert.NoError(t, err)
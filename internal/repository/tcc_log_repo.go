@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"seckill/internal/model"
+)
+
+// TCCLogRepository TCC log repository interface
+type TCCLogRepository interface {
+	// Create records a successful try
+	Create(ctx context.Context, log *model.TCCLog) error
+
+	// RecordOutcome records a confirm or cancel result, moving a pending log to status.
+	// A settled log keeps its status, TCCStatusTry records the result alone.
+	RecordOutcome(ctx context.Context, deductID string, status int8, result string) error
+
+	// MarkRecovered marks a pending log cancelled by the recovery sweeper, false when it is not pending
+	MarkRecovered(ctx context.Context, deductID, result string) (bool, error)
+
+	// ListDangling lists pending logs created before the deadline that no order refers to
+	ListDangling(ctx context.Context, before time.Time, limit int) ([]*model.TCCLog, error)
+}
+
+// tccLogRepository TCC log repository implementation
+type tccLogRepository struct {
+	db *gorm.DB
+}
+
+// NewTCCLogRepository creates a TCC log repository
+func NewTCCLogRepository(db *gorm.DB) TCCLogRepository {
+	return &tccLogRepository{db: db}
+}
+
+// Create records a successful try
+func (r *tccLogRepository) Create(ctx context.Context, log *model.TCCLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// RecordOutcome records a confirm or cancel result
+func (r *tccLogRepository) RecordOutcome(ctx context.Context, deductID string, status int8, result string) error {
+	updates := map[string]interface{}{
+		"result": result,
+	}
+	if status != model.TCCStatusTry {
+		// Only the first settlement changes the status, later results are kept for the record
+		updates["status"] = gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", model.TCCStatusTry, status)
+	}
+	return r.db.WithContext(ctx).
+		Model(&model.TCCLog{}).
+		Where("deduct_id = ?", deductID).
+		Updates(updates).Error
+}
+
+// MarkRecovered marks a pending log cancelled by the recovery sweeper
+func (r *tccLogRepository) MarkRecovered(ctx context.Context, deductID, result string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&model.TCCLog{}).
+		Where("deduct_id = ? AND status = ?", deductID, model.TCCStatusTry).
+		Updates(map[string]interface{}{
+			"status":    model.TCCStatusCancelled,
+			"result":    result,
+			"recovered": true,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListDangling lists pending logs created before the deadline that no order refers to
+func (r *tccLogRepository) ListDangling(ctx context.Context, before time.Time, limit int) ([]*model.TCCLog, error) {
+	var logs []*model.TCCLog
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND created_at < ?", model.TCCStatusTry, before).
+		Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.deduct_id = tcc_logs.deduct_id)").
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
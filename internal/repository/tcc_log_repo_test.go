@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+)
+
+func TestTCCLogRepository_RecordOutcome(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewTCCLogRepository(db)
+
+	// A settled log keeps its status
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tcc_logs` SET `result`=\\?,`status`=CASE WHEN status = \\? THEN \\? ELSE status END,`updated_at`=\\? WHERE deduct_id = \\?").
+		WithArgs("success", model.TCCStatusTry, model.TCCStatusConfirmed, sqlmock.AnyArg(), "deduct:r1:1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.RecordOutcome(context.Background(), "deduct:r1:1", model.TCCStatusConfirmed, "success"))
+
+	// An unresolved result leaves the status alone
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tcc_logs` SET `result`=\\?,`updated_at`=\\? WHERE deduct_id = \\?").
+		WithArgs("deduct_record_not_found", sqlmock.AnyArg(), "deduct:r1:1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, repo.RecordOutcome(context.Background(), "deduct:r1:1", model.TCCStatusTry, "deduct_record_not_found"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTCCLogRepository_MarkRecovered(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewTCCLogRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tcc_logs` SET `recovered`=\\?,`result`=\\?,`status`=\\?,`updated_at`=\\? WHERE deduct_id = \\? AND status = \\?").
+		WithArgs(true, "success", model.TCCStatusCancelled, sqlmock.AnyArg(), "deduct:r1:1", model.TCCStatusTry).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	recovered, err := repo.MarkRecovered(context.Background(), "deduct:r1:1", "success")
+	require.NoError(t, err)
+	assert.True(t, recovered)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTCCLogRepository_ListDangling(t *testing.T) {
+	db, mock := setupActivityMockDB(t)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewTCCLogRepository(db)
+	before := time.Now().Add(-10 * time.Minute)
+
+	mock.ExpectQuery("SELECT \\* FROM `tcc_logs` WHERE \\(status = \\? AND created_at < \\?\\) AND NOT EXISTS \\(SELECT 1 FROM orders WHERE orders.deduct_id = tcc_logs.deduct_id\\) ORDER BY id ASC LIMIT \\?").
+		WithArgs(model.TCCStatusTry, before, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "deduct_id", "activity_id", "quantity", "status"}).
+			AddRow(1, "deduct:r1:1", 1, 2, model.TCCStatusTry))
+
+	logs, err := repo.ListDangling(context.Background(), before, 100)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "deduct:r1:1", logs[0].DeductID)
+	assert.Equal(t, 2, logs[0].Quantity)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"seckill/internal/model"
+)
+
+// VIPGrantRepository VIP grant repository interface
+type VIPGrantRepository interface {
+	// Create creates a grant
+	Create(ctx context.Context, grant *model.VIPGrant) error
+
+	// ListActiveByUser lists grants of a user neither revoked nor expired at now
+	ListActiveByUser(ctx context.Context, userID uint64, now time.Time) ([]*model.VIPGrant, error)
+
+	// ListByUser lists all grants of a user, newest first
+	ListByUser(ctx context.Context, userID uint64) ([]*model.VIPGrant, error)
+
+	// RevokeByUser revokes all active grants of a user, returns revoked count
+	RevokeByUser(ctx context.Context, userID uint64, operator string, now time.Time) (int64, error)
+}
+
+// vipGrantRepository VIP grant repository implementation
+type vipGrantRepository struct {
+	db *gorm.DB
+}
+
+// NewVIPGrantRepository creates a VIP grant repository
+func NewVIPGrantRepository(db *gorm.DB) VIPGrantRepository {
+	return &vipGrantRepository{db: db}
+}
+
+// Create creates a grant
+func (r *vipGrantRepository) Create(ctx context.Context, grant *model.VIPGrant) error {
+	return r.db.WithContext(ctx).Create(grant).Error
+}
+
+// ListActiveByUser lists grants of a user neither revoked nor expired at now
+func (r *vipGrantRepository) ListActiveByUser(ctx context.Context, userID uint64, now time.Time) ([]*model.VIPGrant, error) {
+	var grants []*model.VIPGrant
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("expire_at IS NULL OR expire_at > ?", now).
+		Find(&grants).Error
+	return grants, err
+}
+
+// ListByUser lists all grants of a user, newest first
+func (r *vipGrantRepository) ListByUser(ctx context.Context, userID uint64) ([]*model.VIPGrant, error) {
+	var grants []*model.VIPGrant
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Find(&grants).Error
+	return grants, err
+}
+
+// RevokeByUser revokes all active grants of a user, returns revoked count
+func (r *vipGrantRepository) RevokeByUser(ctx context.Context, userID uint64, operator string, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.VIPGrant{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at": now,
+			"revoked_by": operator,
+		})
+	return result.RowsAffected, result.Error
+}
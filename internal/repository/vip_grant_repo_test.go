@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"seckill/internal/model"
+)
+
+func TestVIPGrantRepository_Create(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewVIPGrantRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `vip_grants`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	grant := &model.VIPGrant{UserID: 1001, Tier: model.VIPTierGold, Reason: "campaign", Operator: "1"}
+	err = repo.Create(context.Background(), grant)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), grant.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVIPGrantRepository_ListActiveByUser(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewVIPGrantRepository(db)
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "tier", "reason", "operator"}).
+		AddRow(1, 1001, 2, "campaign", "1")
+	mock.ExpectQuery("SELECT \\* FROM `vip_grants` WHERE \\(user_id = \\? AND revoked_at IS NULL\\) AND \\(expire_at IS NULL OR expire_at > \\?\\)").
+		WithArgs(uint64(1001), now).
+		WillReturnRows(rows)
+
+	grants, err := repo.ListActiveByUser(context.Background(), 1001, now)
+	assert.NoError(t, err)
+	assert.Len(t, grants, 1)
+	assert.Equal(t, model.VIPTierGold, grants[0].Tier)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVIPGrantRepository_RevokeByUser(t *testing.T) {
+	db, mock, err := setupStockLogTestDB()
+	assert.NoError(t, err)
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	repo := NewVIPGrantRepository(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `vip_grants` SET `revoked_at`=\\?,`revoked_by`=\\?,`updated_at`=\\? WHERE user_id = \\? AND revoked_at IS NULL").
+		WithArgs(now, "1", sqlmock.AnyArg(), uint64(1001)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	revoked, err := repo.RevokeByUser(context.Background(), 1001, "1", now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), revoked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
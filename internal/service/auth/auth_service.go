@@ -64,14 +64,14 @@ type AuthService interface {
 type authService struct {
 	userRepo   repository.UserRepository
 	jwtManager *utils.JWTManager
-	redis      *redis.Client
+	redis      redis.Cmdable
 }
 
 // NewAuthService creates an authentication service
 func NewAuthService(
 	userRepo repository.UserRepository,
 	jwtManager *utils.JWTManager,
-	redis *redis.Client,
+	redis redis.Cmdable,
 ) AuthService {
 	return &authService{
 		userRepo:   userRepo,
@@ -0,0 +1,200 @@
+package blacklist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+)
+
+var (
+	// ErrEntryNotFound blacklist entry does not exist
+	ErrEntryNotFound = errors.New("blacklist entry not found")
+	// ErrInvalidEntry blacklist entry is missing required fields or already expired
+	ErrInvalidEntry = errors.New("invalid blacklist entry")
+)
+
+// AddRequest add blacklist entry request
+type AddRequest struct {
+	UserID     uint64
+	ActivityID uint64 // 0 blacklists the user from all activities
+	Reason     string
+	Operator   string
+	ExpireAt   *time.Time // nil never expires
+}
+
+// BlacklistService blacklist service interface
+//
+// MySQL is the source of truth, the Redis keys read by the seckill fast path
+// are a cache that SyncToRedis rebuilds after a flush.
+type BlacklistService interface {
+	// Add adds or replaces an entry
+	Add(ctx context.Context, req *AddRequest) (*model.Blacklist, error)
+
+	// Remove removes an entry
+	Remove(ctx context.Context, userID, activityID uint64) error
+
+	// List lists and searches entries
+	List(ctx context.Context, filter repository.BlacklistFilter, page, pageSize int) ([]*model.Blacklist, int64, error)
+
+	// SyncToRedis writes all unexpired entries to Redis
+	SyncToRedis(ctx context.Context) (int, error)
+
+	// StartPeriodicSync start periodic cache sync task
+	StartPeriodicSync(ctx context.Context, interval time.Duration)
+}
+
+// blacklistService blacklist service implementation
+type blacklistService struct {
+	blacklistRepo repository.BlacklistRepository
+	redis         redis.Cmdable
+	now           func() time.Time
+}
+
+// NewBlacklistService creates a blacklist service
+func NewBlacklistService(blacklistRepo repository.BlacklistRepository, redis redis.Cmdable) BlacklistService {
+	return &blacklistService{
+		blacklistRepo: blacklistRepo,
+		redis:         redis,
+		now:           time.Now,
+	}
+}
+
+// Add adds or replaces an entry
+func (s *blacklistService) Add(ctx context.Context, req *AddRequest) (*model.Blacklist, error) {
+	if req.UserID == 0 || req.Reason == "" || req.Operator == "" {
+		return nil, ErrInvalidEntry
+	}
+	if req.ExpireAt != nil && !req.ExpireAt.After(s.now()) {
+		return nil, ErrInvalidEntry
+	}
+
+	entry := &model.Blacklist{
+		UserID:     req.UserID,
+		ActivityID: req.ActivityID,
+		Reason:     req.Reason,
+		Operator:   req.Operator,
+		ExpireAt:   req.ExpireAt,
+	}
+	if err := s.blacklistRepo.Upsert(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to save blacklist entry: %w", err)
+	}
+
+	if err := s.cache(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to cache blacklist entry: %w", err)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"user_id":     entry.UserID,
+		"activity_id": entry.ActivityID,
+		"reason":      entry.Reason,
+		"operator":    entry.Operator,
+		"expire_at":   entry.ExpireAt,
+	}).Info("User blacklisted")
+
+	return entry, nil
+}
+
+// Remove removes an entry, the cache key is cleared even if the row is gone
+func (s *blacklistService) Remove(ctx context.Context, userID, activityID uint64) error {
+	err := s.blacklistRepo.Delete(ctx, userID, activityID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to delete blacklist entry: %w", err)
+	}
+
+	if delErr := s.redis.Del(ctx, model.BlacklistCacheKey(userID, activityID)).Err(); delErr != nil {
+		return fmt.Errorf("failed to clear blacklist cache: %w", delErr)
+	}
+	if err != nil {
+		return ErrEntryNotFound
+	}
+
+	log.WithFields(map[string]interface{}{
+		"user_id":     userID,
+		"activity_id": activityID,
+	}).Info("User removed from blacklist")
+
+	return nil
+}
+
+// List lists and searches entries
+func (s *blacklistService) List(ctx context.Context, filter repository.BlacklistFilter, page, pageSize int) ([]*model.Blacklist, int64, error) {
+	return s.blacklistRepo.List(ctx, filter, page, pageSize)
+}
+
+// SyncToRedis writes all unexpired entries to Redis
+func (s *blacklistService) SyncToRedis(ctx context.Context) (int, error) {
+	entries, err := s.blacklistRepo.ListUnexpired(ctx, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to load blacklist: %w", err)
+	}
+
+	synced := 0
+	for _, entry := range entries {
+		if err := s.cache(ctx, entry); err != nil {
+			log.WithFields(map[string]interface{}{
+				"user_id":     entry.UserID,
+				"activity_id": entry.ActivityID,
+				"error":       err.Error(),
+			}).Warn("Failed to cache blacklist entry")
+			continue
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// StartPeriodicSync start periodic cache sync task
+func (s *blacklistService) StartPeriodicSync(ctx context.Context, interval time.Duration) {
+	// Rebuild on start so a flushed Redis is repopulated before traffic arrives
+	s.syncOnce(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.WithFields(map[string]interface{}{
+		"interval": interval,
+	}).Info("Started periodic blacklist sync task")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Periodic blacklist sync task stopped")
+			return
+		case <-ticker.C:
+			s.syncOnce(ctx)
+		}
+	}
+}
+
+// syncOnce runs SyncToRedis and logs the outcome
+func (s *blacklistService) syncOnce(ctx context.Context) {
+	synced, err := s.SyncToRedis(ctx)
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Error("Failed to sync blacklist to Redis")
+		return
+	}
+	log.WithFields(map[string]interface{}{
+		"count": synced,
+	}).Debug("Blacklist synced to Redis")
+}
+
+// cache writes an entry to Redis, expiring together with the entry
+func (s *blacklistService) cache(ctx context.Context, entry *model.Blacklist) error {
+	var ttl time.Duration
+	if entry.ExpireAt != nil {
+		ttl = entry.ExpireAt.Sub(s.now())
+		if ttl <= 0 {
+			return s.redis.Del(ctx, entry.CacheKey()).Err()
+		}
+	}
+	return s.redis.Set(ctx, entry.CacheKey(), entry.Reason, ttl).Err()
+}
@@ -0,0 +1,141 @@
+package blacklist
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// fakeBlacklistRepo in-memory blacklist repository
+type fakeBlacklistRepo struct {
+	repository.BlacklistRepository
+	entries map[string]*model.Blacklist
+	err     error
+}
+
+func newFakeBlacklistRepo() *fakeBlacklistRepo {
+	return &fakeBlacklistRepo{entries: make(map[string]*model.Blacklist)}
+}
+
+func (r *fakeBlacklistRepo) Upsert(ctx context.Context, entry *model.Blacklist) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.entries[entry.CacheKey()] = entry
+	return nil
+}
+
+func (r *fakeBlacklistRepo) Delete(ctx context.Context, userID, activityID uint64) error {
+	key := model.BlacklistCacheKey(userID, activityID)
+	if _, ok := r.entries[key]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.entries, key)
+	return nil
+}
+
+func (r *fakeBlacklistRepo) ListUnexpired(ctx context.Context, now time.Time) ([]*model.Blacklist, error) {
+	var entries []*model.Blacklist
+	for _, entry := range r.entries {
+		if !entry.IsExpired(now) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func setupBlacklistService(t *testing.T) (*blacklistService, *fakeBlacklistRepo, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	repo := newFakeBlacklistRepo()
+	return NewBlacklistService(repo, client).(*blacklistService), repo, mr
+}
+
+func TestBlacklistService_Add(t *testing.T) {
+	service, repo, mr := setupBlacklistService(t)
+	ctx := context.Background()
+
+	t.Run("global entry never expires", func(t *testing.T) {
+		_, err := service.Add(ctx, &AddRequest{UserID: 1001, Reason: "scalper", Operator: "1"})
+		require.NoError(t, err)
+
+		value, err := mr.Get("blacklist:user:1001")
+		require.NoError(t, err)
+		assert.Equal(t, "scalper", value)
+		assert.Zero(t, mr.TTL("blacklist:user:1001"))
+	})
+
+	t.Run("activity entry expires with the cache key", func(t *testing.T) {
+		expireAt := time.Now().Add(time.Hour)
+		_, err := service.Add(ctx, &AddRequest{UserID: 1002, ActivityID: 7, Reason: "bot", Operator: "1", ExpireAt: &expireAt})
+		require.NoError(t, err)
+
+		assert.True(t, mr.Exists("blacklist:activity:7:user:1002"))
+		assert.InDelta(t, time.Hour.Seconds(), mr.TTL("blacklist:activity:7:user:1002").Seconds(), 5)
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		_, err := service.Add(ctx, &AddRequest{UserID: 1003, Reason: "bot", Operator: "1", ExpireAt: &past})
+		assert.ErrorIs(t, err, ErrInvalidEntry)
+
+		_, err = service.Add(ctx, &AddRequest{UserID: 1003, Operator: "1"})
+		assert.ErrorIs(t, err, ErrInvalidEntry)
+	})
+
+	t.Run("database failure does not touch the cache", func(t *testing.T) {
+		repo.err = errors.New("db down")
+		defer func() { repo.err = nil }()
+
+		_, err := service.Add(ctx, &AddRequest{UserID: 1004, Reason: "bot", Operator: "1"})
+		assert.Error(t, err)
+		assert.False(t, mr.Exists("blacklist:user:1004"))
+	})
+}
+
+func TestBlacklistService_Remove(t *testing.T) {
+	service, _, mr := setupBlacklistService(t)
+	ctx := context.Background()
+
+	_, err := service.Add(ctx, &AddRequest{UserID: 1001, ActivityID: 3, Reason: "bot", Operator: "1"})
+	require.NoError(t, err)
+
+	require.NoError(t, service.Remove(ctx, 1001, 3))
+	assert.False(t, mr.Exists("blacklist:activity:3:user:1001"))
+
+	// Stale cache keys are cleared even without a row
+	mr.Set("blacklist:user:1001", "manual")
+	assert.ErrorIs(t, service.Remove(ctx, 1001, 0), ErrEntryNotFound)
+	assert.False(t, mr.Exists("blacklist:user:1001"))
+}
+
+func TestBlacklistService_SyncToRedis(t *testing.T) {
+	service, repo, mr := setupBlacklistService(t)
+	ctx := context.Background()
+
+	expireAt := time.Now().Add(10 * time.Minute)
+	repo.entries["a"] = &model.Blacklist{UserID: 1, Reason: "scalper", Operator: "1"}
+	repo.entries["b"] = &model.Blacklist{UserID: 2, ActivityID: 9, Reason: "bot", Operator: "1", ExpireAt: &expireAt}
+
+	// Redis flushed, MySQL still has the entries
+	synced, err := service.SyncToRedis(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, synced)
+	assert.True(t, mr.Exists("blacklist:user:1"))
+	assert.True(t, mr.Exists("blacklist:activity:9:user:2"))
+}
@@ -0,0 +1,148 @@
+package gray
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"seckill/internal/model"
+)
+
+// extConfigKey key of the gray section in SeckillActivity.ExtConfig
+const extConfigKey = "gray"
+
+// Rollout strategies for SeckillActivity.GrayStrategy
+const (
+	StrategyHash      = "hash"      // Salted user ID hash below GrayRatio
+	StrategyWhitelist = "whitelist" // Whitelisted users only
+	StrategyLevel     = "level"     // User level at or above min_level
+	StrategyCohort    = "cohort"    // Registered within [registered_after, registered_before)
+	StrategyIPCIDR    = "ip_cidr"   // Request IP inside one of ip_cidrs
+)
+
+var (
+	// ErrInvalidConfig gray config is inconsistent
+	ErrInvalidConfig = errors.New("invalid gray config")
+	// ErrActivityNotFound activity to update does not exist
+	ErrActivityNotFound = errors.New("activity not found")
+)
+
+// Config gray rollout config of an activity
+//
+// Strategy and ratio come from GrayStrategy/GrayRatio, whitelisted users from
+// GrayWhitelist are always admitted, strategy parameters live in ExtConfig["gray"], e.g.
+//
+//	{"gray": {"salt": "spring", "min_level": 3, "ip_cidrs": ["10.0.0.0/8"]}}
+type Config struct {
+	Strategy  string   `json:"-"`
+	Ratio     float64  `json:"-"`
+	Whitelist []uint64 `json:"-"`
+
+	Salt             string     `json:"salt,omitempty"`              // Hash salt, defaults to the activity ID
+	MinLevel         int        `json:"min_level,omitempty"`         // Level strategy threshold
+	RegisteredAfter  *time.Time `json:"registered_after,omitempty"`  // Cohort start, inclusive
+	RegisteredBefore *time.Time `json:"registered_before,omitempty"` // Cohort end, exclusive
+	IPCIDRs          []string   `json:"ip_cidrs,omitempty"`          // IP strategy ranges
+
+	networks []*net.IPNet
+}
+
+// ConfigFor builds the gray config of an activity
+func ConfigFor(activity *model.SeckillActivity) *Config {
+	cfg := &Config{
+		Ratio:     activity.GrayRatio,
+		Whitelist: parseWhitelist(activity.GrayWhitelist),
+	}
+	if activity.GrayStrategy != nil {
+		cfg.Strategy = *activity.GrayStrategy
+	}
+
+	if section, ok := activity.ExtConfig[extConfigKey]; ok {
+		if data, err := json.Marshal(section); err == nil {
+			json.Unmarshal(data, cfg)
+		}
+	}
+	if cfg.Salt == "" {
+		cfg.Salt = strconv.FormatUint(activity.ID, 10)
+	}
+	for _, cidr := range cfg.IPCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			cfg.networks = append(cfg.networks, network)
+		}
+	}
+	return cfg
+}
+
+// Enabled whether the activity restricts who may enter, legacy activities
+// without a strategy are open while GrayRatio is 0
+func (c *Config) Enabled() bool {
+	if c.Strategy == "" {
+		return c.Ratio > 0 && c.Ratio < 1
+	}
+	return !(c.Strategy == StrategyHash && c.Ratio >= 1)
+}
+
+// Validate checks that the strategy has the parameters it needs
+func (c *Config) Validate() error {
+	switch c.Strategy {
+	case "", StrategyHash, StrategyWhitelist:
+	case StrategyLevel:
+		if c.MinLevel <= 0 {
+			return fmt.Errorf("%w: level strategy requires min_level", ErrInvalidConfig)
+		}
+	case StrategyCohort:
+		if c.RegisteredAfter == nil && c.RegisteredBefore == nil {
+			return fmt.Errorf("%w: cohort strategy requires registered_after or registered_before", ErrInvalidConfig)
+		}
+	case StrategyIPCIDR:
+		if len(c.IPCIDRs) == 0 {
+			return fmt.Errorf("%w: ip_cidr strategy requires ip_cidrs", ErrInvalidConfig)
+		}
+	default:
+		return fmt.Errorf("%w: unknown strategy %q", ErrInvalidConfig, c.Strategy)
+	}
+	if c.Ratio < 0 || c.Ratio > 1 {
+		return fmt.Errorf("%w: ratio must be between 0 and 1", ErrInvalidConfig)
+	}
+	for _, cidr := range c.IPCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("%w: bad cidr %q", ErrInvalidConfig, cidr)
+		}
+	}
+	return nil
+}
+
+// whitelisted whether a user is on the whitelist
+func (c *Config) whitelisted(userID uint64) bool {
+	for _, id := range c.Whitelist {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWhitelist collects user IDs from GrayWhitelist values, either numbers or arrays of numbers
+func parseWhitelist(whitelist model.JSONObject) []uint64 {
+	var userIDs []uint64
+	for _, value := range whitelist {
+		switch v := value.(type) {
+		case float64:
+			userIDs = append(userIDs, uint64(v))
+		case uint64:
+			userIDs = append(userIDs, v)
+		case []uint64:
+			userIDs = append(userIDs, v...)
+		case []interface{}:
+			for _, item := range v {
+				if id, ok := item.(float64); ok {
+					userIDs = append(userIDs, uint64(id))
+				}
+			}
+		}
+	}
+	return userIDs
+}
@@ -0,0 +1,270 @@
+package gray
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+)
+
+const (
+	// hashBuckets resolution of the hash strategy, ratio 0.0001
+	hashBuckets = 10000
+	// userCacheTTL how long the level and cohort strategies reuse a user profile
+	userCacheTTL = 10 * time.Minute
+)
+
+// grayFields cached activity config fields rewritten by UpdateConfig
+var grayFields = []string{"gray_strategy", "gray_ratio", "gray_whitelist", "ext_config"}
+
+// Controller decides which users may enter a gray activity
+//
+// Admitted users are remembered per activity, so changing the strategy or
+// lowering the ratio of a live activity never locks out users already let in.
+type Controller struct {
+	redis        redis.Cmdable
+	userRepo     repository.UserRepository
+	activityRepo repository.ActivityRepository
+	now          func() time.Time
+}
+
+// NewController creates a gray controller
+func NewController(redisClient redis.Cmdable, userRepo repository.UserRepository, activityRepo repository.ActivityRepository) *Controller {
+	return &Controller{
+		redis:        redisClient,
+		userRepo:     userRepo,
+		activityRepo: activityRepo,
+		now:          time.Now,
+	}
+}
+
+// admittedKey users admitted to an activity
+func admittedKey(activityID uint64) string {
+	return fmt.Sprintf("gray:admitted:{%d}", activityID)
+}
+
+// Admit whether the user may enter the activity
+func (c *Controller) Admit(ctx context.Context, activity *model.SeckillActivity, userID uint64, ip string) bool {
+	cfg := ConfigFor(activity)
+	if !cfg.Enabled() || cfg.whitelisted(userID) {
+		return true
+	}
+
+	key := admittedKey(activity.ID)
+	if admitted, err := c.redis.SIsMember(ctx, key, userID).Result(); err == nil && admitted {
+		return true
+	}
+
+	admitted, err := c.evaluate(ctx, cfg, userID, ip)
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"user_id":     userID,
+			"strategy":    cfg.Strategy,
+			"error":       err.Error(),
+		}).Warn("Gray evaluation failed")
+		return false
+	}
+	if !admitted {
+		return false
+	}
+
+	// Remember the admission until the activity is over
+	ttl := activity.EndTime.Sub(c.now()) + time.Hour
+	if ttl < time.Hour {
+		ttl = time.Hour
+	}
+	pipe := c.redis.Pipeline()
+	pipe.SAdd(ctx, key, userID)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"user_id":     userID,
+			"error":       err.Error(),
+		}).Warn("Failed to record gray admission")
+	}
+	return true
+}
+
+// evaluate applies the configured strategy
+func (c *Controller) evaluate(ctx context.Context, cfg *Config, userID uint64, ip string) (bool, error) {
+	switch cfg.Strategy {
+	case "", StrategyHash:
+		return hashBucket(cfg.Salt, userID) < uint32(cfg.Ratio*hashBuckets), nil
+	case StrategyWhitelist:
+		return false, nil
+	case StrategyLevel:
+		user, err := c.loadUser(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		return user.Level >= cfg.MinLevel, nil
+	case StrategyCohort:
+		user, err := c.loadUser(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		if cfg.RegisteredAfter != nil && user.CreatedAt.Before(*cfg.RegisteredAfter) {
+			return false, nil
+		}
+		if cfg.RegisteredBefore != nil && !user.CreatedAt.Before(*cfg.RegisteredBefore) {
+			return false, nil
+		}
+		return true, nil
+	case StrategyIPCIDR:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return false, nil
+		}
+		for _, network := range cfg.networks {
+			if network.Contains(parsed) {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: unknown strategy %q", ErrInvalidConfig, cfg.Strategy)
+	}
+}
+
+// loadUser loads the user profile through a short Redis cache, users not yet
+// admitted are evaluated on every request
+func (c *Controller) loadUser(ctx context.Context, userID uint64) (*model.User, error) {
+	cacheKey := fmt.Sprintf("gray:user:%d", userID)
+	if data, err := c.redis.Get(ctx, cacheKey).Bytes(); err == nil {
+		var user model.User
+		if err := json.Unmarshal(data, &user); err == nil {
+			return &user, nil
+		}
+	}
+
+	user, err := c.userRepo.GetByID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(user); err == nil {
+		c.redis.SetEx(ctx, cacheKey, data, userCacheTTL)
+	}
+	return user, nil
+}
+
+// hashBucket stable bucket of a user, raising the ratio only ever adds users
+func hashBucket(salt string, userID uint64) uint32 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%d", salt, userID)
+	return h.Sum32() % hashBuckets
+}
+
+// UpdateRequest gray config change
+type UpdateRequest struct {
+	Strategy  string                 `json:"strategy"`
+	Ratio     float64                `json:"ratio"`
+	Whitelist []uint64               `json:"whitelist"`
+	Params    map[string]interface{} `json:"params"` // Stored as ExtConfig["gray"]
+}
+
+// UpdateConfig changes the gray config of an activity and refreshes the
+// cached config of a prewarmed activity so running instances pick it up
+func (c *Controller) UpdateConfig(ctx context.Context, activityID uint64, req *UpdateRequest) (*model.SeckillActivity, error) {
+	activity, err := c.activityRepo.GetByID(ctx, int64(activityID))
+	if err != nil {
+		if errors.Is(err, repository.ErrActivityNotFound) {
+			return nil, ErrActivityNotFound
+		}
+		return nil, err
+	}
+
+	var strategy *string
+	if req.Strategy != "" {
+		strategy = &req.Strategy
+	}
+	whitelist := model.JSONObject{"user_ids": req.Whitelist}
+	extConfig := model.JSONObject{}
+	for key, value := range activity.ExtConfig {
+		extConfig[key] = value
+	}
+	if len(req.Params) > 0 {
+		extConfig[extConfigKey] = req.Params
+	} else {
+		delete(extConfig, extConfigKey)
+	}
+
+	activity.GrayStrategy = strategy
+	activity.GrayRatio = req.Ratio
+	activity.GrayWhitelist = whitelist
+	activity.ExtConfig = extConfig
+	if err := ConfigFor(activity).Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := c.activityRepo.UpdateGray(ctx, int64(activityID), strategy, req.Ratio, whitelist, extConfig); err != nil {
+		return nil, err
+	}
+
+	c.setCachedGray(ctx, activity)
+
+	log.WithFields(map[string]interface{}{
+		"activity_id": activityID,
+		"strategy":    req.Strategy,
+		"ratio":       req.Ratio,
+		"whitelist":   len(req.Whitelist),
+	}).Info("Gray config updated")
+
+	return activity, nil
+}
+
+// setCachedGray rewrites the gray fields of the cached activity config. The rest of the
+// config and its TTL stay as prewarm left them, a missing config is left missing since
+// requests load it from MySQL.
+func (c *Controller) setCachedGray(ctx context.Context, activity *model.SeckillActivity) {
+	configKey := fmt.Sprintf("activity:config:%d", activity.ID)
+	data, err := c.redis.Get(ctx, configKey).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.WithFields(map[string]interface{}{
+				"activity_id": activity.ID,
+				"error":       err.Error(),
+			}).Warn("Failed to read activity config cache")
+		}
+		return
+	}
+
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		// Unreadable, requests reload it from MySQL
+		c.redis.Del(ctx, configKey)
+		return
+	}
+	// Marshal the activity so omitted fields are dropped from the cache too
+	var fields map[string]json.RawMessage
+	data, _ = json.Marshal(activity)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		c.redis.Del(ctx, configKey)
+		return
+	}
+	for _, name := range grayFields {
+		if value, ok := fields[name]; ok {
+			config[name] = value
+		} else {
+			delete(config, name)
+		}
+	}
+	data, _ = json.Marshal(config)
+
+	err = c.redis.SetArgs(ctx, configKey, data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Warn("Failed to refresh activity config cache")
+	}
+}
@@ -0,0 +1,238 @@
+package gray
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// fakeUserRepo returns users from a map
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[int64]*model.User
+	loads int
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	r.loads++
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// fakeActivityRepo stores one activity
+type fakeActivityRepo struct {
+	repository.ActivityRepository
+	activity *model.SeckillActivity
+	err      error
+}
+
+func (r *fakeActivityRepo) GetByID(ctx context.Context, id int64) (*model.SeckillActivity, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.activity == nil || int64(r.activity.ID) != id {
+		return nil, repository.ErrActivityNotFound
+	}
+	copied := *r.activity
+	return &copied, nil
+}
+
+func (r *fakeActivityRepo) UpdateGray(ctx context.Context, id int64, strategy *string, ratio float64, whitelist, extConfig model.JSONObject) error {
+	r.activity.GrayStrategy = strategy
+	r.activity.GrayRatio = ratio
+	r.activity.GrayWhitelist = whitelist
+	r.activity.ExtConfig = extConfig
+	return nil
+}
+
+func setupController(t *testing.T) (*Controller, *fakeActivityRepo, *miniredis.Miniredis) {
+	controller, _, activityRepo, mr := setupControllerWithUsers(t)
+	return controller, activityRepo, mr
+}
+
+func setupControllerWithUsers(t *testing.T) (*Controller, *fakeUserRepo, *fakeActivityRepo, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	now := time.Now()
+	userRepo := &fakeUserRepo{users: map[int64]*model.User{
+		1: {ID: 1, Level: 1, CreatedAt: now.AddDate(-2, 0, 0)},
+		2: {ID: 2, Level: 5, CreatedAt: now.AddDate(0, -1, 0)},
+	}}
+	activityRepo := &fakeActivityRepo{}
+	return NewController(client, userRepo, activityRepo), userRepo, activityRepo, mr
+}
+
+func strategy(name string) *string {
+	return &name
+}
+
+func newActivity(grayStrategy *string, ratio float64) *model.SeckillActivity {
+	return &model.SeckillActivity{
+		ID:           1,
+		Status:       model.ActivityStatusRunning,
+		EndTime:      time.Now().Add(time.Hour),
+		GrayStrategy: grayStrategy,
+		GrayRatio:    ratio,
+	}
+}
+
+func TestController_LegacyRatio(t *testing.T) {
+	controller, _, _ := setupController(t)
+	ctx := context.Background()
+
+	// Ratio 0 without a strategy keeps the activity open
+	assert.True(t, controller.Admit(ctx, newActivity(nil, 0), 1, ""))
+
+	admitted := 0
+	activity := newActivity(nil, 0.3)
+	for userID := uint64(1); userID <= 1000; userID++ {
+		if controller.Admit(ctx, activity, userID, "") {
+			admitted++
+		}
+	}
+	assert.InDelta(t, 300, admitted, 60)
+}
+
+func TestController_HashRatioIncreaseKeepsUsers(t *testing.T) {
+	cfgLow := ConfigFor(newActivity(strategy(StrategyHash), 0.2))
+	cfgHigh := ConfigFor(newActivity(strategy(StrategyHash), 0.5))
+	for userID := uint64(1); userID <= 500; userID++ {
+		if hashBucket(cfgLow.Salt, userID) < uint32(cfgLow.Ratio*hashBuckets) {
+			assert.Less(t, hashBucket(cfgHigh.Salt, userID), uint32(cfgHigh.Ratio*hashBuckets))
+		}
+	}
+}
+
+func TestController_AdmittedUsersAreSticky(t *testing.T) {
+	controller, _, _ := setupController(t)
+	ctx := context.Background()
+
+	activity := newActivity(strategy(StrategyLevel), 0)
+	activity.ExtConfig = model.JSONObject{"gray": map[string]interface{}{"min_level": 3}}
+	assert.False(t, controller.Admit(ctx, activity, 1, ""))
+	assert.True(t, controller.Admit(ctx, activity, 2, ""))
+
+	// Switching to whitelist-only keeps user 2 in
+	activity.GrayStrategy = strategy(StrategyWhitelist)
+	activity.GrayWhitelist = model.JSONObject{"user_ids": []interface{}{float64(3)}}
+	assert.True(t, controller.Admit(ctx, activity, 2, ""))
+	assert.True(t, controller.Admit(ctx, activity, 3, ""))
+	assert.False(t, controller.Admit(ctx, activity, 1, ""))
+}
+
+func TestController_Strategies(t *testing.T) {
+	controller, _, _ := setupController(t)
+	ctx := context.Background()
+
+	t.Run("cohort", func(t *testing.T) {
+		activity := newActivity(strategy(StrategyCohort), 0)
+		activity.ID = 10
+		activity.ExtConfig = model.JSONObject{"gray": map[string]interface{}{
+			"registered_after": time.Now().AddDate(0, -6, 0).Format(time.RFC3339),
+		}}
+		assert.False(t, controller.Admit(ctx, activity, 1, ""))
+		assert.True(t, controller.Admit(ctx, activity, 2, ""))
+	})
+
+	t.Run("ip cidr", func(t *testing.T) {
+		activity := newActivity(strategy(StrategyIPCIDR), 0)
+		activity.ID = 11
+		activity.ExtConfig = model.JSONObject{"gray": map[string]interface{}{
+			"ip_cidrs": []interface{}{"10.0.0.0/8", "192.168.1.0/24"},
+		}}
+		assert.True(t, controller.Admit(ctx, activity, 1, "10.1.2.3"))
+		assert.True(t, controller.Admit(ctx, activity, 2, "192.168.1.50"))
+		assert.False(t, controller.Admit(ctx, activity, 3, "172.16.0.1"))
+		assert.False(t, controller.Admit(ctx, activity, 4, "not-an-ip"))
+	})
+
+	t.Run("user profile is cached", func(t *testing.T) {
+		controller, userRepo, _, _ := setupControllerWithUsers(t)
+		activity := newActivity(strategy(StrategyLevel), 0)
+		activity.ID = 13
+		activity.ExtConfig = model.JSONObject{"gray": map[string]interface{}{"min_level": 3}}
+		for i := 0; i < 5; i++ {
+			assert.False(t, controller.Admit(ctx, activity, 1, ""))
+		}
+		assert.Equal(t, 1, userRepo.loads)
+	})
+
+	t.Run("unknown user fails closed", func(t *testing.T) {
+		activity := newActivity(strategy(StrategyLevel), 0)
+		activity.ID = 12
+		activity.ExtConfig = model.JSONObject{"gray": map[string]interface{}{"min_level": 1}}
+		assert.False(t, controller.Admit(ctx, activity, 99, ""))
+	})
+}
+
+func TestController_UpdateConfig(t *testing.T) {
+	controller, activityRepo, mr := setupController(t)
+	ctx := context.Background()
+
+	activityRepo.activity = newActivity(nil, 0)
+	activityRepo.activity.ExtConfig = model.JSONObject{"risk": map[string]interface{}{"deny_score": 50}}
+	mr.Set("activity:config:1", `{"id":1,"goods":{"id":3},"max_qps":500,"gray_strategy":"hash"}`)
+	mr.SetTTL("activity:config:1", 90*time.Minute)
+
+	updated, err := controller.UpdateConfig(ctx, 1, &UpdateRequest{
+		Strategy:  StrategyIPCIDR,
+		Whitelist: []uint64{7},
+		Params:    map[string]interface{}{"ip_cidrs": []interface{}{"10.0.0.0/8"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StrategyIPCIDR, *updated.GrayStrategy)
+	assert.Contains(t, activityRepo.activity.ExtConfig, "risk")
+
+	// Cached config is refreshed so instances apply it on the next request
+	data, err := mr.Get("activity:config:1")
+	require.NoError(t, err)
+	var cached model.SeckillActivity
+	require.NoError(t, json.Unmarshal([]byte(data), &cached))
+	assert.True(t, controller.Admit(ctx, &cached, 7, ""))
+	assert.True(t, controller.Admit(ctx, &cached, 8, "10.0.0.1"))
+	assert.False(t, controller.Admit(ctx, &cached, 9, "8.8.8.8"))
+
+	// Only the gray fields change, what prewarm cached with them and the TTL are kept
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(data), &fields))
+	assert.JSONEq(t, `{"id":3}`, string(fields["goods"]))
+	assert.JSONEq(t, `500`, string(fields["max_qps"]))
+	assert.Equal(t, 90*time.Minute, mr.TTL("activity:config:1"))
+
+	// Clearing the strategy drops it from the cache
+	_, err = controller.UpdateConfig(ctx, 1, &UpdateRequest{})
+	require.NoError(t, err)
+	data, err = mr.Get("activity:config:1")
+	require.NoError(t, err)
+	assert.NotContains(t, data, "gray_strategy")
+
+	_, err = controller.UpdateConfig(ctx, 1, &UpdateRequest{Strategy: StrategyLevel})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, err = controller.UpdateConfig(ctx, 2, &UpdateRequest{})
+	assert.ErrorIs(t, err, ErrActivityNotFound)
+
+	// Other failures are not reported as a missing activity
+	activityRepo.err = errors.New("connection refused")
+	_, err = controller.UpdateConfig(ctx, 1, &UpdateRequest{})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrActivityNotFound)
+}
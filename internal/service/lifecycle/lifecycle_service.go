@@ -0,0 +1,246 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/internal/service/seckill"
+	"seckill/pkg/lock"
+	"seckill/pkg/log"
+)
+
+const (
+	// lifecycleLockKey only one instance drives transitions per pass
+	lifecycleLockKey = "lock:activity_lifecycle"
+	// scanLimit max activities examined per status per pass
+	scanLimit = 100
+)
+
+// LifecycleService activity lifecycle service interface
+type LifecycleService interface {
+	// Run one pass: prewarm, start and end due activities
+	Schedule(ctx context.Context) error
+
+	// Start lifecycle scheduler
+	StartScheduler(ctx context.Context, interval time.Duration)
+}
+
+// lifecycleService lifecycle service implementation
+type lifecycleService struct {
+	activityRepo   repository.ActivityRepository
+	seckillService seckill.SeckillService
+	inventory      *seckill.MultiLevelInventory
+	redis          redis.Cmdable
+	instanceID     string
+	lockTTL        time.Duration
+}
+
+// NewLifecycleService creates a lifecycle service
+func NewLifecycleService(
+	activityRepo repository.ActivityRepository,
+	seckillService seckill.SeckillService,
+	inventory *seckill.MultiLevelInventory,
+	redis redis.Cmdable,
+) LifecycleService {
+	hostname, _ := os.Hostname()
+	return &lifecycleService{
+		activityRepo:   activityRepo,
+		seckillService: seckillService,
+		inventory:      inventory,
+		redis:          redis,
+		instanceID:     fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		lockTTL:        30 * time.Second,
+	}
+}
+
+// StartScheduler start lifecycle scheduler
+func (s *lifecycleService) StartScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.WithFields(map[string]interface{}{
+		"interval": interval,
+	}).Info("Started activity lifecycle scheduler")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Activity lifecycle scheduler stopped")
+			return
+		case <-ticker.C:
+			if err := s.Schedule(ctx); err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+				}).Error("Activity lifecycle pass failed")
+			}
+		}
+	}
+}
+
+// Schedule run one lifecycle pass
+func (s *lifecycleService) Schedule(ctx context.Context) error {
+	l := lock.NewRedisLock(s.redis, lifecycleLockKey, s.instanceID, s.lockTTL)
+	if err := l.Lock(ctx); err != nil {
+		if err == lock.ErrLockNotAcquired {
+			// Another instance is running this pass
+			return nil
+		}
+		return err
+	}
+	defer l.Unlock(ctx)
+
+	// Not started: prewarm and start
+	pending, err := s.activityRepo.ListByStatus(ctx, model.ActivityStatusNotStarted, scanLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list not started activities: %w", err)
+	}
+	for _, activity := range pending {
+		if activity.ShouldPrewarm() {
+			s.prewarm(ctx, activity)
+		}
+		if activity.ShouldStart() {
+			s.start(ctx, activity)
+		}
+	}
+
+	// Running: end
+	running, err := s.activityRepo.ListByStatus(ctx, model.ActivityStatusRunning, scanLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list running activities: %w", err)
+	}
+	for _, activity := range running {
+		if activity.ShouldEnd() {
+			s.end(ctx, activity)
+		}
+	}
+
+	return nil
+}
+
+// prewarm loads stock and config into Redis and marks the activity prewarmed
+func (s *lifecycleService) prewarm(ctx context.Context, activity *model.SeckillActivity) bool {
+	if err := s.seckillService.PrewarmActivity(ctx, activity.ID); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Error("Failed to prewarm activity")
+		return false
+	}
+
+	if err := s.activityRepo.UpdatePrewarmStatus(ctx, int64(activity.ID), model.PrewarmStatusDone); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Error("Failed to update prewarm status")
+		return false
+	}
+	activity.PrewarmStatus = model.PrewarmStatusDone
+
+	log.WithFields(map[string]interface{}{
+		"activity_id": activity.ID,
+	}).Info("Activity prewarmed by scheduler")
+	return true
+}
+
+// start flips the activity to running, prewarming first if it was missed
+func (s *lifecycleService) start(ctx context.Context, activity *model.SeckillActivity) {
+	if !activity.IsPrewarmed() && !s.prewarm(ctx, activity) {
+		return
+	}
+
+	if err := s.activityRepo.UpdateStatus(ctx, int64(activity.ID), model.ActivityStatusRunning); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Error("Failed to start activity")
+		return
+	}
+	activity.Status = model.ActivityStatusRunning
+
+	// Cached config was written at prewarm time with the old status
+	s.setCachedStatus(ctx, activity.ID, model.ActivityStatusRunning)
+
+	log.WithFields(map[string]interface{}{
+		"activity_id": activity.ID,
+		"start_time":  activity.StartTime,
+	}).Info("Activity started by scheduler")
+}
+
+// setCachedStatus rewrites the status of the cached activity config. Only the status changes:
+// the listed activity lacks what prewarm cached with it, such as the goods. A missing config
+// is left missing, requests load it from MySQL.
+func (s *lifecycleService) setCachedStatus(ctx context.Context, activityID uint64, status int8) {
+	configKey := fmt.Sprintf("activity:config:%d", activityID)
+	data, err := s.redis.Get(ctx, configKey).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.WithFields(map[string]interface{}{
+				"activity_id": activityID,
+				"error":       err.Error(),
+			}).Warn("Failed to read activity config cache")
+		}
+		return
+	}
+
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		// Unreadable, requests reload it from MySQL
+		s.redis.Del(ctx, configKey)
+		return
+	}
+	config["status"], _ = json.Marshal(status)
+	data, _ = json.Marshal(config)
+
+	err = s.redis.SetArgs(ctx, configKey, data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activityID,
+			"error":       err.Error(),
+		}).Warn("Failed to refresh activity config cache")
+	}
+}
+
+// end flips the activity to ended and evicts its Redis config and stock
+func (s *lifecycleService) end(ctx context.Context, activity *model.SeckillActivity) {
+	if err := s.activityRepo.UpdateStatus(ctx, int64(activity.ID), model.ActivityStatusEnded); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Error("Failed to end activity")
+		return
+	}
+
+	configKey := fmt.Sprintf("activity:config:%d", activity.ID)
+	if err := s.redis.Del(ctx, configKey).Err(); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Warn("Failed to evict activity config")
+	}
+
+	if err := s.inventory.EvictStock(ctx, activity.ID); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Warn("Failed to evict activity stock")
+	}
+
+	if err := seckill.ScheduleReconciliation(ctx, s.redis, activity.ID, time.Now()); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activity.ID,
+			"error":       err.Error(),
+		}).Warn("Failed to schedule activity reconciliation")
+	}
+
+	log.WithFields(map[string]interface{}{
+		"activity_id": activity.ID,
+		"end_time":    activity.EndTime,
+	}).Info("Activity ended by scheduler")
+}
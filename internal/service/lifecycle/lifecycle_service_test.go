@@ -0,0 +1,150 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/internal/service/seckill"
+)
+
+// fakeActivityRepo in-memory activity repository
+type fakeActivityRepo struct {
+	repository.ActivityRepository
+	activities map[uint64]*model.SeckillActivity
+}
+
+func (r *fakeActivityRepo) ListByStatus(ctx context.Context, status int8, limit int) ([]*model.SeckillActivity, error) {
+	var result []*model.SeckillActivity
+	for _, activity := range r.activities {
+		if activity.Status == status {
+			copied := *activity
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeActivityRepo) UpdateStatus(ctx context.Context, id int64, status int8) error {
+	r.activities[uint64(id)].Status = status
+	return nil
+}
+
+func (r *fakeActivityRepo) UpdatePrewarmStatus(ctx context.Context, id int64, prewarmStatus int8) error {
+	r.activities[uint64(id)].PrewarmStatus = prewarmStatus
+	return nil
+}
+
+// fakeSeckillService records prewarm calls
+type fakeSeckillService struct {
+	seckill.SeckillService
+	inventory *seckill.MultiLevelInventory
+	redis     redis.Cmdable
+	prewarmed []uint64
+}
+
+// PrewarmActivity caches the config with its goods, as the real prewarm does
+func (s *fakeSeckillService) PrewarmActivity(ctx context.Context, activityID uint64) error {
+	s.prewarmed = append(s.prewarmed, activityID)
+	config, _ := json.Marshal(&model.SeckillActivity{ID: activityID, Goods: &model.Goods{ID: 9, Name: "phone"}})
+	s.redis.SetEx(ctx, fmt.Sprintf("activity:config:%d", activityID), config, 24*time.Hour)
+	return s.inventory.SyncToRedis(ctx, activityID, 10)
+}
+
+func setupLifecycle(t *testing.T, activities ...*model.SeckillActivity) (LifecycleService, *fakeActivityRepo, *fakeSeckillService, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	inventory, err := seckill.NewMultiLevelInventory(client)
+	require.NoError(t, err)
+
+	repo := &fakeActivityRepo{activities: map[uint64]*model.SeckillActivity{}}
+	for _, activity := range activities {
+		repo.activities[activity.ID] = activity
+	}
+	seckillService := &fakeSeckillService{inventory: inventory, redis: client}
+
+	return NewLifecycleService(repo, seckillService, inventory, client), repo, seckillService, mr
+}
+
+func TestLifecycleService_Prewarm(t *testing.T) {
+	prewarmAt := time.Now().Add(-time.Minute)
+	activity := &model.SeckillActivity{
+		ID:          1,
+		PrewarmTime: &prewarmAt,
+		StartTime:   time.Now().Add(time.Hour),
+		EndTime:     time.Now().Add(2 * time.Hour),
+	}
+	service, repo, seckillService, _ := setupLifecycle(t, activity)
+
+	require.NoError(t, service.Schedule(context.Background()))
+
+	assert.Equal(t, []uint64{1}, seckillService.prewarmed)
+	assert.Equal(t, int8(model.PrewarmStatusDone), repo.activities[1].PrewarmStatus)
+	assert.Equal(t, int8(model.ActivityStatusNotStarted), repo.activities[1].Status)
+
+	// Already prewarmed, not prewarmed again
+	require.NoError(t, service.Schedule(context.Background()))
+	assert.Len(t, seckillService.prewarmed, 1)
+}
+
+func TestLifecycleService_StartAndEnd(t *testing.T) {
+	activity := &model.SeckillActivity{
+		ID:        2,
+		StartTime: time.Now().Add(-time.Minute),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	service, repo, seckillService, mr := setupLifecycle(t, activity)
+	ctx := context.Background()
+
+	// Start prewarms the missed activity first
+	require.NoError(t, service.Schedule(ctx))
+	assert.Equal(t, []uint64{2}, seckillService.prewarmed)
+	assert.Equal(t, int8(model.ActivityStatusRunning), repo.activities[2].Status)
+	assert.True(t, mr.Exists("stock:{2}"))
+
+	// Only the cached status changes, the goods prewarm cached stay
+	data, err := mr.Get("activity:config:2")
+	require.NoError(t, err)
+	var cached model.SeckillActivity
+	require.NoError(t, json.Unmarshal([]byte(data), &cached))
+	assert.Equal(t, int8(model.ActivityStatusRunning), cached.Status)
+	require.NotNil(t, cached.Goods)
+	assert.Equal(t, "phone", cached.Goods.Name)
+	assert.Greater(t, mr.TTL("activity:config:2"), time.Duration(0))
+
+	// End evicts config and stock
+	repo.activities[2].EndTime = time.Now().Add(-time.Second)
+	require.NoError(t, service.Schedule(ctx))
+	assert.Equal(t, int8(model.ActivityStatusEnded), repo.activities[2].Status)
+	assert.False(t, mr.Exists("activity:config:2"))
+	assert.False(t, mr.Exists("stock:{2}"))
+}
+
+func TestLifecycleService_SkipWhenLocked(t *testing.T) {
+	activity := &model.SeckillActivity{
+		ID:        3,
+		StartTime: time.Now().Add(-time.Minute),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	service, repo, _, mr := setupLifecycle(t, activity)
+
+	mr.Set(lifecycleLockKey, "other-instance")
+
+	require.NoError(t, service.Schedule(context.Background()))
+	assert.Equal(t, int8(model.ActivityStatusNotStarted), repo.activities[3].Status)
+}
@@ -38,22 +38,28 @@ type OrderService interface {
 type orderService struct {
 	orderRepo   repository.OrderRepository
 	goodsRepo   repository.GoodsRepository
-	inventory   *seckill.MultiLevelInventory
+	inventory   seckill.Inventory
 	idGenerator *snowflake.IDGenerator
+	notifier    *seckill.ResultNotifier
+	compensator *seckill.TCCCompensator
 }
 
 // NewOrderService creates an order service
 func NewOrderService(
 	orderRepo repository.OrderRepository,
 	goodsRepo repository.GoodsRepository,
-	inventory *seckill.MultiLevelInventory,
+	inventory seckill.Inventory,
 	idGenerator *snowflake.IDGenerator,
+	notifier *seckill.ResultNotifier,
+	compensator *seckill.TCCCompensator,
 ) OrderService {
 	return &orderService{
 		orderRepo:   orderRepo,
 		goodsRepo:   goodsRepo,
 		inventory:   inventory,
 		idGenerator: idGenerator,
+		notifier:    notifier,
+		compensator: compensator,
 	}
 }
 
@@ -118,20 +124,35 @@ func (s *orderService) CreateOrder(ctx context.Context, msg *model.OrderMessage)
 		}).Error("Failed to create order")
 
 		// Creation failed, cancel stock deduction
-		s.inventory.CancelDeduct(ctx, msg.DeductID, msg.ActivityID)
+		if cancelErr := s.inventory.CancelDeduct(ctx, msg.DeductID, msg.ActivityID); cancelErr != nil {
+			s.compensate(ctx, model.TCCOperationCancel, msg.DeductID, msg.ActivityID, msg.Quantity, cancelErr)
+		}
+		s.notifyResult(ctx, msg.UserID, seckill.ResultStatusFailed, &seckill.SeckillResult{
+			Success:   false,
+			RequestID: msg.RequestID,
+			Message:   "Order creation failed",
+		})
 		return err
 	}
 
 	// 6. Confirm stock deduction (TCC-Confirm phase)
-	if err := s.inventory.ConfirmDeduct(ctx, msg.DeductID, msg.ActivityID); err != nil {
+	stockCtx := seckill.WithStockMeta(ctx, seckill.StockMeta{RequestID: msg.RequestID, OrderNo: orderNo})
+	if err := s.inventory.ConfirmDeduct(stockCtx, msg.DeductID, msg.ActivityID); err != nil {
 		log.WithFields(map[string]interface{}{
 			"deduct_id": msg.DeductID,
 			"error":     err.Error(),
 		}).Error("Failed to confirm stock deduction")
-		// TODO: Order is already created, but stock confirmation failed
-		// This should be handled by a compensation mechanism
+		// The order is already saved, the confirm is retried until the reservation is sold
+		s.compensate(ctx, model.TCCOperationConfirm, msg.DeductID, msg.ActivityID, msg.Quantity, err)
 	}
 
+	s.notifyResult(ctx, msg.UserID, seckill.ResultStatusOrderCreated, &seckill.SeckillResult{
+		Success:   true,
+		RequestID: msg.RequestID,
+		OrderID:   orderNo,
+		Message:   "Order created, please pay within 15 minutes",
+	})
+
 	log.WithFields(map[string]interface{}{
 		"order_no":  orderNo,
 		"user_id":   msg.UserID,
@@ -179,15 +200,24 @@ func (s *orderService) HandleExpiredOrders(ctx context.Context) error {
 
 		// Rollback stock (TCC-Cancel)
 		if order.DeductID != "" {
-			if err := s.inventory.CancelDeduct(ctx, order.DeductID, order.ActivityID); err != nil {
+			stockCtx := seckill.WithStockMeta(ctx, seckill.StockMeta{RequestID: order.RequestID, OrderNo: order.OrderNo, Operator: "order_timeout"})
+			if err := s.inventory.CancelDeduct(stockCtx, order.DeductID, order.ActivityID); err != nil {
 				log.WithFields(map[string]interface{}{
 					"order_id":  order.ID,
 					"deduct_id": order.DeductID,
 					"error":     err.Error(),
 				}).Error("Failed to rollback stock")
+				s.compensate(ctx, model.TCCOperationCancel, order.DeductID, order.ActivityID, order.Quantity, err)
 			}
 		}
 
+		s.notifyResult(ctx, order.UserID, seckill.ResultStatusExpired, &seckill.SeckillResult{
+			Success:   false,
+			RequestID: order.RequestID,
+			OrderID:   order.OrderNo,
+			Message:   "Order expired without payment",
+		})
+
 		log.WithFields(map[string]interface{}{
 			"order_no": order.OrderNo,
 		}).Info("Expired order processed")
@@ -196,6 +226,14 @@ func (s *orderService) HandleExpiredOrders(ctx context.Context) error {
 	return nil
 }
 
+// compensate queues a failed confirm or cancel for retry
+func (s *orderService) compensate(ctx context.Context, operation, deductID string, activityID uint64, quantity int, cause error) {
+	if s.compensator == nil {
+		return
+	}
+	s.compensator.Enqueue(ctx, operation, deductID, activityID, quantity, cause)
+}
+
 // PayOrder pays an order
 func (s *orderService) PayOrder(ctx context.Context, orderNo string) error {
 	order, err := s.orderRepo.GetByOrderNo(ctx, orderNo)
@@ -209,7 +247,8 @@ func (s *orderService) PayOrder(ctx context.Context, orderNo string) error {
 
 	// Confirm stock deduction (TCC-Confirm)
 	if order.DeductID != "" {
-		if err := s.inventory.ConfirmDeduct(ctx, order.DeductID, order.ActivityID); err != nil {
+		stockCtx := seckill.WithStockMeta(ctx, seckill.StockMeta{RequestID: order.RequestID, OrderNo: order.OrderNo})
+		if err := s.inventory.ConfirmDeduct(stockCtx, order.DeductID, order.ActivityID); err != nil {
 			log.WithFields(map[string]interface{}{
 				"order_id":  order.ID,
 				"deduct_id": order.DeductID,
@@ -239,3 +278,17 @@ func (s *orderService) GetOrderByOrderNo(ctx context.Context, orderNo string) (*
 func (s *orderService) ListUserOrders(ctx context.Context, userID uint64, page, pageSize int) ([]*model.Order, int64, error) {
 	return s.orderRepo.ListUserOrders(ctx, userID, page, pageSize)
 }
+
+// notifyResult pushes a result transition to the client's stream
+func (s *orderService) notifyResult(ctx context.Context, userID uint64, status string, result *seckill.SeckillResult) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.Publish(ctx, userID, status, result); err != nil {
+		log.WithFields(map[string]interface{}{
+			"request_id": result.RequestID,
+			"status":     status,
+			"error":      err.Error(),
+		}).Warn("Failed to publish seckill result")
+	}
+}
@@ -0,0 +1,120 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+	"seckill/pkg/queue"
+)
+
+// OutboxRelay publishes outbox events to the queue topic named by the event and marks them
+// delivered. Relays on several instances claim disjoint events. An event is marked only after
+// the publish succeeded, and the mark commits with the claim, so a crash in between publishes
+// it again: delivery is at least once and consumers deduplicate on the event ID.
+type OutboxRelay struct {
+	repo      repository.OutboxRepository
+	queue     queue.Queue
+	batchSize int
+	retention time.Duration
+}
+
+// NewOutboxRelay creates a relay publishing up to batchSize events per run and
+// deleting delivered events older than retention, a zero retention keeps them
+func NewOutboxRelay(repo repository.OutboxRepository, q queue.Queue, batchSize int, retention time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		repo:      repo,
+		queue:     q,
+		batchSize: batchSize,
+		retention: retention,
+	}
+}
+
+// Run relays pending events every interval until ctx is done
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.WithFields(map[string]interface{}{
+		"interval": interval.String(),
+	}).Info("Outbox relay started")
+
+	lastCleanup := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			if _, err := r.Relay(ctx); err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+				}).Error("Outbox relay failed")
+			}
+			if r.retention > 0 && time.Since(lastCleanup) >= time.Hour {
+				lastCleanup = time.Now()
+				r.cleanup(ctx)
+			}
+		}
+	}
+}
+
+// Relay publishes one batch of pending events, returning how many were delivered.
+// Only the oldest pending event of an order is claimed, so after a failed publish the
+// rest of that order's events wait and are never delivered ahead of it.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	delivered := 0
+	err := r.repo.ClaimPending(ctx, r.batchSize, func(claim repository.OutboxRepository, events []*model.OutboxEvent) error {
+		for _, event := range events {
+			if err := r.queue.Publish(ctx, event.Topic, []byte(event.Payload)); err != nil {
+				log.WithFields(map[string]interface{}{
+					"event_id": event.ID,
+					"topic":    event.Topic,
+					"order_no": event.AggregateID,
+					"error":    err.Error(),
+				}).Warn("Failed to publish outbox event")
+				if repoErr := claim.RecordFailure(ctx, event.ID, truncate(err.Error(), 255)); repoErr != nil {
+					return repoErr
+				}
+				continue
+			}
+
+			if err := claim.MarkDelivered(ctx, event.ID); err != nil {
+				return err
+			}
+			delivered++
+		}
+		return nil
+	})
+	if err != nil {
+		// Published but still pending, the next run publishes them again
+		return 0, err
+	}
+	return delivered, nil
+}
+
+// cleanup deletes delivered events past the retention period
+func (r *OutboxRelay) cleanup(ctx context.Context) {
+	deleted, err := r.repo.DeleteDelivered(ctx, time.Now().Add(-r.retention), 10000)
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Warn("Failed to delete delivered outbox events")
+		return
+	}
+	if deleted > 0 {
+		log.WithFields(map[string]interface{}{
+			"deleted": deleted,
+		}).Info("Delivered outbox events deleted")
+	}
+}
+
+// truncate cuts s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
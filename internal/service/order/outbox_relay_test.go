@@ -0,0 +1,179 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/queue"
+)
+
+// memoryOutbox in-memory outbox
+type memoryOutbox struct {
+	mu     sync.Mutex
+	events []*model.OutboxEvent
+}
+
+func (m *memoryOutbox) add(topic, orderNo string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, &model.OutboxEvent{
+		ID:          uint64(len(m.events) + 1),
+		Topic:       topic,
+		AggregateID: orderNo,
+		Payload:     `{"event_id":"` + orderNo + ":" + topic + `"}`,
+	})
+}
+
+func (m *memoryOutbox) ClaimPending(ctx context.Context, limit int, fn func(claim repository.OutboxRepository, events []*model.OutboxEvent) error) error {
+	m.mu.Lock()
+	var pending []*model.OutboxEvent
+	waiting := make(map[string]bool)
+	for _, event := range m.events {
+		if event.Status != model.OutboxStatusPending || waiting[event.AggregateID] {
+			continue
+		}
+		// Only the oldest pending event of an order is claimed
+		waiting[event.AggregateID] = true
+		if len(pending) < limit {
+			copied := *event
+			pending = append(pending, &copied)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	return fn(m, pending)
+}
+
+func (m *memoryOutbox) MarkDelivered(ctx context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.events[id-1].Status, m.events[id-1].DeliveredAt = model.OutboxStatusDelivered, &now
+	return nil
+}
+
+func (m *memoryOutbox) RecordFailure(ctx context.Context, id uint64, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events[id-1].Attempts++
+	m.events[id-1].LastError = lastError
+	return nil
+}
+
+func (m *memoryOutbox) DeleteDelivered(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *memoryOutbox) get(id uint64) model.OutboxEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.events[id-1]
+}
+
+// flakyQueue fails publishes to one topic
+type flakyQueue struct {
+	queue.Queue
+	failTopic string
+}
+
+func (q *flakyQueue) Publish(ctx context.Context, topic string, message []byte) error {
+	if topic == q.failTopic {
+		return errors.New("broker unavailable")
+	}
+	return q.Queue.Publish(ctx, topic, message)
+}
+
+func newTestQueue(t *testing.T) *queue.MemoryQueue {
+	mq, err := queue.NewMemoryQueue(&queue.MemoryQueueConfig{BufferSize: 10, Timeout: time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = mq.Close() })
+	return mq
+}
+
+func TestOutboxRelay_PublishesAndMarksDelivered(t *testing.T) {
+	outbox := &memoryOutbox{}
+	outbox.add(model.EventOrderCreated, "ORDER1")
+	outbox.add(model.EventOrderPaid, "ORDER1")
+	outbox.add(model.EventOrderCreated, "ORDER2")
+
+	mq := newTestQueue(t)
+	relay := NewOutboxRelay(outbox, mq, 2, 0)
+	ctx := context.Background()
+
+	delivered, err := relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	delivered, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	// Nothing left to relay
+	delivered, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	assert.Equal(t, 2, mq.Backlog(model.EventOrderCreated))
+	message, err := mq.Consume(ctx, model.EventOrderPaid)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"event_id":"ORDER1:OrderPaid"}`, string(message))
+	for id := uint64(1); id <= 3; id++ {
+		event := outbox.get(id)
+		assert.Equal(t, model.OutboxStatusDelivered, int(event.Status))
+		assert.NotNil(t, event.DeliveredAt)
+	}
+}
+
+func TestOutboxRelay_HoldsBackOrderAfterFailedPublish(t *testing.T) {
+	outbox := &memoryOutbox{}
+	outbox.add(model.EventOrderCreated, "ORDER1")
+	outbox.add(model.EventOrderCancelled, "ORDER1")
+	outbox.add(model.EventOrderCreated, "ORDER2")
+	outbox.add(model.EventOrderCancelled, "ORDER2")
+
+	mq := newTestQueue(t)
+	relay := NewOutboxRelay(outbox, &flakyQueue{Queue: mq, failTopic: model.EventOrderCancelled}, 10, 0)
+
+	ctx := context.Background()
+	delivered, err := relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, 2, mq.Backlog(model.EventOrderCreated))
+
+	// The cancellations are claimed once the creations are out, and stay pending
+	delivered, err = relay.Relay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	failed := outbox.get(2)
+	assert.Equal(t, model.OutboxStatusPending, int(failed.Status))
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, "broker unavailable", failed.LastError)
+	assert.Equal(t, model.OutboxStatusPending, int(outbox.get(4).Status))
+}
+
+func TestOutboxRelay_KeepsOrderOfAnOrdersEvents(t *testing.T) {
+	outbox := &memoryOutbox{}
+	outbox.add(model.EventOrderCreated, "ORDER1")
+	outbox.add(model.EventOrderPaid, "ORDER1")
+	outbox.add(model.EventOrderCreated, "ORDER2")
+
+	mq := newTestQueue(t)
+	relay := NewOutboxRelay(outbox, &flakyQueue{Queue: mq, failTopic: model.EventOrderCreated}, 10, 0)
+
+	// ORDER1 created failed, its paid event must wait
+	delivered, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 0, mq.Backlog(model.EventOrderPaid))
+	assert.Equal(t, 0, outbox.get(2).Attempts)
+}
@@ -0,0 +1,129 @@
+package risk
+
+import (
+	"encoding/json"
+	"time"
+
+	"seckill/internal/model"
+)
+
+// extConfigKey key of the risk section in SeckillActivity.ExtConfig
+const extConfigKey = "risk"
+
+// Config per-activity risk engine config
+//
+// Defaults come from the activity's RiskLevel preset, any field set in
+// ExtConfig["risk"] overrides the preset, e.g.
+//
+//	{"risk": {"deny_score": 60, "min_level": 2, "rule_scores": {"ip_velocity": 60}}}
+type Config struct {
+	Enabled               bool           `json:"enabled"`
+	ChallengeScore        int            `json:"challenge_score"`         // Score >= challenge_score requires verification
+	DenyScore             int            `json:"deny_score"`              // Score >= deny_score is rejected
+	MinAccountAgeHours    int            `json:"min_account_age_hours"`   // 0 disables the account age rule
+	MinLevel              int            `json:"min_level"`               // 0 disables the user level rule
+	VelocityWindowSeconds int            `json:"velocity_window_seconds"` // Window of the velocity rules
+	DeviceMaxRequests     int            `json:"device_max_requests"`     // Per device per window, 0 disables
+	IPMaxRequests         int            `json:"ip_max_requests"`         // Per IP per window, 0 disables
+	MaxUsersPerDevice     int            `json:"max_users_per_device"`    // 0 disables shared device detection
+	RuleScores            map[string]int `json:"rule_scores"`             // Score added when a rule hits
+	DisabledRules         []string       `json:"disabled_rules"`
+}
+
+// levelPreset thresholds tightened by SeckillActivity.RiskLevel (1-5)
+type levelPreset struct {
+	challengeScore     int
+	denyScore          int
+	minAccountAgeHours int
+	minLevel           int
+}
+
+var levelPresets = map[int8]levelPreset{
+	1: {challengeScore: 60, denyScore: 100, minAccountAgeHours: 0, minLevel: 0},
+	2: {challengeScore: 50, denyScore: 90, minAccountAgeHours: 24, minLevel: 0},
+	3: {challengeScore: 40, denyScore: 80, minAccountAgeHours: 72, minLevel: 1},
+	4: {challengeScore: 30, denyScore: 70, minAccountAgeHours: 168, minLevel: 2},
+	5: {challengeScore: 20, denyScore: 60, minAccountAgeHours: 720, minLevel: 3},
+}
+
+// defaultRuleScores score of each built-in rule
+var defaultRuleScores = map[string]int{
+	RuleAccountAge:     30,
+	RuleUserLevel:      20,
+	RuleDeviceVelocity: 40,
+	RuleIPVelocity:     40,
+	RuleSharedDevice:   50,
+}
+
+// ConfigFor builds the risk config of an activity
+func ConfigFor(activity *model.SeckillActivity) *Config {
+	riskLevel := activity.RiskLevel
+	if riskLevel < 1 {
+		riskLevel = 1
+	}
+	if riskLevel > 5 {
+		riskLevel = 5
+	}
+	preset := levelPresets[riskLevel]
+
+	cfg := &Config{
+		Enabled:               true,
+		ChallengeScore:        preset.challengeScore,
+		DenyScore:             preset.denyScore,
+		MinAccountAgeHours:    preset.minAccountAgeHours,
+		MinLevel:              preset.minLevel,
+		VelocityWindowSeconds: 60,
+		DeviceMaxRequests:     20,
+		IPMaxRequests:         50,
+		MaxUsersPerDevice:     3,
+		RuleScores:            make(map[string]int, len(defaultRuleScores)),
+	}
+	for rule, score := range defaultRuleScores {
+		cfg.RuleScores[rule] = score
+	}
+
+	section, ok := activity.ExtConfig[extConfigKey]
+	if !ok {
+		return cfg
+	}
+
+	// Overlay ExtConfig, rule_scores are merged rather than replaced
+	scores := cfg.RuleScores
+	data, err := json.Marshal(section)
+	if err != nil {
+		return cfg
+	}
+	overlay := *cfg
+	overlay.RuleScores = nil
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return cfg
+	}
+	for rule, score := range overlay.RuleScores {
+		scores[rule] = score
+	}
+	overlay.RuleScores = scores
+	return &overlay
+}
+
+// ruleEnabled whether a rule is not disabled by config
+func (c *Config) ruleEnabled(name string) bool {
+	for _, disabled := range c.DisabledRules {
+		if disabled == name {
+			return false
+		}
+	}
+	return true
+}
+
+// score score of a rule
+func (c *Config) score(name string) int {
+	return c.RuleScores[name]
+}
+
+// velocityWindow window of the velocity rules
+func (c *Config) velocityWindow() time.Duration {
+	if c.VelocityWindowSeconds <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.VelocityWindowSeconds) * time.Second
+}
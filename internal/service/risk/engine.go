@@ -0,0 +1,260 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/internal/model"
+	redisx "seckill/internal/redis"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+)
+
+// Decision risk decision
+type Decision string
+
+// Risk decisions
+const (
+	DecisionAllow     Decision = "allow"
+	DecisionChallenge Decision = "challenge"
+	DecisionDeny      Decision = "deny"
+)
+
+const (
+	// userCacheTTL how long a user profile is cached for rules
+	userCacheTTL = 10 * time.Minute
+	// decisionTTL how long a non-allow decision is kept
+	decisionTTL = 24 * time.Hour
+)
+
+// Request request to evaluate
+type Request struct {
+	RequestID  string
+	ActivityID uint64
+	UserID     uint64
+	IP         string
+	DeviceID   string
+}
+
+// Result risk evaluation result
+type Result struct {
+	RequestID  string   `json:"request_id"`
+	ActivityID uint64   `json:"activity_id"`
+	UserID     uint64   `json:"user_id"`
+	Decision   Decision `json:"decision"`
+	Score      int      `json:"score"`
+	Hits       []*Hit   `json:"hits,omitempty"`
+	RejectedBy string   `json:"rejected_by,omitempty"` // Highest scoring rule of a non-allow decision
+	Timestamp  int64    `json:"timestamp"`
+}
+
+// Allowed whether the request may proceed
+func (r *Result) Allowed() bool {
+	return r.Decision == DecisionAllow
+}
+
+// Input rule input, user profile is loaded on first use
+type Input struct {
+	Request
+	redis    redis.Cmdable
+	now      time.Time
+	loadUser func(ctx context.Context) (*model.User, error)
+
+	userOnce sync.Once
+	user     *model.User
+	userErr  error
+}
+
+// User user profile of the request
+func (in *Input) User(ctx context.Context) (*model.User, error) {
+	in.userOnce.Do(func() {
+		in.user, in.userErr = in.loadUser(ctx)
+	})
+	return in.user, in.userErr
+}
+
+// windowIncrScript counts an event and starts the window on the first one, in one step so a
+// counter is never left without expiry. A counter found without one is given the window.
+var windowIncrScript = redisx.Scripts.Register("risk:window_incr", `
+	local count = redis.call('INCR', KEYS[1])
+	if count == 1 or redis.call('PTTL', KEYS[1]) == -1 then
+		redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	end
+	return count
+`)
+
+// incrWindow counts an event in a fixed window
+func (in *Input) incrWindow(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return windowIncrScript.Run(ctx, in.redis, []string{key}, window.Milliseconds()).Int64()
+}
+
+// Engine risk rule engine
+type Engine struct {
+	redis    redis.Cmdable
+	userRepo repository.UserRepository
+	rules    []Rule
+	now      func() time.Time
+}
+
+// NewEngine creates a risk engine with the built-in rules
+func NewEngine(redisClient redis.Cmdable, userRepo repository.UserRepository) *Engine {
+	return &Engine{
+		redis:    redisClient,
+		userRepo: userRepo,
+		rules:    DefaultRules(),
+		now:      time.Now,
+	}
+}
+
+// RegisterRule adds a custom rule
+func (e *Engine) RegisterRule(rule Rule) {
+	e.rules = append(e.rules, rule)
+}
+
+// Evaluate scores a request against the activity's rules, rule errors fail open
+func (e *Engine) Evaluate(ctx context.Context, activity *model.SeckillActivity, req *Request) *Result {
+	result := &Result{
+		RequestID:  req.RequestID,
+		ActivityID: req.ActivityID,
+		UserID:     req.UserID,
+		Decision:   DecisionAllow,
+		Timestamp:  e.now().Unix(),
+	}
+
+	cfg := ConfigFor(activity)
+	if !cfg.Enabled {
+		return result
+	}
+
+	in := &Input{
+		Request:  *req,
+		redis:    e.redis,
+		now:      e.now(),
+		loadUser: e.loadUser(req.UserID),
+	}
+
+	var top *Hit
+	for _, rule := range e.rules {
+		if !cfg.ruleEnabled(rule.Name()) {
+			continue
+		}
+		hit, err := rule.Evaluate(ctx, in, cfg)
+		if err != nil {
+			log.WithFields(map[string]interface{}{
+				"rule":       rule.Name(),
+				"request_id": req.RequestID,
+				"error":      err.Error(),
+			}).Warn("Risk rule evaluation failed")
+			continue
+		}
+		if hit == nil || hit.Score <= 0 {
+			continue
+		}
+		result.Hits = append(result.Hits, hit)
+		result.Score += hit.Score
+		if top == nil || hit.Score > top.Score {
+			top = hit
+		}
+	}
+
+	switch {
+	case top == nil:
+	case result.Score >= cfg.DenyScore:
+		result.Decision = DecisionDeny
+	case result.Score >= cfg.ChallengeScore:
+		result.Decision = DecisionChallenge
+	}
+	if !result.Allowed() {
+		result.RejectedBy = top.Rule
+		e.Record(ctx, result)
+	}
+	return result
+}
+
+// Record stores a non-allow decision and counts it per rule
+func (e *Engine) Record(ctx context.Context, result *Result) {
+	data, _ := json.Marshal(result)
+
+	pipe := e.redis.Pipeline()
+	pipe.SetEx(ctx, fmt.Sprintf("risk:decision:%s", result.RequestID), data, decisionTTL)
+	statsKey := fmt.Sprintf("risk:rejections:%d", result.ActivityID)
+	pipe.HIncrBy(ctx, statsKey, result.RejectedBy, 1)
+	pipe.Expire(ctx, statsKey, 7*24*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.WithFields(map[string]interface{}{
+			"request_id": result.RequestID,
+			"error":      err.Error(),
+		}).Warn("Failed to record risk decision")
+	}
+
+	log.WithFields(map[string]interface{}{
+		"request_id":  result.RequestID,
+		"activity_id": result.ActivityID,
+		"user_id":     result.UserID,
+		"decision":    result.Decision,
+		"score":       result.Score,
+		"rejected_by": result.RejectedBy,
+	}).Warn("Request flagged by risk control")
+}
+
+// GetDecision gets the recorded decision of a request, nil if it was allowed
+func (e *Engine) GetDecision(ctx context.Context, requestID string) (*Result, error) {
+	data, err := e.redis.Get(ctx, fmt.Sprintf("risk:decision:%s", requestID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetRejectionStats non-allow decisions of an activity per rule
+func (e *Engine) GetRejectionStats(ctx context.Context, activityID uint64) (map[string]int64, error) {
+	values, err := e.redis.HGetAll(ctx, fmt.Sprintf("risk:rejections:%d", activityID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]int64, len(values))
+	for rule, value := range values {
+		stats[rule], _ = strconv.ParseInt(value, 10, 64)
+	}
+	return stats, nil
+}
+
+// loadUser loads the user profile through a short Redis cache
+func (e *Engine) loadUser(userID uint64) func(ctx context.Context) (*model.User, error) {
+	return func(ctx context.Context) (*model.User, error) {
+		cacheKey := fmt.Sprintf("risk:user:%d", userID)
+		if data, err := e.redis.Get(ctx, cacheKey).Bytes(); err == nil {
+			var user model.User
+			if err := json.Unmarshal(data, &user); err == nil {
+				return &user, nil
+			}
+		}
+
+		if e.userRepo == nil {
+			return nil, nil
+		}
+		user, err := e.userRepo.GetByID(ctx, int64(userID))
+		if err != nil {
+			return nil, err
+		}
+		if data, err := json.Marshal(user); err == nil {
+			e.redis.SetEx(ctx, cacheKey, data, userCacheTTL)
+		}
+		return user, nil
+	}
+}
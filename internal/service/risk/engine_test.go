@@ -0,0 +1,164 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// fakeUserRepo returns a fixed user
+type fakeUserRepo struct {
+	repository.UserRepository
+	user  *model.User
+	calls int
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	r.calls++
+	if r.user == nil {
+		return nil, errors.New("user not found")
+	}
+	return r.user, nil
+}
+
+func setupEngine(t *testing.T, user *model.User) (*Engine, *fakeUserRepo) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	repo := &fakeUserRepo{user: user}
+	return NewEngine(client, repo), repo
+}
+
+func TestEngine_AllowByDefault(t *testing.T) {
+	engine, _ := setupEngine(t, &model.User{ID: 1, Level: 1, CreatedAt: time.Now().Add(-time.Hour)})
+
+	result := engine.Evaluate(context.Background(), &model.SeckillActivity{ID: 1, RiskLevel: 1}, &Request{
+		RequestID:  "req-1",
+		ActivityID: 1,
+		UserID:     1,
+		IP:         "10.0.0.1",
+		DeviceID:   "device-1",
+	})
+
+	assert.Equal(t, DecisionAllow, result.Decision)
+	assert.Zero(t, result.Score)
+	assert.Empty(t, result.RejectedBy)
+}
+
+func TestEngine_RiskLevelThresholds(t *testing.T) {
+	// New low-level account: account age (30) + user level (20) = 50
+	user := &model.User{ID: 1, Level: 0, CreatedAt: time.Now().Add(-time.Hour)}
+	ctx := context.Background()
+
+	engine, repo := setupEngine(t, user)
+	result := engine.Evaluate(ctx, &model.SeckillActivity{ID: 1, RiskLevel: 3}, &Request{RequestID: "req-3", ActivityID: 1, UserID: 1})
+	assert.Equal(t, DecisionChallenge, result.Decision)
+	assert.Equal(t, 50, result.Score)
+	assert.Equal(t, RuleAccountAge, result.RejectedBy)
+	assert.Len(t, result.Hits, 2)
+
+	result = engine.Evaluate(ctx, &model.SeckillActivity{ID: 1, RiskLevel: 1}, &Request{RequestID: "req-1", ActivityID: 1, UserID: 1})
+	assert.Equal(t, DecisionAllow, result.Decision)
+
+	// Raised account age score: 60 + 20 >= deny score of level 5
+	activity := &model.SeckillActivity{
+		ID:        1,
+		RiskLevel: 5,
+		ExtConfig: model.JSONObject{"risk": map[string]interface{}{"rule_scores": map[string]interface{}{RuleAccountAge: 60}}},
+	}
+	result = engine.Evaluate(ctx, activity, &Request{RequestID: "req-5", ActivityID: 1, UserID: 1})
+	assert.Equal(t, DecisionDeny, result.Decision)
+	assert.Equal(t, 80, result.Score)
+
+	// User profile loaded once, then served from cache
+	assert.Equal(t, 1, repo.calls)
+
+	recorded, err := engine.GetDecision(ctx, "req-5")
+	require.NoError(t, err)
+	require.NotNil(t, recorded)
+	assert.Equal(t, RuleAccountAge, recorded.RejectedBy)
+
+	stats, err := engine.GetRejectionStats(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats[RuleAccountAge])
+}
+
+func TestEngine_ExtConfigOverrides(t *testing.T) {
+	engine, _ := setupEngine(t, &model.User{ID: 1, Level: 1, CreatedAt: time.Now().Add(-time.Hour)})
+	ctx := context.Background()
+
+	activity := &model.SeckillActivity{
+		ID:        2,
+		RiskLevel: 1,
+		ExtConfig: model.JSONObject{
+			"risk": map[string]interface{}{
+				"ip_max_requests": 2,
+				"deny_score":      40,
+				"disabled_rules":  []interface{}{RuleAccountAge},
+			},
+		},
+	}
+
+	req := &Request{ActivityID: 2, UserID: 1, IP: "10.0.0.2"}
+	for i := 0; i < 2; i++ {
+		req.RequestID = "ok"
+		assert.True(t, engine.Evaluate(ctx, activity, req).Allowed())
+	}
+
+	req.RequestID = "flood"
+	result := engine.Evaluate(ctx, activity, req)
+	assert.Equal(t, DecisionDeny, result.Decision)
+	assert.Equal(t, RuleIPVelocity, result.RejectedBy)
+}
+
+func TestEngine_Disabled(t *testing.T) {
+	engine, repo := setupEngine(t, nil)
+
+	activity := &model.SeckillActivity{
+		ID:        3,
+		RiskLevel: 5,
+		ExtConfig: model.JSONObject{"risk": map[string]interface{}{"enabled": false}},
+	}
+	result := engine.Evaluate(context.Background(), activity, &Request{RequestID: "r", ActivityID: 3, UserID: 9})
+	assert.True(t, result.Allowed())
+	assert.Zero(t, repo.calls)
+}
+
+func TestInput_IncrWindow(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+	ctx := context.Background()
+	in := &Input{redis: client}
+
+	for want := int64(1); want <= 2; want++ {
+		count, err := in.incrWindow(ctx, "risk:w", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, want, count)
+	}
+	assert.Equal(t, time.Minute, mr.TTL("risk:w"))
+
+	// A counter left without expiry gets the window on its next event
+	mr.Set("risk:stuck", "5")
+	count, err := in.incrWindow(ctx, "risk:stuck", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), count)
+	assert.Equal(t, time.Minute, mr.TTL("risk:stuck"))
+}
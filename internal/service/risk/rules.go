@@ -0,0 +1,176 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Built-in rule names
+const (
+	RuleAccountAge     = "account_age"
+	RuleUserLevel      = "user_level"
+	RuleDeviceVelocity = "device_velocity"
+	RuleIPVelocity     = "ip_velocity"
+	RuleSharedDevice   = "shared_device"
+)
+
+// Rule risk rule, returns a hit when the request looks risky
+type Rule interface {
+	// Name rule name, used in config and decisions
+	Name() string
+
+	// Evaluate returns nil when the rule does not hit
+	Evaluate(ctx context.Context, in *Input, cfg *Config) (*Hit, error)
+}
+
+// Hit a rule that contributed to the score
+type Hit struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// AccountAgeRule hits accounts younger than MinAccountAgeHours
+type AccountAgeRule struct{}
+
+// Name rule name
+func (AccountAgeRule) Name() string { return RuleAccountAge }
+
+// Evaluate evaluates the rule
+func (r AccountAgeRule) Evaluate(ctx context.Context, in *Input, cfg *Config) (*Hit, error) {
+	if cfg.MinAccountAgeHours <= 0 {
+		return nil, nil
+	}
+	user, err := in.User(ctx)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	age := in.now.Sub(user.CreatedAt)
+	if age >= time.Duration(cfg.MinAccountAgeHours)*time.Hour {
+		return nil, nil
+	}
+	return &Hit{
+		Rule:   r.Name(),
+		Score:  cfg.score(r.Name()),
+		Reason: fmt.Sprintf("account age %s below %dh", age.Truncate(time.Minute), cfg.MinAccountAgeHours),
+	}, nil
+}
+
+// UserLevelRule hits users below MinLevel
+type UserLevelRule struct{}
+
+// Name rule name
+func (UserLevelRule) Name() string { return RuleUserLevel }
+
+// Evaluate evaluates the rule
+func (r UserLevelRule) Evaluate(ctx context.Context, in *Input, cfg *Config) (*Hit, error) {
+	if cfg.MinLevel <= 0 {
+		return nil, nil
+	}
+	user, err := in.User(ctx)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	if user.Level >= cfg.MinLevel {
+		return nil, nil
+	}
+	return &Hit{
+		Rule:   r.Name(),
+		Score:  cfg.score(r.Name()),
+		Reason: fmt.Sprintf("user level %d below %d", user.Level, cfg.MinLevel),
+	}, nil
+}
+
+// DeviceVelocityRule hits devices sending too many requests per window
+type DeviceVelocityRule struct{}
+
+// Name rule name
+func (DeviceVelocityRule) Name() string { return RuleDeviceVelocity }
+
+// Evaluate evaluates the rule
+func (r DeviceVelocityRule) Evaluate(ctx context.Context, in *Input, cfg *Config) (*Hit, error) {
+	if cfg.DeviceMaxRequests <= 0 || in.DeviceID == "" {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf("risk:velocity:device:%d:%s", in.ActivityID, in.DeviceID)
+	count, err := in.incrWindow(ctx, key, cfg.velocityWindow())
+	if err != nil || count <= int64(cfg.DeviceMaxRequests) {
+		return nil, err
+	}
+	return &Hit{
+		Rule:   r.Name(),
+		Score:  cfg.score(r.Name()),
+		Reason: fmt.Sprintf("device sent %d requests in %s", count, cfg.velocityWindow()),
+	}, nil
+}
+
+// IPVelocityRule hits IPs sending too many requests per window
+type IPVelocityRule struct{}
+
+// Name rule name
+func (IPVelocityRule) Name() string { return RuleIPVelocity }
+
+// Evaluate evaluates the rule
+func (r IPVelocityRule) Evaluate(ctx context.Context, in *Input, cfg *Config) (*Hit, error) {
+	if cfg.IPMaxRequests <= 0 || in.IP == "" {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf("risk:velocity:ip:%d:%s", in.ActivityID, in.IP)
+	count, err := in.incrWindow(ctx, key, cfg.velocityWindow())
+	if err != nil || count <= int64(cfg.IPMaxRequests) {
+		return nil, err
+	}
+	return &Hit{
+		Rule:   r.Name(),
+		Score:  cfg.score(r.Name()),
+		Reason: fmt.Sprintf("ip sent %d requests in %s", count, cfg.velocityWindow()),
+	}, nil
+}
+
+// SharedDeviceRule hits devices used by too many accounts in one activity
+type SharedDeviceRule struct{}
+
+// Name rule name
+func (SharedDeviceRule) Name() string { return RuleSharedDevice }
+
+// Evaluate evaluates the rule
+func (r SharedDeviceRule) Evaluate(ctx context.Context, in *Input, cfg *Config) (*Hit, error) {
+	if cfg.MaxUsersPerDevice <= 0 || in.DeviceID == "" {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf("risk:device_users:%d:%s", in.ActivityID, in.DeviceID)
+	pipe := in.redis.TxPipeline()
+	pipe.SAdd(ctx, key, in.UserID)
+	pipe.Expire(ctx, key, 24*time.Hour)
+	card := pipe.SCard(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	users := card.Val()
+	if users <= int64(cfg.MaxUsersPerDevice) {
+		return nil, nil
+	}
+	return &Hit{
+		Rule:   r.Name(),
+		Score:  cfg.score(r.Name()),
+		Reason: fmt.Sprintf("device shared by %d accounts", users),
+	}, nil
+}
+
+// DefaultRules built-in rules in evaluation order
+func DefaultRules() []Rule {
+	return []Rule{
+		AccountAgeRule{},
+		UserLevelRule{},
+		DeviceVelocityRule{},
+		IPVelocityRule{},
+		SharedDeviceRule{},
+	}
+}
@@ -0,0 +1,63 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+)
+
+func TestSharedDeviceRule(t *testing.T) {
+	engine, _ := setupEngine(t, nil)
+	ctx := context.Background()
+	activity := &model.SeckillActivity{ID: 1, RiskLevel: 1}
+
+	// Three accounts on one device are fine, the fourth is flagged
+	for userID := uint64(1); userID <= 3; userID++ {
+		result := engine.Evaluate(ctx, activity, &Request{RequestID: fmt.Sprintf("r%d", userID), ActivityID: 1, UserID: userID, DeviceID: "shared"})
+		assert.True(t, result.Allowed())
+	}
+
+	result := engine.Evaluate(ctx, activity, &Request{RequestID: "r4", ActivityID: 1, UserID: 4, DeviceID: "shared"})
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, RuleSharedDevice, result.Hits[0].Rule)
+	assert.Equal(t, 50, result.Score)
+}
+
+func TestDeviceVelocityRule(t *testing.T) {
+	engine, _ := setupEngine(t, nil)
+	ctx := context.Background()
+	activity := &model.SeckillActivity{
+		ID:        1,
+		RiskLevel: 1,
+		ExtConfig: model.JSONObject{"risk": map[string]interface{}{"device_max_requests": 1}},
+	}
+
+	req := &Request{RequestID: "a", ActivityID: 1, UserID: 1, DeviceID: "d1"}
+	assert.True(t, engine.Evaluate(ctx, activity, req).Allowed())
+
+	result := engine.Evaluate(ctx, activity, req)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, RuleDeviceVelocity, result.Hits[0].Rule)
+}
+
+// blockAllRule custom rule
+type blockAllRule struct{}
+
+func (blockAllRule) Name() string { return "block_all" }
+
+func (blockAllRule) Evaluate(ctx context.Context, in *Input, cfg *Config) (*Hit, error) {
+	return &Hit{Rule: "block_all", Score: 1000, Reason: "blocked"}, nil
+}
+
+func TestEngine_RegisterRule(t *testing.T) {
+	engine, _ := setupEngine(t, nil)
+	engine.RegisterRule(blockAllRule{})
+
+	result := engine.Evaluate(context.Background(), &model.SeckillActivity{ID: 1, RiskLevel: 1}, &Request{RequestID: "x", ActivityID: 1, UserID: 1})
+	assert.Equal(t, DecisionDeny, result.Decision)
+	assert.Equal(t, "block_all", result.RejectedBy)
+}
@@ -0,0 +1,88 @@
+package seckill
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+)
+
+func setupActivityGuard(t *testing.T) *ActivityGuard {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+	return NewActivityGuard(client)
+}
+
+func TestActivityGuard_MaxQPS(t *testing.T) {
+	guard := setupActivityGuard(t)
+	ctx := context.Background()
+	activity := &model.SeckillActivity{ID: 1, MaxQPS: 2}
+
+	for i := 0; i < 2; i++ {
+		release, err := guard.Acquire(ctx, activity)
+		require.NoError(t, err)
+		release()
+	}
+
+	_, err := guard.Acquire(ctx, activity)
+	assert.ErrorIs(t, err, ErrActivityQPSExceeded)
+
+	// Raised limit applies to the next request
+	activity.MaxQPS = 10
+	release, err := guard.Acquire(ctx, activity)
+	require.NoError(t, err)
+	release()
+}
+
+func TestActivityGuard_MaxConcurrent(t *testing.T) {
+	guard := setupActivityGuard(t)
+	ctx := context.Background()
+	activity := &model.SeckillActivity{ID: 1, MaxConcurrent: 1}
+
+	release, err := guard.Acquire(ctx, activity)
+	require.NoError(t, err)
+
+	_, err = guard.Acquire(ctx, activity)
+	assert.ErrorIs(t, err, ErrActivityConcurrencyExceeded)
+
+	// Double release must not free someone else's slot
+	release()
+	release()
+	inFlight, err := guard.InFlight(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), inFlight)
+}
+
+func TestActivityGuard_ReleaseOnPanicAndCancel(t *testing.T) {
+	guard := setupActivityGuard(t)
+	activity := &model.SeckillActivity{ID: 1, MaxConcurrent: 1}
+
+	func() {
+		defer func() { recover() }()
+		release, err := guard.Acquire(context.Background(), activity)
+		require.NoError(t, err)
+		defer release()
+		panic("handler panic")
+	}()
+
+	// Request context cancelled (timeout) before release
+	ctx, cancel := context.WithCancel(context.Background())
+	release, err := guard.Acquire(ctx, activity)
+	require.NoError(t, err)
+	cancel()
+	release()
+
+	inFlight, err := guard.InFlight(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), inFlight)
+}
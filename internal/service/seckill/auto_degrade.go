@@ -0,0 +1,208 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/degrade"
+	"seckill/pkg/log"
+	"seckill/pkg/queue"
+)
+
+// degradeExtConfigKey key of the auto degrade section in SeckillActivity.ExtConfig
+const degradeExtConfigKey = "degrade"
+
+// Auto degrade defaults, overridable per activity
+const (
+	defaultDegradeP99Latency   = 500 * time.Millisecond
+	defaultDegradeBacklog      = 800 // Memory queue buffers 1000 messages per topic
+	defaultDegradeMinRequests  = 20
+	defaultDegradeRecoverRatio = 0.5
+	defaultDegradeRecoverAfter = 3
+	defaultDegradeTTL          = time.Minute
+	defaultLotteryRatio        = 0.5
+	defaultDegradeMessage      = "System busy, please try again later"
+	defaultQueueMessage        = "High traffic, you are in the queue"
+)
+
+// autoDegradeConfig optional overrides in ExtConfig["degrade"], e.g.
+//
+//	{"degrade": {"p99_latency_ms": 300, "max_backlog": 500, "recover_after": 5, "lottery_ratio": 0.2}}
+type autoDegradeConfig struct {
+	P99LatencyMs *int     `json:"p99_latency_ms"`
+	MaxBacklog   *int64   `json:"max_backlog"`
+	MinRequests  *int64   `json:"min_requests"`
+	RecoverRatio *float64 `json:"recover_ratio"`
+	RecoverAfter *int     `json:"recover_after"`
+	TTLSeconds   *int     `json:"ttl_seconds"`
+	LotteryRatio *float64 `json:"lottery_ratio"`
+	AdmitRate    *int     `json:"admit_rate"`
+	Message      *string  `json:"message"`
+}
+
+// AutoDegradePolicy builds the auto degrade policy of an activity
+// DegradeThreshold is the error rate that triggers degradation, 0 disables auto degradation
+func AutoDegradePolicy(activity *model.SeckillActivity) *degrade.AutoPolicy {
+	if activity.DegradeThreshold <= 0 {
+		return nil
+	}
+
+	policy := &degrade.AutoPolicy{
+		ErrorRate:    activity.DegradeThreshold,
+		P99Latency:   defaultDegradeP99Latency,
+		Backlog:      defaultDegradeBacklog,
+		MinRequests:  defaultDegradeMinRequests,
+		RecoverRatio: defaultDegradeRecoverRatio,
+		RecoverAfter: defaultDegradeRecoverAfter,
+		TTL:          defaultDegradeTTL,
+	}
+
+	var cfg autoDegradeConfig
+	if section, ok := activity.ExtConfig[degradeExtConfigKey]; ok {
+		if data, err := json.Marshal(section); err == nil {
+			json.Unmarshal(data, &cfg)
+		}
+	}
+	if cfg.P99LatencyMs != nil {
+		policy.P99Latency = time.Duration(*cfg.P99LatencyMs) * time.Millisecond
+	}
+	if cfg.MaxBacklog != nil {
+		policy.Backlog = *cfg.MaxBacklog
+	}
+	if cfg.MinRequests != nil {
+		policy.MinRequests = *cfg.MinRequests
+	}
+	if cfg.RecoverRatio != nil && *cfg.RecoverRatio > 0 && *cfg.RecoverRatio < 1 {
+		policy.RecoverRatio = *cfg.RecoverRatio
+	}
+	if cfg.RecoverAfter != nil && *cfg.RecoverAfter > 0 {
+		policy.RecoverAfter = *cfg.RecoverAfter
+	}
+	if cfg.TTLSeconds != nil && *cfg.TTLSeconds > 0 {
+		policy.TTL = time.Duration(*cfg.TTLSeconds) * time.Second
+	}
+
+	policy.Strategy = degradeStrategyOf(activity, &cfg)
+	return policy
+}
+
+// degradeStrategyOf maps SeckillActivity.DegradeStrategy to the strategy applied while degraded
+func degradeStrategyOf(activity *model.SeckillActivity, cfg *autoDegradeConfig) *degrade.DegradeStrategy {
+	strategy := &degrade.DegradeStrategy{Type: "return_error", Message: defaultDegradeMessage}
+	switch activity.DegradeStrategy {
+	case "queue", "queue_only":
+		strategy.Type = "queue_only"
+		strategy.Message = defaultQueueMessage
+		if cfg.AdmitRate != nil {
+			strategy.AdmitRate = *cfg.AdmitRate
+		}
+	case "lottery":
+		strategy.Type = "lottery"
+		strategy.Ratio = defaultLotteryRatio
+		if cfg.LotteryRatio != nil {
+			strategy.Ratio = *cfg.LotteryRatio
+		}
+	}
+	if cfg.Message != nil {
+		strategy.Message = *cfg.Message
+	}
+	return strategy
+}
+
+// OrderBacklog returns a function summing the pending order messages of every priority queue
+func OrderBacklog(reporter queue.BacklogReporter) func() int64 {
+	topics := append([]string{model.OrderTopicNormal}, model.VIPOrderTopics()...)
+	return func() int64 {
+		var backlog int64
+		for _, topic := range topics {
+			backlog += int64(reporter.Backlog(topic))
+		}
+		return backlog
+	}
+}
+
+// AutoDegrader periodically evaluates the running activities against their degrade policy
+type AutoDegrader struct {
+	controller   *degrade.AutoController
+	activityRepo repository.ActivityRepository
+}
+
+// NewAutoDegrader creates an auto degrader
+func NewAutoDegrader(controller *degrade.AutoController, activityRepo repository.ActivityRepository) *AutoDegrader {
+	return &AutoDegrader{
+		controller:   controller,
+		activityRepo: activityRepo,
+	}
+}
+
+// Run evaluates running activities every interval until ctx is done
+func (d *AutoDegrader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.WithFields(map[string]interface{}{
+		"interval": interval,
+	}).Info("Auto degrader started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Auto degrader stopped")
+			return
+		case <-ticker.C:
+			d.evaluate(ctx)
+		}
+	}
+}
+
+// evaluate runs one evaluation round
+func (d *AutoDegrader) evaluate(ctx context.Context) {
+	activities, err := d.activityRepo.ListByStatus(ctx, model.ActivityStatusRunning, 100)
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+		}).Warn("Failed to list running activities for auto degrade")
+		return
+	}
+
+	running := make(map[uint64]bool, len(activities))
+	for _, activity := range activities {
+		running[activity.ID] = true
+
+		policy := AutoDegradePolicy(activity)
+		if policy == nil {
+			continue
+		}
+
+		transition, err := d.controller.Evaluate(ctx, activity.ID, policy)
+		if err != nil {
+			log.WithFields(map[string]interface{}{
+				"activity_id": activity.ID,
+				"error":       err.Error(),
+			}).Error("Failed to evaluate auto degrade")
+			continue
+		}
+		if transition != nil {
+			log.WithFields(map[string]interface{}{
+				"activity_id": transition.ActivityID,
+				"from":        transition.From,
+				"to":          transition.To,
+				"reason":      transition.Reason,
+				"strategy":    transition.Strategy,
+				"error_rate":  transition.Signals.ErrorRate,
+				"p99_ms":      transition.Signals.P99Latency.Milliseconds(),
+				"backlog":     transition.Signals.Backlog,
+			}).Warn("Auto degrade transition")
+		}
+	}
+
+	// Ended activities no longer need local state, their degrade keys expire with the TTL
+	for _, state := range d.controller.States() {
+		if !running[state.ActivityID] {
+			d.controller.Forget(state.ActivityID)
+		}
+	}
+}
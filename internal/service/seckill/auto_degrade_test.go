@@ -0,0 +1,113 @@
+package seckill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/degrade"
+	"seckill/pkg/queue"
+)
+
+func TestAutoDegradePolicy(t *testing.T) {
+	t.Run("disabled without threshold", func(t *testing.T) {
+		assert.Nil(t, AutoDegradePolicy(&model.SeckillActivity{ID: 1}))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		policy := AutoDegradePolicy(&model.SeckillActivity{ID: 1, DegradeThreshold: 0.3, DegradeStrategy: "queue"})
+		require.NotNil(t, policy)
+		assert.Equal(t, 0.3, policy.ErrorRate)
+		assert.Equal(t, defaultDegradeP99Latency, policy.P99Latency)
+		assert.Equal(t, "queue_only", policy.Strategy.Type)
+	})
+
+	t.Run("ext config overrides", func(t *testing.T) {
+		policy := AutoDegradePolicy(&model.SeckillActivity{
+			ID:               1,
+			DegradeThreshold: 0.5,
+			DegradeStrategy:  "lottery",
+			ExtConfig: model.JSONObject{"degrade": map[string]interface{}{
+				"p99_latency_ms": float64(200),
+				"max_backlog":    float64(0),
+				"recover_after":  float64(5),
+				"lottery_ratio":  0.2,
+			}},
+		})
+		require.NotNil(t, policy)
+		assert.Equal(t, 200*time.Millisecond, policy.P99Latency)
+		assert.Equal(t, int64(0), policy.Backlog)
+		assert.Equal(t, 5, policy.RecoverAfter)
+		assert.Equal(t, "lottery", policy.Strategy.Type)
+		assert.Equal(t, 0.2, policy.Strategy.Ratio)
+	})
+
+	t.Run("unknown strategy returns error", func(t *testing.T) {
+		policy := AutoDegradePolicy(&model.SeckillActivity{ID: 1, DegradeThreshold: 0.5, DegradeStrategy: "unknown"})
+		assert.Equal(t, "return_error", policy.Strategy.Type)
+	})
+}
+
+func TestOrderBacklog(t *testing.T) {
+	q, err := queue.NewMemoryQueue(nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, q.Publish(ctx, model.OrderTopicNormal, []byte("a")))
+	require.NoError(t, q.Publish(ctx, model.OrderTopicNormal, []byte("b")))
+	require.NoError(t, q.Publish(ctx, model.OrderTopicVIPGold, []byte("c")))
+	require.NoError(t, q.Publish(ctx, "other", []byte("d")))
+
+	assert.Equal(t, int64(3), OrderBacklog(q)())
+}
+
+// runningActivityRepo lists a fixed set of running activities
+type runningActivityRepo struct {
+	repository.ActivityRepository
+	activities []*model.SeckillActivity
+}
+
+func (r *runningActivityRepo) ListByStatus(ctx context.Context, status int8, limit int) ([]*model.SeckillActivity, error) {
+	return r.activities, nil
+}
+
+func TestAutoDegrader_Evaluate(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	dm := degrade.NewDegradeManager(client)
+	controller := degrade.NewAutoController(dm, nil)
+	repo := &runningActivityRepo{activities: []*model.SeckillActivity{
+		{ID: 1, DegradeThreshold: 0.5, DegradeStrategy: "lottery"},
+		{ID: 2},
+	}}
+	degrader := NewAutoDegrader(controller, repo)
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		dm.RecordRequest(1, time.Millisecond, true)
+		dm.RecordRequest(2, time.Millisecond, true)
+	}
+	degrader.evaluate(ctx)
+
+	assert.True(t, dm.IsDegrade(ctx, 1))
+	assert.Equal(t, "lottery", dm.GetStrategy(ctx, 1).Type)
+	// No threshold, never degraded automatically
+	assert.False(t, dm.IsDegrade(ctx, 2))
+
+	// Local state is dropped once the activity stops running
+	repo.activities = nil
+	degrader.evaluate(ctx)
+	assert.Empty(t, controller.States())
+}
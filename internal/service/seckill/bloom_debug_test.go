@@ -36,7 +36,7 @@ func TestBloomFilterDebug(t *testing.T) {
 	assert.False(t, exists, "Activity should not exist initially")
 
 	// Test 2: Add to bloom filter
-	inventory.AddToBloomFilter(activityID)
+	err = inventory.AddToBloomFilter(ctx, activityID)
 	require.NoError(t, err)
 	fmt.Printf("2. Added %d to bloom filter\n", activityID)
 
@@ -46,7 +46,7 @@ func TestBloomFilterDebug(t *testing.T) {
 	assert.True(t, exists, "Activity should exist after adding to bloom filter")
 
 	// Test 4: Mark as sold out
-	err = inventory.MarkSoldOut(activityID)
+	err = inventory.MarkSoldOut(ctx, activityID)
 	require.NoError(t, err)
 	fmt.Printf("4. Marked %d as sold out\n", activityID)
 
@@ -56,24 +56,26 @@ func TestBloomFilterDebug(t *testing.T) {
 	assert.False(t, exists, "Activity should return false after being marked sold out")
 
 	// Test 6: Test direct bloom filter operations
-	bloomKey := fmt.Sprintf("goods:{%d}", activityID)
+	bloomKey := bloomElement(activityID)
 	fmt.Printf("6. Testing direct bloom filter with key: %s\n", bloomKey)
 
 	// Add directly to bloom filter
-	inventory.bloomFilter.Add([]byte(bloomKey))
+	require.NoError(t, inventory.bloomFilter.Add(ctx, bloomKey))
 	fmt.Printf("7. Added key directly to bloom filter\n")
 
 	// Test directly
-	bloomExists := inventory.bloomFilter.Test([]byte(bloomKey))
+	bloomExists, err := inventory.bloomFilter.Test(ctx, bloomKey)
+	require.NoError(t, err)
 	fmt.Printf("8. Direct bloom filter test: %v (should be true)\n", bloomExists)
 	assert.True(t, bloomExists, "Direct bloom filter test should return true")
 
 	// Remove directly
-	inventory.bloomFilter.Remove([]byte(bloomKey))
+	require.NoError(t, inventory.bloomFilter.Remove(ctx, bloomKey))
 	fmt.Printf("9. Removed key directly from bloom filter\n")
 
 	// Test after removal
-	bloomExists = inventory.bloomFilter.Test([]byte(bloomKey))
+	bloomExists, err = inventory.bloomFilter.Test(ctx, bloomKey)
+	require.NoError(t, err)
 	fmt.Printf("10. Direct bloom filter test after removal: %v (should be false)\n", bloomExists)
 	assert.False(t, bloomExists, "Direct bloom filter test should return false after removal")
 }
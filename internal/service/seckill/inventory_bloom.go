@@ -0,0 +1,179 @@
+package seckill
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// bloomKeyPrefix Redis prefix of the activity bloom filter counters
+	bloomKeyPrefix = "bloom:activity:counters"
+	// bloomMembersKey set of activities added to the filter, keeps add/remove idempotent
+	// since a counting filter cannot tell a repeated add from two elements
+	bloomMembersKey = "bloom:activity:members"
+	// bloomCacheTTL how long a filter result is cached locally, bounds how stale other instances can be
+	bloomCacheTTL = 2 * time.Second
+)
+
+// cachedBloomResult locally cached bloom filter result
+type cachedBloomResult struct {
+	present  bool
+	expireAt time.Time
+}
+
+// bloomElement the single key scheme of activities in the bloom filter
+func bloomElement(activityID uint64) string {
+	return fmt.Sprintf("activity:%d", activityID)
+}
+
+// soldOutKey local cache key of a sold out activity
+func soldOutKey(activityID uint64) string {
+	return fmt.Sprintf("sold_out:{%d}", activityID)
+}
+
+// LocalCheck local cache quick check (bloom filter)
+func (m *MultiLevelInventory) LocalCheck(ctx context.Context, activityID uint64) bool {
+	// Check local cache for sold out status
+	if _, err := m.localCache.Get(soldOutKey(activityID)); err == nil {
+		return false
+	}
+
+	// Check bloom filter, if not exists then definitely sold out
+	if !m.bloomTest(ctx, activityID) {
+		logrus.WithField("activity_id", activityID).Debug("Activity not found in bloom filter")
+		return false
+	}
+	return true
+}
+
+// bloomTest tests the shared filter through the local cache, Redis errors fail open
+// since the deduction itself is authoritative
+func (m *MultiLevelInventory) bloomTest(ctx context.Context, activityID uint64) bool {
+	if v, ok := m.bloomCache.Load(activityID); ok {
+		cached := v.(cachedBloomResult)
+		if time.Now().Before(cached.expireAt) {
+			return cached.present
+		}
+	}
+
+	present, err := m.bloomFilter.Test(ctx, bloomElement(activityID))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"activity_id": activityID,
+			"error":       err.Error(),
+		}).Warn("Bloom filter check failed")
+		return true
+	}
+
+	m.cacheBloomResult(activityID, present)
+	return present
+}
+
+func (m *MultiLevelInventory) cacheBloomResult(activityID uint64, present bool) {
+	m.bloomCache.Store(activityID, cachedBloomResult{
+		present:  present,
+		expireAt: time.Now().Add(bloomCacheTTL),
+	})
+}
+
+// MarkSoldOut mark as sold out on every instance (update bloom filter)
+func (m *MultiLevelInventory) MarkSoldOut(ctx context.Context, activityID uint64) error {
+	err := m.localCache.Set(soldOutKey(activityID), []byte("1"))
+	if err != nil {
+		return fmt.Errorf("failed to mark activity as sold out in local cache: %w", err)
+	}
+
+	// Remove from bloom filter to prevent unnecessary cache queries
+	return m.removeFromBloomFilter(ctx, activityID)
+}
+
+// AddToBloomFilter add to bloom filter
+func (m *MultiLevelInventory) AddToBloomFilter(ctx context.Context, activityID uint64) error {
+	added, err := m.redisClient.SAdd(ctx, bloomMembersKey, activityID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to add bloom filter member: %w", err)
+	}
+	if added == 1 {
+		if err := m.bloomFilter.Add(ctx, bloomElement(activityID)); err != nil {
+			// Let a retry add it again, an over-counted element only costs a false positive
+			m.redisClient.SRem(ctx, bloomMembersKey, activityID)
+			return fmt.Errorf("failed to add to bloom filter: %w", err)
+		}
+	}
+
+	// Restocked activities are no longer sold out
+	m.localCache.Delete(soldOutKey(activityID))
+	m.cacheBloomResult(activityID, true)
+	if added == 1 {
+		m.publishStockEvent(ctx, StockEventInStock, activityID)
+	}
+	return nil
+}
+
+// removeFromBloomFilter removes an activity, repeated removals are no-ops
+func (m *MultiLevelInventory) removeFromBloomFilter(ctx context.Context, activityID uint64) error {
+	removed, err := m.redisClient.SRem(ctx, bloomMembersKey, activityID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove bloom filter member: %w", err)
+	}
+	if removed == 1 {
+		if err := m.bloomFilter.Remove(ctx, bloomElement(activityID)); err != nil {
+			return fmt.Errorf("failed to remove from bloom filter: %w", err)
+		}
+	}
+
+	m.cacheBloomResult(activityID, false)
+	if removed == 1 {
+		m.publishStockEvent(ctx, StockEventSoldOut, activityID)
+	}
+	return nil
+}
+
+// RebuildBloomFilter reconciles the bloom filter with the activities holding stock in Redis
+// Only missing and stale members are touched, so other instances keep serving while it runs
+func (m *MultiLevelInventory) RebuildBloomFilter(ctx context.Context, activityIDs []uint64) error {
+	active := make(map[uint64]bool, len(activityIDs))
+	for _, activityID := range activityIDs {
+		active[activityID] = true
+		if err := m.AddToBloomFilter(ctx, activityID); err != nil {
+			return err
+		}
+
+		// Counters lost between the member add and the filter update are restored
+		present, err := m.bloomFilter.Test(ctx, bloomElement(activityID))
+		if err != nil {
+			return err
+		}
+		if !present {
+			if err := m.bloomFilter.Add(ctx, bloomElement(activityID)); err != nil {
+				return err
+			}
+		}
+	}
+
+	members, err := m.redisClient.SMembers(ctx, bloomMembersKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list bloom filter members: %w", err)
+	}
+	removed := 0
+	for _, member := range members {
+		activityID, err := strconv.ParseUint(member, 10, 64)
+		if err != nil || active[activityID] {
+			continue
+		}
+		if err := m.removeFromBloomFilter(ctx, activityID); err != nil {
+			return err
+		}
+		removed++
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"active":  len(activityIDs),
+		"removed": removed,
+	}).Info("Bloom filter rebuilt")
+	return nil
+}
@@ -0,0 +1,105 @@
+package seckill
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	redisx "seckill/internal/redis"
+)
+
+// setupBloomInventories two instances sharing one Redis
+func setupBloomInventories(t *testing.T) (*MultiLevelInventory, *MultiLevelInventory, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	redisx.RejectCrossSlot(client)
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	first, err := NewMultiLevelInventory(client)
+	require.NoError(t, err)
+	second, err := NewMultiLevelInventory(client)
+	require.NoError(t, err)
+	return first, second, mr
+}
+
+func TestBloomFilter_SharedAcrossInstances(t *testing.T) {
+	first, second, _ := setupBloomInventories(t)
+	ctx := context.Background()
+
+	assert.False(t, second.LocalCheck(ctx, 7))
+	require.NoError(t, first.AddToBloomFilter(ctx, 7))
+	assert.True(t, first.LocalCheck(ctx, 7))
+
+	// The miss is cached locally until it expires
+	assert.False(t, second.LocalCheck(ctx, 7))
+	second.bloomCache.Delete(uint64(7))
+	assert.True(t, second.LocalCheck(ctx, 7))
+
+	require.NoError(t, first.MarkSoldOut(ctx, 7))
+	assert.False(t, first.LocalCheck(ctx, 7))
+	second.bloomCache.Delete(uint64(7))
+	assert.False(t, second.LocalCheck(ctx, 7))
+}
+
+func TestBloomFilter_PrewarmUsesSameKey(t *testing.T) {
+	inventory, _, _ := setupBloomInventories(t)
+	ctx := context.Background()
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 9, 10, NewShardLayout(1, "")))
+	inventory.bloomCache.Delete(uint64(9))
+	assert.True(t, inventory.LocalCheck(ctx, 9))
+}
+
+func TestBloomFilter_IdempotentAddRemove(t *testing.T) {
+	inventory, _, _ := setupBloomInventories(t)
+	ctx := context.Background()
+
+	// Repeated syncs must not leave extra counts behind after one removal
+	for i := 0; i < 3; i++ {
+		require.NoError(t, inventory.AddToBloomFilter(ctx, 11))
+	}
+	require.NoError(t, inventory.MarkSoldOut(ctx, 11))
+	present, err := inventory.bloomFilter.Test(ctx, bloomElement(11))
+	require.NoError(t, err)
+	assert.False(t, present)
+
+	// Removing twice must not drive counters shared with other activities below their count
+	require.NoError(t, inventory.AddToBloomFilter(ctx, 12))
+	require.NoError(t, inventory.MarkSoldOut(ctx, 11))
+	present, err = inventory.bloomFilter.Test(ctx, bloomElement(12))
+	require.NoError(t, err)
+	assert.True(t, present)
+
+	// Restocking clears the local sold out mark
+	require.NoError(t, inventory.AddToBloomFilter(ctx, 11))
+	assert.True(t, inventory.LocalCheck(ctx, 11))
+}
+
+func TestBloomFilter_Rebuild(t *testing.T) {
+	inventory, _, _ := setupBloomInventories(t)
+	ctx := context.Background()
+
+	require.NoError(t, inventory.AddToBloomFilter(ctx, 1))
+	require.NoError(t, inventory.AddToBloomFilter(ctx, 2))
+	// Counters lost while the member was recorded
+	require.NoError(t, inventory.bloomFilter.Remove(ctx, bloomElement(2)))
+
+	require.NoError(t, inventory.RebuildBloomFilter(ctx, []uint64{2, 3}))
+
+	members, err := inventory.redisClient.SMembers(ctx, bloomMembersKey).Result()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"2", "3"}, members)
+	for id, want := range map[uint64]bool{1: false, 2: true, 3: true} {
+		present, err := inventory.bloomFilter.Test(ctx, bloomElement(id))
+		require.NoError(t, err)
+		assert.Equal(t, want, present, "activity %d", id)
+	}
+}
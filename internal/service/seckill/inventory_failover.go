@@ -0,0 +1,124 @@
+package seckill
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"seckill/pkg/log"
+)
+
+// sentinelSwitchChannel Sentinel publishes "<master> <old ip> <old port> <new ip> <new port>" here
+const sentinelSwitchChannel = "+switch-master"
+
+// FailoverCheck runs once local state is reset after a failover, e.g. a stock consistency check
+type FailoverCheck func(ctx context.Context) error
+
+// FailoverMonitor watches Sentinel for primary switches. Writes acknowledged by the old
+// primary but not yet replicated are lost on failover, so every switch drops this
+// instance's view of Redis and triggers a consistency check against MySQL.
+type FailoverMonitor struct {
+	inventory  *MultiLevelInventory
+	sentinels  []*redis.SentinelClient
+	masterName string
+	check      FailoverCheck
+	failovers  atomic.Int64
+}
+
+// NewFailoverMonitor creates a failover monitor for the named master
+func NewFailoverMonitor(inventory *MultiLevelInventory, sentinels []*redis.SentinelClient, masterName string, check FailoverCheck) *FailoverMonitor {
+	return &FailoverMonitor{
+		inventory:  inventory,
+		sentinels:  sentinels,
+		masterName: masterName,
+		check:      check,
+	}
+}
+
+// Failovers number of failovers handled since start
+func (f *FailoverMonitor) Failovers() int64 {
+	return f.failovers.Load()
+}
+
+// Run listens for switches until ctx is done, moving on to the next sentinel when one goes away
+func (f *FailoverMonitor) Run(ctx context.Context) {
+	if len(f.sentinels) == 0 {
+		return
+	}
+
+	log.WithFields(map[string]interface{}{
+		"master_name": f.masterName,
+		"sentinels":   len(f.sentinels),
+	}).Info("Redis failover monitor started")
+
+	for i := 0; ; i = (i + 1) % len(f.sentinels) {
+		f.watch(ctx, f.sentinels[i])
+		select {
+		case <-ctx.Done():
+			log.Info("Redis failover monitor stopped")
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// watch handles switch messages from one sentinel until its connection fails
+func (f *FailoverMonitor) watch(ctx context.Context, sentinel *redis.SentinelClient) {
+	pubsub := sentinel.Subscribe(ctx, sentinelSwitchChannel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+				}).Warn("Sentinel subscription interrupted")
+			}
+			return
+		}
+
+		fields := strings.Fields(msg.Payload)
+		if len(fields) < 5 || fields[0] != f.masterName {
+			continue
+		}
+		f.handleFailover(ctx, fields[3]+":"+fields[4])
+	}
+}
+
+// handleFailover resets local state and runs the consistency check
+func (f *FailoverMonitor) handleFailover(ctx context.Context, newMaster string) {
+	f.failovers.Add(1)
+	log.WithFields(map[string]interface{}{
+		"master_name": f.masterName,
+		"new_master":  newMaster,
+	}).Warn("Redis primary failover detected, checking stock consistency")
+
+	f.inventory.resetAfterFailover(ctx)
+	if f.check == nil {
+		return
+	}
+	if err := f.check(ctx); err != nil {
+		log.WithFields(map[string]interface{}{
+			"master_name": f.masterName,
+			"error":       err.Error(),
+		}).Error("Stock consistency check after failover failed")
+	}
+}
+
+// resetAfterFailover forgets everything this instance learned from the old primary:
+// cached shard layouts, local lease balances and the L1 sold out view
+func (m *MultiLevelInventory) resetAfterFailover(ctx context.Context) {
+	m.shardLayouts.Range(func(key, _ interface{}) bool {
+		m.shardLayouts.Delete(key)
+		return true
+	})
+	m.leases.Range(func(key, _ interface{}) bool {
+		m.dropLeased(key.(uint64))
+		return true
+	})
+	m.resyncLocalCache(ctx)
+}
@@ -0,0 +1,45 @@
+package seckill
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverMonitor_ResyncsAndChecks(t *testing.T) {
+	inventory, _, mr := setupBloomInventories(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The sentinel is played by the same miniredis
+	sentinel := redis.NewSentinelClient(&redis.Options{Addr: mr.Addr()})
+	defer sentinel.Close()
+
+	var checks atomic.Int32
+	monitor := NewFailoverMonitor(inventory, []*redis.SentinelClient{sentinel}, "mymaster", func(ctx context.Context) error {
+		checks.Add(1)
+		return nil
+	})
+	go monitor.Run(ctx)
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub(sentinelSwitchChannel)[sentinelSwitchChannel] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// A sold out view learned from the old primary that the new one does not share
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 9, 3, NewShardLayout(1, "")))
+	inventory.applyStockEvent(&StockEvent{Type: StockEventSoldOut, ActivityID: 9})
+	require.False(t, inventory.LocalCheck(ctx, 9))
+
+	// Switches of other masters are ignored
+	mr.Publish(sentinelSwitchChannel, "othermaster 10.0.0.1 6379 10.0.0.2 6379")
+	mr.Publish(sentinelSwitchChannel, "mymaster 10.0.0.1 6379 10.0.0.2 6379")
+
+	require.Eventually(t, func() bool { return checks.Load() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), monitor.Failovers())
+	assert.True(t, inventory.LocalCheck(ctx, 9))
+}
@@ -0,0 +1,260 @@
+package seckill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/breaker"
+	"seckill/pkg/log"
+)
+
+// ErrNoFallbackInventory a MySQL deduction is confirmed or cancelled without a MySQL inventory
+var ErrNoFallbackInventory = errors.New("no fallback inventory configured")
+
+// ErrDeductRecordNotFound a deduction to confirm has no record, the sale is not settled
+// until compensation confirms it with the order's quantity
+var ErrDeductRecordNotFound = errors.New("deduct record not found")
+
+// Inventory TCC stock deduction, implemented on Redis by MultiLevelInventory and on MySQL by MySQLInventory
+type Inventory interface {
+	// TryDeductWithLimit Try phase: reserves stock within the user's purchase limit
+	TryDeductWithLimit(ctx context.Context, req *DeductRequest, limitPerUser int) (*DeductResult, error)
+
+	// ConfirmDeduct Confirm phase: the reserved stock is sold
+	ConfirmDeduct(ctx context.Context, deductID string, activityID uint64) error
+
+	// CancelDeduct Cancel phase: the reserved stock is returned
+	CancelDeduct(ctx context.Context, deductID string, activityID uint64) error
+}
+
+// settlingInventory an Inventory reporting how confirm and cancel were resolved, in the
+// terms of the Redis scripts: success, already_confirmed, already_cancelled, deduct_record_not_found
+type settlingInventory interface {
+	confirmDeduct(ctx context.Context, deductID string, activityID uint64) (string, error)
+	cancelDeduct(ctx context.Context, deductID string, activityID uint64) (string, error)
+}
+
+// InventoryFailover deducts from Redis and switches to MySQL while the Redis breaker is open.
+// Confirm and cancel go to the store that made the deduction, whichever is active now.
+type InventoryFailover struct {
+	redis    *MultiLevelInventory
+	mysql    Inventory
+	breakers *breaker.Manager
+	onMySQL  atomic.Bool
+
+	// Persistent TCC log, nil when disabled
+	logs repository.TCCLogRepository
+}
+
+// NewInventoryFailover creates a failover controller, a nil mysql inventory disables the fallback
+func NewInventoryFailover(redis *MultiLevelInventory, mysql Inventory, breakers *breaker.Manager) *InventoryFailover {
+	return &InventoryFailover{
+		redis:    redis,
+		mysql:    mysql,
+		breakers: breakers,
+	}
+}
+
+// EnableTCCLog records every successful try and its settlement, so tries stranded
+// by a lost order message can be recovered after their Redis record expires
+func (f *InventoryFailover) EnableTCCLog(logs repository.TCCLogRepository) {
+	f.logs = logs
+}
+
+// Available whether a deduction can be attempted at all
+func (f *InventoryFailover) Available() bool {
+	return f.mysql != nil || f.breakers.State(BreakerRedis) != breaker.StateOpen
+}
+
+// TryDeductWithLimit Try phase on Redis, on MySQL when the Redis breaker rejects the call
+func (f *InventoryFailover) TryDeductWithLimit(ctx context.Context, req *DeductRequest, limitPerUser int) (*DeductResult, error) {
+	var result *DeductResult
+	err := f.breakers.Execute(ctx, BreakerRedis, func() error {
+		var deductErr error
+		result, deductErr = f.redis.TryDeductWithLimit(ctx, req, limitPerUser)
+		return deductErr
+	})
+	if f.mysql == nil || !breaker.IsCircuitBreakerError(err) {
+		if err == nil {
+			f.switchTo(false)
+			f.logTry(ctx, req, result)
+		}
+		return result, err
+	}
+
+	f.switchTo(true)
+	err = f.breakers.Execute(ctx, BreakerMySQL, func() error {
+		var deductErr error
+		result, deductErr = f.mysql.TryDeductWithLimit(ctx, req, limitPerUser)
+		return deductErr
+	})
+	if err == nil {
+		f.logTry(ctx, req, result)
+	}
+	return result, err
+}
+
+// ConfirmDeduct Confirm phase on the store that made the deduction
+func (f *InventoryFailover) ConfirmDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	result, err := f.confirm(ctx, deductID, activityID)
+	if err == nil && result == "deduct_record_not_found" {
+		err = ErrDeductRecordNotFound
+	}
+	f.logOutcome(ctx, deductID, model.TCCStatusConfirmed, result, err)
+	return err
+}
+
+// CancelDeduct Cancel phase on the store that made the deduction
+func (f *InventoryFailover) CancelDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	result, err := f.cancel(ctx, deductID, activityID)
+	f.logOutcome(ctx, deductID, model.TCCStatusCancelled, result, err)
+	return err
+}
+
+// Recover cancels a try stranded without an order, returning the cancel result.
+// A Redis try is cancelled with the logged quantity even after its record has expired.
+func (f *InventoryFailover) Recover(ctx context.Context, tccLog *model.TCCLog) (string, error) {
+	var result string
+	var err error
+	if isMySQLDeduct(tccLog.DeductID) {
+		result, err = f.cancel(ctx, tccLog.DeductID, tccLog.ActivityID)
+	} else {
+		result, err = f.redis.RecoverDeduct(ctx, tccLog.DeductID, tccLog.ActivityID, tccLog.Quantity)
+	}
+	if err != nil || result != "success" {
+		f.logOutcome(ctx, tccLog.DeductID, model.TCCStatusCancelled, result, err)
+		return result, err
+	}
+
+	if f.logs != nil {
+		if _, logErr := f.logs.MarkRecovered(ctx, tccLog.DeductID, result); logErr != nil {
+			log.WithFields(map[string]interface{}{
+				"deduct_id": tccLog.DeductID,
+				"error":     logErr.Error(),
+			}).Warn("Failed to record TCC recovery")
+		}
+	}
+	return result, nil
+}
+
+// Compensate retries a confirm or cancel that failed, returning its result. Repeating it is
+// safe, the deduction record's status decides whether stock moves. A Redis deduction whose
+// record has expired is settled with the given quantity.
+func (f *InventoryFailover) Compensate(ctx context.Context, operation, deductID string, activityID uint64, quantity int) (string, error) {
+	var result string
+	var err error
+	switch {
+	case operation != model.TCCOperationConfirm && operation != model.TCCOperationCancel:
+		return "", fmt.Errorf("unknown TCC operation %q", operation)
+	case isMySQLDeduct(deductID) && operation == model.TCCOperationConfirm:
+		result, err = f.confirm(ctx, deductID, activityID)
+	case isMySQLDeduct(deductID):
+		result, err = f.cancel(ctx, deductID, activityID)
+	case operation == model.TCCOperationConfirm:
+		result, err = f.redis.confirmDeduct(ctx, deductID, activityID, quantity)
+	default:
+		result, err = f.redis.cancelDeduct(ctx, deductID, activityID, quantity)
+	}
+
+	status := int8(model.TCCStatusConfirmed)
+	if operation == model.TCCOperationCancel {
+		status = model.TCCStatusCancelled
+	}
+	f.logOutcome(ctx, deductID, status, result, err)
+	return result, err
+}
+
+// confirm routes a confirm to the store that made the deduction
+func (f *InventoryFailover) confirm(ctx context.Context, deductID string, activityID uint64) (string, error) {
+	if !isMySQLDeduct(deductID) {
+		return f.redis.confirmDeduct(ctx, deductID, activityID, 0)
+	}
+	if f.mysql == nil {
+		return "", ErrNoFallbackInventory
+	}
+	if settling, ok := f.mysql.(settlingInventory); ok {
+		return settling.confirmDeduct(ctx, deductID, activityID)
+	}
+	return "success", f.mysql.ConfirmDeduct(ctx, deductID, activityID)
+}
+
+// cancel routes a cancel to the store that made the deduction
+func (f *InventoryFailover) cancel(ctx context.Context, deductID string, activityID uint64) (string, error) {
+	if !isMySQLDeduct(deductID) {
+		return f.redis.cancelDeduct(ctx, deductID, activityID, 0)
+	}
+	if f.mysql == nil {
+		return "", ErrNoFallbackInventory
+	}
+	if settling, ok := f.mysql.(settlingInventory); ok {
+		return settling.cancelDeduct(ctx, deductID, activityID)
+	}
+	return "success", f.mysql.CancelDeduct(ctx, deductID, activityID)
+}
+
+// logTry records a successful try in the TCC log
+func (f *InventoryFailover) logTry(ctx context.Context, req *DeductRequest, result *DeductResult) {
+	if f.logs == nil || result == nil || !result.Success {
+		return
+	}
+	// Only successful tries are written, so the log grows with units sold rather than requests
+	err := f.logs.Create(ctx, &model.TCCLog{
+		DeductID:   result.DeductID,
+		RequestID:  req.RequestID,
+		ActivityID: req.ActivityID,
+		UserID:     req.UserID,
+		Quantity:   req.Quantity,
+		Status:     model.TCCStatusTry,
+	})
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"deduct_id": result.DeductID,
+			"error":     err.Error(),
+		}).Warn("Failed to write TCC log, try cannot be recovered if stranded")
+	}
+}
+
+// logOutcome records how a confirm or cancel was resolved in the TCC log
+func (f *InventoryFailover) logOutcome(ctx context.Context, deductID string, status int8, result string, err error) {
+	if f.logs == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		status, result = model.TCCStatusTry, "error: "+err.Error()
+	case result == "already_confirmed":
+		status = model.TCCStatusConfirmed
+	case result == "already_cancelled":
+		status = model.TCCStatusCancelled
+	case result != "success":
+		status = model.TCCStatusTry
+	}
+	if len(result) > 255 {
+		result = result[:255]
+	}
+	if logErr := f.logs.RecordOutcome(ctx, deductID, status, result); logErr != nil {
+		log.WithFields(map[string]interface{}{
+			"deduct_id": deductID,
+			"result":    result,
+			"error":     logErr.Error(),
+		}).Warn("Failed to record TCC outcome")
+	}
+}
+
+// switchTo logs switches between the Redis and MySQL paths
+func (f *InventoryFailover) switchTo(mysql bool) {
+	if f.onMySQL.Swap(mysql) == mysql {
+		return
+	}
+	if mysql {
+		log.WithFields(map[string]interface{}{
+			"breaker": BreakerRedis,
+		}).Warn("Redis breaker open, deducting stock from MySQL")
+	} else {
+		log.Info("Redis recovered, deducting stock from Redis again")
+	}
+}
@@ -0,0 +1,201 @@
+package seckill
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/breaker"
+)
+
+// fakeInventory records the deductions routed to it
+type fakeInventory struct {
+	mu        sync.Mutex
+	tries     int
+	confirmed []string
+	cancelled []string
+}
+
+func (f *fakeInventory) TryDeductWithLimit(ctx context.Context, req *DeductRequest, limitPerUser int) (*DeductResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tries++
+	return &DeductResult{Success: true, DeductID: mysqlDeductPrefix + "deduct:" + req.RequestID, Message: "success"}, nil
+}
+
+func (f *fakeInventory) ConfirmDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.confirmed = append(f.confirmed, deductID)
+	return nil
+}
+
+func (f *fakeInventory) CancelDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancelled = append(f.cancelled, deductID)
+	return nil
+}
+
+// fakePurchaseRepository fails Try with err, blocking until release is closed when set
+type fakePurchaseRepository struct {
+	err     error
+	release chan struct{}
+}
+
+func (f *fakePurchaseRepository) Try(ctx context.Context, purchase *model.Purchase, limitPerUser int) (int, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	return 0, f.err
+}
+
+func (f *fakePurchaseRepository) Confirm(ctx context.Context, deductID string) (bool, error) {
+	return true, nil
+}
+
+func (f *fakePurchaseRepository) Cancel(ctx context.Context, deductID string) (*model.Purchase, int, error) {
+	return nil, 0, nil
+}
+
+func (f *fakePurchaseRepository) GetByDeductID(ctx context.Context, deductID string) (*model.Purchase, error) {
+	return &model.Purchase{DeductID: deductID, Status: model.PurchaseStatusCancelled}, nil
+}
+
+func setupInventoryFailover(t *testing.T, mysql Inventory) (*InventoryFailover, *MultiLevelInventory, *breaker.Manager) {
+	inventory, _, _ := setupBloomInventories(t)
+	breakers := breaker.NewManager(breaker.Config{
+		MaxRequests: 1,
+		Timeout:     time.Minute,
+		ReadyToTrip: func(counts breaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+	})
+	return NewInventoryFailover(inventory, mysql, breakers), inventory, breakers
+}
+
+// tripRedisBreaker opens the Redis breaker
+func tripRedisBreaker(t *testing.T, breakers *breaker.Manager) {
+	_ = breakers.Execute(context.Background(), BreakerRedis, func() error { return errors.New("redis down") })
+	require.Equal(t, breaker.StateOpen, breakers.State(BreakerRedis))
+}
+
+func TestInventoryFailover_SwitchesToMySQL(t *testing.T) {
+	mysql := &fakeInventory{}
+	failover, inventory, breakers := setupInventoryFailover(t, mysql)
+	ctx := context.Background()
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+
+	req := &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 1}
+	result, err := failover.TryDeductWithLimit(ctx, req, 5)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.False(t, isMySQLDeduct(result.DeductID))
+	assert.Equal(t, 0, mysql.tries)
+
+	tripRedisBreaker(t, breakers)
+	assert.True(t, failover.Available())
+
+	req.RequestID = "r2"
+	result, err = failover.TryDeductWithLimit(ctx, req, 5)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.True(t, isMySQLDeduct(result.DeductID))
+	assert.Equal(t, 1, mysql.tries)
+}
+
+func TestInventoryFailover_RoutesByDeductID(t *testing.T) {
+	mysql := &fakeInventory{}
+	failover, inventory, breakers := setupInventoryFailover(t, mysql)
+	ctx := context.Background()
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+
+	redisResult, err := failover.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 1}, 5)
+	require.NoError(t, err)
+	require.True(t, redisResult.Success)
+
+	// Settling goes to the store that made the deduction, not the active one
+	tripRedisBreaker(t, breakers)
+	require.NoError(t, failover.ConfirmDeduct(ctx, redisResult.DeductID, 1))
+	require.NoError(t, failover.CancelDeduct(ctx, "mysql:deduct:r2:1", 1))
+	require.NoError(t, failover.ConfirmDeduct(ctx, "mysql:deduct:r3:1", 1))
+
+	assert.Equal(t, []string{"mysql:deduct:r3:1"}, mysql.confirmed)
+	assert.Equal(t, []string{"mysql:deduct:r2:1"}, mysql.cancelled)
+}
+
+func TestInventoryFailover_WithoutFallback(t *testing.T) {
+	failover, _, breakers := setupInventoryFailover(t, nil)
+	ctx := context.Background()
+
+	tripRedisBreaker(t, breakers)
+	assert.False(t, failover.Available())
+
+	_, err := failover.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 1}, 5)
+	assert.True(t, breaker.IsCircuitBreakerError(err))
+	assert.ErrorIs(t, failover.ConfirmDeduct(ctx, "mysql:deduct:r1:1", 1), ErrNoFallbackInventory)
+}
+
+func TestMySQLInventory_MapsRepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	req := &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 1}
+
+	cases := map[error]string{
+		nil:                                 "success",
+		repository.ErrInsufficientStock:     "insufficient_stock",
+		repository.ErrPurchaseLimitExceeded: "purchase_limit_exceeded",
+	}
+	for repoErr, message := range cases {
+		inventory := NewMySQLInventory(&fakePurchaseRepository{err: repoErr}, 100, 10)
+		result, err := inventory.TryDeductWithLimit(ctx, req, 1)
+		require.NoError(t, err)
+		assert.Equal(t, message, result.Message)
+		assert.Equal(t, repoErr == nil, result.Success)
+		assert.True(t, strings.HasPrefix(result.DeductID, "mysql:deduct:r1:"))
+	}
+
+	inventory := NewMySQLInventory(&fakePurchaseRepository{err: errors.New("connection refused")}, 100, 10)
+	_, err := inventory.TryDeductWithLimit(ctx, req, 1)
+	assert.Error(t, err)
+}
+
+func TestMySQLInventory_RateLimited(t *testing.T) {
+	ctx := context.Background()
+	req := &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 1}
+
+	// The burst is spent, the next request is turned away
+	inventory := NewMySQLInventory(&fakePurchaseRepository{}, 2, 10)
+	for i := 0; i < 2; i++ {
+		result, err := inventory.TryDeductWithLimit(ctx, req, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Success)
+	}
+	result, err := inventory.TryDeductWithLimit(ctx, req, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "system_busy", result.Message)
+
+	// Every slot is held by a pending deduction
+	repo := &fakePurchaseRepository{release: make(chan struct{})}
+	inventory = NewMySQLInventory(repo, 100, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = inventory.TryDeductWithLimit(ctx, req, 1)
+	}()
+	require.Eventually(t, func() bool { return len(inventory.slots) == 1 }, time.Second, 5*time.Millisecond)
+
+	result, err = inventory.TryDeductWithLimit(ctx, req, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "system_busy", result.Message)
+
+	close(repo.release)
+	<-done
+}
@@ -0,0 +1,482 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	redisx "seckill/internal/redis"
+)
+
+// LeaseConfig stock lease mode: each instance leases a batch of stock and turns requests
+// beyond it away from memory. Per request Redis runs one script, which checks the purchase
+// limit, moves the units from the lease to reserved stock and writes the deduct record, so a
+// deduction reported successful is always settleable. Only the deduct log entries are
+// written in batches every FlushInterval. Only unsharded stock is leased, sharding already
+// spreads the hot key.
+//
+// Retried requests are answered from the local cache, a retry reaching another instance
+// is only held back by the purchase limit. Deduct log entries still queued when an instance
+// dies are lost with it, reconciliation reports the orders missing from the log.
+type LeaseConfig struct {
+	Batch         int           // Units leased at a time
+	TTL           time.Duration // Unused units go back to Redis stock after this long
+	FlushInterval time.Duration // Queued deduct log entries are written at least this often
+}
+
+// stockLease local view of this instance's lease, Redis stays authoritative
+type stockLease struct {
+	mu        sync.Mutex
+	remaining int
+	expireAt  time.Time
+	pending   []leasedDeduct // Reserved, deduct log entry not yet written
+}
+
+// leasedDeduct a reservation made from the lease
+type leasedDeduct struct {
+	deductID string
+	quantity int
+	takenAt  int64 // Unix seconds, as Redis TIME in the deduct log
+}
+
+// leaseFlushChunk deduct log entries written per script call
+const leaseFlushChunk = 200
+
+// stockLeaseKey units leased per instance (instance -> units)
+func stockLeaseKey(activityID uint64) string {
+	return fmt.Sprintf("stock:lease:{%d}", activityID)
+}
+
+// stockLeaseDeadlineKey lease deadlines (instance -> unix ms), expired leases are reclaimed
+func stockLeaseDeadlineKey(activityID uint64) string {
+	return fmt.Sprintf("stock:lease_deadline:{%d}", activityID)
+}
+
+// EnableStockLease switches unsharded activities to stock lease mode
+func (m *MultiLevelInventory) EnableStockLease(cfg LeaseConfig) {
+	if cfg.Batch <= 0 {
+		cfg.Batch = 50
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Second
+	}
+	if cfg.FlushInterval <= 0 || cfg.FlushInterval > cfg.TTL/4 {
+		cfg.FlushInterval = min(100*time.Millisecond, cfg.TTL/4)
+	}
+	m.leaseConfig = &cfg
+}
+
+// leaseAcquireScript reclaims expired leases, then tops this instance's lease up to
+// the wanted units and renews its deadline
+var leaseAcquireScript = redisx.Scripts.Register("inventory:lease_acquire", `
+	local stock_key = KEYS[1]
+	local lease_key = KEYS[2]
+	local deadline_key = KEYS[3]
+	local instance = ARGV[1]
+	local want = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local ttl = tonumber(ARGV[4])
+
+	-- Reclaim leases of instances that stopped renewing them
+	local expired = redis.call('ZRANGEBYSCORE', deadline_key, '-inf', now)
+	for _, holder in ipairs(expired) do
+		local units = tonumber(redis.call('HGET', lease_key, holder) or 0)
+		if units > 0 then
+			redis.call('INCRBY', stock_key, units)
+		end
+		redis.call('HDEL', lease_key, holder)
+		redis.call('ZREM', deadline_key, holder)
+	end
+
+	local held = tonumber(redis.call('HGET', lease_key, instance) or 0)
+	local stock = tonumber(redis.call('GET', stock_key) or 0)
+	local take = math.min(want - held, stock)
+	if take > 0 then
+		redis.call('DECRBY', stock_key, take)
+		held = redis.call('HINCRBY', lease_key, instance, take)
+	end
+
+	if held > 0 then
+		redis.call('ZADD', deadline_key, now + ttl, instance)
+		redis.call('EXPIRE', lease_key, 86400)
+		redis.call('EXPIRE', deadline_key, 86400)
+	else
+		redis.call('HDEL', lease_key, instance)
+		redis.call('ZREM', deadline_key, instance)
+	end
+	return held
+`)
+
+// leaseDeductScript reserves units served from this instance's lease: the purchase limit is
+// checked, the units move from the lease to reserved stock and the deduct record is written.
+// Units the lease no longer holds, because it was reclaimed or resynced, come out of stock
+// when enough is left there, otherwise nothing is reserved.
+var leaseDeductScript = redisx.Scripts.Register("inventory:lease_deduct", `
+	local lease_key = KEYS[1]
+	local stock_key = KEYS[2]
+	local reserve_key = KEYS[3]
+	local deduct_record_key = KEYS[4]
+	local purchase_count_key = KEYS[5]
+	local instance = ARGV[1]
+	local deduct_id = ARGV[2]
+	local quantity = tonumber(ARGV[3])
+	local limit_per_user = tonumber(ARGV[4])
+	local expire_time = tonumber(ARGV[5])
+
+	local bought = tonumber(redis.call('GET', purchase_count_key) or 0)
+	if bought + quantity > limit_per_user then
+		return {0, 'purchase_limit_exceeded'}
+	end
+
+	local held = tonumber(redis.call('HGET', lease_key, instance) or 0)
+	local from_lease = math.min(held, quantity)
+	local shortfall = quantity - from_lease
+	if shortfall > 0 and tonumber(redis.call('GET', stock_key) or 0) < shortfall then
+		return {0, 'insufficient_stock'}
+	end
+
+	if from_lease > 0 then
+		redis.call('HINCRBY', lease_key, instance, -from_lease)
+	end
+	if shortfall > 0 then
+		redis.call('DECRBY', stock_key, shortfall)
+	end
+	redis.call('INCRBY', reserve_key, quantity)
+	redis.call('INCRBY', purchase_count_key, quantity)
+	redis.call('EXPIRE', purchase_count_key, 86400)
+
+	redis.call('SET', deduct_record_key, cjson.encode({
+		deduct_id = deduct_id,
+		quantity = quantity,
+		timestamp = redis.call('TIME')[1],
+		status = 'try'
+	}), 'EX', expire_time)
+	return {1, 'success', shortfall}
+`)
+
+// leaseLogScript writes the deduct log entries of reservations made from the lease. An entry
+// already moved on by confirm or cancel is kept.
+var leaseLogScript = redisx.Scripts.Register("inventory:lease_log", `
+	local deduct_log_key = KEYS[1]
+	local expire_time = tonumber(ARGV[1])
+
+	for i = 2, #ARGV, 3 do
+		redis.call('HSETNX', deduct_log_key, ARGV[i], cjson.encode({
+			deduct_id = ARGV[i],
+			quantity = tonumber(ARGV[i + 1]),
+			timestamp = ARGV[i + 2],
+			status = 'try'
+		}))
+	end
+	redis.call('EXPIRE', deduct_log_key, expire_time)
+	return 1
+`)
+
+// leaseReleaseScript returns this instance's leased units to stock
+var leaseReleaseScript = redisx.Scripts.Register("inventory:lease_release", `
+	local stock_key = KEYS[1]
+	local lease_key = KEYS[2]
+	local deadline_key = KEYS[3]
+	local instance = ARGV[1]
+
+	local held = tonumber(redis.call('HGET', lease_key, instance) or 0)
+	if held > 0 then
+		redis.call('INCRBY', stock_key, held)
+	end
+	redis.call('HDEL', lease_key, instance)
+	redis.call('ZREM', deadline_key, instance)
+	return held
+`)
+
+func (m *MultiLevelInventory) stockLease(activityID uint64) *stockLease {
+	v, _ := m.leases.LoadOrStore(activityID, &stockLease{})
+	return v.(*stockLease)
+}
+
+// takeLeased takes units from the local lease, topping it up from Redis when it runs short
+// or expires. Returns the units left in it.
+func (m *MultiLevelInventory) takeLeased(ctx context.Context, activityID uint64, quantity int) (bool, int, error) {
+	lease := m.stockLease(activityID)
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	now := time.Now()
+	if lease.remaining < quantity || !now.Before(lease.expireAt) {
+		want := m.leaseConfig.Batch
+		if quantity > want {
+			want = quantity
+		}
+		held, err := leaseAcquireScript.Run(ctx, m.redisClient,
+			[]string{stockShardKey(activityID, noShard), stockLeaseKey(activityID), stockLeaseDeadlineKey(activityID)},
+			m.instanceID, want, now.UnixMilli(), m.leaseConfig.TTL.Milliseconds()).Int()
+		if err != nil {
+			return false, 0, err
+		}
+		lease.remaining = held
+		lease.expireAt = now.Add(m.leaseConfig.TTL)
+	}
+
+	if lease.remaining < quantity {
+		return false, lease.remaining, nil
+	}
+	lease.remaining -= quantity
+	return true, lease.remaining, nil
+}
+
+// giveBackLeased returns units taken from the local lease but not reserved
+func (m *MultiLevelInventory) giveBackLeased(activityID uint64, quantity int) {
+	lease := m.stockLease(activityID)
+	lease.mu.Lock()
+	lease.remaining += quantity
+	lease.mu.Unlock()
+}
+
+// queueLeasedLog queues the deduct log entry of a reservation made from the lease
+func (m *MultiLevelInventory) queueLeasedLog(activityID uint64, deductID string, quantity int) {
+	lease := m.stockLease(activityID)
+	lease.mu.Lock()
+	lease.pending = append(lease.pending, leasedDeduct{
+		deductID: deductID,
+		quantity: quantity,
+		takenAt:  time.Now().Unix(),
+	})
+	lease.mu.Unlock()
+}
+
+// dropLeased forgets the local lease, the next request re-reads it from Redis
+func (m *MultiLevelInventory) dropLeased(activityID uint64) {
+	lease := m.stockLease(activityID)
+	lease.mu.Lock()
+	lease.remaining = 0
+	lease.expireAt = time.Time{}
+	lease.mu.Unlock()
+}
+
+// flushLease writes the queued deduct log entries of an activity
+func (m *MultiLevelInventory) flushLease(ctx context.Context, activityID uint64) error {
+	v, ok := m.leases.Load(activityID)
+	if !ok {
+		return nil
+	}
+	lease := v.(*stockLease)
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+	return m.flushLeaseLocked(ctx, activityID, lease)
+}
+
+// flushLeaseLocked writes the queued deduct log entries, the ones not written stay queued
+func (m *MultiLevelInventory) flushLeaseLocked(ctx context.Context, activityID uint64, lease *stockLease) error {
+	for len(lease.pending) > 0 {
+		chunk := lease.pending[:min(len(lease.pending), leaseFlushChunk)]
+
+		args := []interface{}{900}
+		for _, entry := range chunk {
+			args = append(args, entry.deductID, entry.quantity, strconv.FormatInt(entry.takenAt, 10))
+		}
+		if err := leaseLogScript.Run(ctx, m.redisClient, []string{deductLogShardKey(activityID, noShard)}, args...).Err(); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"activity_id": activityID,
+				"pending":     len(lease.pending),
+				"error":       err.Error(),
+			}).Error("Failed to write leased deduct logs")
+			return err
+		}
+		lease.pending = lease.pending[len(chunk):]
+	}
+	lease.pending = nil
+	return nil
+}
+
+// flushLeases writes the queued deduct log entries of every activity
+func (m *MultiLevelInventory) flushLeases(ctx context.Context) error {
+	var firstErr error
+	m.leases.Range(func(key, _ interface{}) bool {
+		if err := m.flushLease(ctx, key.(uint64)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return true
+	})
+	return firstErr
+}
+
+// tryDeductLeased Try phase served from this instance's stock lease: requests the lease
+// cannot cover are turned away locally, the others reserve their units in one script
+func (m *MultiLevelInventory) tryDeductLeased(ctx context.Context, req *DeductRequest, limitPerUser int) (*DeductResult, error) {
+	// Retries are answered locally, the lease keeps no per-request result in Redis to check
+	resultKey := fmt.Sprintf("deduct_result:%s", req.RequestID)
+	if data, err := m.localCache.Get(resultKey); err == nil {
+		var result DeductResult
+		if json.Unmarshal(data, &result) == nil {
+			return &result, nil
+		}
+	}
+
+	deductResult, err := m.deductLeased(ctx, req, limitPerUser)
+	if err != nil {
+		return nil, err
+	}
+	m.logDeduct(ctx, req, deductResult)
+	if data, _ := json.Marshal(deductResult); data != nil {
+		m.localCache.Set(resultKey, data)
+	}
+	if deductResult.Message == "insufficient_stock" {
+		m.markSoldOutIfEmpty(ctx, req.ActivityID)
+	}
+	return deductResult, nil
+}
+
+// deductLeased takes the units from the local lease, then reserves them in Redis within the
+// user's purchase limit. Success is only reported once the deduct record is written.
+func (m *MultiLevelInventory) deductLeased(ctx context.Context, req *DeductRequest, limitPerUser int) (*DeductResult, error) {
+	deductID := fmt.Sprintf("deduct:%s:%d", req.RequestID, time.Now().UnixNano())
+
+	ok, remaining, err := m.takeLeased(ctx, req.ActivityID, req.Quantity)
+	if err != nil {
+		logrus.WithField("error", err.Error()).Error("Redis eval failed")
+		return nil, err
+	}
+	if !ok {
+		return &DeductResult{Success: false, DeductID: deductID, Message: "insufficient_stock"}, nil
+	}
+
+	result, err := leaseDeductScript.Run(ctx, m.redisClient,
+		[]string{
+			stockLeaseKey(req.ActivityID),
+			stockShardKey(req.ActivityID, noShard),
+			reservedShardKey(req.ActivityID, noShard),
+			deductRecordKey(req.ActivityID, noShard, deductID),
+			userPurchaseCountKey(req.ActivityID, req.UserID),
+		},
+		m.instanceID, deductID, req.Quantity, limitPerUser, 900).Result()
+	if err != nil {
+		// Redis decides on the units, a lease counted twice locally is caught by the next script
+		logrus.WithField("error", err.Error()).Error("Redis eval failed")
+		m.giveBackLeased(req.ActivityID, req.Quantity)
+		return nil, err
+	}
+
+	switch scriptMessage(result) {
+	case "success":
+	case "purchase_limit_exceeded":
+		m.giveBackLeased(req.ActivityID, req.Quantity)
+		return &DeductResult{Success: false, DeductID: deductID, Message: "purchase_limit_exceeded"}, nil
+	default:
+		// The lease was reclaimed or resynced and stock cannot cover it, re-lease on the next request
+		m.dropLeased(req.ActivityID)
+		logrus.WithFields(logrus.Fields{
+			"activity_id": req.ActivityID,
+			"deduct_id":   deductID,
+		}).Warn("Stock lease lost and stock short, deduction refused")
+		return &DeductResult{Success: false, DeductID: deductID, Message: "insufficient_stock"}, nil
+	}
+
+	if values, ok := result.([]interface{}); ok && len(values) > 2 {
+		// Part of it came out of stock, the local lease counts units Redis no longer holds
+		if shortfall, _ := values[2].(int64); shortfall > 0 {
+			m.dropLeased(req.ActivityID)
+		}
+	}
+	m.queueLeasedLog(req.ActivityID, deductID, req.Quantity)
+	return &DeductResult{
+		Success:     true,
+		DeductID:    deductID,
+		Message:     "success",
+		RemainStock: remaining,
+	}, nil
+}
+
+// releaseLease returns an activity's unused leased units to Redis stock, with expiredOnly
+// a lease renewed in the meantime is kept
+func (m *MultiLevelInventory) releaseLease(ctx context.Context, activityID uint64, lease *stockLease, expiredOnly bool) error {
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	// Sold units already left the lease, their log entries are written before it goes
+	if err := m.flushLeaseLocked(ctx, activityID, lease); err != nil {
+		return err
+	}
+	if expiredOnly && (lease.expireAt.IsZero() || time.Now().Before(lease.expireAt)) {
+		return nil
+	}
+
+	returned, err := leaseReleaseScript.Run(ctx, m.redisClient,
+		[]string{stockShardKey(activityID, noShard), stockLeaseKey(activityID), stockLeaseDeadlineKey(activityID)},
+		m.instanceID).Int()
+	if err != nil {
+		return err
+	}
+	lease.remaining = 0
+	lease.expireAt = time.Time{}
+
+	if returned > 0 {
+		logrus.WithFields(logrus.Fields{
+			"activity_id": activityID,
+			"returned":    returned,
+		}).Info("Stock lease released")
+	}
+	return nil
+}
+
+// ReleaseLeases returns all unused leased stock, called on shutdown
+func (m *MultiLevelInventory) ReleaseLeases(ctx context.Context) error {
+	var firstErr error
+	m.leases.Range(func(key, value interface{}) bool {
+		if err := m.releaseLease(ctx, key.(uint64), value.(*stockLease), false); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return true
+	})
+	return firstErr
+}
+
+// releaseExpiredLeases writes queued deduct log entries and returns leases not renewed within
+// their TTL, i.e. idle ones
+func (m *MultiLevelInventory) releaseExpiredLeases(ctx context.Context) {
+	m.leases.Range(func(key, value interface{}) bool {
+		if err := m.releaseLease(ctx, key.(uint64), value.(*stockLease), true); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"activity_id": key,
+				"error":       err.Error(),
+			}).Warn("Failed to release stock lease")
+		}
+		return true
+	})
+}
+
+// RunLeaseKeeper writes queued deduct log entries and returns expired leases every flush interval
+// until the context is cancelled
+func (m *MultiLevelInventory) RunLeaseKeeper(ctx context.Context) {
+	if m.leaseConfig == nil {
+		return
+	}
+	ticker := time.NewTicker(m.leaseConfig.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.releaseExpiredLeases(ctx)
+		}
+	}
+}
+
+// leasedStock units currently leased out by all instances
+func (m *MultiLevelInventory) leasedStock(ctx context.Context, activityID uint64) (int, error) {
+	values, err := m.redisClient.HVals(ctx, stockLeaseKey(activityID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, value := range values {
+		var units int
+		fmt.Sscan(value, &units)
+		total += units
+	}
+	return total, nil
+}
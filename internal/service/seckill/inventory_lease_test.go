@@ -0,0 +1,269 @@
+package seckill
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commandCounter counts the commands sent to Redis by name
+type commandCounter struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (c *commandCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (c *commandCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		c.mu.Lock()
+		c.names = append(c.names, cmd.Name())
+		c.mu.Unlock()
+		return next(ctx, cmd)
+	}
+}
+
+func (c *commandCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		c.mu.Lock()
+		for _, cmd := range cmds {
+			c.names = append(c.names, cmd.Name())
+		}
+		c.mu.Unlock()
+		return next(ctx, cmds)
+	}
+}
+
+func (c *commandCounter) reset() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := c.names
+	c.names = nil
+	return names
+}
+
+func setupLeasedInventories(t *testing.T, stock int, cfg LeaseConfig) (*MultiLevelInventory, *MultiLevelInventory) {
+	first, second, _ := setupBloomInventories(t)
+	first.EnableStockLease(cfg)
+	second.EnableStockLease(cfg)
+	require.NoError(t, first.SyncShardsToRedis(context.Background(), 1, stock, NewShardLayout(1, "")))
+	return first, second
+}
+
+func deductLeased(t *testing.T, m *MultiLevelInventory, requestID string, userID uint64, limit int) *DeductResult {
+	result, err := m.TryDeductWithLimit(context.Background(), &DeductRequest{
+		RequestID:  requestID,
+		ActivityID: 1,
+		UserID:     userID,
+		Quantity:   1,
+	}, limit)
+	require.NoError(t, err)
+	return result
+}
+
+func TestStockLease_ServesFromBatch(t *testing.T) {
+	inventory, _ := setupLeasedInventories(t, 100, LeaseConfig{Batch: 10, TTL: time.Minute})
+	ctx := context.Background()
+
+	var deductIDs []string
+	for i := 0; i < 3; i++ {
+		result := deductLeased(t, inventory, fmt.Sprintf("r%d", i), uint64(i), 1)
+		assert.True(t, result.Success)
+		deductIDs = append(deductIDs, result.DeductID)
+	}
+
+	// A retry is answered from the local cache
+	assert.Equal(t, deductIDs[0], deductLeased(t, inventory, "r0", 0, 1).DeductID)
+
+	// Records are written with each deduction, the deduct log with the next flush
+	for _, deductID := range deductIDs {
+		assert.Equal(t, int64(1), inventory.redisClient.Exists(ctx, deductRecordKey(1, noShard, deductID)).Val())
+	}
+	assert.Equal(t, int64(0), inventory.redisClient.HLen(ctx, deductLogShardKey(1, noShard)).Val())
+	require.NoError(t, inventory.flushLeases(ctx))
+	assert.Equal(t, int64(3), inventory.redisClient.HLen(ctx, deductLogShardKey(1, noShard)).Val())
+
+	// One batch leased, the stock key was touched once
+	stock, err := inventory.redisClient.Get(ctx, stockShardKey(1, noShard)).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 90, stock)
+	total, err := inventory.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 97, total)
+	reserved, err := inventory.GetReservedFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reserved)
+}
+
+func TestStockLease_RedisCommandsPerDeduction(t *testing.T) {
+	inventory, _ := setupLeasedInventories(t, 100, LeaseConfig{Batch: 50, TTL: time.Minute})
+	counter := &commandCounter{}
+	inventory.redisClient.(*redis.Client).AddHook(counter)
+
+	// The first request leases the batch, the first flush loads its script
+	assert.True(t, deductLeased(t, inventory, "warmup", 1000, 1).Success)
+	require.NoError(t, inventory.flushLeases(context.Background()))
+	counter.reset()
+
+	// Then each deduction runs one script, the purchase limit check that also reserves its units
+	for i := 0; i < 20; i++ {
+		assert.True(t, deductLeased(t, inventory, fmt.Sprintf("r%d", i), uint64(i), 1).Success)
+	}
+	commands := counter.reset()
+	assert.Len(t, commands, 20)
+	for _, name := range commands {
+		assert.Equal(t, "evalsha", name)
+	}
+
+	// Their deduct log entries are written in one call
+	require.NoError(t, inventory.flushLeases(context.Background()))
+	assert.Equal(t, []string{"evalsha"}, counter.reset())
+}
+
+func TestStockLease_UserLimit(t *testing.T) {
+	inventory, _ := setupLeasedInventories(t, 10, LeaseConfig{Batch: 5, TTL: time.Minute})
+
+	assert.True(t, deductLeased(t, inventory, "r1", 7, 1).Success)
+	result := deductLeased(t, inventory, "r2", 7, 1)
+	assert.False(t, result.Success)
+	assert.Equal(t, "purchase_limit_exceeded", result.Message)
+
+	// The refused unit stays in the lease
+	assert.Equal(t, 4, inventory.stockLease(1).remaining)
+}
+
+func TestStockLease_NeverOversells(t *testing.T) {
+	first, second := setupLeasedInventories(t, 25, LeaseConfig{Batch: 4, TTL: time.Minute})
+
+	var mu sync.Mutex
+	sold := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m := first
+			if i%2 == 1 {
+				m = second
+			}
+			if deductLeased(t, m, fmt.Sprintf("r%d", i), uint64(i), 1).Success {
+				mu.Lock()
+				sold++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 25, sold)
+	total, err := first.GetStockFromRedis(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func TestStockLease_ReleaseAndReclaim(t *testing.T) {
+	first, second := setupLeasedInventories(t, 10, LeaseConfig{Batch: 6, TTL: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	assert.True(t, deductLeased(t, first, "r1", 1, 1).Success)
+
+	// Shutdown returns the unused units
+	require.NoError(t, first.ReleaseLeases(ctx))
+	stock, err := first.redisClient.Get(ctx, stockShardKey(1, noShard)).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 9, stock)
+
+	// A lease its holder stopped renewing is reclaimed by the next acquirer, units already
+	// sold from it are not
+	assert.True(t, deductLeased(t, first, "r2", 2, 1).Success)
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, deductLeased(t, second, "r3", 3, 1).Success)
+	leased, err := second.leasedStock(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 5, leased)
+
+	// The holder re-leases what stock is left
+	assert.True(t, deductLeased(t, first, "r4", 4, 1).Success)
+	total, err := first.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 6, total)
+	reserved, err := first.GetReservedFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, reserved)
+}
+
+func TestStockLease_SyncResetsLeases(t *testing.T) {
+	inventory, _ := setupLeasedInventories(t, 10, LeaseConfig{Batch: 5, TTL: time.Minute})
+	ctx := context.Background()
+
+	assert.True(t, deductLeased(t, inventory, "r1", 1, 1).Success)
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 1, 10, NewShardLayout(1, "")))
+
+	assert.True(t, deductLeased(t, inventory, "r2", 2, 1).Success)
+	total, err := inventory.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 9, total)
+}
+
+func TestStockLease_LostLeaseNeverOversells(t *testing.T) {
+	first, second := setupLeasedInventories(t, 5, LeaseConfig{Batch: 5, TTL: time.Minute})
+	ctx := context.Background()
+
+	// The first instance still counts 4 leased units locally when a resync drops its lease
+	assert.True(t, deductLeased(t, first, "r1", 1, 1).Success)
+	require.NoError(t, second.SyncShardsToRedis(ctx, 1, 1, NewShardLayout(1, "")))
+
+	// Only the unit left in stock is sold, then the lease is re-read and found empty
+	assert.True(t, deductLeased(t, first, "r2", 2, 1).Success)
+	result := deductLeased(t, first, "r3", 3, 1)
+	assert.False(t, result.Success)
+	assert.Equal(t, "insufficient_stock", result.Message)
+	assert.Equal(t, int64(0), first.redisClient.Exists(ctx, deductRecordKey(1, noShard, result.DeductID)).Val())
+
+	stock, err := first.redisClient.Get(ctx, stockShardKey(1, noShard)).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 0, stock)
+	reserved, err := first.GetReservedFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reserved)
+}
+
+func TestStockLease_SettleOnAnotherInstance(t *testing.T) {
+	first, second := setupLeasedInventories(t, 10, LeaseConfig{Batch: 5, TTL: time.Minute})
+	ctx := context.Background()
+
+	// Nothing is left to flush for a deduction to be settled, wherever that happens
+	sold := deductLeased(t, first, "r1", 1, 1)
+	require.NoError(t, second.ConfirmDeduct(ctx, sold.DeductID, 1))
+	refunded := deductLeased(t, first, "r2", 2, 1)
+	require.NoError(t, second.CancelDeduct(ctx, refunded.DeductID, 1))
+
+	// A recovery elsewhere before the deduct log is flushed, the record keeps its outcome
+	recovered := deductLeased(t, first, "r3", 3, 1)
+	message, err := second.RecoverDeduct(ctx, recovered.DeductID, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "success", message)
+	require.NoError(t, first.flushLeases(ctx))
+	record, err := first.redisClient.Get(ctx, deductRecordKey(1, noShard, recovered.DeductID)).Result()
+	require.NoError(t, err)
+	assert.Contains(t, record, "cancelled")
+
+	// Only the confirmed unit is gone
+	total, err := first.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 9, total)
+	reserved, err := first.GetReservedFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, reserved)
+
+	// A deduction without a record is not settled by confirming it
+	assert.ErrorIs(t, second.ConfirmDeduct(ctx, "deduct:lost:1", 1), ErrDeductRecordNotFound)
+}
@@ -8,9 +8,12 @@ import (
 	"time"
 
 	"github.com/allegro/bigcache/v3"
-	"github.com/pmylund/go-bloom"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	"seckill/internal/model"
+	redisx "seckill/internal/redis"
+	"seckill/pkg/bloom"
+	"seckill/pkg/utils"
 )
 
 // MultiLevelInventory multi-level inventory manager
@@ -21,8 +24,25 @@ type MultiLevelInventory struct {
 	// L2: Redis
 	redisClient redis.Cmdable
 
-	// Bloom filter (prevent cache penetration)
-	bloomFilter *bloom.CountingFilter
+	// Bloom filter of activities with stock in Redis, shared by all instances (prevent cache penetration)
+	bloomFilter *bloom.CountingBloomFilter
+	// Local read-through cache of bloom filter results (activityID -> cachedBloomResult)
+	bloomCache sync.Map
+
+	// Stock shard layouts (activityID -> cachedShardLayout)
+	shardLayouts sync.Map
+	// Round-robin shard counters (activityID -> *uint64)
+	rrCounters sync.Map
+
+	// Stock lease mode, nil when disabled
+	leaseConfig *LeaseConfig
+	// Stock leased by this instance (activityID -> *stockLease)
+	leases sync.Map
+	// Identifies this instance's leases in Redis
+	instanceID string
+
+	// Stock log writer, nil when disabled
+	stockLogs *StockLogWriter
 
 	mu sync.RWMutex
 }
@@ -36,12 +56,17 @@ func NewMultiLevelInventory(redisClient redis.Cmdable) (*MultiLevelInventory, er
 	}
 
 	// Initialize bloom filter (estimate 10000 elements, false positive rate 0.01)
-	bloomFilter := bloom.NewCounting(10000, 0.01)
+	bloomFilter := bloom.NewCountingBloomFilter(redisClient, bloom.CountingBloomFilterConfig{
+		KeyPrefix:         bloomKeyPrefix,
+		ExpectedElements:  10000,
+		FalsePositiveRate: 0.01,
+	})
 
 	return &MultiLevelInventory{
 		localCache:  localCache,
 		redisClient: redisClient,
 		bloomFilter: bloomFilter,
+		instanceID:  utils.GenerateRandomString(16),
 	}, nil
 }
 
@@ -61,44 +86,76 @@ type DeductResult struct {
 	RemainStock int    `json:"remain_stock"`
 }
 
-// LocalCheck local cache quick check (bloom filter)
-func (m *MultiLevelInventory) LocalCheck(ctx context.Context, activityID uint64) bool {
-	// Check bloom filter, if not exists then definitely sold out
-	goodsKey := fmt.Sprintf("goods:{%d}", activityID)
-	if !m.bloomFilter.Test([]byte(goodsKey)) {
-		logrus.WithField("activity_id", activityID).Debug("Activity not found in bloom filter")
-		return false
-	}
+// tryDeductScript atomic deduction with purchase limit check
+var tryDeductScript = redisx.Scripts.Register("inventory:try_deduct", `
+	local stock_key = KEYS[1]
+	local reserve_key = KEYS[2]
+	local deduct_log_key = KEYS[3]
+	local purchase_count_key = KEYS[4]
+	local deduct_record_key = KEYS[5]
+	local deduct_id = ARGV[1]
+	local quantity = tonumber(ARGV[2])
+	local expire_time = tonumber(ARGV[3])
+	local limit_per_user = tonumber(ARGV[4])
+
+	if DEBUG then
+		redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: purchase_count_key=" .. purchase_count_key .. ", limit_per_user=" .. limit_per_user)
+	end
 
-	// Check local cache for sold out status
-	soldOutKey := fmt.Sprintf("sold_out:{%d}", activityID)
-	_, err := m.localCache.Get(soldOutKey)
+	-- Atomically increment purchase count and check limit
+	local new_purchase_count = redis.call('INCRBY', purchase_count_key, quantity)
+	redis.call('EXPIRE', purchase_count_key, 86400) -- 24 hours expiration
+	
+	if DEBUG then
+		redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: new_purchase_count=" .. new_purchase_count .. ", limit=" .. limit_per_user)
+	end
+	
+	-- Check if the new count exceeds the limit
+	if new_purchase_count > limit_per_user then
+		-- Rollback the increment
+		redis.call('DECRBY', purchase_count_key, quantity)
+		if DEBUG then
+			redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: Purchase limit exceeded, rolling back")
+		end
+		return {0, 'purchase_limit_exceeded', 0}
+	end
 
-	// If found in sold out cache, return false (sold out)
-	// If not found in sold out cache, return true (potentially available)
-	return err != nil
-}
+	-- Get current stock
+	local current_stock = tonumber(redis.call('GET', stock_key) or 0)
 
-// MarkSoldOut mark as sold out (update bloom filter)
-func (m *MultiLevelInventory) MarkSoldOut(activityID uint64) error {
-	soldOutKey := fmt.Sprintf("sold_out:{%d}", activityID)
-	err := m.localCache.Set(soldOutKey, []byte("1"))
-	if err != nil {
-		return fmt.Errorf("failed to mark activity as sold out in local cache: %w", err)
-	}
+	-- Check stock availability
+	if current_stock < quantity then
+		-- Rollback the purchase count increment
+		redis.call('DECRBY', purchase_count_key, quantity)
+		if DEBUG then
+			redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: Insufficient stock, rolling back")
+		end
+		return {0, 'insufficient_stock', current_stock}
+	end
 
-	// Remove from bloom filter to prevent unnecessary cache queries
-	bloomKey := fmt.Sprintf("goods:{%d}", activityID)
-	m.bloomFilter.Remove([]byte(bloomKey))
+	-- Pre-deduct stock (transfer to reserved stock)
+	redis.call('DECRBY', stock_key, quantity)
+	redis.call('INCRBY', reserve_key, quantity)
 
-	return nil
-}
+	if DEBUG then
+		redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: Success, stock deducted")
+	end
 
-// AddToBloomFilter add to bloom filter
-func (m *MultiLevelInventory) AddToBloomFilter(activityID uint64) {
-	key := []byte(fmt.Sprintf("stock:%d", activityID))
-	m.bloomFilter.Add(key)
-}
+	-- Record deduction log
+	local log_data = cjson.encode({
+		deduct_id = deduct_id,
+		quantity = quantity,
+		timestamp = redis.call('TIME')[1],
+		status = 'try'
+	})
+	redis.call('HSET', deduct_log_key, deduct_id, log_data)
+	redis.call('EXPIRE', deduct_log_key, expire_time)
+
+	-- Set deduction record expiration (15 minutes)
+	redis.call('SETEX', deduct_record_key, expire_time, log_data)
+
+	return {1, 'success', current_stock - quantity}
+`)
 
 // TryDeduct Try phase: pre-deduct stock
 func (m *MultiLevelInventory) TryDeductWithLimit(ctx context.Context, req *DeductRequest, limitPerUser int) (*DeductResult, error) {
@@ -111,6 +168,12 @@ func (m *MultiLevelInventory) TryDeductWithLimit(ctx context.Context, req *Deduc
 		}, nil
 	}
 
+	// Serve from this instance's stock lease, it keeps its own idempotency records
+	layout := m.GetShardLayout(ctx, req.ActivityID)
+	if m.leaseConfig != nil && !layout.Sharded() {
+		return m.tryDeductLeased(ctx, req, limitPerUser)
+	}
+
 	// Generate deduction ID (ensure idempotency)
 	deductID := fmt.Sprintf("deduct:%s:%d", req.RequestID, time.Now().UnixNano())
 
@@ -124,77 +187,33 @@ func (m *MultiLevelInventory) TryDeductWithLimit(ctx context.Context, req *Deduc
 		return &result, nil
 	}
 
+	// Sharded stock spreads the hot key across cluster slots
+	if layout.Sharded() {
+		deductResult, err := m.tryDeductSharded(ctx, req, layout, limitPerUser)
+		if err != nil {
+			return nil, err
+		}
+		m.logDeduct(ctx, req, deductResult)
+		if deductResult.Message == "insufficient_stock" {
+			m.markSoldOutIfEmpty(ctx, req.ActivityID)
+		}
+		if data, _ := json.Marshal(deductResult); data != nil {
+			m.redisClient.SetEx(ctx, existKey, data, 5*time.Minute)
+		}
+		return deductResult, nil
+	}
+
 	// Execute Lua script for atomic deduction with purchase limit check
 	// Use hash tag to ensure all keys are in the same slot for Redis cluster
 	stockKey := fmt.Sprintf("stock:{%d}", req.ActivityID)
 	reserveKey := fmt.Sprintf("stock:reserved:{%d}", req.ActivityID)
 	logKey := fmt.Sprintf("stock:deduct_log:{%d}", req.ActivityID)
-	purchaseCountKey := fmt.Sprintf("purchase_count:{%d}:%d", req.ActivityID, req.UserID)
-
-	script := `
-		local stock_key = KEYS[1]
-		local reserve_key = KEYS[2]
-		local deduct_log_key = KEYS[3]
-		local purchase_count_key = KEYS[4]
-		local deduct_id = ARGV[1]
-		local quantity = tonumber(ARGV[2])
-		local expire_time = tonumber(ARGV[3])
-		local limit_per_user = tonumber(ARGV[4])
-
-		-- Debug: Log the parameters
-		redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: purchase_count_key=" .. purchase_count_key .. ", limit_per_user=" .. limit_per_user)
-
-		-- Atomically increment purchase count and check limit
-		local new_purchase_count = redis.call('INCRBY', purchase_count_key, quantity)
-		redis.call('EXPIRE', purchase_count_key, 86400) -- 24 hours expiration
-		
-		-- Debug: Log the purchase count
-		redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: new_purchase_count=" .. new_purchase_count .. ", limit=" .. limit_per_user)
-		
-		-- Check if the new count exceeds the limit
-		if new_purchase_count > limit_per_user then
-			-- Rollback the increment
-			redis.call('DECRBY', purchase_count_key, quantity)
-			redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: Purchase limit exceeded, rolling back")
-			return {0, 'purchase_limit_exceeded', 0}
-		end
+	purchaseCountKey := userPurchaseCountKey(req.ActivityID, req.UserID)
+	recordKey := deductRecordKey(req.ActivityID, noShard, deductID)
 
-		-- Get current stock
-		local current_stock = tonumber(redis.call('GET', stock_key) or 0)
-
-		-- Check stock availability
-		if current_stock < quantity then
-			-- Rollback the purchase count increment
-			redis.call('DECRBY', purchase_count_key, quantity)
-			redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: Insufficient stock, rolling back")
-			return {0, 'insufficient_stock', current_stock}
-		end
-
-		-- Pre-deduct stock (transfer to reserved stock)
-		redis.call('DECRBY', stock_key, quantity)
-		redis.call('INCRBY', reserve_key, quantity)
-
-		redis.log(redis.LOG_NOTICE, "TryDeductWithLimit: Success, stock deducted")
-
-		-- Record deduction log
-		local log_data = cjson.encode({
-			deduct_id = deduct_id,
-			quantity = quantity,
-			timestamp = redis.call('TIME')[1],
-			status = 'try'
-		})
-		redis.call('HSET', deduct_log_key, deduct_id, log_data)
-		redis.call('EXPIRE', deduct_log_key, expire_time)
-
-		-- Set deduction record expiration (15 minutes)
-		redis.call('SETEX', 'deduct_record:{' .. ARGV[5] .. '}:' .. deduct_id, expire_time, log_data)
-
-		return {1, 'success', current_stock - quantity}
-	`
-
-	result, err := m.redisClient.Eval(ctx, script,
-		[]string{stockKey, reserveKey, logKey, purchaseCountKey},
-		deductID, req.Quantity, 900, limitPerUser, req.ActivityID).Result()
+	result, err := tryDeductScript.Run(ctx, m.redisClient,
+		[]string{stockKey, reserveKey, logKey, purchaseCountKey, recordKey},
+		deductID, req.Quantity, 900, limitPerUser).Result()
 
 	if err != nil {
 		logrus.WithField("error", err.Error()).Error("Redis eval failed")
@@ -213,152 +232,253 @@ func (m *MultiLevelInventory) TryDeductWithLimit(ctx context.Context, req *Deduc
 		Message:     message,
 		RemainStock: remainStock,
 	}
+	m.logDeduct(ctx, req, deductResult)
 
 	// Cache result for idempotency (5 minutes)
 	if data, _ := json.Marshal(deductResult); data != nil {
 		m.redisClient.SetEx(ctx, existKey, data, 5*time.Minute)
 	}
 
+	// Stop every instance from hitting Redis for a sold out activity
+	if message == "insufficient_stock" && remainStock == 0 {
+		m.markSoldOutIfEmpty(ctx, req.ActivityID)
+	}
+
 	return deductResult, nil
 }
 
-// ConfirmDeduct Confirm phase: confirm deduction
-func (m *MultiLevelInventory) ConfirmDeduct(ctx context.Context, deductID string, activityID uint64) error {
-	script := `
-		local deduct_record_key = KEYS[1]
-		local reserve_key = KEYS[2]
-
-		-- Get deduction record
-		local log_data = redis.call('GET', deduct_record_key)
-		if not log_data then
+// confirmDeductScript moves reserved stock to sold
+var confirmDeductScript = redisx.Scripts.Register("inventory:confirm_deduct", `
+	local deduct_record_key = KEYS[1]
+	local reserve_key = KEYS[2]
+
+	-- Get deduction record
+	local log_data = redis.call('GET', deduct_record_key)
+	local recreated = false
+	if not log_data then
+		-- Compensation passes the quantity of an expired record
+		local fallback_quantity = tonumber(ARGV[2])
+		if not fallback_quantity then
 			return {0, 'deduct_record_not_found'}
 		end
-
-		local log = cjson.decode(log_data)
-
-		-- Check status
-		if log.status == 'confirmed' then
-			return {1, 'already_confirmed'}
-		end
-
-		if log.status == 'cancelled' then
-			return {0, 'already_cancelled'}
-		end
-
-		-- Deduct from reserved stock (confirm deduction)
-		local reserve_quantity = tonumber(log.quantity)
-		redis.call('DECRBY', reserve_key, reserve_quantity)
-
-		-- Update status to confirmed
-		log.status = 'confirmed'
-		log.confirm_time = redis.call('TIME')[1]
+		log_data = cjson.encode({deduct_id = ARGV[1], quantity = fallback_quantity, status = 'try'})
+		recreated = true
+	end
+
+	local log = cjson.decode(log_data)
+
+	-- Check status
+	if log.status == 'confirmed' then
+		return {1, 'already_confirmed'}
+	end
+
+	if log.status == 'cancelled' then
+		return {0, 'already_cancelled'}
+	end
+
+	-- Deduct from reserved stock (confirm deduction)
+	local reserve_quantity = tonumber(log.quantity)
+	local reserved = redis.call('DECRBY', reserve_key, reserve_quantity)
+
+	-- Update status to confirmed
+	log.status = 'confirmed'
+	log.confirm_time = redis.call('TIME')[1]
+	if recreated then
+		-- Keeps a repeated compensation from moving the stock twice
+		redis.call('SET', deduct_record_key, cjson.encode(log), 'EX', tonumber(ARGV[3]))
+	else
 		redis.call('SET', deduct_record_key, cjson.encode(log))
+	end
 
-		return {1, 'success'}
-	`
+	return {1, 'success', reserve_quantity, reserved}
+`)
 
-	recordKey := fmt.Sprintf("deduct_record:{%d}:%s", activityID, deductID)
-	reserveKey := fmt.Sprintf("stock:reserved:{%d}", activityID)
+// ConfirmDeduct Confirm phase: confirm deduction
+func (m *MultiLevelInventory) ConfirmDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	result, err := m.confirmDeduct(ctx, deductID, activityID, 0)
+	if err == nil && result == "deduct_record_not_found" {
+		return ErrDeductRecordNotFound
+	}
+	return err
+}
 
-	_, err := m.redisClient.Eval(ctx, script,
+// confirmDeduct Confirm phase, returning the script result. A positive quantity stands in
+// for an expired deduction record.
+func (m *MultiLevelInventory) confirmDeduct(ctx context.Context, deductID string, activityID uint64, quantity int) (string, error) {
+	shard := deductShard(deductID)
+	recordKey := deductRecordKey(activityID, shard, deductID)
+	reserveKey := reservedShardKey(activityID, shard)
+
+	args := []interface{}{deductID}
+	if quantity > 0 {
+		args = append(args, quantity, 900)
+	}
+	result, err := confirmDeductScript.Run(ctx, m.redisClient,
 		[]string{recordKey, reserveKey},
-		deductID).Result()
+		args...).Result()
 
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"deduct_id": deductID,
 			"error":     err.Error(),
 		}).Error("Confirm deduct failed")
-		return err
+		return "", err
+	}
+
+	message := scriptMessage(result)
+	if message == "success" && m.stockLogs != nil {
+		// Confirming moves no available stock, the entry tracks the shard's reserved stock
+		quantity, reserved := scriptCounts(result)
+		m.stockLogs.Record(ctx, deductStockLog(activityID, model.OperationTypeConfirm, -quantity, reserved+quantity, reserved, deductID))
 	}
 
 	logrus.WithField("deduct_id", deductID).Info("Stock deduction confirmed successfully")
-	return nil
+	return message, nil
 }
 
-// CancelDeduct Cancel phase: cancel deduction (rollback)
-func (m *MultiLevelInventory) CancelDeduct(ctx context.Context, deductID string, activityID uint64) error {
-	script := `
-		local stock_key = KEYS[1]
-		local reserve_key = KEYS[2]
-		local deduct_record_key = KEYS[3]
-
-		-- Get deduction record
-		local log_data = redis.call('GET', deduct_record_key)
-		if not log_data then
+// cancelDeductScript returns reserved stock to the shard it was taken from
+var cancelDeductScript = redisx.Scripts.Register("inventory:cancel_deduct", `
+	local stock_key = KEYS[1]
+	local reserve_key = KEYS[2]
+	local deduct_record_key = KEYS[3]
+
+	-- Get deduction record
+	local log_data = redis.call('GET', deduct_record_key)
+	local recreated = false
+	if not log_data then
+		-- Recovery passes the quantity of an expired record from the persistent TCC log
+		local fallback_quantity = tonumber(ARGV[2])
+		if not fallback_quantity then
 			return {0, 'deduct_record_not_found'}
 		end
-
-		local log = cjson.decode(log_data)
-
-		-- Check status
-		if log.status == 'cancelled' then
-			return {1, 'already_cancelled'}
-		end
-
-		if log.status == 'confirmed' then
-			return {0, 'already_confirmed'}
-		end
-
-		-- Rollback stock
-		local quantity = tonumber(log.quantity)
-		redis.call('INCRBY', stock_key, quantity)
-		redis.call('DECRBY', reserve_key, quantity)
-
-		-- Update status to cancelled
-		log.status = 'cancelled'
-		log.cancel_time = redis.call('TIME')[1]
+		log_data = cjson.encode({deduct_id = ARGV[1], quantity = fallback_quantity, status = 'try'})
+		recreated = true
+	end
+
+	local log = cjson.decode(log_data)
+
+	-- Check status
+	if log.status == 'cancelled' then
+		return {1, 'already_cancelled'}
+	end
+
+	if log.status == 'confirmed' then
+		return {0, 'already_confirmed'}
+	end
+
+	-- Rollback stock
+	local quantity = tonumber(log.quantity)
+	local stock = redis.call('INCRBY', stock_key, quantity)
+	redis.call('DECRBY', reserve_key, quantity)
+
+	-- Update status to cancelled
+	log.status = 'cancelled'
+	log.cancel_time = redis.call('TIME')[1]
+	if recreated then
+		-- Keeps a repeated recovery from returning the stock twice
+		redis.call('SET', deduct_record_key, cjson.encode(log), 'EX', tonumber(ARGV[3]))
+	else
 		redis.call('SET', deduct_record_key, cjson.encode(log))
+	end
 
-		return {1, 'success'}
-	`
+	return {1, 'success', quantity, stock}
+`)
 
-	stockKey := fmt.Sprintf("stock:{%d}", activityID)
-	reserveKey := fmt.Sprintf("stock:reserved:{%d}", activityID)
-	recordKey := fmt.Sprintf("deduct_record:{%d}:%s", activityID, deductID)
+// CancelDeduct Cancel phase: cancel deduction (rollback)
+func (m *MultiLevelInventory) CancelDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	_, err := m.cancelDeduct(ctx, deductID, activityID, 0)
+	return err
+}
 
-	_, err := m.redisClient.Eval(ctx, script,
+// RecoverDeduct cancels a stranded try, returning quantity units when its deduction record has expired
+func (m *MultiLevelInventory) RecoverDeduct(ctx context.Context, deductID string, activityID uint64, quantity int) (string, error) {
+	return m.cancelDeduct(ctx, deductID, activityID, quantity)
+}
+
+// cancelDeduct Cancel phase, returning the script result. A positive quantity stands in
+// for an expired deduction record.
+func (m *MultiLevelInventory) cancelDeduct(ctx context.Context, deductID string, activityID uint64, quantity int) (string, error) {
+	// Roll back into the shard the stock was taken from
+	shard := deductShard(deductID)
+	stockKey := stockShardKey(activityID, shard)
+	reserveKey := reservedShardKey(activityID, shard)
+	recordKey := deductRecordKey(activityID, shard, deductID)
+
+	args := []interface{}{deductID}
+	if quantity > 0 {
+		args = append(args, quantity, 900)
+	}
+	result, err := cancelDeductScript.Run(ctx, m.redisClient,
 		[]string{stockKey, reserveKey, recordKey},
-		deductID).Result()
+		args...).Result()
 
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"deduct_id": deductID,
 			"error":     err.Error(),
 		}).Error("Cancel deduct failed")
-		return err
+		return "", err
+	}
+
+	message := scriptMessage(result)
+	if message == "success" && m.stockLogs != nil {
+		quantity, stock := scriptCounts(result)
+		m.stockLogs.Record(ctx, deductStockLog(activityID, model.OperationTypeRevert, quantity, stock-quantity, stock, deductID))
+	}
+
+	// Returned stock brings a sold out activity back on every instance, unless it was evicted
+	if message == "success" && m.redisClient.Exists(ctx, shardLayoutKey(activityID)).Val() > 0 {
+		if err := m.AddToBloomFilter(ctx, activityID); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"activity_id": activityID,
+				"error":       err.Error(),
+			}).Warn("Failed to restore activity to bloom filter")
+		}
 	}
 
 	logrus.WithField("deduct_id", deductID).Info("Stock deduction cancelled successfully")
-	return nil
+	return message, nil
 }
 
-// SyncToRedis sync stock to Redis
-func (m *MultiLevelInventory) SyncToRedis(ctx context.Context, activityID uint64, stock int) error {
-	stockKey := fmt.Sprintf("stock:{%d}", activityID)
-
-	// Set stock (24 hours expiration)
-	err := m.redisClient.Set(ctx, stockKey, stock, 24*time.Hour).Err()
-	if err != nil {
-		return err
+// scriptMessage message of a {code, message} script result
+func scriptMessage(result interface{}) string {
+	if resultSlice, ok := result.([]interface{}); ok && len(resultSlice) > 1 {
+		if message, ok := resultSlice[1].(string); ok {
+			return message
+		}
 	}
+	return ""
+}
 
-	// Add to bloom filter
-	m.AddToBloomFilter(activityID)
+// scriptCounts quantity and resulting counter of a {1, 'success', quantity, counter} script result
+func scriptCounts(result interface{}) (int, int) {
+	if resultSlice, ok := result.([]interface{}); ok && len(resultSlice) > 3 {
+		quantity, _ := resultSlice[2].(int64)
+		counter, _ := resultSlice[3].(int64)
+		return int(quantity), int(counter)
+	}
+	return 0, 0
+}
 
-	logrus.WithFields(logrus.Fields{
-		"activity_id": activityID,
-		"stock":       stock,
-	}).Info("Stock synced to Redis")
-	return nil
+// SyncToRedis sync stock to Redis using the activity's current shard layout
+func (m *MultiLevelInventory) SyncToRedis(ctx context.Context, activityID uint64, stock int) error {
+	return m.SyncShardsToRedis(ctx, activityID, stock, m.GetShardLayout(ctx, activityID))
 }
 
-// GetStockFromRedis get stock from Redis
+// GetStockFromRedis get stock from Redis (sum of all shards)
 func (m *MultiLevelInventory) GetStockFromRedis(ctx context.Context, activityID uint64) (int, error) {
-	stockKey := fmt.Sprintf("stock:{%d}", activityID)
-	stock, err := m.redisClient.Get(ctx, stockKey).Int()
-	if err != nil {
-		return 0, err
+	layout := m.GetShardLayout(ctx, activityID)
+	if !layout.Sharded() {
+		stock, err := m.redisClient.Get(ctx, stockShardKey(activityID, noShard)).Int()
+		if err != nil {
+			return 0, err
+		}
+		// Leased units are still unsold
+		leased, err := m.leasedStock(ctx, activityID)
+		if err != nil {
+			return 0, err
+		}
+		return stock + leased, nil
 	}
-	return stock, nil
+	return m.sumShards(ctx, activityID, layout, stockShardKey)
 }
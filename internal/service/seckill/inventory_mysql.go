@@ -0,0 +1,171 @@
+package seckill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+)
+
+// mysqlDeductPrefix marks deduction IDs issued by the MySQL inventory
+const mysqlDeductPrefix = "mysql:"
+
+// isMySQLDeduct whether a deduction was made by the MySQL inventory
+func isMySQLDeduct(deductID string) bool {
+	return strings.HasPrefix(deductID, mysqlDeductPrefix)
+}
+
+// MySQLInventory deducts stock straight from MySQL while Redis is unavailable.
+// Every deduction takes the activity row lock, so requests beyond a small per-instance
+// budget are turned away instead of queueing on it. Units sold on Redis are known by their
+// orders, or by their TCC logs while the order is still queued, so they are not sold again
+// and count against purchase limits. Without the TCC log a queued order is not seen and its
+// units can be oversold. The stock consistency check brings Redis back in line afterwards.
+type MySQLInventory struct {
+	purchases repository.PurchaseRepository
+	limiter   *rate.Limiter
+	slots     chan struct{}
+
+	// Stock log writer, nil when disabled
+	stockLogs *StockLogWriter
+}
+
+// NewMySQLInventory creates a MySQL inventory allowing qps deductions per second, at most maxConcurrent at once
+func NewMySQLInventory(purchases repository.PurchaseRepository, qps, maxConcurrent int) *MySQLInventory {
+	return &MySQLInventory{
+		purchases: purchases,
+		limiter:   rate.NewLimiter(rate.Limit(qps), qps),
+		slots:     make(chan struct{}, maxConcurrent),
+	}
+}
+
+// TryDeductWithLimit Try phase: deducts stock and records the purchase in one transaction
+func (m *MySQLInventory) TryDeductWithLimit(ctx context.Context, req *DeductRequest, limitPerUser int) (*DeductResult, error) {
+	if !m.limiter.Allow() {
+		return &DeductResult{Success: false, Message: "system_busy"}, nil
+	}
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	default:
+		return &DeductResult{Success: false, Message: "system_busy"}, nil
+	}
+
+	deductID := fmt.Sprintf("%sdeduct:%s:%d", mysqlDeductPrefix, req.RequestID, time.Now().UnixNano())
+	stock, err := m.purchases.Try(ctx, &model.Purchase{
+		DeductID:   deductID,
+		ActivityID: req.ActivityID,
+		UserID:     req.UserID,
+		Quantity:   req.Quantity,
+	}, limitPerUser)
+	switch {
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return &DeductResult{Success: false, DeductID: deductID, Message: "insufficient_stock"}, nil
+	case errors.Is(err, repository.ErrPurchaseLimitExceeded):
+		return &DeductResult{Success: false, DeductID: deductID, Message: "purchase_limit_exceeded"}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if m.stockLogs != nil {
+		entry := deductStockLog(req.ActivityID, model.OperationTypeDeduct, -req.Quantity, stock+req.Quantity, stock, deductID)
+		entry.Operator = userOperator(req.UserID)
+		m.stockLogs.Record(ctx, entry)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"activity_id": req.ActivityID,
+		"deduct_id":   deductID,
+	}).Info("Stock deducted from MySQL")
+	return &DeductResult{Success: true, DeductID: deductID, Message: "success"}, nil
+}
+
+// ConfirmDeduct Confirm phase, confirming a purchase that is no longer pending is a no-op
+func (m *MySQLInventory) ConfirmDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	_, err := m.confirmDeduct(ctx, deductID, activityID)
+	return err
+}
+
+// confirmDeduct Confirm phase, returning the result in the Redis scripts' terms
+func (m *MySQLInventory) confirmDeduct(ctx context.Context, deductID string, activityID uint64) (string, error) {
+	confirmed, err := m.purchases.Confirm(ctx, deductID)
+	if err != nil {
+		return "", err
+	}
+	if confirmed {
+		m.logConfirm(ctx, deductID, activityID)
+		return "success", nil
+	}
+
+	log.WithFields(map[string]interface{}{
+		"activity_id": activityID,
+		"deduct_id":   deductID,
+	}).Warn("MySQL deduction not pending, confirm skipped")
+	return m.settledResult(ctx, deductID)
+}
+
+// CancelDeduct Cancel phase, the stock of a purchase is returned at most once
+func (m *MySQLInventory) CancelDeduct(ctx context.Context, deductID string, activityID uint64) error {
+	_, err := m.cancelDeduct(ctx, deductID, activityID)
+	return err
+}
+
+// cancelDeduct Cancel phase, returning the result in the Redis scripts' terms
+func (m *MySQLInventory) cancelDeduct(ctx context.Context, deductID string, activityID uint64) (string, error) {
+	cancelled, stock, err := m.purchases.Cancel(ctx, deductID)
+	if err != nil {
+		return "", err
+	}
+	if cancelled != nil {
+		if m.stockLogs != nil {
+			m.stockLogs.Record(ctx, deductStockLog(activityID, model.OperationTypeRevert, cancelled.Quantity, stock-cancelled.Quantity, stock, deductID))
+		}
+		return "success", nil
+	}
+
+	log.WithFields(map[string]interface{}{
+		"activity_id": activityID,
+		"deduct_id":   deductID,
+	}).Warn("MySQL deduction not pending, cancel skipped")
+	return m.settledResult(ctx, deductID)
+}
+
+// logConfirm records a confirmed purchase. MySQL keeps no reserved stock, so the entry
+// moves none: before and after are zero.
+func (m *MySQLInventory) logConfirm(ctx context.Context, deductID string, activityID uint64) {
+	if m.stockLogs == nil {
+		return
+	}
+	purchase, err := m.purchases.GetByDeductID(ctx, deductID)
+	if err != nil || purchase == nil {
+		log.WithFields(map[string]interface{}{
+			"deduct_id": deductID,
+		}).Warn("Confirmed purchase not found, stock log skipped")
+		return
+	}
+	m.stockLogs.Record(ctx, deductStockLog(activityID, model.OperationTypeConfirm, -purchase.Quantity, 0, 0, deductID))
+}
+
+// settledResult why a purchase could not be confirmed or cancelled
+func (m *MySQLInventory) settledResult(ctx context.Context, deductID string) (string, error) {
+	purchase, err := m.purchases.GetByDeductID(ctx, deductID)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case purchase == nil:
+		return "deduct_record_not_found", nil
+	case purchase.Status == model.PurchaseStatusConfirmed:
+		return "already_confirmed", nil
+	case purchase.Status == model.PurchaseStatusCancelled:
+		return "already_cancelled", nil
+	}
+	return "", fmt.Errorf("purchase %s still pending", deductID)
+}
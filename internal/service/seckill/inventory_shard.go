@@ -0,0 +1,415 @@
+package seckill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"seckill/internal/model"
+	redisx "seckill/internal/redis"
+)
+
+const (
+	// noShard marks the legacy single stock key layout
+	noShard = -1
+	// shardLayoutTTL how long a shard layout is cached locally
+	shardLayoutTTL = time.Minute
+)
+
+// ShardLayout stock shard layout of an activity
+type ShardLayout struct {
+	Count    int    `json:"count"`
+	Strategy string `json:"strategy"` // hash / round_robin
+}
+
+// NewShardLayout creates a normalized shard layout
+func NewShardLayout(count int, strategy string) ShardLayout {
+	if count < 1 {
+		count = 1
+	}
+	if strategy != model.ShardStrategyRoundRobin {
+		strategy = model.ShardStrategyHash
+	}
+	return ShardLayout{Count: count, Strategy: strategy}
+}
+
+// ShardLayoutOf shard layout configured on the activity
+func ShardLayoutOf(activity *model.SeckillActivity) ShardLayout {
+	return NewShardLayout(activity.ShardCount, activity.ShardStrategy)
+}
+
+// Sharded whether stock is split across multiple keys
+func (l ShardLayout) Sharded() bool {
+	return l.Count > 1
+}
+
+// shards shard indexes of the layout, noShard for a single key
+func (l ShardLayout) shards() []int {
+	if !l.Sharded() {
+		return []int{noShard}
+	}
+	shards := make([]int, l.Count)
+	for i := range shards {
+		shards[i] = i
+	}
+	return shards
+}
+
+// owns whether a shard key belongs to this layout
+func (l ShardLayout) owns(shard int) bool {
+	if !l.Sharded() {
+		return shard == noShard
+	}
+	return shard >= 0 && shard < l.Count
+}
+
+type cachedShardLayout struct {
+	layout   ShardLayout
+	expireAt time.Time
+}
+
+// shardTag hash tag of a shard, each shard lands on its own cluster slot
+func shardTag(activityID uint64, shard int) string {
+	if shard == noShard {
+		return strconv.FormatUint(activityID, 10)
+	}
+	return fmt.Sprintf("%d:%d", activityID, shard)
+}
+
+func shardLayoutKey(activityID uint64) string {
+	return fmt.Sprintf("stock:shards:{%d}", activityID)
+}
+
+func stockShardKey(activityID uint64, shard int) string {
+	return fmt.Sprintf("stock:{%s}", shardTag(activityID, shard))
+}
+
+func reservedShardKey(activityID uint64, shard int) string {
+	return fmt.Sprintf("stock:reserved:{%s}", shardTag(activityID, shard))
+}
+
+func deductLogShardKey(activityID uint64, shard int) string {
+	return fmt.Sprintf("stock:deduct_log:{%s}", shardTag(activityID, shard))
+}
+
+func deductRecordKey(activityID uint64, shard int, deductID string) string {
+	return fmt.Sprintf("deduct_record:{%s}:%s", shardTag(activityID, shard), deductID)
+}
+
+// userPurchaseCountKey the user's purchase count, one key whatever the shard layout so that
+// resharding or leasing keeps every user's count
+func userPurchaseCountKey(activityID, userID uint64) string {
+	return fmt.Sprintf("purchase_count:{%d}:%d", activityID, userID)
+}
+
+// deductShard shard encoded in a deduct ID ("...@shard"), noShard for legacy IDs
+func deductShard(deductID string) int {
+	idx := strings.LastIndex(deductID, "@")
+	if idx < 0 {
+		return noShard
+	}
+	shard, err := strconv.Atoi(deductID[idx+1:])
+	if err != nil || shard < 0 {
+		return noShard
+	}
+	return shard
+}
+
+// userShard home shard of a user, stable regardless of routing strategy
+func userShard(userID uint64, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.FormatUint(userID, 10)))
+	return int(h.Sum32() % uint32(count))
+}
+
+// GetShardLayout returns the shard layout of an activity, single key if not synced yet
+func (m *MultiLevelInventory) GetShardLayout(ctx context.Context, activityID uint64) ShardLayout {
+	if v, ok := m.shardLayouts.Load(activityID); ok {
+		cached := v.(cachedShardLayout)
+		if time.Now().Before(cached.expireAt) {
+			return cached.layout
+		}
+	}
+
+	values, err := m.redisClient.HGetAll(ctx, shardLayoutKey(activityID)).Result()
+	if err != nil || len(values) == 0 {
+		// Not cached: another instance may sync the layout any moment
+		return NewShardLayout(1, "")
+	}
+
+	count, _ := strconv.Atoi(values["count"])
+	layout := NewShardLayout(count, values["strategy"])
+	m.shardLayouts.Store(activityID, cachedShardLayout{
+		layout:   layout,
+		expireAt: time.Now().Add(shardLayoutTTL),
+	})
+	return layout
+}
+
+// SyncShardsToRedis sync stock to Redis split across the layout's shards
+func (m *MultiLevelInventory) SyncShardsToRedis(ctx context.Context, activityID uint64, stock int, layout ShardLayout) error {
+	layout = NewShardLayout(layout.Count, layout.Strategy)
+	previous := m.GetShardLayout(ctx, activityID)
+
+	// Stock replaced by the sync, none when it was not in Redis
+	before := 0
+	if m.stockLogs != nil {
+		before, _ = m.GetStockFromRedis(ctx, activityID)
+	}
+
+	pipe := m.redisClient.Pipeline()
+	for _, shard := range layout.shards() {
+		pipe.Set(ctx, stockShardKey(activityID, shard), shardStock(stock, layout.Count, shard), 24*time.Hour)
+	}
+
+	// Drop stock keys of the previous layout, reserved keys stay for in-flight deductions
+	if previous != layout {
+		for _, shard := range previous.shards() {
+			if !layout.owns(shard) {
+				pipe.Del(ctx, stockShardKey(activityID, shard))
+			}
+		}
+	}
+
+	// Synced stock replaces all outstanding leases, their holders re-lease on the next request
+	pipe.Del(ctx, stockLeaseKey(activityID), stockLeaseDeadlineKey(activityID))
+
+	pipe.HSet(ctx, shardLayoutKey(activityID), "count", layout.Count, "strategy", layout.Strategy)
+	pipe.Expire(ctx, shardLayoutKey(activityID), 24*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	m.shardLayouts.Store(activityID, cachedShardLayout{
+		layout:   layout,
+		expireAt: time.Now().Add(shardLayoutTTL),
+	})
+	if m.leaseConfig != nil {
+		m.dropLeased(activityID)
+	}
+
+	if m.stockLogs != nil {
+		operation := StockMetaFrom(ctx).Operation
+		if operation == 0 {
+			operation = model.OperationTypeSync
+		}
+		m.stockLogs.Record(ctx, &model.StockLog{
+			ActivityID:    activityID,
+			OperationType: operation,
+			Quantity:      stock - before,
+			BeforeStock:   before,
+			AfterStock:    stock,
+		})
+	}
+
+	// Add to bloom filter
+	if err := m.AddToBloomFilter(ctx, activityID); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"activity_id": activityID,
+		"stock":       stock,
+		"shard_count": layout.Count,
+	}).Info("Stock synced to Redis")
+	return nil
+}
+
+// EvictStock removes an ended activity's stock keys from Redis and local cache,
+// reserved stock and deduct records are left to expire with in-flight orders
+func (m *MultiLevelInventory) EvictStock(ctx context.Context, activityID uint64) error {
+	layout := m.GetShardLayout(ctx, activityID)
+
+	pipe := m.redisClient.Pipeline()
+	for _, shard := range layout.shards() {
+		pipe.Del(ctx, stockShardKey(activityID, shard))
+	}
+	pipe.Del(ctx, shardLayoutKey(activityID), stockLeaseKey(activityID), stockLeaseDeadlineKey(activityID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	m.shardLayouts.Delete(activityID)
+	m.rrCounters.Delete(activityID)
+	return m.MarkSoldOut(ctx, activityID)
+}
+
+// shardStock stock assigned to a shard, remainder goes to the first shards
+func shardStock(stock, count, shard int) int {
+	if shard == noShard {
+		return stock
+	}
+	per := stock / count
+	if shard < stock%count {
+		per++
+	}
+	return per
+}
+
+// GetReservedFromRedis get reserved stock from Redis
+func (m *MultiLevelInventory) GetReservedFromRedis(ctx context.Context, activityID uint64) (int, error) {
+	layout := m.GetShardLayout(ctx, activityID)
+	reserved, err := m.sumShards(ctx, activityID, layout, reservedShardKey)
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return reserved, err
+}
+
+// sumShards sums an integer key across all shards, redis.Nil if none exists
+func (m *MultiLevelInventory) sumShards(ctx context.Context, activityID uint64, layout ShardLayout, keyFn func(uint64, int) string) (int, error) {
+	pipe := m.redisClient.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, layout.Count)
+	for _, shard := range layout.shards() {
+		cmds = append(cmds, pipe.Get(ctx, keyFn(activityID, shard)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+
+	total, found := 0, false
+	for _, cmd := range cmds {
+		value, err := cmd.Int()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += value
+		found = true
+	}
+	if !found {
+		return 0, redis.Nil
+	}
+	return total, nil
+}
+
+// pickShard first shard to try for a request
+func (m *MultiLevelInventory) pickShard(layout ShardLayout, activityID, userID uint64) int {
+	if layout.Strategy == model.ShardStrategyRoundRobin {
+		v, _ := m.rrCounters.LoadOrStore(activityID, new(uint64))
+		n := atomic.AddUint64(v.(*uint64), 1)
+		return int((n - 1) % uint64(layout.Count))
+	}
+	return userShard(userID, layout.Count)
+}
+
+// purchaseLimitScript reserves the user's purchase quota
+var purchaseLimitScript = redisx.Scripts.Register("inventory:purchase_limit", `
+	local purchase_count_key = KEYS[1]
+	local quantity = tonumber(ARGV[1])
+	local limit_per_user = tonumber(ARGV[2])
+
+	local new_purchase_count = redis.call('INCRBY', purchase_count_key, quantity)
+	redis.call('EXPIRE', purchase_count_key, 86400)
+	if new_purchase_count > limit_per_user then
+		redis.call('DECRBY', purchase_count_key, quantity)
+		return 0
+	end
+	return 1
+`)
+
+// shardDeductScript pre-deducts stock from a single shard
+var shardDeductScript = redisx.Scripts.Register("inventory:shard_deduct", `
+	local stock_key = KEYS[1]
+	local reserve_key = KEYS[2]
+	local deduct_log_key = KEYS[3]
+	local deduct_record_key = KEYS[4]
+	local deduct_id = ARGV[1]
+	local quantity = tonumber(ARGV[2])
+	local expire_time = tonumber(ARGV[3])
+
+	local current_stock = tonumber(redis.call('GET', stock_key) or 0)
+	if current_stock < quantity then
+		return {0, 'insufficient_stock', current_stock}
+	end
+
+	redis.call('DECRBY', stock_key, quantity)
+	redis.call('INCRBY', reserve_key, quantity)
+
+	local log_data = cjson.encode({
+		deduct_id = deduct_id,
+		quantity = quantity,
+		timestamp = redis.call('TIME')[1],
+		status = 'try'
+	})
+	redis.call('HSET', deduct_log_key, deduct_id, log_data)
+	redis.call('EXPIRE', deduct_log_key, expire_time)
+	redis.call('SETEX', deduct_record_key, expire_time, log_data)
+
+	return {1, 'success', current_stock - quantity}
+`)
+
+// tryDeductSharded Try phase on sharded stock: reserve the user quota, then deduct
+// from the routed shard, falling back to the other shards when it runs short.
+//
+// A deduction is taken from a single shard: a request for more units than any one shard
+// has left fails with insufficient_stock, even when the shards together hold enough.
+// Shards start within one unit of each other, so this only bites multi-unit requests on
+// the last few units.
+func (m *MultiLevelInventory) tryDeductSharded(ctx context.Context, req *DeductRequest, layout ShardLayout, limitPerUser int) (*DeductResult, error) {
+	purchaseCountKey := userPurchaseCountKey(req.ActivityID, req.UserID)
+
+	allowed, err := purchaseLimitScript.Run(ctx, m.redisClient,
+		[]string{purchaseCountKey}, req.Quantity, limitPerUser).Int()
+	if err != nil {
+		logrus.WithField("error", err.Error()).Error("Redis eval failed")
+		return nil, err
+	}
+	if allowed == 0 {
+		return &DeductResult{Success: false, Message: "purchase_limit_exceeded"}, nil
+	}
+
+	baseID := fmt.Sprintf("deduct:%s:%d", req.RequestID, time.Now().UnixNano())
+	start := m.pickShard(layout, req.ActivityID, req.UserID)
+
+	for i := 0; i < layout.Count; i++ {
+		shard := (start + i) % layout.Count
+		deductID := fmt.Sprintf("%s@%d", baseID, shard)
+
+		result, err := shardDeductScript.Run(ctx, m.redisClient,
+			[]string{
+				stockShardKey(req.ActivityID, shard),
+				reservedShardKey(req.ActivityID, shard),
+				deductLogShardKey(req.ActivityID, shard),
+				deductRecordKey(req.ActivityID, shard, deductID),
+			},
+			deductID, req.Quantity, 900).Result()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"activity_id": req.ActivityID,
+				"shard":       shard,
+				"error":       err.Error(),
+			}).Error("Redis eval failed")
+			m.redisClient.DecrBy(ctx, purchaseCountKey, int64(req.Quantity))
+			return nil, err
+		}
+
+		resultSlice := result.([]interface{})
+		if resultSlice[0].(int64) == 1 {
+			// Report the stock of all shards, the shard's own is a lower bound if they cannot be read
+			remain := int(resultSlice[2].(int64))
+			if total, err := m.sumShards(ctx, req.ActivityID, layout, stockShardKey); err == nil {
+				remain = total
+			}
+			return &DeductResult{
+				Success:     true,
+				DeductID:    deductID,
+				Message:     resultSlice[1].(string),
+				RemainStock: remain,
+			}, nil
+		}
+	}
+
+	// All shards empty, give the quota back
+	m.redisClient.DecrBy(ctx, purchaseCountKey, int64(req.Quantity))
+	return &DeductResult{Success: false, Message: "insufficient_stock"}, nil
+}
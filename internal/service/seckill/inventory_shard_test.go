@@ -0,0 +1,219 @@
+package seckill
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	redisx "seckill/internal/redis"
+)
+
+func setupShardedInventory(t *testing.T) (*MultiLevelInventory, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	redisx.RejectCrossSlot(client)
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	inventory, err := NewMultiLevelInventory(client)
+	require.NoError(t, err)
+	return inventory, mr
+}
+
+// passBloom lets LocalCheck pass for the activity
+func passBloom(m *MultiLevelInventory, activityID uint64) {
+	m.AddToBloomFilter(context.Background(), activityID)
+}
+
+func TestShardStock(t *testing.T) {
+	assert.Equal(t, 4, shardStock(10, 3, 0))
+	assert.Equal(t, 3, shardStock(10, 3, 1))
+	assert.Equal(t, 3, shardStock(10, 3, 2))
+	assert.Equal(t, 10, shardStock(10, 1, noShard))
+}
+
+func TestDeductShard(t *testing.T) {
+	assert.Equal(t, 3, deductShard("deduct:req-1:123@3"))
+	assert.Equal(t, noShard, deductShard("deduct:req-1:123"))
+	assert.Equal(t, noShard, deductShard("deduct:a@b:123"))
+}
+
+func TestSyncShardsToRedis(t *testing.T) {
+	inventory, mr := setupShardedInventory(t)
+	ctx := context.Background()
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 1, 10, NewShardLayout(3, model.ShardStrategyHash)))
+
+	for shard, want := range []string{"4", "3", "3"} {
+		got, err := mr.Get(stockShardKey(1, shard))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	stock, err := inventory.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 10, stock)
+
+	// Back to a single key drops the shard keys
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 1, 7, NewShardLayout(1, "")))
+	assert.False(t, mr.Exists(stockShardKey(1, 0)))
+	stock, err = inventory.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 7, stock)
+}
+
+func TestTryDeductSharded_FallbackToOtherShards(t *testing.T) {
+	inventory, _ := setupShardedInventory(t)
+	ctx := context.Background()
+	activityID := uint64(2)
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, activityID, 2, NewShardLayout(2, model.ShardStrategyRoundRobin)))
+	passBloom(inventory, activityID)
+
+	// Three requests against two units: both shards drain, the last one fails
+	var shards []int
+	for i := 0; i < 3; i++ {
+		result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{
+			RequestID:  fmt.Sprintf("req-%d", i),
+			ActivityID: activityID,
+			UserID:     uint64(100 + i),
+			Quantity:   1,
+		}, 1)
+		require.NoError(t, err)
+		if i < 2 {
+			require.True(t, result.Success)
+			shards = append(shards, deductShard(result.DeductID))
+		} else {
+			assert.False(t, result.Success)
+			assert.Equal(t, "insufficient_stock", result.Message)
+		}
+	}
+	assert.ElementsMatch(t, []int{0, 1}, shards)
+
+	stock, err := inventory.GetStockFromRedis(ctx, activityID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stock)
+
+	reserved, err := inventory.GetReservedFromRedis(ctx, activityID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reserved)
+}
+
+func TestTryDeductSharded_PurchaseLimit(t *testing.T) {
+	inventory, _ := setupShardedInventory(t)
+	ctx := context.Background()
+	activityID := uint64(3)
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, activityID, 100, NewShardLayout(4, model.ShardStrategyRoundRobin)))
+	passBloom(inventory, activityID)
+
+	// Round-robin sends the same user to different shards, the limit still holds
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "a", ActivityID: activityID, UserID: 7, Quantity: 1}, 1)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+
+	result, err = inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "b", ActivityID: activityID, UserID: 7, Quantity: 1}, 1)
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "purchase_limit_exceeded", result.Message)
+}
+
+func TestTryDeductSharded_PurchaseLimitSurvivesResharding(t *testing.T) {
+	inventory, _ := setupShardedInventory(t)
+	ctx := context.Background()
+	activityID := uint64(5)
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, activityID, 100, NewShardLayout(1, "")))
+	passBloom(inventory, activityID)
+
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "a", ActivityID: activityID, UserID: 7, Quantity: 1}, 2)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	// Each layout change keeps the count the user already has
+	for i, count := range []int{4, 3, 1} {
+		require.NoError(t, inventory.SyncShardsToRedis(ctx, activityID, 100, NewShardLayout(count, model.ShardStrategyHash)))
+		result, err = inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: fmt.Sprintf("b%d", i), ActivityID: activityID, UserID: 7, Quantity: 1}, 2)
+		require.NoError(t, err)
+		if i == 0 {
+			assert.True(t, result.Success)
+		} else {
+			assert.Equal(t, "purchase_limit_exceeded", result.Message, count)
+		}
+	}
+}
+
+func TestTryDeductSharded_RemainStockSpansShards(t *testing.T) {
+	inventory, _ := setupShardedInventory(t)
+	ctx := context.Background()
+	activityID := uint64(6)
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, activityID, 10, NewShardLayout(3, model.ShardStrategyHash)))
+	passBloom(inventory, activityID)
+
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "a", ActivityID: activityID, UserID: 7, Quantity: 2}, 5)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.Equal(t, 8, result.RemainStock)
+}
+
+func TestTryDeductSharded_MultiUnitTakenFromOneShard(t *testing.T) {
+	inventory, _ := setupShardedInventory(t)
+	ctx := context.Background()
+	activityID := uint64(7)
+
+	// One unit per shard: two units exist, no shard can serve both
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, activityID, 2, NewShardLayout(2, model.ShardStrategyHash)))
+	passBloom(inventory, activityID)
+
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "a", ActivityID: activityID, UserID: 7, Quantity: 2}, 5)
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "insufficient_stock", result.Message)
+
+	// Nothing moved, the quota is given back and single units still sell
+	stock, err := inventory.GetStockFromRedis(ctx, activityID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stock)
+	for i := 0; i < 2; i++ {
+		result, err = inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: fmt.Sprintf("b%d", i), ActivityID: activityID, UserID: 7, Quantity: 1}, 2)
+		require.NoError(t, err)
+		assert.True(t, result.Success)
+	}
+}
+
+func TestShardedConfirmAndCancel(t *testing.T) {
+	inventory, _ := setupShardedInventory(t)
+	ctx := context.Background()
+	activityID := uint64(4)
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, activityID, 4, NewShardLayout(2, model.ShardStrategyHash)))
+	passBloom(inventory, activityID)
+
+	first, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "c1", ActivityID: activityID, UserID: 1, Quantity: 1}, 5)
+	require.NoError(t, err)
+	require.True(t, first.Success)
+	second, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "c2", ActivityID: activityID, UserID: 2, Quantity: 1}, 5)
+	require.NoError(t, err)
+	require.True(t, second.Success)
+
+	require.NoError(t, inventory.ConfirmDeduct(ctx, first.DeductID, activityID))
+	require.NoError(t, inventory.CancelDeduct(ctx, second.DeductID, activityID))
+
+	stock, err := inventory.GetStockFromRedis(ctx, activityID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stock)
+
+	reserved, err := inventory.GetReservedFromRedis(ctx, activityID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, reserved)
+}
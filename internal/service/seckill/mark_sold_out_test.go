@@ -28,14 +28,14 @@ func TestMarkSoldOut_BloomFilterRemoval(t *testing.T) {
 	activityID := uint64(12345)
 
 	// Add activity to bloom filter first (using activityID as goodsID for testing)
-	inventory.AddToBloomFilter(activityID)
+	inventory.AddToBloomFilter(ctx, activityID)
 
 	// Verify activity exists in bloom filter (through LocalCheck)
 	exists := inventory.LocalCheck(ctx, activityID)
 	assert.True(t, exists, "Activity should exist in bloom filter before marking sold out")
 
 	// Mark activity as sold out
-	err = inventory.MarkSoldOut(activityID)
+	err = inventory.MarkSoldOut(ctx, activityID)
 	require.NoError(t, err)
 
 	// Verify activity is marked as sold out in local cache
@@ -50,7 +50,7 @@ func TestMarkSoldOut_BloomFilterRemoval(t *testing.T) {
 	assert.False(t, exists, "New activity should not exist in bloom filter")
 
 	// Mark it as sold out
-	err = inventory.MarkSoldOut(newActivityID)
+	err = inventory.MarkSoldOut(ctx, newActivityID)
 	require.NoError(t, err)
 
 	// Should still return false (sold out)
@@ -80,7 +80,7 @@ func TestMarkSoldOut_BloomFilterConsistency(t *testing.T) {
 
 	// Add all activities to bloom filter (using activityID as goodsID for testing)
 	for _, activityID := range activityIDs {
-		inventory.AddToBloomFilter(activityID)
+		inventory.AddToBloomFilter(ctx, activityID)
 	}
 
 	// Verify all activities exist in bloom filter
@@ -92,7 +92,7 @@ func TestMarkSoldOut_BloomFilterConsistency(t *testing.T) {
 	// Mark some activities as sold out
 	soldOutActivities := []uint64{1001, 1003, 1005}
 	for _, activityID := range soldOutActivities {
-		err = inventory.MarkSoldOut(activityID)
+		err = inventory.MarkSoldOut(ctx, activityID)
 		require.NoError(t, err)
 	}
 
@@ -125,9 +125,11 @@ func BenchmarkMarkSoldOut_WithBloomFilter(b *testing.B) {
 	inventory, err := NewMultiLevelInventory(redisClient)
 	require.NoError(b, err)
 
+	ctx := context.Background()
+
 	// Pre-populate bloom filter
 	for i := 0; i < 1000; i++ {
-		inventory.AddToBloomFilter(uint64(i))
+		inventory.AddToBloomFilter(ctx, uint64(i))
 	}
 
 	b.ResetTimer()
@@ -135,7 +137,7 @@ func BenchmarkMarkSoldOut_WithBloomFilter(b *testing.B) {
 		activityID := uint64(0)
 		for pb.Next() {
 			activityID = (activityID + 1) % 1000
-			inventory.MarkSoldOut(activityID)
+			inventory.MarkSoldOut(ctx, activityID)
 		}
 	})
 }
@@ -0,0 +1,525 @@
+package seckill
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// reconcileBatch rows and keys read per round trip
+const reconcileBatch = 1000
+
+// Reconciliation issue kinds
+const (
+	IssueOrphanDeduction       = "orphan_deduction"        // Stock left the pool without a live order
+	IssueOrderWithoutDeduction = "order_without_deduction" // A live order no stock source accounts for
+	IssueDoubleConfirm         = "double_confirm"          // One deduction sold more than once
+)
+
+// Reconciliation sources
+const (
+	SourceRedis     = "redis"
+	SourceStockLogs = "stock_logs"
+	SourceOrders    = "orders"
+)
+
+// ReconcileIssue one discrepancy between the sources
+type ReconcileIssue struct {
+	Kind      string `json:"kind"`
+	Source    string `json:"source"` // Where the discrepancy was found
+	DeductID  string `json:"deduct_id,omitempty"`
+	OrderNo   string `json:"order_no,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Quantity  int    `json:"quantity"`
+	Detail    string `json:"detail"`
+}
+
+// ReconcileReport result of reconciling an activity's orders, Redis deduct logs, stock_logs and total stock
+type ReconcileReport struct {
+	ActivityID  uint64    `json:"activity_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	TotalStock        int `json:"total_stock"`
+	Orders            int `json:"orders"`
+	SoldQuantity      int `json:"sold_quantity"`      // Pending, paid and completed orders
+	CancelledQuantity int `json:"cancelled_quantity"` // Cancelled and refunded orders
+
+	RedisStockPresent bool `json:"redis_stock_present"` // False once the stock was evicted at the end
+	RedisAvailable    int  `json:"redis_available"`
+	RedisReserved     int  `json:"redis_reserved"`
+	RedisDeductions   int  `json:"redis_deductions"` // Entries in the Redis deduct logs
+	InFlight          int  `json:"in_flight"`        // Tries too recent to judge
+
+	StockLogDeducted  int `json:"stock_log_deducted"`
+	StockLogReverted  int `json:"stock_log_reverted"`
+	StockLogConfirmed int `json:"stock_log_confirmed"`
+
+	OrphanQuantity int `json:"orphan_quantity"` // Units of orphan Redis deductions
+	Oversell       int `json:"oversell"`
+	Undersell      int `json:"undersell"`
+	Net            int `json:"net"` // Oversell minus undersell
+
+	Issues   []ReconcileIssue `json:"issues"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// WriteJSON writes the whole report as JSON
+func (r *ReconcileReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes the issues as CSV, one row per issue
+func (r *ReconcileReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"activity_id", "kind", "source", "deduct_id", "order_no", "request_id", "quantity", "detail"}); err != nil {
+		return err
+	}
+	activityID := strconv.FormatUint(r.ActivityID, 10)
+	for _, issue := range r.Issues {
+		if err := writer.Write([]string{
+			activityID, issue.Kind, issue.Source, issue.DeductID, issue.OrderNo, issue.RequestID,
+			strconv.Itoa(issue.Quantity), issue.Detail,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// redisDeduction an entry of a Redis deduct log with the status of its deduction record
+type redisDeduction struct {
+	DeductID  string      `json:"deduct_id"`
+	Quantity  int         `json:"quantity"`
+	Timestamp json.Number `json:"timestamp"` // Redis TIME seconds
+	Status    string      `json:"status"`    // try / confirmed / cancelled, empty once the record expired
+}
+
+// Reconciler compares what the stores say was sold for an activity. The Redis deduct logs
+// expire 15 minutes after the last try, reconcile an ended activity before that.
+type Reconciler struct {
+	inventory  *MultiLevelInventory
+	activities repository.ActivityRepository
+	orders     repository.OrderRepository
+	stockLogs  repository.StockLogRepository
+	inFlight   time.Duration
+}
+
+// NewReconciler creates a reconciler, tries younger than inFlight may still become orders and are not judged
+func NewReconciler(inventory *MultiLevelInventory, activities repository.ActivityRepository, orders repository.OrderRepository, stockLogs repository.StockLogRepository, inFlight time.Duration) *Reconciler {
+	return &Reconciler{
+		inventory:  inventory,
+		activities: activities,
+		orders:     orders,
+		stockLogs:  stockLogs,
+		inFlight:   inFlight,
+	}
+}
+
+// Reconcile builds the report of an activity
+func (r *Reconciler) Reconcile(ctx context.Context, activityID uint64) (*ReconcileReport, error) {
+	activity, err := r.activities.GetByID(ctx, int64(activityID))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{
+		ActivityID:  activityID,
+		GeneratedAt: time.Now(),
+		TotalStock:  activity.Stock,
+		Issues:      []ReconcileIssue{},
+	}
+
+	deductions, err := r.loadDeductions(ctx, activityID, ShardLayoutOf(activity))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Redis deduct logs: %w", err)
+	}
+	orders, err := r.loadOrders(ctx, activityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orders: %w", err)
+	}
+	logs, err := r.loadStockLogs(ctx, activityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stock logs: %w", err)
+	}
+	if err := r.loadStock(ctx, activityID, report); err != nil {
+		return nil, fmt.Errorf("failed to read Redis stock: %w", err)
+	}
+
+	report.compare(deductions, orders, logs, report.GeneratedAt.Add(-r.inFlight))
+	report.settle()
+	return report, nil
+}
+
+// loadDeductions reads every shard's deduct log and the status of each deduction record
+func (r *Reconciler) loadDeductions(ctx context.Context, activityID uint64, layout ShardLayout) (map[string]*redisDeduction, error) {
+	client := r.inventory.redisClient
+	deductions := make(map[string]*redisDeduction)
+
+	for _, shard := range layout.shards() {
+		key := deductLogShardKey(activityID, shard)
+		var cursor uint64
+		for {
+			fields, next, err := client.HScan(ctx, key, cursor, "*", reconcileBatch).Result()
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i+1 < len(fields); i += 2 {
+				deduction := &redisDeduction{}
+				if err := json.Unmarshal([]byte(fields[i+1]), deduction); err != nil {
+					return nil, fmt.Errorf("deduct log entry %s: %w", fields[i], err)
+				}
+				deduction.DeductID, deduction.Status = fields[i], ""
+				deductions[deduction.DeductID] = deduction
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+
+	// The log keeps the try, the record tracks confirm and cancel
+	ids := make([]string, 0, len(deductions))
+	for id := range deductions {
+		ids = append(ids, id)
+	}
+	for start := 0; start < len(ids); start += reconcileBatch {
+		end := start + reconcileBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		pipe := client.Pipeline()
+		cmds := make([]*redis.StringCmd, 0, end-start)
+		for _, id := range ids[start:end] {
+			cmds = append(cmds, pipe.Get(ctx, deductRecordKey(activityID, deductShard(id), id)))
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		for i, cmd := range cmds {
+			data, err := cmd.Bytes()
+			if err != nil {
+				continue
+			}
+			var record struct {
+				Status string `json:"status"`
+			}
+			if json.Unmarshal(data, &record) == nil {
+				deductions[ids[start+i]].Status = record.Status
+			}
+		}
+	}
+	return deductions, nil
+}
+
+// loadOrders reads every order of the activity
+func (r *Reconciler) loadOrders(ctx context.Context, activityID uint64) ([]*model.Order, error) {
+	var all []*model.Order
+	var afterID uint64
+	for {
+		orders, err := r.orders.ListByActivity(ctx, activityID, afterID, reconcileBatch)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, orders...)
+		if len(orders) < reconcileBatch {
+			return all, nil
+		}
+		afterID = orders[len(orders)-1].ID
+	}
+}
+
+// loadStockLogs reads every stock log of the activity
+func (r *Reconciler) loadStockLogs(ctx context.Context, activityID uint64) ([]model.StockLog, error) {
+	var all []model.StockLog
+	var afterID uint64
+	for {
+		logs, err := r.stockLogs.ListByActivity(ctx, activityID, afterID, reconcileBatch)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, logs...)
+		if len(logs) < reconcileBatch {
+			return all, nil
+		}
+		afterID = logs[len(logs)-1].ID
+	}
+}
+
+// loadStock reads the stock still in Redis, absent once the activity ended
+func (r *Reconciler) loadStock(ctx context.Context, activityID uint64, report *ReconcileReport) error {
+	available, err := r.inventory.GetStockFromRedis(ctx, activityID)
+	switch {
+	case errors.Is(err, redis.Nil):
+		report.RedisStockPresent = false
+	case err != nil:
+		return err
+	default:
+		report.RedisStockPresent, report.RedisAvailable = true, available
+	}
+
+	reserved, err := r.inventory.GetReservedFromRedis(ctx, activityID)
+	if err != nil {
+		return err
+	}
+	report.RedisReserved = reserved
+	return nil
+}
+
+// compare fills in the counts and issues, tries after cutoff are in flight
+func (r *ReconcileReport) compare(deductions map[string]*redisDeduction, orders []*model.Order, logs []model.StockLog, cutoff time.Time) {
+	ordersByDeduct := make(map[string][]*model.Order)
+	orderNos := make(map[string]bool, len(orders))
+	for _, order := range orders {
+		r.Orders++
+		if orderSold(order) {
+			r.SoldQuantity += order.Quantity
+		} else {
+			r.CancelledQuantity += order.Quantity
+		}
+		orderNos[order.OrderNo] = true
+		if order.DeductID != "" {
+			ordersByDeduct[order.DeductID] = append(ordersByDeduct[order.DeductID], order)
+		}
+	}
+
+	// Tries carry no order number yet, confirms tie a deduction to its order
+	confirmLogs := make(map[string][]model.StockLog)
+	for _, stockLog := range logs {
+		switch stockLog.OperationType {
+		case model.OperationTypeDeduct:
+			r.StockLogDeducted += abs(stockLog.Quantity)
+		case model.OperationTypeRevert:
+			r.StockLogReverted += abs(stockLog.Quantity)
+		case model.OperationTypeConfirm:
+			r.StockLogConfirmed += abs(stockLog.Quantity)
+			if stockLog.OrderNo != nil && *stockLog.OrderNo != "" {
+				confirmLogs[*stockLog.OrderNo] = append(confirmLogs[*stockLog.OrderNo], stockLog)
+			}
+		}
+	}
+	r.RedisDeductions = len(deductions)
+
+	// A deduction backing more than one live order
+	for deductID, list := range ordersByDeduct {
+		var live []string
+		quantity := 0
+		for _, order := range list {
+			if !orderSold(order) {
+				continue
+			}
+			// The first live order is the legitimate one
+			if len(live) > 0 {
+				quantity += order.Quantity
+			}
+			live = append(live, order.OrderNo)
+		}
+		if len(live) > 1 {
+			r.addIssue(ReconcileIssue{
+				Kind:     IssueDoubleConfirm,
+				Source:   SourceOrders,
+				DeductID: deductID,
+				OrderNo:  strings.Join(live, " "),
+				Quantity: quantity,
+				Detail:   fmt.Sprintf("deduction backs %d live orders", len(live)),
+			})
+		}
+	}
+
+	// An order confirmed more than once
+	for orderNo, entries := range confirmLogs {
+		if len(entries) < 2 {
+			continue
+		}
+		quantity := 0
+		for _, entry := range entries[1:] {
+			quantity += abs(entry.Quantity)
+		}
+		r.addIssue(ReconcileIssue{
+			Kind:      IssueDoubleConfirm,
+			Source:    SourceStockLogs,
+			OrderNo:   orderNo,
+			RequestID: stringValue(entries[0].RequestID),
+			Quantity:  quantity,
+			Detail:    fmt.Sprintf("%d confirm logs for one order", len(entries)),
+		})
+	}
+
+	// Redis deductions no live order accounts for
+	for deductID, deduction := range deductions {
+		if seconds, err := deduction.Timestamp.Int64(); err == nil && time.Unix(seconds, 0).After(cutoff) {
+			r.InFlight++
+			continue
+		}
+
+		list := ordersByDeduct[deductID]
+		detail := ""
+		switch {
+		case len(list) == 0 && deduction.Status == "cancelled":
+			// Returned to stock, a failed order or a recovered try
+		case len(list) == 0 && deduction.Status == "":
+			detail = "no order, deduction record expired"
+		case len(list) == 0:
+			detail = "no order, deduction " + deduction.Status
+		case deduction.Status == "confirmed" && !anySold(list):
+			detail = "order cancelled, deduction confirmed"
+		}
+		if detail == "" {
+			continue
+		}
+		r.OrphanQuantity += deduction.Quantity
+		issue := ReconcileIssue{
+			Kind:     IssueOrphanDeduction,
+			Source:   SourceRedis,
+			DeductID: deductID,
+			Quantity: deduction.Quantity,
+			Detail:   detail,
+		}
+		if len(list) > 0 {
+			issue.OrderNo, issue.RequestID = list[0].OrderNo, list[0].RequestID
+		}
+		r.addIssue(issue)
+	}
+
+	// Stock logs confirming for orders that do not exist
+	for orderNo, entries := range confirmLogs {
+		if orderNos[orderNo] {
+			continue
+		}
+		quantity := 0
+		for _, entry := range entries {
+			quantity += abs(entry.Quantity)
+		}
+		r.addIssue(ReconcileIssue{
+			Kind:      IssueOrphanDeduction,
+			Source:    SourceStockLogs,
+			OrderNo:   orderNo,
+			RequestID: stringValue(entries[0].RequestID),
+			Quantity:  quantity,
+			Detail:    "no order for confirm log",
+		})
+	}
+
+	// Live orders missing from every source that could hold their deduction
+	redisChecked, logsChecked := len(deductions) > 0, len(confirmLogs) > 0
+	if !redisChecked {
+		r.Warnings = append(r.Warnings, "no Redis deduct log entries, they expire 15 minutes after the last try: orders were not checked against Redis")
+	}
+	if !logsChecked {
+		r.Warnings = append(r.Warnings, "no confirm entries in stock_logs: orders were not checked against stock_logs")
+	}
+	for _, order := range orders {
+		if !orderSold(order) {
+			continue
+		}
+		if order.DeductID == "" {
+			r.addIssue(ReconcileIssue{
+				Kind:      IssueOrderWithoutDeduction,
+				Source:    SourceOrders,
+				OrderNo:   order.OrderNo,
+				RequestID: order.RequestID,
+				Quantity:  order.Quantity,
+				Detail:    "order has no deduct ID",
+			})
+			continue
+		}
+
+		checked, found := false, false
+		if redisChecked && !isMySQLDeduct(order.DeductID) {
+			checked = true
+			_, found = deductions[order.DeductID]
+		}
+		if !found && logsChecked {
+			checked = true
+			_, found = confirmLogs[order.OrderNo]
+		}
+		if checked && !found {
+			r.addIssue(ReconcileIssue{
+				Kind:      IssueOrderWithoutDeduction,
+				Source:    SourceOrders,
+				DeductID:  order.DeductID,
+				OrderNo:   order.OrderNo,
+				RequestID: order.RequestID,
+				Quantity:  order.Quantity,
+				Detail:    "deduction not found in Redis deduct logs or stock_logs",
+			})
+		}
+	}
+
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		a, b := r.Issues[i], r.Issues[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.DeductID != b.DeductID {
+			return a.DeductID < b.DeductID
+		}
+		return a.OrderNo < b.OrderNo
+	})
+}
+
+// settle works out the net oversell or undersell. While Redis holds the stock every unit
+// must be sold, available or reserved; once it is evicted the unsold remainder is unknown
+// and only orphan deductions count as undersold.
+func (r *ReconcileReport) settle() {
+	if r.RedisStockPresent {
+		diff := r.SoldQuantity + r.RedisAvailable + r.RedisReserved - r.TotalStock
+		r.Oversell, r.Undersell = max(diff, 0), max(-diff, 0)
+	} else {
+		r.Oversell, r.Undersell = max(r.SoldQuantity-r.TotalStock, 0), r.OrphanQuantity
+		r.Warnings = append(r.Warnings, "stock evicted from Redis: undersell counts orphan deductions only")
+	}
+	r.Net = r.Oversell - r.Undersell
+}
+
+func (r *ReconcileReport) addIssue(issue ReconcileIssue) {
+	r.Issues = append(r.Issues, issue)
+}
+
+// orderSold whether an order holds its stock
+func orderSold(order *model.Order) bool {
+	switch order.Status {
+	case model.OrderStatusPending, model.OrderStatusPaid, model.OrderStatusCompleted:
+		return true
+	}
+	return false
+}
+
+func anySold(orders []*model.Order) bool {
+	for _, order := range orders {
+		if orderSold(order) {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
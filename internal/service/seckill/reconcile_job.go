@@ -0,0 +1,168 @@
+package seckill
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/pkg/log"
+)
+
+// reconcilePendingKey ended activities awaiting reconciliation, scored by end time
+const reconcilePendingKey = "reconcile:pending"
+
+// ScheduleReconciliation queues an ended activity for the reconciliation job
+func ScheduleReconciliation(ctx context.Context, client redis.Cmdable, activityID uint64, endedAt time.Time) error {
+	return client.ZAdd(ctx, reconcilePendingKey, redis.Z{
+		Score:  float64(endedAt.Unix()),
+		Member: strconv.FormatUint(activityID, 10),
+	}).Err()
+}
+
+// ReconcileJob reconciles activities once delay has passed since they ended, writing each
+// report as JSON and CSV under reportDir. The delay lets queued orders land and must stay
+// under the 15 minute lifetime of the Redis deduct logs.
+type ReconcileJob struct {
+	reconciler *Reconciler
+	redis      redis.Cmdable
+	delay      time.Duration
+	reportDir  string
+}
+
+// NewReconcileJob creates a reconciliation job
+func NewReconcileJob(reconciler *Reconciler, client redis.Cmdable, delay time.Duration, reportDir string) *ReconcileJob {
+	return &ReconcileJob{
+		reconciler: reconciler,
+		redis:      client,
+		delay:      delay,
+		reportDir:  reportDir,
+	}
+}
+
+// Run reconciles due activities every interval until ctx is done
+func (j *ReconcileJob) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.WithFields(map[string]interface{}{
+		"interval":   interval.String(),
+		"delay":      j.delay.String(),
+		"report_dir": j.reportDir,
+	}).Info("Reconciliation job started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Reconciliation job stopped")
+			return
+		case <-ticker.C:
+			if _, err := j.RunOnce(ctx); err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+				}).Error("Reconciliation job failed")
+			}
+		}
+	}
+}
+
+// RunOnce reconciles the activities that are due, returning how many reports were written
+func (j *ReconcileJob) RunOnce(ctx context.Context) (int, error) {
+	due, err := j.redis.ZRangeByScore(ctx, reconcilePendingKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Add(-j.delay).Unix(), 10),
+		Count: 10,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, member := range due {
+		// Removing the entry claims it, another instance may have got there first
+		removed, err := j.redis.ZRem(ctx, reconcilePendingKey, member).Result()
+		if err != nil {
+			return written, err
+		}
+		if removed == 0 {
+			continue
+		}
+
+		activityID, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		if err := j.reconcile(ctx, activityID); err != nil {
+			log.WithFields(map[string]interface{}{
+				"activity_id": activityID,
+				"error":       err.Error(),
+			}).Error("Failed to reconcile activity, retrying later")
+			_ = ScheduleReconciliation(ctx, j.redis, activityID, time.Now())
+			continue
+		}
+		written++
+	}
+	return written, nil
+}
+
+// reconcile builds and writes the report of one activity
+func (j *ReconcileJob) reconcile(ctx context.Context, activityID uint64) error {
+	report, err := j.reconciler.Reconcile(ctx, activityID)
+	if err != nil {
+		return err
+	}
+
+	path, err := j.write(report)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		"activity_id": activityID,
+		"sold":        report.SoldQuantity,
+		"total_stock": report.TotalStock,
+		"issues":      len(report.Issues),
+		"oversell":    report.Oversell,
+		"undersell":   report.Undersell,
+		"report":      path,
+	}
+	if len(report.Issues) > 0 || report.Net != 0 {
+		log.WithFields(fields).Warn("Activity reconciliation found discrepancies")
+	} else {
+		log.WithFields(fields).Info("Activity reconciled")
+	}
+	return nil
+}
+
+// write saves the report as JSON and CSV, returning the JSON path
+func (j *ReconcileJob) write(report *ReconcileReport) (string, error) {
+	if err := os.MkdirAll(j.reportDir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(j.reportDir, fmt.Sprintf("reconcile_%d_%s", report.ActivityID, report.GeneratedAt.Format("20060102T150405")))
+
+	if err := writeReportFile(base+".json", report.WriteJSON); err != nil {
+		return "", err
+	}
+	if err := writeReportFile(base+".csv", report.WriteCSV); err != nil {
+		return "", err
+	}
+	return base + ".json", nil
+}
+
+// writeReportFile creates path and fills it with write
+func writeReportFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
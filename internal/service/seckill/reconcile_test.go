@@ -0,0 +1,220 @@
+package seckill
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// reconcileActivityRepo serves one activity
+type reconcileActivityRepo struct {
+	repository.ActivityRepository
+	activity *model.SeckillActivity
+}
+
+func (r *reconcileActivityRepo) GetByID(ctx context.Context, id int64) (*model.SeckillActivity, error) {
+	return r.activity, nil
+}
+
+// reconcileOrderRepo lists a fixed set of orders
+type reconcileOrderRepo struct {
+	repository.OrderRepository
+	orders []*model.Order
+}
+
+func (r *reconcileOrderRepo) ListByActivity(ctx context.Context, activityID, afterID uint64, limit int) ([]*model.Order, error) {
+	var orders []*model.Order
+	for _, order := range r.orders {
+		if order.ID > afterID && len(orders) < limit {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+// reconcileStockLogRepo lists a fixed set of stock logs
+type reconcileStockLogRepo struct {
+	repository.StockLogRepository
+	logs []model.StockLog
+}
+
+func (r *reconcileStockLogRepo) ListByActivity(ctx context.Context, activityID, afterID uint64, limit int) ([]model.StockLog, error) {
+	var logs []model.StockLog
+	for _, log := range r.logs {
+		if log.ID > afterID && len(logs) < limit {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func stockLogFor(id uint64, orderNo string, quantity int) model.StockLog {
+	return model.StockLog{ID: id, ActivityID: 1, OperationType: model.OperationTypeConfirm, Quantity: -quantity, OrderNo: &orderNo}
+}
+
+// setupReconciler sells activity 1 (stock 10) with one of each discrepancy:
+//   - r2 confirmed 2 units in Redis without an order
+//   - ORDER3 has no deduction anywhere
+//   - ORDER4 reuses ORDER1's deduction
+//   - stock_logs confirm ORDER1 twice and ORDER9, which does not exist
+func setupReconciler(t *testing.T) (*Reconciler, *MultiLevelInventory) {
+	inventory, _, _ := setupBloomInventories(t)
+	ctx := context.Background()
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+
+	try := func(requestID string, quantity int) string {
+		result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: requestID, ActivityID: 1, UserID: 7, Quantity: quantity}, 10)
+		require.NoError(t, err)
+		require.True(t, result.Success)
+		return result.DeductID
+	}
+	sold := try("r1", 1)
+	require.NoError(t, inventory.ConfirmDeduct(ctx, sold, 1))
+	require.NoError(t, inventory.ConfirmDeduct(ctx, try("r2", 2), 1))
+	require.NoError(t, inventory.CancelDeduct(ctx, try("r3", 1), 1))
+
+	orders := &reconcileOrderRepo{orders: []*model.Order{
+		{ID: 1, OrderNo: "ORDER1", RequestID: "r1", ActivityID: 1, Quantity: 1, Status: model.OrderStatusPaid, DeductID: sold},
+		{ID: 2, OrderNo: "ORDER2", RequestID: "r3", ActivityID: 1, Quantity: 1, Status: model.OrderStatusCancelled},
+		{ID: 3, OrderNo: "ORDER3", RequestID: "r4", ActivityID: 1, Quantity: 1, Status: model.OrderStatusPending, DeductID: "deduct:r4:1"},
+		{ID: 4, OrderNo: "ORDER4", RequestID: "r5", ActivityID: 1, Quantity: 2, Status: model.OrderStatusPaid, DeductID: sold},
+	}}
+	logs := &reconcileStockLogRepo{logs: []model.StockLog{
+		stockLogFor(1, "ORDER1", 1),
+		stockLogFor(2, "ORDER1", 1),
+		stockLogFor(3, "ORDER4", 2),
+		stockLogFor(4, "ORDER9", 3),
+	}}
+	activities := &reconcileActivityRepo{activity: &model.SeckillActivity{ID: 1, Stock: 10}}
+	return NewReconciler(inventory, activities, orders, logs, 0), inventory
+}
+
+func TestReconciler_FindsDiscrepancies(t *testing.T) {
+	reconciler, _ := setupReconciler(t)
+
+	report, err := reconciler.Reconcile(context.Background(), 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Orders)
+	assert.Equal(t, 4, report.SoldQuantity)
+	assert.Equal(t, 1, report.CancelledQuantity)
+	assert.Equal(t, 3, report.RedisDeductions)
+	assert.Equal(t, 7, report.StockLogConfirmed)
+
+	byKind := make(map[string][]ReconcileIssue)
+	for _, issue := range report.Issues {
+		byKind[issue.Kind+"/"+issue.Source] = append(byKind[issue.Kind+"/"+issue.Source], issue)
+	}
+
+	orphans := byKind[IssueOrphanDeduction+"/"+SourceRedis]
+	require.Len(t, orphans, 1)
+	assert.Equal(t, 2, orphans[0].Quantity)
+	assert.Equal(t, "no order, deduction confirmed", orphans[0].Detail)
+	assert.Equal(t, 2, report.OrphanQuantity)
+
+	logOrphans := byKind[IssueOrphanDeduction+"/"+SourceStockLogs]
+	require.Len(t, logOrphans, 1)
+	assert.Equal(t, "ORDER9", logOrphans[0].OrderNo)
+
+	missing := byKind[IssueOrderWithoutDeduction+"/"+SourceOrders]
+	require.Len(t, missing, 1)
+	assert.Equal(t, "ORDER3", missing[0].OrderNo)
+
+	doubles := byKind[IssueDoubleConfirm+"/"+SourceOrders]
+	require.Len(t, doubles, 1)
+	assert.Equal(t, "ORDER1 ORDER4", doubles[0].OrderNo)
+	assert.Equal(t, 2, doubles[0].Quantity)
+	logDoubles := byKind[IssueDoubleConfirm+"/"+SourceStockLogs]
+	require.Len(t, logDoubles, 1)
+	assert.Equal(t, "ORDER1", logDoubles[0].OrderNo)
+
+	// 4 sold and 7 still in Redis out of 10: one unit oversold
+	assert.True(t, report.RedisStockPresent)
+	assert.Equal(t, 7, report.RedisAvailable)
+	assert.Equal(t, 1, report.Oversell)
+	assert.Equal(t, 0, report.Undersell)
+	assert.Equal(t, 1, report.Net)
+}
+
+func TestReconciler_AfterStockEvicted(t *testing.T) {
+	reconciler, inventory := setupReconciler(t)
+	ctx := context.Background()
+	require.NoError(t, inventory.EvictStock(ctx, 1))
+
+	report, err := reconciler.Reconcile(ctx, 1)
+	require.NoError(t, err)
+
+	// The unsold remainder is gone with the stock, only the orphan units count
+	assert.False(t, report.RedisStockPresent)
+	assert.Equal(t, 0, report.Oversell)
+	assert.Equal(t, 2, report.Undersell)
+	assert.Equal(t, -2, report.Net)
+	assert.NotEmpty(t, report.Warnings)
+}
+
+func TestReconciler_SkipsInFlightTries(t *testing.T) {
+	reconciler, _ := setupReconciler(t)
+	reconciler.inFlight = time.Hour
+
+	report, err := reconciler.Reconcile(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.InFlight)
+	assert.Equal(t, 0, report.OrphanQuantity)
+}
+
+func TestReconcileReport_WriteCSV(t *testing.T) {
+	reconciler, _ := setupReconciler(t)
+	report, err := reconciler.Reconcile(context.Background(), 1)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, len(report.Issues)+1)
+	assert.Equal(t, []string{"activity_id", "kind", "source", "deduct_id", "order_no", "request_id", "quantity", "detail"}, rows[0])
+	assert.Equal(t, "1", rows[1][0])
+}
+
+func TestReconcileJob_ReconcilesScheduledActivities(t *testing.T) {
+	reconciler, inventory := setupReconciler(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	job := NewReconcileJob(reconciler, inventory.redisClient, time.Minute, dir)
+
+	// Not due until the delay has passed
+	require.NoError(t, ScheduleReconciliation(ctx, inventory.redisClient, 1, time.Now()))
+	written, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, written)
+
+	require.NoError(t, ScheduleReconciliation(ctx, inventory.redisClient, 1, time.Now().Add(-2*time.Minute)))
+	written, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, written)
+
+	jsonReports, err := filepath.Glob(filepath.Join(dir, "reconcile_1_*.json"))
+	require.NoError(t, err)
+	require.Len(t, jsonReports, 1)
+	csvReports, err := filepath.Glob(filepath.Join(dir, "reconcile_1_*.csv"))
+	require.NoError(t, err)
+	require.Len(t, csvReports, 1)
+	data, err := os.ReadFile(jsonReports[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"oversell": 1`)
+
+	// Claimed once
+	written, err = job.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, written)
+}
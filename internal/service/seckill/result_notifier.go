@@ -0,0 +1,174 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/pkg/log"
+)
+
+// Result status transitions pushed to clients
+const (
+	ResultStatusAccepted     = "accepted"      // Stock reserved, order queued
+	ResultStatusOrderCreated = "order_created" // Order persisted
+	ResultStatusFailed       = "failed"        // Order creation failed
+	ResultStatusExpired      = "expired"       // Order not paid in time
+)
+
+const (
+	// resultEventChannel pub/sub channel shared by all API instances
+	resultEventChannel = "seckill:result:events"
+	// resultTTL how long a result stays queryable
+	resultTTL = 30 * time.Minute
+	// subscriberBuffer events buffered per stream before dropping
+	subscriberBuffer = 16
+)
+
+// ResultEvent seckill result status transition
+type ResultEvent struct {
+	RequestID string `json:"request_id"`
+	UserID    uint64 `json:"user_id"`
+	Status    string `json:"status"`
+	OrderID   string `json:"order_id,omitempty"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Final whether no further transition follows
+func (e *ResultEvent) Final() bool {
+	return e.Status == ResultStatusOrderCreated || e.Status == ResultStatusFailed || e.Status == ResultStatusExpired
+}
+
+// ResultSubscription a client's subscription to its request IDs
+type ResultSubscription struct {
+	userID     uint64
+	requestIDs []string
+	events     chan *ResultEvent
+}
+
+// Events receives pushed transitions
+func (s *ResultSubscription) Events() <-chan *ResultEvent {
+	return s.events
+}
+
+// ResultNotifier stores seckill results and fans transitions out over Redis pub/sub
+type ResultNotifier struct {
+	redisClient redis.UniversalClient
+
+	mu          sync.RWMutex
+	subscribers map[string]map[*ResultSubscription]struct{} // requestID -> subscriptions
+}
+
+// NewResultNotifier creates a result notifier
+func NewResultNotifier(redisClient redis.UniversalClient) *ResultNotifier {
+	return &ResultNotifier{
+		redisClient: redisClient,
+		subscribers: make(map[string]map[*ResultSubscription]struct{}),
+	}
+}
+
+// seckillResultKey result cache key, also read by QuerySeckillResult
+func seckillResultKey(requestID string, userID uint64) string {
+	return fmt.Sprintf("seckill:result:%s:%d", requestID, userID)
+}
+
+// Publish caches the result for polling clients and pushes the transition to streams
+func (n *ResultNotifier) Publish(ctx context.Context, userID uint64, status string, result *SeckillResult) error {
+	result.Status = status
+	resultData, _ := json.Marshal(result)
+	if err := n.redisClient.SetEx(ctx, seckillResultKey(result.RequestID, userID), resultData, resultTTL).Err(); err != nil {
+		return err
+	}
+
+	event := &ResultEvent{
+		RequestID: result.RequestID,
+		UserID:    userID,
+		Status:    status,
+		OrderID:   result.OrderID,
+		Message:   result.Message,
+		Timestamp: time.Now().Unix(),
+	}
+	eventData, _ := json.Marshal(event)
+	return n.redisClient.Publish(ctx, resultEventChannel, eventData).Err()
+}
+
+// Subscribe registers a stream for the user's request IDs
+func (n *ResultNotifier) Subscribe(userID uint64, requestIDs []string) *ResultSubscription {
+	sub := &ResultSubscription{
+		userID:     userID,
+		requestIDs: requestIDs,
+		events:     make(chan *ResultEvent, subscriberBuffer),
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, requestID := range requestIDs {
+		if n.subscribers[requestID] == nil {
+			n.subscribers[requestID] = make(map[*ResultSubscription]struct{})
+		}
+		n.subscribers[requestID][sub] = struct{}{}
+	}
+	return sub
+}
+
+// Unsubscribe removes a stream
+func (n *ResultNotifier) Unsubscribe(sub *ResultSubscription) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, requestID := range sub.requestIDs {
+		delete(n.subscribers[requestID], sub)
+		if len(n.subscribers[requestID]) == 0 {
+			delete(n.subscribers, requestID)
+		}
+	}
+}
+
+// Run receives transitions from all instances and dispatches them to local streams
+func (n *ResultNotifier) Run(ctx context.Context) {
+	pubsub := n.redisClient.Subscribe(ctx, resultEventChannel)
+	defer pubsub.Close()
+
+	log.WithFields(map[string]interface{}{
+		"channel": resultEventChannel,
+	}).Info("Result notifier started")
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Result notifier stopped")
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var event ResultEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+				}).Warn("Invalid result event")
+				continue
+			}
+			n.dispatch(&event)
+		}
+	}
+}
+
+// dispatch delivers an event to the owner's streams, slow streams drop events
+func (n *ResultNotifier) dispatch(event *ResultEvent) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for sub := range n.subscribers[event.RequestID] {
+		if sub.userID != event.UserID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
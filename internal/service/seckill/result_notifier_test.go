@@ -0,0 +1,66 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultNotifier_PublishAndDispatch(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() {
+		client.Close()
+		mr.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifier := NewResultNotifier(client)
+	go notifier.Run(ctx)
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub(resultEventChannel)[resultEventChannel] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	own := notifier.Subscribe(1, []string{"req-1"})
+	other := notifier.Subscribe(2, []string{"req-1"})
+	defer notifier.Unsubscribe(own)
+	defer notifier.Unsubscribe(other)
+
+	require.NoError(t, notifier.Publish(ctx, 1, ResultStatusOrderCreated, &SeckillResult{
+		Success:   true,
+		RequestID: "req-1",
+		OrderID:   "SK100",
+	}))
+
+	select {
+	case event := <-own.Events():
+		assert.Equal(t, ResultStatusOrderCreated, event.Status)
+		assert.Equal(t, "SK100", event.OrderID)
+		assert.True(t, event.Final())
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	// Another user's stream never sees the event
+	select {
+	case <-other.Events():
+		t.Fatal("event delivered to another user")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Polling clients see the same transition
+	data, err := mr.Get(seckillResultKey("req-1", 1))
+	require.NoError(t, err)
+	var result SeckillResult
+	require.NoError(t, json.Unmarshal([]byte(data), &result))
+	assert.Equal(t, ResultStatusOrderCreated, result.Status)
+}
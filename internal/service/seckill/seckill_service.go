@@ -9,6 +9,9 @@ import (
 
 	"seckill/internal/model"
 	"seckill/internal/repository"
+	"seckill/internal/service/gray"
+	"seckill/internal/service/risk"
+	"seckill/internal/service/vip"
 	"seckill/pkg/breaker"
 	"seckill/pkg/degrade"
 	"seckill/pkg/limiter"
@@ -18,6 +21,13 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Circuit breakers guarding the dependencies of the seckill path
+const (
+	BreakerRedis = "seckill:redis" // Stock deduction
+	BreakerMySQL = "seckill:mysql" // Activity lookup on cache miss
+	BreakerQueue = "seckill:queue" // Order message publish
+)
+
 // SeckillService seckill service interface
 type SeckillService interface {
 	// Execute seckill
@@ -28,17 +38,31 @@ type SeckillService interface {
 
 	// Query seckill result
 	QuerySeckillResult(ctx context.Context, requestID string, userID uint64) (*SeckillResult, error)
+
+	// Query waiting room position
+	QueryQueueStatus(ctx context.Context, activityID, userID uint64) (*WaitingTicket, error)
+
+	// Update activity MaxQPS and MaxConcurrent
+	UpdateActivityLimits(ctx context.Context, activityID uint64, maxQPS, maxConcurrent int) (*model.SeckillActivity, error)
 }
 
 // seckillService seckill service implementation
 type seckillService struct {
 	activityRepo   repository.ActivityRepository
 	inventory      *MultiLevelInventory
+	stock          *InventoryFailover
 	rateLimiter    *limiter.MultiDimensionLimiter
 	circuitBreaker *breaker.Manager
 	degradeManager *degrade.DegradeManager
+	riskEngine     *risk.Engine
+	vipService     vip.VIPService
+	grayController *gray.Controller
 	orderQueue     queue.MessageQueue
-	redis          *redis.Client
+	waitingRoom    *WaitingRoom
+	notifier       *ResultNotifier
+	activityGuard  *ActivityGuard
+	redis          redis.UniversalClient
+	replica        redis.Cmdable
 }
 
 // NewSeckillService creates a seckill service
@@ -48,17 +72,38 @@ func NewSeckillService(
 	rateLimiter *limiter.MultiDimensionLimiter,
 	circuitBreaker *breaker.Manager,
 	degradeManager *degrade.DegradeManager,
+	riskEngine *risk.Engine,
+	vipService vip.VIPService,
+	grayController *gray.Controller,
 	orderQueue queue.MessageQueue,
-	redis *redis.Client,
+	redis redis.UniversalClient,
+	replica redis.Cmdable,
+	stock *InventoryFailover,
 ) SeckillService {
+	// Read-only queries go to the primary unless a replica is given
+	if replica == nil {
+		replica = redis
+	}
+	// Deductions stay on Redis unless a MySQL fallback is given
+	if stock == nil {
+		stock = NewInventoryFailover(inventory, nil, circuitBreaker)
+	}
 	return &seckillService{
 		activityRepo:   activityRepo,
 		inventory:      inventory,
+		stock:          stock,
 		rateLimiter:    rateLimiter,
 		circuitBreaker: circuitBreaker,
 		degradeManager: degradeManager,
+		riskEngine:     riskEngine,
+		vipService:     vipService,
+		grayController: grayController,
 		orderQueue:     orderQueue,
+		waitingRoom:    NewWaitingRoom(redis),
+		notifier:       NewResultNotifier(redis),
+		activityGuard:  NewActivityGuard(redis),
 		redis:          redis,
+		replica:        replica,
 	}
 }
 
@@ -79,11 +124,14 @@ type SeckillResult struct {
 	RequestID string `json:"request_id"`
 	OrderID   string `json:"order_id,omitempty"`
 	Message   string `json:"message"`
-	QueuePos  int    `json:"queue_pos,omitempty"` // Queue position
+	Status    string `json:"status,omitempty"`        // accepted / order_created / failed / expired
+	Challenge bool   `json:"challenge,omitempty"`     // Risk control requires verification before retry
+	QueuePos  int    `json:"queue_pos,omitempty"`     // Queue position
+	EstWait   int    `json:"est_wait_time,omitempty"` // Estimated wait (seconds)
 }
 
 // DoSeckill execute seckill (16-step complete process)
-func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*SeckillResult, error) {
+func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (result *SeckillResult, err error) {
 	startTime := time.Now()
 	activityID := req.ActivityID
 	userID := req.UserID
@@ -97,12 +145,12 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 	// ========== Step 1: Idempotency check ==========
 	resultKey := fmt.Sprintf("seckill:result:%s:%d", req.RequestID, userID)
 	if existingResult, err := s.redis.Get(ctx, resultKey).Bytes(); err == nil {
-		var result SeckillResult
-		json.Unmarshal(existingResult, &result)
+		var cached SeckillResult
+		json.Unmarshal(existingResult, &cached)
 		log.WithFields(map[string]interface{}{
 			"request_id": req.RequestID,
 		}).Info("Return idempotent result")
-		return &result, nil
+		return &cached, nil
 	}
 
 	// ========== Step 2: Parameter validation ==========
@@ -119,15 +167,17 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 	}
 
 	// ========== Step 4: Circuit breaker check ==========
-	cbName := fmt.Sprintf("activity:%d", activityID)
-	if s.circuitBreaker.State(cbName) == breaker.StateOpen {
+	// Nothing can be sold while Redis is tripped without a MySQL fallback, reject before doing any work
+	if !s.stock.Available() {
 		log.WithFields(map[string]interface{}{
 			"activity_id": activityID,
+			"breaker":     BreakerRedis,
 		}).Warn("Circuit breaker is open")
 		return s.failResult(req.RequestID, "System busy, please try again later"), nil
 	}
 
 	// ========== Step 5: Degradation check ==========
+	fromWaitingRoom := false
 	if s.degradeManager.IsDegrade(ctx, activityID) {
 		strategy := s.degradeManager.GetStrategy(ctx, activityID)
 		log.WithFields(map[string]interface{}{
@@ -137,27 +187,46 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 
 		switch strategy.Type {
 		case "queue_only":
-			return &SeckillResult{
-				Success:   false,
-				RequestID: req.RequestID,
-				Message:   strategy.Message,
-				QueuePos:  strategy.EstWaitTime,
-			}, nil
+			// Only tickets admitted by the waiting room continue
+			ticket, err := s.waitingRoom.Enter(ctx, activityID, userID, strategy.AdmitRate)
+			if err != nil {
+				log.WithFields(map[string]interface{}{
+					"activity_id": activityID,
+					"error":       err.Error(),
+				}).Error("Failed to enter waiting room")
+				return s.failResult(req.RequestID, strategy.Message), nil
+			}
+			if !ticket.Admitted {
+				return &SeckillResult{
+					Success:   false,
+					RequestID: req.RequestID,
+					Message:   strategy.Message,
+					QueuePos:  int(ticket.QueuePos),
+					EstWait:   ticket.EstWaitTime,
+				}, nil
+			}
+			fromWaitingRoom = true
 		case "lottery":
-			// Implement lottery logic based on ratio
-			// For now, return error
-			return s.failResult(req.RequestID, strategy.Message), nil
+			// Admit a stable fraction of users, the rest continue to be rejected
+			if !s.degradeManager.DrawLottery(ctx, activityID, userID, strategy.Ratio) {
+				log.WithFields(map[string]interface{}{
+					"activity_id": activityID,
+					"user_id":     userID,
+					"ratio":       strategy.Ratio,
+				}).Info("User not selected in lottery")
+				return s.failResult(req.RequestID, "Not selected in lottery, please try again later"), nil
+			}
 		default: // return_error
 			return s.failResult(req.RequestID, strategy.Message), nil
 		}
 	}
 
 	// ========== Step 6: Multi-dimension rate limiting ==========
+	// Activity level limits come from the activity record, see Step 10
 	dimensions := map[string]string{
-		"global":   fmt.Sprintf("global:%d", activityID),
-		"user":     fmt.Sprintf("%d", userID),
-		"ip":       req.IP,
-		"activity": fmt.Sprintf("%d", activityID),
+		"global": fmt.Sprintf("global:%d", activityID),
+		"user":   fmt.Sprintf("%d", userID),
+		"ip":     req.IP,
 	}
 
 	if allowed, err := s.rateLimiter.Allow(ctx, dimensions); err != nil || !allowed {
@@ -168,11 +237,16 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 		return s.failResult(req.RequestID, "Request too frequent, please try again later"), nil
 	}
 
+	// Requests past the fast-path rejections feed automatic degradation
+	defer func() {
+		s.degradeManager.RecordRequest(activityID, time.Since(startTime), err != nil)
+	}()
+
 	// ========== Step 7: Activity validity check ==========
 	// First try to get from Redis cache
 	var activity *model.SeckillActivity
 	configKey := fmt.Sprintf("activity:config:%d", activityID)
-	if configData, err := s.redis.Get(ctx, configKey).Bytes(); err == nil {
+	if configData, err := s.replica.Get(ctx, configKey).Bytes(); err == nil {
 		// Found in cache, unmarshal
 		if err := json.Unmarshal(configData, &activity); err == nil {
 			log.WithFields(map[string]interface{}{
@@ -185,13 +259,25 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 
 	// If not found in cache, query from database
 	if activity == nil {
-		var err error
-		activity, err = s.activityRepo.GetByID(ctx, int64(activityID))
+		var lookupErr error
+		err = s.circuitBreaker.Execute(ctx, BreakerMySQL, func() error {
+			activity, lookupErr = s.activityRepo.GetByID(ctx, int64(activityID))
+			// Unknown activity is a caller error, not a database failure
+			if errors.Is(lookupErr, repository.ErrActivityNotFound) {
+				return nil
+			}
+			return lookupErr
+		})
+		if err == nil {
+			err = lookupErr
+		}
+		if breaker.IsCircuitBreakerError(err) {
+			return s.breakerResult(req.RequestID, BreakerMySQL), nil
+		}
 		if err != nil {
 			log.WithFields(map[string]interface{}{
 				"error": err.Error(),
 			}).Error("Failed to query activity")
-			s.recordCircuitBreakerError(cbName)
 			return nil, err
 		}
 		log.WithFields(map[string]interface{}{
@@ -199,27 +285,51 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 		}).Debug("Activity loaded from database")
 	}
 
-	if !activity.IsRunning() {
+	// VIP tiers may enter before StartTime
+	benefits := vip.BenefitsFor(activity, s.getUserVIPTier(ctx, userID))
+	if !benefits.CanEnter(activity, time.Now()) {
 		return s.failResult(req.RequestID, "Activity not started or ended"), nil
 	}
 
 	// ========== Step 8: Gray control ==========
-	if !s.checkGrayControl(ctx, activity, int64(userID)) {
+	if !s.checkGrayControl(ctx, activity, req) {
 		log.WithFields(map[string]interface{}{
-			"user_id": userID,
-		}).Info("User not in gray whitelist")
+			"activity_id": activityID,
+			"user_id":     userID,
+		}).Info("User not in gray rollout")
 		return s.failResult(req.RequestID, "Activity not available for you"), nil
 	}
 
 	// ========== Step 9: User eligibility verification (risk control) ==========
-	if !s.checkUserEligibility(ctx, activityID, userID) {
+	riskResult := s.checkUserEligibility(ctx, activity, req)
+	switch riskResult.Decision {
+	case risk.DecisionDeny:
 		log.WithFields(map[string]interface{}{
-			"user_id": userID,
+			"user_id":     userID,
+			"rejected_by": riskResult.RejectedBy,
 		}).Warn("User eligibility verification failed")
 		return s.failResult(req.RequestID, "You are not eligible for this activity"), nil
+	case risk.DecisionChallenge:
+		result := s.failResult(req.RequestID, "Verification required, please complete it and retry")
+		result.Challenge = true
+		return result, nil
+	}
+
+	// ========== Step 10: Activity MaxQPS and MaxConcurrent ==========
+	// Slot is held until the order message is queued, deferred so panics release it too
+	release, err := s.activityGuard.Acquire(ctx, activity)
+	if err != nil {
+		if !errors.Is(err, ErrActivityQPSExceeded) && !errors.Is(err, ErrActivityConcurrencyExceeded) {
+			log.WithFields(map[string]interface{}{
+				"activity_id": activityID,
+				"error":       err.Error(),
+			}).Error("Failed to check activity limits")
+		}
+		return s.failResult(req.RequestID, "System busy, please try again later"), nil
 	}
+	defer release()
 
-	// ========== Step 10: TCC-Try phase with purchase limit check ==========
+	// ========== Step 11: TCC-Try phase with purchase limit check ==========
 	//  need to check limit and deduct in one step ,otherwise a user can bypass per user limit
 
 	deductReq := &DeductRequest{
@@ -229,12 +339,15 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 		Quantity:   req.Quantity,
 	}
 
-	deductResult, err := s.inventory.TryDeductWithLimit(ctx, deductReq, activity.LimitPerUser)
+	// Runs on Redis, or on MySQL while the Redis breaker is open
+	deductResult, err := s.stock.TryDeductWithLimit(ctx, deductReq, benefits.LimitPerUser(activity))
+	if breaker.IsCircuitBreakerError(err) {
+		return s.breakerResult(req.RequestID, BreakerRedis), nil
+	}
 	if err != nil {
 		log.WithFields(map[string]interface{}{
 			"error": err.Error(),
 		}).Error("Stock deduction failed")
-		s.recordCircuitBreakerError(cbName)
 		return nil, err
 	}
 
@@ -246,9 +359,15 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 		return s.failResult(req.RequestID, deductResult.Message), nil
 	}
 
-	// ========== Step 11: Generate pre-order and send to message queue ==========
-	// Determine if user is VIP (simplified check, can be enhanced)
-	isVIP := s.checkUserVIPStatus(ctx, userID)
+	// Admitted ticket is single use
+	if fromWaitingRoom {
+		s.waitingRoom.Consume(ctx, activityID, userID)
+	}
+
+	// ========== Step 12: Generate pre-order and send to message queue ==========
+	// Priority tiers are routed to their own queue
+	queueTopic := benefits.OrderTopic()
+	isVIP := queueTopic != model.OrderTopicNormal
 
 	orderMsg := &model.OrderMessage{
 		RequestID:  req.RequestID,
@@ -259,20 +378,18 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 		Price:      activity.Price,
 		DeductID:   deductResult.DeductID,
 		IsVIP:      isVIP,
+		VIPTier:    benefits.Tier,
 		IP:         req.IP,
 		DeviceID:   req.DeviceID,
 		Timestamp:  time.Now().Unix(),
 		TraceID:    req.RequestID, // Use request ID as trace ID
 	}
 
-	// Route to VIP queue or normal queue based on VIP status
-	queueTopic := "seckill_orders"
-	if isVIP {
-		queueTopic = "seckill_orders_vip"
-	}
-
 	orderData, _ := json.Marshal(orderMsg)
-	if err := s.orderQueue.Publish(ctx, queueTopic, orderData); err != nil {
+	err = s.circuitBreaker.Execute(ctx, BreakerQueue, func() error {
+		return s.orderQueue.Publish(ctx, queueTopic, orderData)
+	})
+	if err != nil {
 		log.WithFields(map[string]interface{}{
 			"error":  err.Error(),
 			"queue":  queueTopic,
@@ -280,8 +397,10 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 		}).Error("Failed to send order message")
 
 		// Rollback stock (TCC-Cancel)
-		s.inventory.CancelDeduct(ctx, deductResult.DeductID, activityID)
-		s.recordCircuitBreakerError(cbName)
+		s.stock.CancelDeduct(ctx, deductResult.DeductID, activityID)
+		if breaker.IsCircuitBreakerError(err) {
+			return s.breakerResult(req.RequestID, BreakerQueue), nil
+		}
 		return nil, err
 	}
 
@@ -291,27 +410,30 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 		"is_vip":     isVIP,
 	}).Info("Order message sent to queue")
 
-	// ========== Step 12: Record user purchase count ==========
+	// ========== Step 13: Record user purchase count ==========
 	// Note: Purchase count is now incremented atomically in TryDeductWithLimit
 	// s.incrUserPurchaseCount(ctx, activityID, userID, req.Quantity)
 
-	// ========== Step 13: Record seckill log ==========
+	// ========== Step 14: Record seckill log ==========
 	s.recordSeckillLog(ctx, req, deductResult.DeductID, "success")
 
-	// ========== Step 14: Construct success result ==========
-	result := &SeckillResult{
+	// ========== Step 15: Construct success result ==========
+	result = &SeckillResult{
 		Success:   true,
 		RequestID: req.RequestID,
 		OrderID:   "", // Order ID will be generated asynchronously by order service
 		Message:   "Seckill successful, order processing",
 	}
 
-	// ========== Step 15: Cache result (idempotency guarantee) ==========
-	resultData, _ := json.Marshal(result)
-	s.redis.SetEx(ctx, resultKey, resultData, 30*time.Minute)
+	// ========== Step 16: Cache result (idempotency guarantee) and push to result streams ==========
+	if err := s.notifier.Publish(ctx, userID, ResultStatusAccepted, result); err != nil {
+		log.WithFields(map[string]interface{}{
+			"request_id": req.RequestID,
+			"error":      err.Error(),
+		}).Warn("Failed to publish seckill result")
+	}
 
 	// ========== Step 17: Record success metrics ==========
-	s.recordCircuitBreakerSuccess(cbName)
 	duration := time.Since(startTime)
 
 	log.WithFields(map[string]interface{}{
@@ -323,55 +445,66 @@ func (s *seckillService) DoSeckill(ctx context.Context, req *SeckillRequest) (*S
 }
 
 // checkGrayControl gray control check
-func (s *seckillService) checkGrayControl(ctx context.Context, activity *model.SeckillActivity, userID int64) bool {
-	// If gray ratio is 0, allow all
-	if activity.GrayRatio == 0 {
+func (s *seckillService) checkGrayControl(ctx context.Context, activity *model.SeckillActivity, req *SeckillRequest) bool {
+	if s.grayController == nil {
 		return true
 	}
+	return s.grayController.Admit(ctx, activity, req.UserID, req.IP)
+}
 
-	// Check if in whitelist
-	if activity.GrayWhitelist != nil {
-		for _, id := range activity.GrayWhitelist {
-			if uid, ok := id.(float64); ok && int64(uid) == userID {
-				return true
+// checkUserEligibility user eligibility verification
+func (s *seckillService) checkUserEligibility(ctx context.Context, activity *model.SeckillActivity, req *SeckillRequest) *risk.Result {
+	activityID, userID := req.ActivityID, req.UserID
+	denied := &risk.Result{
+		RequestID:  req.RequestID,
+		ActivityID: activityID,
+		UserID:     userID,
+		Decision:   risk.DecisionDeny,
+		RejectedBy: "blacklist",
+		Timestamp:  time.Now().Unix(),
+	}
+
+	// Check blacklist, then activity blacklist
+	blacklistKeys := []string{
+		model.BlacklistCacheKey(userID, 0),
+		model.BlacklistCacheKey(userID, activityID),
+	}
+	for _, blacklistKey := range blacklistKeys {
+		if exists, _ := s.redis.Exists(ctx, blacklistKey).Result(); exists > 0 {
+			if s.riskEngine != nil {
+				s.riskEngine.Record(ctx, denied)
 			}
+			return denied
 		}
 	}
 
-	// Random allow based on gray ratio
-	if activity.GrayRatio >= 1.0 {
-		return true
+	// Risk rules (account age, level, velocity, shared device)
+	if s.riskEngine == nil {
+		return &risk.Result{RequestID: req.RequestID, ActivityID: activityID, UserID: userID, Decision: risk.DecisionAllow}
 	}
-
-	// Use user ID hash to determine if in gray range
-	hash := userID % 100
-	return float64(hash) < activity.GrayRatio*100
+	return s.riskEngine.Evaluate(ctx, activity, &risk.Request{
+		RequestID:  req.RequestID,
+		ActivityID: activityID,
+		UserID:     userID,
+		IP:         req.IP,
+		DeviceID:   req.DeviceID,
+	})
 }
 
-// checkUserEligibility user eligibility verification
-func (s *seckillService) checkUserEligibility(ctx context.Context, activityID, userID uint64) bool {
-	// Check blacklist
-	blacklistKey := fmt.Sprintf("blacklist:user:%d", userID)
-	if exists, _ := s.redis.Exists(ctx, blacklistKey).Result(); exists > 0 {
-		return false
+// getUserVIPTier gets the user's VIP tier, failures fall back to no tier
+func (s *seckillService) getUserVIPTier(ctx context.Context, userID uint64) model.VIPTier {
+	if s.vipService == nil {
+		return model.VIPTierNone
 	}
-
-	// Check activity blacklist
-	activityBlacklistKey := fmt.Sprintf("blacklist:activity:%d:user:%d", activityID, userID)
-	if exists, _ := s.redis.Exists(ctx, activityBlacklistKey).Result(); exists > 0 {
-		return false
+	tier, err := s.vipService.GetTier(ctx, userID)
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Failed to get vip tier")
+		return model.VIPTierNone
 	}
-
-	// Can add more risk control checks...
-	return true
-}
-
-// checkUserVIPStatus checks if user is VIP
-func (s *seckillService) checkUserVIPStatus(ctx context.Context, userID uint64) bool {
-	// Check VIP status from Redis
-	vipKey := fmt.Sprintf("user:vip:%d", userID)
-	exists, _ := s.redis.Exists(ctx, vipKey).Result()
-	return exists > 0
+	return tier
 }
 
 // getUserPurchaseCount get user purchase count
@@ -419,14 +552,13 @@ func (s *seckillService) failResult(requestID, message string) *SeckillResult {
 	}
 }
 
-// recordCircuitBreakerSuccess record circuit breaker success
-func (s *seckillService) recordCircuitBreakerSuccess(name string) {
-	// Circuit breaker will record automatically in Execute method
-}
-
-// recordCircuitBreakerError record circuit breaker error
-func (s *seckillService) recordCircuitBreakerError(name string) {
-	// Circuit breaker will record automatically in Execute method
+// breakerResult failure result for a request rejected by an open circuit breaker
+func (s *seckillService) breakerResult(requestID, name string) *SeckillResult {
+	log.WithFields(map[string]interface{}{
+		"request_id": requestID,
+		"breaker":    name,
+	}).Warn("Circuit breaker rejected request")
+	return s.failResult(requestID, "System busy, please try again later")
 }
 
 // PrewarmActivity prewarm activity
@@ -452,7 +584,7 @@ func (s *seckillService) PrewarmActivity(ctx context.Context, activityID uint64)
 		"activity_id": activityID,
 		"stock":       activity.Stock,
 	}).Info("Syncing stock to Redis")
-	if err := s.inventory.SyncToRedis(ctx, activityID, activity.Stock); err != nil {
+	if err := s.inventory.SyncShardsToRedis(ctx, activityID, activity.Stock, ShardLayoutOf(activity)); err != nil {
 		return err
 	}
 
@@ -467,10 +599,78 @@ func (s *seckillService) PrewarmActivity(ctx context.Context, activityID uint64)
 	return nil
 }
 
+// UpdateActivityLimits updates activity MaxQPS and MaxConcurrent, 0 means unlimited
+func (s *seckillService) UpdateActivityLimits(ctx context.Context, activityID uint64, maxQPS, maxConcurrent int) (*model.SeckillActivity, error) {
+	if maxQPS < 0 || maxConcurrent < 0 {
+		return nil, errors.New("limits must not be negative")
+	}
+
+	activity, err := s.activityRepo.GetByID(ctx, int64(activityID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.activityRepo.UpdateLimits(ctx, int64(activityID), maxQPS, maxConcurrent); err != nil {
+		return nil, err
+	}
+	activity.MaxQPS = maxQPS
+	activity.MaxConcurrent = maxConcurrent
+
+	// Limits are read from the cached config, rewrite them so every instance applies them on the next request
+	s.setCachedLimits(ctx, activityID, maxQPS, maxConcurrent)
+
+	log.WithFields(map[string]interface{}{
+		"activity_id":    activityID,
+		"max_qps":        maxQPS,
+		"max_concurrent": maxConcurrent,
+	}).Info("Activity limits updated")
+
+	return activity, nil
+}
+
+// setCachedLimits rewrites the limits of the cached activity config. The rest of the config
+// and its TTL stay as prewarm left them, a missing config is left missing since requests
+// load it from MySQL.
+func (s *seckillService) setCachedLimits(ctx context.Context, activityID uint64, maxQPS, maxConcurrent int) {
+	configKey := fmt.Sprintf("activity:config:%d", activityID)
+	data, err := s.redis.Get(ctx, configKey).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.WithFields(map[string]interface{}{
+				"activity_id": activityID,
+				"error":       err.Error(),
+			}).Warn("Failed to read activity config cache")
+		}
+		return
+	}
+
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		// Unreadable, requests reload it from MySQL
+		s.redis.Del(ctx, configKey)
+		return
+	}
+	config["max_qps"], _ = json.Marshal(maxQPS)
+	config["max_concurrent"], _ = json.Marshal(maxConcurrent)
+	data, _ = json.Marshal(config)
+
+	err = s.redis.SetArgs(ctx, configKey, data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activityID,
+			"error":       err.Error(),
+		}).Warn("Failed to refresh activity config cache")
+	}
+}
+
 // QuerySeckillResult query seckill result
 func (s *seckillService) QuerySeckillResult(ctx context.Context, requestID string, userID uint64) (*SeckillResult, error) {
 	resultKey := fmt.Sprintf("seckill:result:%s:%d", requestID, userID)
-	data, err := s.redis.Get(ctx, resultKey).Bytes()
+	data, err := s.replica.Get(ctx, resultKey).Bytes()
+	if errors.Is(err, redis.Nil) && s.replica != s.redis {
+		// The result may not have replicated yet
+		data, err = s.redis.Get(ctx, resultKey).Bytes()
+	}
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, errors.New("seckill result not found")
@@ -486,4 +686,19 @@ func (s *seckillService) QuerySeckillResult(ctx context.Context, requestID strin
 	return &result, nil
 }
 
+// QueryQueueStatus query waiting room position
+func (s *seckillService) QueryQueueStatus(ctx context.Context, activityID, userID uint64) (*WaitingTicket, error) {
+	strategy := s.degradeManager.GetStrategy(ctx, activityID)
+
+	ticket, err := s.waitingRoom.Status(ctx, activityID, userID, strategy.AdmitRate)
+	if err != nil {
+		return nil, err
+	}
+	if ticket == nil {
+		return nil, ErrNotInWaitingRoom
+	}
+
+	return ticket, nil
+}
+
 // OrderMessage has been moved to internal/model/message.go
@@ -2,11 +2,21 @@ package seckill
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"seckill/internal/model"
+	redisx "seckill/internal/redis"
+	"seckill/internal/repository"
+	"seckill/pkg/breaker"
+	"seckill/pkg/degrade"
+	"seckill/pkg/limiter"
+	"seckill/pkg/queue"
 )
 
 func TestSeckillRequest_Validation(t *testing.T) {
@@ -261,4 +271,168 @@ func TestSuccessResult(t *testing.T) {
 	assert.Equal(t, "test-123", result.RequestID)
 	assert.Equal(t, "order-456", result.OrderID)
 	assert.Equal(t, "秒杀成功，订单处理中", result.Message)
-}
\ No newline at end of file
+}
+// flakyActivityRepo fails lookups while err is set
+type flakyActivityRepo struct {
+	repository.ActivityRepository
+	err   error
+	calls int
+}
+
+func (r *flakyActivityRepo) GetByID(ctx context.Context, id int64) (*model.SeckillActivity, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return nil, repository.ErrActivityNotFound
+}
+
+func setupBreakerService(t *testing.T, repo repository.ActivityRepository) (SeckillService, *breaker.Manager) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	redisx.RejectCrossSlot(client)
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	inventory, err := NewMultiLevelInventory(client)
+	require.NoError(t, err)
+	passBloom(inventory, 1)
+
+	breakers := breaker.NewManager(breaker.Config{
+		MaxRequests: 1,
+		Timeout:     50 * time.Millisecond,
+		ReadyToTrip: func(counts breaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 3
+		},
+	})
+	service := NewSeckillService(
+		repo,
+		inventory,
+		limiter.NewMultiDimensionLimiter(client),
+		breakers,
+		degrade.NewDegradeManager(client),
+		nil,
+		nil,
+		nil,
+		queue.NewMemoryMessageQueue(),
+		client,
+		nil,
+		nil,
+	)
+	return service, breakers
+}
+
+func TestDoSeckill_ActivityLookupBreaker(t *testing.T) {
+	repo := &flakyActivityRepo{err: errors.New("connection refused")}
+	service, breakers := setupBreakerService(t, repo)
+	ctx := context.Background()
+
+	request := func(id string) (*SeckillResult, error) {
+		return service.DoSeckill(ctx, &SeckillRequest{RequestID: id, ActivityID: 1, UserID: 1, Quantity: 1})
+	}
+
+	// Real failures surface as errors until the breaker trips
+	for i := 0; i < 3; i++ {
+		_, err := request("req")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, breaker.StateOpen, breakers.State(BreakerMySQL))
+
+	// Open breaker sheds the lookup without touching the database
+	result, err := request("req")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "System busy, please try again later", result.Message)
+	assert.Equal(t, 3, repo.calls)
+
+	// Half-open probe succeeds once the database is back, unknown activities do not count as failures
+	repo.err = nil
+	time.Sleep(60 * time.Millisecond)
+	_, err = request("req")
+	assert.ErrorIs(t, err, repository.ErrActivityNotFound)
+	assert.Equal(t, 4, repo.calls)
+	assert.Equal(t, breaker.StateClosed, breakers.State(BreakerMySQL))
+}
+
+func TestQuerySeckillResult_ReplicaReads(t *testing.T) {
+	primaryServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer primaryServer.Close()
+	replicaServer, err := miniredis.Run()
+	require.NoError(t, err)
+	defer replicaServer.Close()
+
+	primary := redis.NewClient(&redis.Options{Addr: primaryServer.Addr()})
+	defer primary.Close()
+	replica := redis.NewClient(&redis.Options{Addr: replicaServer.Addr()})
+	defer replica.Close()
+
+	service := &seckillService{redis: primary, replica: replica}
+	ctx := context.Background()
+
+	// Served by the replica once replicated
+	require.NoError(t, replicaServer.Set("seckill:result:r1:1", `{"success":true,"order_id":"o1"}`))
+	result, err := service.QuerySeckillResult(ctx, "r1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "o1", result.OrderID)
+
+	// Falls back to the primary while the replica lags behind
+	require.NoError(t, primaryServer.Set("seckill:result:r2:1", `{"success":true,"order_id":"o2"}`))
+	result, err = service.QuerySeckillResult(ctx, "r2", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "o2", result.OrderID)
+
+	_, err = service.QuerySeckillResult(ctx, "r3", 1)
+	assert.Error(t, err)
+}
+
+// limitsActivityRepo records limit updates of one activity
+type limitsActivityRepo struct {
+	repository.ActivityRepository
+	activity *model.SeckillActivity
+}
+
+func (r *limitsActivityRepo) GetByID(ctx context.Context, id int64) (*model.SeckillActivity, error) {
+	copied := *r.activity
+	return &copied, nil
+}
+
+func (r *limitsActivityRepo) UpdateLimits(ctx context.Context, id int64, maxQPS, maxConcurrent int) error {
+	r.activity.MaxQPS = maxQPS
+	r.activity.MaxConcurrent = maxConcurrent
+	return nil
+}
+
+func TestUpdateActivityLimits_PatchesCachedConfig(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	repo := &limitsActivityRepo{activity: &model.SeckillActivity{ID: 1, MaxQPS: 100, MaxConcurrent: 50}}
+	service := &seckillService{activityRepo: repo, redis: client}
+	ctx := context.Background()
+
+	// Prewarmed config carries more than the activity row and expires with the activity
+	mr.Set("activity:config:1", `{"id":1,"goods":{"id":3},"max_qps":100,"max_concurrent":50}`)
+	mr.SetTTL("activity:config:1", 90*time.Minute)
+
+	activity, err := service.UpdateActivityLimits(ctx, 1, 200, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 200, activity.MaxQPS)
+
+	data, err := mr.Get("activity:config:1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"goods":{"id":3},"max_qps":200,"max_concurrent":0}`, data)
+	assert.Equal(t, 90*time.Minute, mr.TTL("activity:config:1"))
+
+	// Unprewarmed activities stay uncached
+	mr.Del("activity:config:1")
+	_, err = service.UpdateActivityLimits(ctx, 1, 300, 10)
+	require.NoError(t, err)
+	assert.False(t, mr.Exists("activity:config:1"))
+}
@@ -0,0 +1,161 @@
+package seckill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/pkg/log"
+)
+
+// stockEventChannel pub/sub channel of stock availability transitions shared by all API instances
+const stockEventChannel = "seckill:stock:events"
+
+// Stock availability transitions
+const (
+	StockEventSoldOut = "sold_out" // Authoritative stock exhausted
+	StockEventInStock = "in_stock" // Stock synced or returned by a cancellation
+)
+
+// StockEvent activity stock availability transition
+type StockEvent struct {
+	Type       string `json:"type"`
+	ActivityID uint64 `json:"activity_id"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// publishStockEvent is best effort, subscribers resync from Redis after reconnecting
+func (m *MultiLevelInventory) publishStockEvent(ctx context.Context, eventType string, activityID uint64) {
+	data, _ := json.Marshal(&StockEvent{
+		Type:       eventType,
+		ActivityID: activityID,
+		Timestamp:  time.Now().Unix(),
+	})
+	if err := m.redisClient.Publish(ctx, stockEventChannel, data).Err(); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activityID,
+			"type":        eventType,
+			"error":       err.Error(),
+		}).Warn("Failed to publish stock event")
+	}
+}
+
+// applyStockEvent updates the L1 cache, the shared bloom filter was updated by the publisher
+func (m *MultiLevelInventory) applyStockEvent(event *StockEvent) {
+	switch event.Type {
+	case StockEventSoldOut:
+		m.localCache.Set(soldOutKey(event.ActivityID), []byte("1"))
+		m.cacheBloomResult(event.ActivityID, false)
+	case StockEventInStock:
+		m.localCache.Delete(soldOutKey(event.ActivityID))
+		m.cacheBloomResult(event.ActivityID, true)
+	}
+}
+
+// markSoldOutIfEmpty marks the activity sold out once authoritative stock is exhausted,
+// a failed deduction alone may only have asked for more than is left
+func (m *MultiLevelInventory) markSoldOutIfEmpty(ctx context.Context, activityID uint64) {
+	stock, err := m.GetStockFromRedis(ctx, activityID)
+	if err != nil || stock > 0 {
+		return
+	}
+	if err := m.MarkSoldOut(ctx, activityID); err != nil {
+		log.WithFields(map[string]interface{}{
+			"activity_id": activityID,
+			"error":       err.Error(),
+		}).Warn("Failed to mark activity sold out")
+	}
+}
+
+// resyncLocalCache rebuilds the L1 view of every activity this instance has checked from
+// authoritative stock, restoring filter members lost to a sold out/restock race on the way
+func (m *MultiLevelInventory) resyncLocalCache(ctx context.Context) {
+	m.bloomCache.Range(func(key, _ interface{}) bool {
+		activityID := key.(uint64)
+		stock, err := m.GetStockFromRedis(ctx, activityID)
+		switch {
+		case errors.Is(err, redis.Nil):
+			// Not synced or evicted, the shared bloom filter decides
+			m.bloomCache.Delete(activityID)
+		case err != nil:
+			log.WithFields(map[string]interface{}{
+				"activity_id": activityID,
+				"error":       err.Error(),
+			}).Warn("Failed to resync activity stock")
+		case stock > 0:
+			if err := m.AddToBloomFilter(ctx, activityID); err != nil {
+				log.WithFields(map[string]interface{}{
+					"activity_id": activityID,
+					"error":       err.Error(),
+				}).Warn("Failed to resync activity stock")
+			}
+		default:
+			m.applyStockEvent(&StockEvent{Type: StockEventSoldOut, ActivityID: activityID})
+		}
+		return true
+	})
+}
+
+// StockBroadcaster keeps this instance's L1 sold out cache in line with the cluster
+type StockBroadcaster struct {
+	inventory   *MultiLevelInventory
+	redisClient redis.UniversalClient
+}
+
+// NewStockBroadcaster creates a stock broadcaster
+func NewStockBroadcaster(inventory *MultiLevelInventory, redisClient redis.UniversalClient) *StockBroadcaster {
+	return &StockBroadcaster{
+		inventory:   inventory,
+		redisClient: redisClient,
+	}
+}
+
+// Run applies stock events from all instances. Events published while disconnected are
+// lost, so every (re)subscription resyncs the local cache from Redis.
+func (b *StockBroadcaster) Run(ctx context.Context) {
+	pubsub := b.redisClient.Subscribe(ctx, stockEventChannel)
+	defer pubsub.Close()
+
+	log.WithFields(map[string]interface{}{
+		"channel": stockEventChannel,
+	}).Info("Stock broadcaster started")
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Info("Stock broadcaster stopped")
+				return
+			}
+			// The next Receive reconnects and resubscribes
+			log.WithFields(map[string]interface{}{
+				"error": err.Error(),
+			}).Warn("Stock event subscription interrupted")
+			select {
+			case <-ctx.Done():
+				log.Info("Stock broadcaster stopped")
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				b.inventory.resyncLocalCache(ctx)
+			}
+		case *redis.Message:
+			var event StockEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+				}).Warn("Invalid stock event")
+				continue
+			}
+			b.inventory.applyStockEvent(&event)
+		}
+	}
+}
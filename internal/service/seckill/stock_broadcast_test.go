@@ -0,0 +1,72 @@
+package seckill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStockBroadcaster_SoldOutAndRestock(t *testing.T) {
+	first, second, mr := setupBloomInventories(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	go NewStockBroadcaster(second, client).Run(ctx)
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub(stockEventChannel)[stockEventChannel] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, first.SyncShardsToRedis(ctx, 5, 1, NewShardLayout(1, "")))
+	require.Eventually(t, func() bool { return second.LocalCheck(ctx, 5) }, time.Second, 10*time.Millisecond)
+
+	// Draining the stock on one instance turns the activity away on the other
+	sold, err := first.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 5, UserID: 1, Quantity: 1}, 5)
+	require.NoError(t, err)
+	require.True(t, sold.Success)
+	failed, err := first.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r2", ActivityID: 5, UserID: 2, Quantity: 1}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "insufficient_stock", failed.Message)
+	assert.Eventually(t, func() bool { return !second.LocalCheck(ctx, 5) }, time.Second, 10*time.Millisecond)
+
+	// The cancellation returns the unit everywhere
+	require.NoError(t, first.CancelDeduct(ctx, sold.DeductID, 5))
+	assert.True(t, first.LocalCheck(ctx, 5))
+	assert.Eventually(t, func() bool { return second.LocalCheck(ctx, 5) }, time.Second, 10*time.Millisecond)
+}
+
+func TestStockBroadcaster_PartialShortageIsNotSoldOut(t *testing.T) {
+	inventory, _, _ := setupBloomInventories(t)
+	ctx := context.Background()
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 6, 1, NewShardLayout(1, "")))
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 6, UserID: 1, Quantity: 2}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "insufficient_stock", result.Message)
+	assert.True(t, inventory.LocalCheck(ctx, 6))
+}
+
+func TestResyncLocalCache(t *testing.T) {
+	inventory, _, mr := setupBloomInventories(t)
+	ctx := context.Background()
+
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 7, 3, NewShardLayout(1, "")))
+	require.NoError(t, inventory.SyncShardsToRedis(ctx, 8, 3, NewShardLayout(1, "")))
+	require.NoError(t, inventory.MarkSoldOut(ctx, 7))
+
+	// Events missed while disconnected: 7 restocked, 8 drained
+	require.NoError(t, mr.Set(stockShardKey(7, noShard), "2"))
+	require.NoError(t, mr.Set(stockShardKey(8, noShard), "0"))
+
+	inventory.resyncLocalCache(ctx)
+	assert.True(t, inventory.LocalCheck(ctx, 7))
+	assert.False(t, inventory.LocalCheck(ctx, 8))
+	present, err := inventory.bloomFilter.Test(ctx, bloomElement(7))
+	require.NoError(t, err)
+	assert.True(t, present)
+}
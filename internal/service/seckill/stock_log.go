@@ -0,0 +1,240 @@
+package seckill
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+)
+
+// stockMetaKey context key of the StockMeta
+type stockMetaKey struct{}
+
+// StockMeta who moved stock and why, recorded on the stock logs of the mutations made under it
+type StockMeta struct {
+	RequestID string
+	OrderNo   string
+	Operator  string // Admin user ID or worker name, "system" when empty
+	Operation int8   // Recorded for syncs, model.OperationTypeSync when zero
+	Remark    string
+}
+
+// WithStockMeta attaches meta to the stock mutations made with ctx
+func WithStockMeta(ctx context.Context, meta StockMeta) context.Context {
+	return context.WithValue(ctx, stockMetaKey{}, meta)
+}
+
+// StockMetaFrom the meta attached to ctx, empty when there is none
+func StockMetaFrom(ctx context.Context) StockMeta {
+	meta, _ := ctx.Value(stockMetaKey{}).(StockMeta)
+	return meta
+}
+
+// deductStockLog the stock log of a TCC phase, the deduction ID gives its request ID and is kept as the remark
+func deductStockLog(activityID uint64, operation int8, quantity, before, after int, deductID string) *model.StockLog {
+	entry := &model.StockLog{
+		ActivityID:    activityID,
+		OperationType: operation,
+		Quantity:      quantity,
+		BeforeStock:   before,
+		AfterStock:    after,
+		Remark:        &deductID,
+	}
+	if requestID := deductRequestID(deductID); requestID != "" {
+		entry.RequestID = &requestID
+	}
+	return entry
+}
+
+// userOperator the operator of a try, the user buying
+func userOperator(userID uint64) *string {
+	operator := "user:" + strconv.FormatUint(userID, 10)
+	return &operator
+}
+
+// deductRequestID the request ID inside a deduction ID, [mysql:]deduct:<request ID>:<nanos>
+func deductRequestID(deductID string) string {
+	id := strings.TrimPrefix(strings.TrimPrefix(deductID, mysqlDeductPrefix), "deduct:")
+	if i := strings.LastIndex(id, ":"); i > 0 {
+		return id[:i]
+	}
+	return ""
+}
+
+// StockLogWriter writes stock logs in batches off the request path. Record never blocks:
+// once the buffer is full entries are dropped and counted, the stock itself has already moved.
+type StockLogWriter struct {
+	repo       repository.StockLogRepository
+	activities repository.ActivityRepository
+	entries    chan *model.StockLog
+	batchSize  int
+	dropped    atomic.Int64
+
+	// Goods of each activity (activityID -> uint64), stock logs carry both
+	goods sync.Map
+}
+
+// NewStockLogWriter creates a writer queueing up to bufferSize entries and inserting batchSize per statement
+func NewStockLogWriter(repo repository.StockLogRepository, activities repository.ActivityRepository, bufferSize, batchSize int) *StockLogWriter {
+	return &StockLogWriter{
+		repo:       repo,
+		activities: activities,
+		entries:    make(chan *model.StockLog, bufferSize),
+		batchSize:  batchSize,
+	}
+}
+
+// Dropped number of entries lost to a full buffer or a failed insert
+func (w *StockLogWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Record queues the stock log of a mutation made under ctx, filling in from its StockMeta
+// what the entry leaves empty
+func (w *StockLogWriter) Record(ctx context.Context, entry *model.StockLog) {
+	meta := StockMetaFrom(ctx)
+	if entry.RequestID == nil && meta.RequestID != "" {
+		entry.RequestID = &meta.RequestID
+	}
+	if entry.OrderNo == nil && meta.OrderNo != "" {
+		entry.OrderNo = &meta.OrderNo
+	}
+	if entry.Operator == nil {
+		operator := meta.Operator
+		if operator == "" {
+			operator = "system"
+		}
+		entry.Operator = &operator
+	}
+	if entry.Remark == nil && meta.Remark != "" {
+		entry.Remark = &meta.Remark
+	}
+	// Stamped now, the batch is written later
+	entry.CreatedAt = time.Now()
+
+	select {
+	case w.entries <- entry:
+	default:
+		if w.dropped.Add(1)%1000 == 1 {
+			log.WithFields(map[string]interface{}{
+				"activity_id": entry.ActivityID,
+				"dropped":     w.dropped.Load(),
+			}).Warn("Stock log buffer full, entries dropped")
+		}
+	}
+}
+
+// Run writes queued entries every interval, or as soon as a batch fills, until ctx is done.
+// The entries still queued then are written before it returns.
+func (w *StockLogWriter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.WithFields(map[string]interface{}{
+		"interval":   interval.String(),
+		"batch_size": w.batchSize,
+	}).Info("Stock log writer started")
+
+	batch := make([]*model.StockLog, 0, w.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			w.write(flushCtx, batch)
+			w.Flush(flushCtx)
+			cancel()
+			log.Info("Stock log writer stopped")
+			return
+		case entry := <-w.entries:
+			batch = append(batch, entry)
+			if len(batch) >= w.batchSize {
+				w.write(ctx, batch)
+				batch = make([]*model.StockLog, 0, w.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.write(ctx, batch)
+				batch = make([]*model.StockLog, 0, w.batchSize)
+			}
+		}
+	}
+}
+
+// Flush writes every queued entry, e.g. after the server stopped taking requests
+func (w *StockLogWriter) Flush(ctx context.Context) {
+	for {
+		batch := make([]*model.StockLog, 0, w.batchSize)
+	fill:
+		for len(batch) < w.batchSize {
+			select {
+			case entry := <-w.entries:
+				batch = append(batch, entry)
+			default:
+				break fill
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		w.write(ctx, batch)
+	}
+}
+
+// write inserts one batch, counting it as dropped when the insert fails
+func (w *StockLogWriter) write(ctx context.Context, batch []*model.StockLog) {
+	if len(batch) == 0 {
+		return
+	}
+	for _, entry := range batch {
+		if entry.GoodsID == 0 {
+			entry.GoodsID = w.goodsOf(ctx, entry.ActivityID)
+		}
+	}
+	if err := w.repo.CreateBatch(ctx, batch); err != nil {
+		w.dropped.Add(int64(len(batch)))
+		log.WithFields(map[string]interface{}{
+			"entries": len(batch),
+			"error":   err.Error(),
+		}).Error("Failed to write stock logs")
+	}
+}
+
+// goodsOf the goods sold by an activity, 0 when it cannot be read
+func (w *StockLogWriter) goodsOf(ctx context.Context, activityID uint64) uint64 {
+	if goodsID, ok := w.goods.Load(activityID); ok {
+		return goodsID.(uint64)
+	}
+	activity, err := w.activities.GetByID(ctx, int64(activityID))
+	if err != nil {
+		return 0
+	}
+	w.goods.Store(activityID, activity.GoodsID)
+	return activity.GoodsID
+}
+
+// EnableStockLog records every stock movement of the Redis inventory through writer
+func (m *MultiLevelInventory) EnableStockLog(writer *StockLogWriter) {
+	m.stockLogs = writer
+}
+
+// logDeduct records a successful try against the stock it was taken from: the shard, the
+// instance's lease or the single stock key
+func (m *MultiLevelInventory) logDeduct(ctx context.Context, req *DeductRequest, result *DeductResult) {
+	if m.stockLogs == nil || result == nil || !result.Success {
+		return
+	}
+	entry := deductStockLog(req.ActivityID, model.OperationTypeDeduct, -req.Quantity, result.RemainStock+req.Quantity, result.RemainStock, result.DeductID)
+	entry.Operator = userOperator(req.UserID)
+	m.stockLogs.Record(ctx, entry)
+}
+
+// EnableStockLog records every stock movement of the MySQL inventory through writer
+func (m *MySQLInventory) EnableStockLog(writer *StockLogWriter) {
+	m.stockLogs = writer
+}
@@ -0,0 +1,154 @@
+package seckill
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// batchStockLogRepo keeps every batch written
+type batchStockLogRepo struct {
+	repository.StockLogRepository
+	mu      sync.Mutex
+	batches [][]*model.StockLog
+}
+
+func (r *batchStockLogRepo) CreateBatch(ctx context.Context, logs []*model.StockLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, logs)
+	return nil
+}
+
+func (r *batchStockLogRepo) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sizes []int
+	for _, batch := range r.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+// drainStockLogs takes every entry queued on the writer
+func drainStockLogs(w *StockLogWriter) []*model.StockLog {
+	var entries []*model.StockLog
+	for {
+		select {
+		case entry := <-w.entries:
+			entries = append(entries, entry)
+		default:
+			return entries
+		}
+	}
+}
+
+func TestStockLogWriter_Batches(t *testing.T) {
+	repo := &batchStockLogRepo{}
+	activities := &reconcileActivityRepo{activity: &model.SeckillActivity{ID: 1, GoodsID: 9}}
+	writer := NewStockLogWriter(repo, activities, 10, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writer.Run(ctx, time.Hour)
+	}()
+
+	stockCtx := WithStockMeta(context.Background(), StockMeta{OrderNo: "ORDER1", Operator: "42"})
+	for i := 0; i < 3; i++ {
+		writer.Record(stockCtx, deductStockLog(1, model.OperationTypeConfirm, -1, 1, 0, "deduct:r1:1"))
+	}
+
+	// A full batch goes out at once, the rest waits for the interval or shutdown
+	require.Eventually(t, func() bool { return len(repo.sizes()) == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	assert.Equal(t, []int{2, 1}, repo.sizes())
+
+	entry := repo.batches[0][0]
+	assert.Equal(t, uint64(9), entry.GoodsID)
+	assert.Equal(t, "r1", *entry.RequestID)
+	assert.Equal(t, "ORDER1", *entry.OrderNo)
+	assert.Equal(t, "42", *entry.Operator)
+	assert.Equal(t, "deduct:r1:1", *entry.Remark)
+	assert.False(t, entry.CreatedAt.IsZero())
+	assert.Zero(t, writer.Dropped())
+}
+
+func TestStockLogWriter_DropsWhenFull(t *testing.T) {
+	writer := NewStockLogWriter(&batchStockLogRepo{}, &reconcileActivityRepo{}, 1, 10)
+
+	// Recording never waits for the writer
+	writer.Record(context.Background(), &model.StockLog{ActivityID: 1})
+	writer.Record(context.Background(), &model.StockLog{ActivityID: 1})
+	assert.Equal(t, int64(1), writer.Dropped())
+
+	entries := drainStockLogs(writer)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "system", *entries[0].Operator)
+}
+
+func TestMultiLevelInventory_RecordsStockLogs(t *testing.T) {
+	inventory, _, _ := setupBloomInventories(t)
+	writer := NewStockLogWriter(&batchStockLogRepo{}, &reconcileActivityRepo{}, 100, 10)
+	inventory.EnableStockLog(writer)
+	ctx := context.Background()
+
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+	try := func(requestID string, quantity int) string {
+		result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: requestID, ActivityID: 1, UserID: 7, Quantity: quantity}, 10)
+		require.NoError(t, err)
+		require.True(t, result.Success)
+		return result.DeductID
+	}
+	sold := try("r1", 2)
+	require.NoError(t, inventory.ConfirmDeduct(WithStockMeta(ctx, StockMeta{OrderNo: "ORDER1"}), sold, 1))
+	require.NoError(t, inventory.CancelDeduct(ctx, try("r2", 1), 1))
+	// Settled deductions move nothing and log nothing
+	require.NoError(t, inventory.ConfirmDeduct(ctx, sold, 1))
+
+	repairCtx := WithStockMeta(ctx, StockMeta{Operator: "42", Operation: model.OperationTypeRepair})
+	require.NoError(t, inventory.SyncToRedis(repairCtx, 1, 5))
+
+	entries := drainStockLogs(writer)
+	require.Len(t, entries, 6)
+	type movement struct {
+		operation            int8
+		quantity, before, to int
+	}
+	var movements []movement
+	for _, entry := range entries {
+		movements = append(movements, movement{entry.OperationType, entry.Quantity, entry.BeforeStock, entry.AfterStock})
+	}
+	assert.Equal(t, []movement{
+		{model.OperationTypeSync, 10, 0, 10},
+		{model.OperationTypeDeduct, -2, 10, 8},
+		{model.OperationTypeConfirm, -2, 2, 0},
+		{model.OperationTypeDeduct, -1, 8, 7},
+		{model.OperationTypeRevert, 1, 7, 8},
+		{model.OperationTypeRepair, -3, 8, 5},
+	}, movements)
+
+	assert.Equal(t, "r1", *entries[1].RequestID)
+	assert.Equal(t, "user:7", *entries[1].Operator)
+	assert.Equal(t, sold, *entries[1].Remark)
+	assert.Equal(t, "ORDER1", *entries[2].OrderNo)
+	assert.Equal(t, "r1", *entries[2].RequestID)
+	assert.Equal(t, "system", *entries[4].Operator)
+	assert.Equal(t, "42", *entries[5].Operator)
+}
+
+func TestDeductRequestID(t *testing.T) {
+	assert.Equal(t, "r1", deductRequestID("deduct:r1:1700000000"))
+	assert.Equal(t, "r1", deductRequestID("mysql:deduct:r1:1700000000"))
+	assert.Equal(t, "", deductRequestID("deduct:r4"))
+	assert.Equal(t, "", deductRequestID(""))
+}
@@ -0,0 +1,262 @@
+package seckill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+)
+
+var (
+	// ErrDeadLetterClosed the dead letter was already retried or resolved
+	ErrDeadLetterClosed = errors.New("dead letter already closed")
+	// ErrCompensationConflict the deduction was settled the other way, retrying cannot help
+	ErrCompensationConflict = errors.New("deduction settled the other way")
+)
+
+// CompensationConfig compensation retry policy
+type CompensationConfig struct {
+	MaxAttempts int           // Attempts, the failed original included, before escalating
+	BaseBackoff time.Duration // Delay before the first retry, doubled after every failure
+	MaxBackoff  time.Duration
+	BatchSize   int // Compensations processed per run
+}
+
+// compensationOutcome what to do with a compensation after an attempt
+type compensationOutcome int
+
+const (
+	compensationRetry compensationOutcome = iota
+	compensationDone
+	compensationConflict
+)
+
+// TCCCompensator retries confirms and cancels that failed after the order side committed.
+// Attempts run the same Lua scripts as the original calls, so the deduction record's status
+// makes them idempotent: a confirm of a confirmed deduction is done, a confirm of a cancelled
+// one is a conflict for a human to look at.
+type TCCCompensator struct {
+	stock  *InventoryFailover
+	repo   repository.CompensationRepository
+	config CompensationConfig
+}
+
+// NewTCCCompensator creates a compensator
+func NewTCCCompensator(stock *InventoryFailover, repo repository.CompensationRepository, config CompensationConfig) *TCCCompensator {
+	return &TCCCompensator{
+		stock:  stock,
+		repo:   repo,
+		config: config,
+	}
+}
+
+// Enqueue queues a confirm or cancel that failed with cause for retry
+func (c *TCCCompensator) Enqueue(ctx context.Context, operation, deductID string, activityID uint64, quantity int, cause error) {
+	item := &model.TCCCompensation{
+		DeductID:    deductID,
+		Operation:   operation,
+		ActivityID:  activityID,
+		Quantity:    quantity,
+		Attempts:    1,
+		NextRetryAt: time.Now().Add(c.backoff(1)),
+		LastError:   truncateError(cause),
+	}
+	if err := c.repo.Enqueue(ctx, item); err != nil {
+		log.WithFields(map[string]interface{}{
+			"operation": operation,
+			"deduct_id": deductID,
+			"error":     err.Error(),
+		}).Error("Failed to queue TCC compensation")
+		return
+	}
+
+	log.WithFields(map[string]interface{}{
+		"operation": operation,
+		"deduct_id": deductID,
+		"cause":     item.LastError,
+	}).Warn("TCC compensation queued")
+}
+
+// Run processes due compensations every interval until ctx is done
+func (c *TCCCompensator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.WithFields(map[string]interface{}{
+		"interval": interval.String(),
+	}).Info("TCC compensation worker started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("TCC compensation worker stopped")
+			return
+		case <-ticker.C:
+			if _, err := c.Process(ctx); err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+				}).Error("Failed to process TCC compensations")
+			}
+		}
+	}
+}
+
+// Process attempts one batch of due compensations, returning how many completed. The batch
+// stays claimed until it is moved on, so instances running side by side never retry the same one.
+func (c *TCCCompensator) Process(ctx context.Context) (int, error) {
+	completed := 0
+	err := c.repo.ClaimDue(ctx, time.Now(), c.config.BatchSize, func(claim repository.CompensationRepository, items []*model.TCCCompensation) error {
+		for _, item := range items {
+			if c.attempt(ctx, claim, item) {
+				completed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Attempts are idempotent, the next run retries those whose update was lost
+		return 0, err
+	}
+	return completed, nil
+}
+
+// attempt runs one compensation and moves it on through the claim, true when it completed
+func (c *TCCCompensator) attempt(ctx context.Context, claim repository.CompensationRepository, item *model.TCCCompensation) bool {
+	stockCtx := WithStockMeta(ctx, StockMeta{Operator: "tcc_compensation"})
+	result, err := c.stock.Compensate(stockCtx, item.Operation, item.DeductID, item.ActivityID, item.Quantity)
+	item.Attempts++
+
+	var repoErr error
+	switch outcomeOf(item.Operation, result, err) {
+	case compensationDone:
+		repoErr = claim.Complete(ctx, item.ID)
+		log.WithFields(map[string]interface{}{
+			"operation": item.Operation,
+			"deduct_id": item.DeductID,
+			"attempts":  item.Attempts,
+			"result":    result,
+		}).Info("TCC compensation completed")
+		if repoErr == nil {
+			return true
+		}
+	case compensationConflict:
+		item.LastError = result
+		repoErr = c.escalate(ctx, claim, item)
+	default:
+		item.LastError = truncateError(err)
+		if item.Attempts >= c.config.MaxAttempts {
+			repoErr = c.escalate(ctx, claim, item)
+		} else {
+			repoErr = claim.Reschedule(ctx, item.ID, item.Attempts, time.Now().Add(c.backoff(item.Attempts)), item.LastError)
+		}
+	}
+
+	if repoErr != nil {
+		log.WithFields(map[string]interface{}{
+			"deduct_id": item.DeductID,
+			"error":     repoErr.Error(),
+		}).Error("Failed to update TCC compensation")
+	}
+	return false
+}
+
+// escalate moves a compensation to the dead letter table
+func (c *TCCCompensator) escalate(ctx context.Context, repo repository.CompensationRepository, item *model.TCCCompensation) error {
+	log.WithFields(map[string]interface{}{
+		"operation":  item.Operation,
+		"deduct_id":  item.DeductID,
+		"attempts":   item.Attempts,
+		"last_error": item.LastError,
+	}).Error("TCC compensation escalated to dead letter")
+	return repo.Escalate(ctx, item)
+}
+
+// backoff delay after the given number of failed attempts
+func (c *TCCCompensator) backoff(attempts int) time.Duration {
+	delay := c.config.BaseBackoff
+	for i := 1; i < attempts && delay < c.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > c.config.MaxBackoff {
+		delay = c.config.MaxBackoff
+	}
+	return delay
+}
+
+// ListDeadLetters lists dead letters, status < 0 lists all of them
+func (c *TCCCompensator) ListDeadLetters(ctx context.Context, status int, page, pageSize int) ([]*model.TCCDeadLetter, int64, error) {
+	return c.repo.ListDeadLetters(ctx, status, page, pageSize)
+}
+
+// RetryDeadLetter attempts an open dead letter once more, closing it when the deduction settles
+func (c *TCCCompensator) RetryDeadLetter(ctx context.Context, id uint64, operator string) (string, error) {
+	letter, err := c.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if letter.Status != model.DeadLetterStatusOpen {
+		return "", ErrDeadLetterClosed
+	}
+
+	stockCtx := WithStockMeta(ctx, StockMeta{Operator: operator})
+	result, err := c.stock.Compensate(stockCtx, letter.Operation, letter.DeductID, letter.ActivityID, letter.Quantity)
+	switch outcomeOf(letter.Operation, result, err) {
+	case compensationConflict:
+		return result, fmt.Errorf("%w: %s", ErrCompensationConflict, result)
+	case compensationRetry:
+		return result, err
+	}
+
+	if _, err := c.repo.ResolveDeadLetter(ctx, id, model.DeadLetterStatusRetried, operator, "retried: "+result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// ResolveDeadLetter closes an open dead letter handled outside the system
+func (c *TCCCompensator) ResolveDeadLetter(ctx context.Context, id uint64, operator, note string) error {
+	resolved, err := c.repo.ResolveDeadLetter(ctx, id, model.DeadLetterStatusResolved, operator, note)
+	if err != nil || resolved {
+		return err
+	}
+	if _, err := c.repo.GetDeadLetter(ctx, id); err != nil {
+		return err
+	}
+	return ErrDeadLetterClosed
+}
+
+// outcomeOf classifies a compensation attempt by the deduction record's status
+func outcomeOf(operation, result string, err error) compensationOutcome {
+	if err != nil {
+		return compensationRetry
+	}
+	switch result {
+	case "success":
+		return compensationDone
+	case "already_confirmed":
+		if operation == model.TCCOperationConfirm {
+			return compensationDone
+		}
+	case "already_cancelled":
+		if operation == model.TCCOperationCancel {
+			return compensationDone
+		}
+	}
+	return compensationConflict
+}
+
+// truncateError error text fitting the last_error column
+func truncateError(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := err.Error()
+	if len(msg) > 255 {
+		msg = msg[:255]
+	}
+	return msg
+}
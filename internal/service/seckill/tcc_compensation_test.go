@@ -0,0 +1,269 @@
+package seckill
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/breaker"
+)
+
+// memoryCompensations in-memory compensation queue and dead letter table
+type memoryCompensations struct {
+	mu      sync.Mutex
+	nextID  uint64
+	queue   map[uint64]*model.TCCCompensation
+	letters map[uint64]*model.TCCDeadLetter
+}
+
+func newMemoryCompensations() *memoryCompensations {
+	return &memoryCompensations{
+		queue:   make(map[uint64]*model.TCCCompensation),
+		letters: make(map[uint64]*model.TCCDeadLetter),
+	}
+}
+
+func (m *memoryCompensations) Enqueue(ctx context.Context, item *model.TCCCompensation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, queued := range m.queue {
+		if queued.DeductID == item.DeductID && queued.Operation == item.Operation {
+			return nil
+		}
+	}
+	m.nextID++
+	item.ID = m.nextID
+	m.queue[item.ID] = item
+	return nil
+}
+
+func (m *memoryCompensations) ClaimDue(ctx context.Context, now time.Time, limit int, fn func(claim repository.CompensationRepository, items []*model.TCCCompensation) error) error {
+	m.mu.Lock()
+	var due []*model.TCCCompensation
+	for _, item := range m.queue {
+		if !item.NextRetryAt.After(now) && len(due) < limit {
+			copied := *item
+			due = append(due, &copied)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(due) == 0 {
+		return nil
+	}
+	return fn(m, due)
+}
+
+func (m *memoryCompensations) Reschedule(ctx context.Context, id uint64, attempts int, nextRetryAt time.Time, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item := m.queue[id]
+	item.Attempts, item.NextRetryAt, item.LastError = attempts, nextRetryAt, lastError
+	return nil
+}
+
+func (m *memoryCompensations) Complete(ctx context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.queue, id)
+	return nil
+}
+
+func (m *memoryCompensations) Escalate(ctx context.Context, item *model.TCCCompensation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.queue, item.ID)
+	m.nextID++
+	m.letters[m.nextID] = &model.TCCDeadLetter{
+		ID:         m.nextID,
+		DeductID:   item.DeductID,
+		Operation:  item.Operation,
+		ActivityID: item.ActivityID,
+		Quantity:   item.Quantity,
+		Attempts:   item.Attempts,
+		LastError:  item.LastError,
+	}
+	return nil
+}
+
+func (m *memoryCompensations) ListDeadLetters(ctx context.Context, status int, page, pageSize int) ([]*model.TCCDeadLetter, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var letters []*model.TCCDeadLetter
+	for _, letter := range m.letters {
+		if status < 0 || int(letter.Status) == status {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, int64(len(letters)), nil
+}
+
+func (m *memoryCompensations) GetDeadLetter(ctx context.Context, id uint64) (*model.TCCDeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	letter, ok := m.letters[id]
+	if !ok {
+		return nil, repository.ErrDeadLetterNotFound
+	}
+	copied := *letter
+	return &copied, nil
+}
+
+func (m *memoryCompensations) ResolveDeadLetter(ctx context.Context, id uint64, status int8, resolvedBy, note string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	letter, ok := m.letters[id]
+	if !ok || letter.Status != model.DeadLetterStatusOpen {
+		return false, nil
+	}
+	letter.Status, letter.ResolvedBy, letter.ResolveNote = status, &resolvedBy, &note
+	return true, nil
+}
+
+// due makes every queued compensation due now
+func (m *memoryCompensations) due() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, item := range m.queue {
+		item.NextRetryAt = time.Now().Add(-time.Second)
+	}
+}
+
+func setupCompensator(t *testing.T) (*TCCCompensator, *MultiLevelInventory, *memoryCompensations) {
+	inventory, _, _ := setupBloomInventories(t)
+	repo := newMemoryCompensations()
+	failover := NewInventoryFailover(inventory, nil, breaker.NewManager(breaker.Config{}))
+	compensator := NewTCCCompensator(failover, repo, CompensationConfig{
+		MaxAttempts: 3,
+		BaseBackoff: time.Second,
+		MaxBackoff:  3 * time.Second,
+		BatchSize:   10,
+	})
+	return compensator, inventory, repo
+}
+
+func TestTCCCompensator_ConfirmsExpiredRecord(t *testing.T) {
+	compensator, inventory, repo := setupCompensator(t)
+	ctx := context.Background()
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 2}, 5)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	// The confirm failed and the record expired while the compensation waited
+	compensator.Enqueue(ctx, model.TCCOperationConfirm, result.DeductID, 1, 2, errors.New("i/o timeout"))
+	compensator.Enqueue(ctx, model.TCCOperationConfirm, result.DeductID, 1, 2, errors.New("i/o timeout"))
+	require.Len(t, repo.queue, 1)
+	require.NoError(t, inventory.redisClient.Del(ctx, deductRecordKey(1, noShard, result.DeductID)).Err())
+
+	completed, err := compensator.Process(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, completed, "not due before the first backoff")
+
+	repo.due()
+	completed, err = compensator.Process(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+	assert.Empty(t, repo.queue)
+
+	reserved, err := inventory.redisClient.Get(ctx, reservedShardKey(1, noShard)).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 0, reserved)
+
+	// Compensating twice leaves the stock where it is
+	compensator.Enqueue(ctx, model.TCCOperationConfirm, result.DeductID, 1, 2, errors.New("i/o timeout"))
+	repo.due()
+	completed, err = compensator.Process(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+	reserved, err = inventory.redisClient.Get(ctx, reservedShardKey(1, noShard)).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 0, reserved)
+}
+
+func TestTCCCompensator_EscalatesConflict(t *testing.T) {
+	compensator, inventory, repo := setupCompensator(t)
+	ctx := context.Background()
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 1}, 5)
+	require.NoError(t, err)
+	require.NoError(t, inventory.CancelDeduct(ctx, result.DeductID, 1))
+
+	// Confirming a cancelled deduction cannot succeed, it goes straight to a human
+	compensator.Enqueue(ctx, model.TCCOperationConfirm, result.DeductID, 1, 1, errors.New("i/o timeout"))
+	repo.due()
+	_, err = compensator.Process(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, repo.queue)
+	require.Len(t, repo.letters, 1)
+
+	letters, total, err := compensator.ListDeadLetters(ctx, model.DeadLetterStatusOpen, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	assert.Equal(t, "already_cancelled", letters[0].LastError)
+
+	_, err = compensator.RetryDeadLetter(ctx, letters[0].ID, "1")
+	assert.ErrorIs(t, err, ErrCompensationConflict)
+
+	require.NoError(t, compensator.ResolveDeadLetter(ctx, letters[0].ID, "1", "refunded the order"))
+	assert.ErrorIs(t, compensator.ResolveDeadLetter(ctx, letters[0].ID, "1", "again"), ErrDeadLetterClosed)
+	assert.ErrorIs(t, compensator.ResolveDeadLetter(ctx, 404, "1", "missing"), repository.ErrDeadLetterNotFound)
+}
+
+func TestTCCCompensator_BacksOffThenDeadLetters(t *testing.T) {
+	compensator, inventory, repo := setupCompensator(t)
+	ctx := context.Background()
+
+	// A MySQL deduction without a MySQL inventory fails every attempt
+	compensator.Enqueue(ctx, model.TCCOperationCancel, "mysql:deduct:r1:1", 1, 1, errors.New("deadlock"))
+	repo.due()
+	_, err := compensator.Process(ctx)
+	require.NoError(t, err)
+	require.Len(t, repo.queue, 1)
+	for _, item := range repo.queue {
+		assert.Equal(t, 2, item.Attempts)
+		assert.Equal(t, ErrNoFallbackInventory.Error(), item.LastError)
+		assert.WithinDuration(t, time.Now().Add(2*time.Second), item.NextRetryAt, time.Second)
+	}
+
+	// The third attempt is the last
+	repo.due()
+	_, err = compensator.Process(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, repo.queue)
+	require.Len(t, repo.letters, 1)
+
+	// Once the deduction can be settled a retry closes the dead letter
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+	result, err := inventory.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r2", ActivityID: 1, UserID: 7, Quantity: 1}, 5)
+	require.NoError(t, err)
+	for _, letter := range repo.letters {
+		letter.DeductID = result.DeductID
+		message, err := compensator.RetryDeadLetter(ctx, letter.ID, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "success", message)
+		assert.Equal(t, int8(model.DeadLetterStatusRetried), letter.Status)
+
+		_, err = compensator.RetryDeadLetter(ctx, letter.ID, "1")
+		assert.ErrorIs(t, err, ErrDeadLetterClosed)
+	}
+}
+
+func TestTCCCompensator_Backoff(t *testing.T) {
+	compensator := NewTCCCompensator(nil, nil, CompensationConfig{BaseBackoff: 5 * time.Second, MaxBackoff: time.Minute})
+
+	assert.Equal(t, 5*time.Second, compensator.backoff(1))
+	assert.Equal(t, 10*time.Second, compensator.backoff(2))
+	assert.Equal(t, 40*time.Second, compensator.backoff(4))
+	assert.Equal(t, time.Minute, compensator.backoff(5))
+	assert.Equal(t, time.Minute, compensator.backoff(50))
+}
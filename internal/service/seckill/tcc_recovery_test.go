@@ -0,0 +1,156 @@
+package seckill
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seckill/internal/model"
+	"seckill/pkg/breaker"
+)
+
+// memoryTCCLogs in-memory TCC log, every pending log counts as dangling
+type memoryTCCLogs struct {
+	mu   sync.Mutex
+	logs map[string]*model.TCCLog
+}
+
+func newMemoryTCCLogs() *memoryTCCLogs {
+	return &memoryTCCLogs{logs: make(map[string]*model.TCCLog)}
+}
+
+func (m *memoryTCCLogs) Create(ctx context.Context, log *model.TCCLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log.CreatedAt = time.Now()
+	m.logs[log.DeductID] = log
+	return nil
+}
+
+func (m *memoryTCCLogs) RecordOutcome(ctx context.Context, deductID string, status int8, result string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if log, ok := m.logs[deductID]; ok {
+		if log.Status == model.TCCStatusTry {
+			log.Status = status
+		}
+		log.Result = result
+	}
+	return nil
+}
+
+func (m *memoryTCCLogs) MarkRecovered(ctx context.Context, deductID, result string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log, ok := m.logs[deductID]
+	if !ok || log.Status != model.TCCStatusTry {
+		return false, nil
+	}
+	log.Status, log.Result, log.Recovered = model.TCCStatusCancelled, result, true
+	return true, nil
+}
+
+func (m *memoryTCCLogs) ListDangling(ctx context.Context, before time.Time, limit int) ([]*model.TCCLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var dangling []*model.TCCLog
+	for _, log := range m.logs {
+		if log.Status == model.TCCStatusTry && log.CreatedAt.Before(before) && len(dangling) < limit {
+			copied := *log
+			dangling = append(dangling, &copied)
+		}
+	}
+	return dangling, nil
+}
+
+func (m *memoryTCCLogs) get(deductID string) model.TCCLog {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *m.logs[deductID]
+}
+
+func setupTCCSweeper(t *testing.T) (*TCCSweeper, *InventoryFailover, *MultiLevelInventory, *memoryTCCLogs) {
+	inventory, _, _ := setupBloomInventories(t)
+	logs := newMemoryTCCLogs()
+	failover := NewInventoryFailover(inventory, nil, breaker.NewManager(breaker.Config{}))
+	failover.EnableTCCLog(logs)
+	return NewTCCSweeper(failover, logs, 0, 10), failover, inventory, logs
+}
+
+func TestTCCSweeper_RecoversExpiredTry(t *testing.T) {
+	sweeper, failover, inventory, logs := setupTCCSweeper(t)
+	ctx := context.Background()
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+
+	result, err := failover.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 2}, 5)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.Equal(t, model.TCCStatusTry, int(logs.get(result.DeductID).Status))
+
+	// The order message is lost and the Redis record expires
+	require.NoError(t, inventory.redisClient.Del(ctx, deductRecordKey(1, noShard, result.DeductID)).Err())
+	require.NoError(t, failover.CancelDeduct(ctx, result.DeductID, 1))
+	assert.Equal(t, "deduct_record_not_found", logs.get(result.DeductID).Result)
+
+	units, err := sweeper.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, units)
+
+	stock, err := inventory.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 10, stock)
+	reserved, err := inventory.redisClient.Get(ctx, reservedShardKey(1, noShard)).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 0, reserved)
+
+	recovered := logs.get(result.DeductID)
+	assert.Equal(t, model.TCCStatusCancelled, int(recovered.Status))
+	assert.True(t, recovered.Recovered)
+
+	// Recovered once: repeating it returns nothing and a late order cannot confirm it
+	_, err = failover.Recover(ctx, &recovered)
+	require.NoError(t, err)
+	stock, err = inventory.GetStockFromRedis(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 10, stock)
+	require.NoError(t, failover.ConfirmDeduct(ctx, result.DeductID, 1))
+	assert.Equal(t, "already_cancelled", logs.get(result.DeductID).Result)
+
+	stats := sweeper.Stats()
+	assert.Equal(t, int64(1), stats.Sweeps)
+	assert.Equal(t, int64(1), stats.RecoveredTries)
+	assert.Equal(t, int64(2), stats.RecoveredUnits)
+	assert.False(t, stats.LastSweepAt.IsZero())
+}
+
+func TestTCCSweeper_RecordsSettledTries(t *testing.T) {
+	sweeper, failover, inventory, logs := setupTCCSweeper(t)
+	ctx := context.Background()
+	require.NoError(t, inventory.SyncToRedis(ctx, 1, 10))
+
+	confirmed, err := failover.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r1", ActivityID: 1, UserID: 7, Quantity: 1}, 5)
+	require.NoError(t, err)
+	require.NoError(t, failover.ConfirmDeduct(ctx, confirmed.DeductID, 1))
+	assert.Equal(t, model.TCCStatusConfirmed, int(logs.get(confirmed.DeductID).Status))
+	assert.Equal(t, "success", logs.get(confirmed.DeductID).Result)
+
+	// Confirmed in Redis without the outcome reaching the log
+	lost, err := failover.TryDeductWithLimit(ctx, &DeductRequest{RequestID: "r2", ActivityID: 1, UserID: 8, Quantity: 1}, 5)
+	require.NoError(t, err)
+	require.NoError(t, inventory.ConfirmDeduct(ctx, lost.DeductID, 1))
+
+	units, err := sweeper.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, units)
+	assert.Equal(t, model.TCCStatusConfirmed, int(logs.get(lost.DeductID).Status))
+	assert.Equal(t, "already_confirmed", logs.get(lost.DeductID).Result)
+	assert.False(t, logs.get(lost.DeductID).Recovered)
+
+	stats := sweeper.Stats()
+	assert.Equal(t, int64(0), stats.RecoveredUnits)
+	assert.Equal(t, int64(1), stats.Settled)
+}
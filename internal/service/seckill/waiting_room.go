@@ -0,0 +1,172 @@
+package seckill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	redisx "seckill/internal/redis"
+)
+
+const (
+	// defaultAdmitRate tickets admitted per second when the strategy does not set one
+	defaultAdmitRate = 100
+	// admitWindow how long an admitted ticket stays valid
+	admitWindow = 60 * time.Second
+	// waitingTicketTTL how long a waiting ticket is kept
+	waitingTicketTTL = 30 * time.Minute
+)
+
+// ErrNotInWaitingRoom the user holds no ticket for the activity
+var ErrNotInWaitingRoom = errors.New("not in waiting room")
+
+// WaitingRoom Redis-backed virtual waiting room per activity
+type WaitingRoom struct {
+	redisClient redis.Cmdable
+	now         func() time.Time
+}
+
+// NewWaitingRoom creates a waiting room
+func NewWaitingRoom(redisClient redis.Cmdable) *WaitingRoom {
+	return &WaitingRoom{
+		redisClient: redisClient,
+		now:         time.Now,
+	}
+}
+
+// WaitingTicket waiting room ticket
+type WaitingTicket struct {
+	ActivityID  uint64 `json:"activity_id"`
+	UserID      uint64 `json:"user_id"`
+	Position    int64  `json:"position"`            // Ticket number, increases monotonically
+	QueuePos    int64  `json:"queue_pos"`           // Remaining position, 0 once admitted
+	Admitted    bool   `json:"admitted"`            // Allowed to enter seckill
+	EstWaitTime int    `json:"est_wait_time"`       // Estimated wait (seconds)
+	ExpireAt    int64  `json:"expire_at,omitempty"` // Admitted ticket must be used before (unix)
+}
+
+// waitingRoomScript issues tickets and advances the admission pointer at a controlled rate
+var waitingRoomScript = redisx.Scripts.Register("waiting_room:enter", `
+	local seq_key = KEYS[1]
+	local admit_key = KEYS[2]
+	local ticket_key = KEYS[3]
+	local rate = tonumber(ARGV[1])
+	local window = tonumber(ARGV[2])
+	local ttl = tonumber(ARGV[3])
+	local now = tonumber(ARGV[4])
+	local create = ARGV[5] == '1'
+
+	-- Get or issue ticket
+	local pos = tonumber(redis.call('HGET', ticket_key, 'pos') or 0)
+	if pos == 0 then
+		if not create then
+			return {0, 0, 0, 0}
+		end
+		pos = redis.call('INCR', seq_key)
+		redis.call('EXPIRE', seq_key, ttl)
+		redis.call('HSET', ticket_key, 'pos', pos)
+		redis.call('EXPIRE', ticket_key, ttl)
+	end
+
+	-- Advance admission pointer by elapsed time * rate
+	local seq = tonumber(redis.call('GET', seq_key) or 0)
+	local admitted = tonumber(redis.call('HGET', admit_key, 'admitted') or 0)
+	local last = tonumber(redis.call('HGET', admit_key, 'last') or now)
+	local grant = math.floor((now - last) * rate / 1000)
+	if grant > 0 then
+		admitted = math.min(seq, admitted + grant)
+		last = last + math.floor(grant * 1000 / rate)
+	end
+	if admitted >= seq then
+		-- Room drained, do not bank idle time as a burst
+		last = now
+	end
+	redis.call('HSET', admit_key, 'admitted', admitted, 'last', last)
+	redis.call('EXPIRE', admit_key, ttl)
+
+	if pos > admitted then
+		return {1, pos, admitted, 0}
+	end
+
+	-- Admitted: start the usage window on first observation
+	local admitted_at = tonumber(redis.call('HGET', ticket_key, 'admitted_at') or 0)
+	if admitted_at == 0 then
+		admitted_at = now
+		redis.call('HSET', ticket_key, 'admitted_at', admitted_at)
+		redis.call('PEXPIRE', ticket_key, window)
+	end
+
+	return {2, pos, admitted, admitted_at}
+`)
+
+// Enter joins the waiting room or returns the existing ticket
+func (w *WaitingRoom) Enter(ctx context.Context, activityID, userID uint64, admitRate int) (*WaitingTicket, error) {
+	return w.run(ctx, activityID, userID, admitRate, true)
+}
+
+// Status returns the current ticket, nil if the user is not in the waiting room
+func (w *WaitingRoom) Status(ctx context.Context, activityID, userID uint64, admitRate int) (*WaitingTicket, error) {
+	return w.run(ctx, activityID, userID, admitRate, false)
+}
+
+// Consume uses up an admitted ticket
+func (w *WaitingRoom) Consume(ctx context.Context, activityID, userID uint64) error {
+	return w.redisClient.Del(ctx, waitingTicketKey(activityID, userID)).Err()
+}
+
+func (w *WaitingRoom) run(ctx context.Context, activityID, userID uint64, admitRate int, create bool) (*WaitingTicket, error) {
+	if admitRate <= 0 {
+		admitRate = defaultAdmitRate
+	}
+
+	createFlag := "0"
+	if create {
+		createFlag = "1"
+	}
+
+	keys := []string{
+		fmt.Sprintf("waiting_room:seq:{%d}", activityID),
+		fmt.Sprintf("waiting_room:admit:{%d}", activityID),
+		waitingTicketKey(activityID, userID),
+	}
+
+	result, err := waitingRoomScript.Run(ctx, w.redisClient, keys,
+		admitRate, admitWindow.Milliseconds(), int(waitingTicketTTL.Seconds()),
+		w.now().UnixMilli(), createFlag).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run waiting room script: %w", err)
+	}
+
+	resultSlice := result.([]interface{})
+	state := resultSlice[0].(int64)
+	if state == 0 {
+		return nil, nil
+	}
+
+	position := resultSlice[1].(int64)
+	admitted := resultSlice[2].(int64)
+
+	ticket := &WaitingTicket{
+		ActivityID: activityID,
+		UserID:     userID,
+		Position:   position,
+	}
+
+	if state == 2 {
+		admittedAt := resultSlice[3].(int64)
+		ticket.Admitted = true
+		ticket.ExpireAt = time.UnixMilli(admittedAt).Add(admitWindow).Unix()
+		return ticket, nil
+	}
+
+	ticket.QueuePos = position - admitted
+	ticket.EstWaitTime = int((ticket.QueuePos + int64(admitRate) - 1) / int64(admitRate))
+	return ticket, nil
+}
+
+// waitingTicketKey ticket key, same slot as the activity's waiting room
+func waitingTicketKey(activityID, userID uint64) string {
+	return fmt.Sprintf("waiting_room:ticket:{%d}:%d", activityID, userID)
+}
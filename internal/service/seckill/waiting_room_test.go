@@ -0,0 +1,110 @@
+package seckill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	redisx "seckill/internal/redis"
+)
+
+func setupWaitingRoom(t *testing.T) (*WaitingRoom, *miniredis.Miniredis, *time.Time) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	redisx.RejectCrossSlot(client)
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	now := time.Unix(1700000000, 0)
+	wr := NewWaitingRoom(client)
+	wr.now = func() time.Time { return now }
+	return wr, mr, &now
+}
+
+func TestWaitingRoom_EnterAndAdmit(t *testing.T) {
+	wr, _, now := setupWaitingRoom(t)
+	ctx := context.Background()
+	activityID := uint64(1)
+
+	// Issue tickets to 5 users at the same instant, nobody admitted yet
+	for i := uint64(1); i <= 5; i++ {
+		ticket, err := wr.Enter(ctx, activityID, i, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), ticket.Position)
+		assert.False(t, ticket.Admitted)
+		assert.Equal(t, int64(i), ticket.QueuePos)
+	}
+
+	// Re-entering keeps the same ticket
+	ticket, err := wr.Enter(ctx, activityID, 3, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), ticket.Position)
+	assert.Equal(t, 2, ticket.EstWaitTime)
+
+	// After 1 second at 2/s, first two users are admitted
+	*now = now.Add(time.Second)
+	ticket, err = wr.Status(ctx, activityID, 2, 2)
+	require.NoError(t, err)
+	assert.True(t, ticket.Admitted)
+	assert.Equal(t, now.Add(admitWindow).Unix(), ticket.ExpireAt)
+
+	ticket, err = wr.Status(ctx, activityID, 3, 2)
+	require.NoError(t, err)
+	assert.False(t, ticket.Admitted)
+	assert.Equal(t, int64(1), ticket.QueuePos)
+}
+
+func TestWaitingRoom_StatusWithoutTicket(t *testing.T) {
+	wr, _, _ := setupWaitingRoom(t)
+
+	ticket, err := wr.Status(context.Background(), 1, 42, 10)
+	require.NoError(t, err)
+	assert.Nil(t, ticket)
+}
+
+func TestWaitingRoom_AdmittedTicketExpires(t *testing.T) {
+	wr, mr, now := setupWaitingRoom(t)
+	ctx := context.Background()
+
+	_, err := wr.Enter(ctx, 1, 1, 10)
+	require.NoError(t, err)
+
+	*now = now.Add(time.Second)
+	ticket, err := wr.Status(ctx, 1, 1, 10)
+	require.NoError(t, err)
+	require.True(t, ticket.Admitted)
+
+	// Unused admission expires after the window
+	mr.FastForward(admitWindow + time.Second)
+	ticket, err = wr.Status(ctx, 1, 1, 10)
+	require.NoError(t, err)
+	assert.Nil(t, ticket)
+
+	// Re-entering issues a new ticket at the back of the queue
+	ticket, err = wr.Enter(ctx, 1, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ticket.Position)
+}
+
+func TestWaitingRoom_Consume(t *testing.T) {
+	wr, _, now := setupWaitingRoom(t)
+	ctx := context.Background()
+
+	_, err := wr.Enter(ctx, 1, 1, 10)
+	require.NoError(t, err)
+	*now = now.Add(time.Second)
+
+	require.NoError(t, wr.Consume(ctx, 1, 1))
+
+	ticket, err := wr.Status(ctx, 1, 1, 10)
+	require.NoError(t, err)
+	assert.Nil(t, ticket)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"seckill/internal/model"
 	"seckill/internal/repository"
 	"seckill/internal/service/seckill"
 	"seckill/pkg/log"
@@ -25,6 +26,9 @@ type StockService interface {
 	// Repair stock inconsistency
 	RepairStockInconsistency(ctx context.Context, activityID uint64) error
 
+	// Check and repair every active activity, e.g. after a Redis failover
+	CheckActiveActivities(ctx context.Context) error
+
 	// Start periodic sync task
 	StartPeriodicSync(ctx context.Context, interval time.Duration)
 }
@@ -34,7 +38,7 @@ type stockService struct {
 	activityRepo repository.ActivityRepository
 	goodsRepo    repository.GoodsRepository
 	inventory    *seckill.MultiLevelInventory
-	redis        *redis.Client
+	redis        redis.Cmdable
 }
 
 // NewStockService creates a stock service
@@ -42,7 +46,7 @@ func NewStockService(
 	activityRepo repository.ActivityRepository,
 	goodsRepo repository.GoodsRepository,
 	inventory *seckill.MultiLevelInventory,
-	redis *redis.Client,
+	redis redis.Cmdable,
 ) StockService {
 	return &stockService{
 		activityRepo: activityRepo,
@@ -82,7 +86,7 @@ func (s *stockService) SyncStockToRedis(ctx context.Context, activityID uint64)
 	}
 
 	// Sync to Redis
-	if err := s.inventory.SyncToRedis(ctx, activityID, availableStock); err != nil {
+	if err := s.inventory.SyncShardsToRedis(ctx, activityID, availableStock, seckill.ShardLayoutOf(activity)); err != nil {
 		return fmt.Errorf("failed to sync to Redis: %w", err)
 	}
 
@@ -106,9 +110,8 @@ func (s *stockService) SyncStockToMySQL(ctx context.Context, activityID uint64)
 		return fmt.Errorf("failed to get stock from Redis: %w", err)
 	}
 
-	// Get reserved stock
-	reservedKey := fmt.Sprintf("stock:reserved:%d", activityID)
-	reservedStock, _ := s.redis.Get(ctx, reservedKey).Int()
+	// Get reserved stock (sum of all shards)
+	reservedStock, _ := s.inventory.GetReservedFromRedis(ctx, activityID)
 
 	// Get activity from MySQL
 	activity, err := s.activityRepo.GetByID(ctx, int64(activityID))
@@ -150,9 +153,8 @@ func (s *stockService) CheckStockConsistency(ctx context.Context, activityID uin
 		redisStock = 0 // If Redis doesn't have the data, treat as 0
 	}
 
-	// Get reserved stock from Redis
-	reservedKey := fmt.Sprintf("stock:reserved:%d", activityID)
-	reservedStock, _ := s.redis.Get(ctx, reservedKey).Int()
+	// Get reserved stock from Redis (sum of all shards)
+	reservedStock, _ := s.inventory.GetReservedFromRedis(ctx, activityID)
 
 	// Get activity from MySQL
 	activity, err := s.activityRepo.GetByID(ctx, int64(activityID))
@@ -215,8 +217,11 @@ func (s *stockService) RepairStockInconsistency(ctx context.Context, activityID
 	}
 
 	// Strategy: Use MySQL as the source of truth
-	// Sync MySQL stock to Redis
-	if err := s.SyncStockToRedis(ctx, activityID); err != nil {
+	// Sync MySQL stock to Redis, logged as a repair of the difference
+	meta := seckill.StockMetaFrom(ctx)
+	meta.Operation = model.OperationTypeRepair
+	meta.Remark = fmt.Sprintf("redis %d, mysql %d, reserved %d", report.RedisStock, report.MySQLStock, report.ReservedStock)
+	if err := s.SyncStockToRedis(seckill.WithStockMeta(ctx, meta), activityID); err != nil {
 		return fmt.Errorf("failed to repair: %w", err)
 	}
 
@@ -252,15 +257,23 @@ func (s *stockService) StartPeriodicSync(ctx context.Context, interval time.Dura
 func (s *stockService) performPeriodicSync(ctx context.Context) {
 	log.Info("Performing periodic stock sync")
 
-	// Get all active activities
-	activities, _, err := s.activityRepo.ListActive(ctx, 1, 100)
-	if err != nil {
+	if err := s.CheckActiveActivities(ctx); err != nil {
 		log.WithFields(map[string]interface{}{
 			"error": err.Error(),
 		}).Error("Failed to list active activities")
 		return
 	}
 
+	log.Info("Periodic stock sync completed")
+}
+
+// CheckActiveActivities check and repair stock of all active activities
+func (s *stockService) CheckActiveActivities(ctx context.Context) error {
+	activities, _, err := s.activityRepo.ListActive(ctx, 1, 100)
+	if err != nil {
+		return fmt.Errorf("failed to list active activities: %w", err)
+	}
+
 	for _, activity := range activities {
 		activityID := activity.ID
 
@@ -285,6 +298,6 @@ func (s *stockService) performPeriodicSync(ctx context.Context) {
 		}
 	}
 
-	log.Info("Periodic stock sync completed")
+	return nil
 }
 
@@ -0,0 +1,97 @@
+package vip
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"seckill/internal/model"
+)
+
+// extConfigKey key of the VIP section in SeckillActivity.ExtConfig
+const extConfigKey = "vip"
+
+// Benefits what a tier unlocks in one activity
+type Benefits struct {
+	Tier        model.VIPTier `json:"tier"`
+	EarlyAccess time.Duration `json:"early_access"` // Seckill opens this long before StartTime
+	ExtraLimit  int           `json:"extra_limit"`  // Added to LimitPerUser
+	Priority    bool          `json:"priority"`     // Orders routed to the tier's priority queue
+}
+
+// benefitsConfig per-tier benefits, keyed by tier number
+//
+// Any tier set in ExtConfig["vip"] overrides the default of that tier, e.g.
+//
+//	{"vip": {"early_access_seconds": {"3": 900}, "extra_limit": {"2": 2}, "priority": {"1": false}}}
+type benefitsConfig struct {
+	EarlyAccessSeconds map[string]int  `json:"early_access_seconds"`
+	ExtraLimit         map[string]int  `json:"extra_limit"`
+	Priority           map[string]bool `json:"priority"`
+}
+
+// defaultBenefits benefits of each tier when the activity does not override them
+var defaultBenefits = map[model.VIPTier]Benefits{
+	model.VIPTierSilver:   {EarlyAccess: 0, ExtraLimit: 0, Priority: true},
+	model.VIPTierGold:     {EarlyAccess: 5 * time.Minute, ExtraLimit: 1, Priority: true},
+	model.VIPTierPlatinum: {EarlyAccess: 10 * time.Minute, ExtraLimit: 2, Priority: true},
+}
+
+// BenefitsFor builds the benefits of a tier in an activity
+func BenefitsFor(activity *model.SeckillActivity, tier model.VIPTier) *Benefits {
+	benefits := defaultBenefits[tier]
+	benefits.Tier = tier
+	if tier == model.VIPTierNone {
+		return &benefits
+	}
+
+	section, ok := activity.ExtConfig[extConfigKey]
+	if !ok {
+		return &benefits
+	}
+	data, err := json.Marshal(section)
+	if err != nil {
+		return &benefits
+	}
+	var cfg benefitsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return &benefits
+	}
+
+	key := strconv.Itoa(int(tier))
+	if seconds, ok := cfg.EarlyAccessSeconds[key]; ok {
+		benefits.EarlyAccess = time.Duration(seconds) * time.Second
+	}
+	if extra, ok := cfg.ExtraLimit[key]; ok {
+		benefits.ExtraLimit = extra
+	}
+	if priority, ok := cfg.Priority[key]; ok {
+		benefits.Priority = priority
+	}
+	return &benefits
+}
+
+// CanEnter whether the activity is open to this tier at now, early access
+// applies only to activities that have not started yet
+func (b *Benefits) CanEnter(activity *model.SeckillActivity, now time.Time) bool {
+	if activity.IsRunning() {
+		return true
+	}
+	if !activity.IsNotStarted() || b.EarlyAccess <= 0 {
+		return false
+	}
+	return !now.Before(activity.StartTime.Add(-b.EarlyAccess)) && now.Before(activity.EndTime)
+}
+
+// LimitPerUser purchase limit of this tier
+func (b *Benefits) LimitPerUser(activity *model.SeckillActivity) int {
+	return activity.LimitPerUser + b.ExtraLimit
+}
+
+// OrderTopic queue topic of this tier's orders
+func (b *Benefits) OrderTopic() string {
+	if !b.Priority {
+		return model.OrderTopicNormal
+	}
+	return model.OrderTopic(b.Tier)
+}
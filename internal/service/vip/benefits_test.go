@@ -0,0 +1,71 @@
+package vip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"seckill/internal/model"
+)
+
+func TestBenefitsFor(t *testing.T) {
+	activity := &model.SeckillActivity{ID: 1, LimitPerUser: 1}
+
+	none := BenefitsFor(activity, model.VIPTierNone)
+	assert.Equal(t, 1, none.LimitPerUser(activity))
+	assert.Equal(t, model.OrderTopicNormal, none.OrderTopic())
+
+	platinum := BenefitsFor(activity, model.VIPTierPlatinum)
+	assert.Equal(t, 10*time.Minute, platinum.EarlyAccess)
+	assert.Equal(t, 3, platinum.LimitPerUser(activity))
+	assert.Equal(t, model.OrderTopicVIPPlatinum, platinum.OrderTopic())
+
+	assert.Equal(t, model.OrderTopicVIP, BenefitsFor(activity, model.VIPTierSilver).OrderTopic())
+}
+
+func TestBenefitsFor_ExtConfigOverrides(t *testing.T) {
+	activity := &model.SeckillActivity{
+		ID:           1,
+		LimitPerUser: 2,
+		ExtConfig: model.JSONObject{
+			"vip": map[string]interface{}{
+				"early_access_seconds": map[string]interface{}{"2": 60},
+				"extra_limit":          map[string]interface{}{"2": 3},
+				"priority":             map[string]interface{}{"2": false},
+			},
+		},
+	}
+
+	gold := BenefitsFor(activity, model.VIPTierGold)
+	assert.Equal(t, time.Minute, gold.EarlyAccess)
+	assert.Equal(t, 5, gold.LimitPerUser(activity))
+	assert.Equal(t, model.OrderTopicNormal, gold.OrderTopic())
+
+	// Other tiers keep their defaults
+	platinum := BenefitsFor(activity, model.VIPTierPlatinum)
+	assert.Equal(t, 10*time.Minute, platinum.EarlyAccess)
+	assert.True(t, platinum.Priority)
+}
+
+func TestBenefits_CanEnter(t *testing.T) {
+	now := time.Now()
+	activity := &model.SeckillActivity{
+		Status:    model.ActivityStatusNotStarted,
+		StartTime: now.Add(3 * time.Minute),
+		EndTime:   now.Add(time.Hour),
+	}
+
+	assert.False(t, BenefitsFor(activity, model.VIPTierNone).CanEnter(activity, now))
+	assert.False(t, BenefitsFor(activity, model.VIPTierSilver).CanEnter(activity, now))
+	assert.True(t, BenefitsFor(activity, model.VIPTierGold).CanEnter(activity, now))
+
+	// Too early even for the best tier
+	assert.False(t, BenefitsFor(activity, model.VIPTierPlatinum).CanEnter(activity, now.Add(-10*time.Minute)))
+
+	// Ended activities are closed to everyone
+	activity.Status = model.ActivityStatusEnded
+	assert.False(t, BenefitsFor(activity, model.VIPTierPlatinum).CanEnter(activity, now))
+
+	activity.Status = model.ActivityStatusRunning
+	assert.True(t, BenefitsFor(activity, model.VIPTierNone).CanEnter(activity, now))
+}
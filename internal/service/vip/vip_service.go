@@ -0,0 +1,235 @@
+package vip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+	"seckill/pkg/log"
+)
+
+// ErrInvalidGrant grant is missing required fields or already expired
+var ErrInvalidGrant = errors.New("invalid vip grant")
+
+// tierCacheTTL how long a resolved tier is cached
+const tierCacheTTL = 10 * time.Minute
+
+// tierThresholds level or points needed for each derived tier, highest first
+var tierThresholds = []struct {
+	tier      model.VIPTier
+	minLevel  int
+	minPoints int
+}{
+	{tier: model.VIPTierPlatinum, minLevel: 8, minPoints: 50000},
+	{tier: model.VIPTierGold, minLevel: 5, minPoints: 10000},
+	{tier: model.VIPTierSilver, minLevel: 3, minPoints: 2000},
+}
+
+// TierOf tier derived from a user's level or points
+func TierOf(user *model.User) model.VIPTier {
+	for _, threshold := range tierThresholds {
+		if user.Level >= threshold.minLevel || user.Points >= threshold.minPoints {
+			return threshold.tier
+		}
+	}
+	return model.VIPTierNone
+}
+
+// GrantRequest grant VIP tier request
+type GrantRequest struct {
+	UserID   uint64
+	Tier     model.VIPTier
+	Reason   string
+	Operator string
+	ExpireAt *time.Time // nil never expires
+}
+
+// UserTier a user's effective tier and its sources
+type UserTier struct {
+	UserID      uint64            `json:"user_id"`
+	Tier        model.VIPTier     `json:"tier"`
+	TierName    string            `json:"tier_name"`
+	DerivedTier model.VIPTier     `json:"derived_tier"` // From level and points
+	Grants      []*model.VIPGrant `json:"grants"`
+}
+
+// VIPService VIP tier service interface
+type VIPService interface {
+	// GetTier effective tier of a user, the higher of the derived tier and active grants
+	GetTier(ctx context.Context, userID uint64) (model.VIPTier, error)
+
+	// GetUserTier effective tier with grant history
+	GetUserTier(ctx context.Context, userID uint64) (*UserTier, error)
+
+	// Grant grants a tier
+	Grant(ctx context.Context, req *GrantRequest) (*model.VIPGrant, error)
+
+	// Revoke revokes all active grants of a user
+	Revoke(ctx context.Context, userID uint64, operator string) (int64, error)
+
+	// Invalidate drops the cached tier, call after level or points change
+	Invalidate(ctx context.Context, userID uint64) error
+}
+
+// vipService VIP tier service implementation
+type vipService struct {
+	userRepo  repository.UserRepository
+	grantRepo repository.VIPGrantRepository
+	redis     redis.Cmdable
+	now       func() time.Time
+}
+
+// NewVIPService creates a VIP tier service
+func NewVIPService(userRepo repository.UserRepository, grantRepo repository.VIPGrantRepository, redis redis.Cmdable) VIPService {
+	return &vipService{
+		userRepo:  userRepo,
+		grantRepo: grantRepo,
+		redis:     redis,
+		now:       time.Now,
+	}
+}
+
+// tierCacheKey cached effective tier of a user
+func tierCacheKey(userID uint64) string {
+	return fmt.Sprintf("vip:tier:%d", userID)
+}
+
+// GetTier effective tier of a user
+func (s *vipService) GetTier(ctx context.Context, userID uint64) (model.VIPTier, error) {
+	cacheKey := tierCacheKey(userID)
+	if cached, err := s.redis.Get(ctx, cacheKey).Int(); err == nil {
+		return model.VIPTier(cached), nil
+	}
+
+	tier, ttl, err := s.resolve(ctx, userID)
+	if err != nil {
+		return model.VIPTierNone, err
+	}
+
+	if err := s.redis.Set(ctx, cacheKey, int(tier), ttl).Err(); err != nil {
+		log.WithFields(map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Failed to cache vip tier")
+	}
+	return tier, nil
+}
+
+// resolve computes the effective tier, the TTL ends no later than the first grant expiry
+func (s *vipService) resolve(ctx context.Context, userID uint64) (model.VIPTier, time.Duration, error) {
+	user, err := s.userRepo.GetByID(ctx, int64(userID))
+	if err != nil {
+		return model.VIPTierNone, 0, err
+	}
+	now := s.now()
+	grants, err := s.grantRepo.ListActiveByUser(ctx, userID, now)
+	if err != nil {
+		return model.VIPTierNone, 0, err
+	}
+
+	tier := TierOf(user)
+	ttl := tierCacheTTL
+	for _, grant := range grants {
+		if grant.Tier > tier {
+			tier = grant.Tier
+		}
+		if grant.ExpireAt != nil {
+			if untilExpiry := grant.ExpireAt.Sub(now); untilExpiry < ttl {
+				ttl = untilExpiry
+			}
+		}
+	}
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return tier, ttl, nil
+}
+
+// GetUserTier effective tier with grant history
+func (s *vipService) GetUserTier(ctx context.Context, userID uint64) (*UserTier, error) {
+	user, err := s.userRepo.GetByID(ctx, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	grants, err := s.grantRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	derived := TierOf(user)
+	tier := derived
+	for _, grant := range grants {
+		if grant.IsActive(now) && grant.Tier > tier {
+			tier = grant.Tier
+		}
+	}
+	return &UserTier{
+		UserID:      userID,
+		Tier:        tier,
+		TierName:    tier.String(),
+		DerivedTier: derived,
+		Grants:      grants,
+	}, nil
+}
+
+// Grant grants a tier
+func (s *vipService) Grant(ctx context.Context, req *GrantRequest) (*model.VIPGrant, error) {
+	if req.UserID == 0 || req.Tier == model.VIPTierNone || !req.Tier.IsValid() || req.Reason == "" || req.Operator == "" {
+		return nil, ErrInvalidGrant
+	}
+	if req.ExpireAt != nil && !req.ExpireAt.After(s.now()) {
+		return nil, ErrInvalidGrant
+	}
+
+	grant := &model.VIPGrant{
+		UserID:   req.UserID,
+		Tier:     req.Tier,
+		Reason:   req.Reason,
+		Operator: req.Operator,
+		ExpireAt: req.ExpireAt,
+	}
+	if err := s.grantRepo.Create(ctx, grant); err != nil {
+		return nil, fmt.Errorf("failed to save vip grant: %w", err)
+	}
+	if err := s.Invalidate(ctx, req.UserID); err != nil {
+		return nil, fmt.Errorf("failed to invalidate vip tier: %w", err)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"user_id":   grant.UserID,
+		"tier":      grant.Tier.String(),
+		"operator":  grant.Operator,
+		"expire_at": grant.ExpireAt,
+	}).Info("VIP tier granted")
+
+	return grant, nil
+}
+
+// Revoke revokes all active grants of a user
+func (s *vipService) Revoke(ctx context.Context, userID uint64, operator string) (int64, error) {
+	revoked, err := s.grantRepo.RevokeByUser(ctx, userID, operator, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke vip grants: %w", err)
+	}
+	if err := s.Invalidate(ctx, userID); err != nil {
+		return revoked, fmt.Errorf("failed to invalidate vip tier: %w", err)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"user_id":  userID,
+		"revoked":  revoked,
+		"operator": operator,
+	}).Info("VIP grants revoked")
+
+	return revoked, nil
+}
+
+// Invalidate drops the cached tier
+func (s *vipService) Invalidate(ctx context.Context, userID uint64) error {
+	return s.redis.Del(ctx, tierCacheKey(userID)).Err()
+}
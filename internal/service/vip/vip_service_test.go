@@ -0,0 +1,162 @@
+package vip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"seckill/internal/model"
+	"seckill/internal/repository"
+)
+
+// fakeUserRepo returns users from a map
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[int64]*model.User
+	calls int
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	r.calls++
+	return r.users[id], nil
+}
+
+// fakeGrantRepo in-memory grant repository
+type fakeGrantRepo struct {
+	grants []*model.VIPGrant
+}
+
+func (r *fakeGrantRepo) Create(ctx context.Context, grant *model.VIPGrant) error {
+	grant.ID = uint64(len(r.grants) + 1)
+	r.grants = append(r.grants, grant)
+	return nil
+}
+
+func (r *fakeGrantRepo) ListActiveByUser(ctx context.Context, userID uint64, now time.Time) ([]*model.VIPGrant, error) {
+	var grants []*model.VIPGrant
+	for _, grant := range r.grants {
+		if grant.UserID == userID && grant.IsActive(now) {
+			grants = append(grants, grant)
+		}
+	}
+	return grants, nil
+}
+
+func (r *fakeGrantRepo) ListByUser(ctx context.Context, userID uint64) ([]*model.VIPGrant, error) {
+	var grants []*model.VIPGrant
+	for _, grant := range r.grants {
+		if grant.UserID == userID {
+			grants = append(grants, grant)
+		}
+	}
+	return grants, nil
+}
+
+func (r *fakeGrantRepo) RevokeByUser(ctx context.Context, userID uint64, operator string, now time.Time) (int64, error) {
+	var revoked int64
+	for _, grant := range r.grants {
+		if grant.UserID == userID && grant.RevokedAt == nil {
+			grant.RevokedAt = &now
+			grant.RevokedBy = &operator
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func setupVIPService(t *testing.T) (*vipService, *fakeUserRepo, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		mr.Close()
+	})
+
+	userRepo := &fakeUserRepo{users: map[int64]*model.User{
+		1: {ID: 1, Level: 1, Points: 0},
+		2: {ID: 2, Level: 5, Points: 0},
+		3: {ID: 3, Level: 1, Points: 60000},
+	}}
+	return NewVIPService(userRepo, &fakeGrantRepo{}, client).(*vipService), userRepo, mr
+}
+
+func TestTierOf(t *testing.T) {
+	assert.Equal(t, model.VIPTierNone, TierOf(&model.User{Level: 1}))
+	assert.Equal(t, model.VIPTierSilver, TierOf(&model.User{Level: 3}))
+	assert.Equal(t, model.VIPTierSilver, TierOf(&model.User{Level: 1, Points: 2000}))
+	assert.Equal(t, model.VIPTierGold, TierOf(&model.User{Level: 5}))
+	assert.Equal(t, model.VIPTierPlatinum, TierOf(&model.User{Level: 1, Points: 50000}))
+}
+
+func TestVIPService_GetTier(t *testing.T) {
+	service, userRepo, mr := setupVIPService(t)
+	ctx := context.Background()
+
+	tier, err := service.GetTier(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, model.VIPTierGold, tier)
+
+	tier, err = service.GetTier(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, model.VIPTierPlatinum, tier)
+
+	// Served from cache
+	_, err = service.GetTier(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, userRepo.calls)
+	assert.True(t, mr.Exists("vip:tier:2"))
+}
+
+func TestVIPService_GrantAndRevoke(t *testing.T) {
+	service, _, mr := setupVIPService(t)
+	ctx := context.Background()
+
+	tier, err := service.GetTier(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.VIPTierNone, tier)
+
+	expireAt := time.Now().Add(30 * time.Second)
+	_, err = service.Grant(ctx, &GrantRequest{UserID: 1, Tier: model.VIPTierGold, Reason: "campaign", Operator: "9", ExpireAt: &expireAt})
+	require.NoError(t, err)
+
+	// Grant invalidates the cached tier
+	tier, err = service.GetTier(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.VIPTierGold, tier)
+
+	// Cache never outlives the grant
+	assert.LessOrEqual(t, mr.TTL("vip:tier:1"), 30*time.Second)
+
+	revoked, err := service.Revoke(ctx, 1, "9")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+
+	tier, err = service.GetTier(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.VIPTierNone, tier)
+
+	userTier, err := service.GetUserTier(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.VIPTierNone, userTier.Tier)
+	assert.Len(t, userTier.Grants, 1)
+}
+
+func TestVIPService_GrantValidation(t *testing.T) {
+	service, _, _ := setupVIPService(t)
+	ctx := context.Background()
+
+	_, err := service.Grant(ctx, &GrantRequest{UserID: 1, Tier: model.VIPTierNone, Reason: "x", Operator: "9"})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	_, err = service.Grant(ctx, &GrantRequest{UserID: 1, Tier: 7, Reason: "x", Operator: "9"})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = service.Grant(ctx, &GrantRequest{UserID: 1, Tier: model.VIPTierGold, Reason: "x", Operator: "9", ExpireAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
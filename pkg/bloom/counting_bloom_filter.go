@@ -7,8 +7,97 @@ import (
 	"math"
 
 	"github.com/redis/go-redis/v9"
+
+	"seckill/pkg/utils"
+)
+
+// Script runs a Lua script by hash, satisfied by *redis.Script and the shared script registry
+type Script interface {
+	Run(ctx context.Context, c redis.Scripter, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// CountingAddScript increments the element's counter in one bucket, up to the max count
+const CountingAddScript = `
+	local bucket_key = KEYS[1]
+	local bit_offset = tonumber(ARGV[1])
+	local max_count = tonumber(ARGV[2])
+
+	-- Get current counter value (4 bits per counter)
+	local byte_val = redis.call('GETBIT', bucket_key, bit_offset * 4) * 8 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 1) * 4 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 2) * 2 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 3)
+
+	-- Increment counter if not at max
+	if byte_val < max_count then
+		byte_val = byte_val + 1
+
+		-- Set the 4-bit counter value
+		redis.call('SETBIT', bucket_key, bit_offset * 4, math.floor(byte_val / 8) % 2)
+		redis.call('SETBIT', bucket_key, bit_offset * 4 + 1, math.floor(byte_val / 4) % 2)
+		redis.call('SETBIT', bucket_key, bit_offset * 4 + 2, math.floor(byte_val / 2) % 2)
+		redis.call('SETBIT', bucket_key, bit_offset * 4 + 3, byte_val % 2)
+	end
+
+	return "OK"
+`
+
+// CountingRemoveScript decrements the element's counter in one bucket
+const CountingRemoveScript = `
+	local bucket_key = KEYS[1]
+	local bit_offset = tonumber(ARGV[1])
+
+	-- Get current counter value (4 bits per counter)
+	local byte_val = redis.call('GETBIT', bucket_key, bit_offset * 4) * 8 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 1) * 4 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 2) * 2 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 3)
+
+	-- Decrement counter if greater than 0
+	if byte_val > 0 then
+		byte_val = byte_val - 1
+
+		-- Set the 4-bit counter value
+		redis.call('SETBIT', bucket_key, bit_offset * 4, math.floor(byte_val / 8) % 2)
+		redis.call('SETBIT', bucket_key, bit_offset * 4 + 1, math.floor(byte_val / 4) % 2)
+		redis.call('SETBIT', bucket_key, bit_offset * 4 + 2, math.floor(byte_val / 2) % 2)
+		redis.call('SETBIT', bucket_key, bit_offset * 4 + 3, byte_val % 2)
+	end
+
+	return "OK"
+`
+
+// CountingTestScript whether the element's counter in one bucket is set
+const CountingTestScript = `
+	local bucket_key = KEYS[1]
+	local bit_offset = tonumber(ARGV[1])
+
+	-- Get current counter value (4 bits per counter)
+	local byte_val = redis.call('GETBIT', bucket_key, bit_offset * 4) * 8 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 1) * 4 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 2) * 2 +
+					redis.call('GETBIT', bucket_key, bit_offset * 4 + 3)
+
+	-- If any counter is 0, element is definitely not in the set
+	if byte_val == 0 then
+		return 0
+	end
+
+	return 1
+`
+
+var (
+	addScript    Script = redis.NewScript(CountingAddScript)
+	removeScript Script = redis.NewScript(CountingRemoveScript)
+	testScript   Script = redis.NewScript(CountingTestScript)
 )
 
+// UseCountingScripts runs the counting bloom filter through a shared script registry,
+// must be called before any filter is used
+func UseCountingScripts(add, remove, test Script) {
+	addScript, removeScript, testScript = add, remove, test
+}
+
 // CountingBloomFilter Redis-based counting bloom filter that supports deletion
 type CountingBloomFilter struct {
 	redis     redis.Cmdable
@@ -64,33 +153,7 @@ func (cbf *CountingBloomFilter) Add(ctx context.Context, element string) error {
 		bitOffset := (hash % cbf.m) % 8
 		bucketKey := fmt.Sprintf("%s:%d", cbf.keyPrefix, bucketIndex)
 		
-		// Use individual operations instead of Lua script for cluster compatibility
-		script := `
-			local bucket_key = KEYS[1]
-			local bit_offset = tonumber(ARGV[1])
-			local max_count = tonumber(ARGV[2])
-			
-			-- Get current counter value (4 bits per counter)
-			local byte_val = redis.call('GETBIT', bucket_key, bit_offset * 4) * 8 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 1) * 4 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 2) * 2 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 3)
-			
-			-- Increment counter if not at max
-			if byte_val < max_count then
-				byte_val = byte_val + 1
-				
-				-- Set the 4-bit counter value
-				redis.call('SETBIT', bucket_key, bit_offset * 4, math.floor(byte_val / 8) % 2)
-				redis.call('SETBIT', bucket_key, bit_offset * 4 + 1, math.floor(byte_val / 4) % 2)
-				redis.call('SETBIT', bucket_key, bit_offset * 4 + 2, math.floor(byte_val / 2) % 2)
-				redis.call('SETBIT', bucket_key, bit_offset * 4 + 3, byte_val % 2)
-			end
-			
-			return "OK"
-		`
-		
-		err := cbf.redis.Eval(ctx, script, []string{bucketKey}, bitOffset, cbf.maxCount).Err()
+		err := addScript.Run(ctx, cbf.redis, []string{bucketKey}, bitOffset, cbf.maxCount).Err()
 		if err != nil {
 			return err
 		}
@@ -109,32 +172,7 @@ func (cbf *CountingBloomFilter) Remove(ctx context.Context, element string) erro
 		bitOffset := (hash % cbf.m) % 8
 		bucketKey := fmt.Sprintf("%s:%d", cbf.keyPrefix, bucketIndex)
 		
-		// Use individual operations instead of Lua script for cluster compatibility
-		script := `
-			local bucket_key = KEYS[1]
-			local bit_offset = tonumber(ARGV[1])
-			
-			-- Get current counter value (4 bits per counter)
-			local byte_val = redis.call('GETBIT', bucket_key, bit_offset * 4) * 8 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 1) * 4 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 2) * 2 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 3)
-			
-			-- Decrement counter if greater than 0
-			if byte_val > 0 then
-				byte_val = byte_val - 1
-				
-				-- Set the 4-bit counter value
-				redis.call('SETBIT', bucket_key, bit_offset * 4, math.floor(byte_val / 8) % 2)
-				redis.call('SETBIT', bucket_key, bit_offset * 4 + 1, math.floor(byte_val / 4) % 2)
-				redis.call('SETBIT', bucket_key, bit_offset * 4 + 2, math.floor(byte_val / 2) % 2)
-				redis.call('SETBIT', bucket_key, bit_offset * 4 + 3, byte_val % 2)
-			end
-			
-			return "OK"
-		`
-		
-		err := cbf.redis.Eval(ctx, script, []string{bucketKey}, bitOffset).Err()
+		err := removeScript.Run(ctx, cbf.redis, []string{bucketKey}, bitOffset).Err()
 		if err != nil {
 			return err
 		}
@@ -153,26 +191,7 @@ func (cbf *CountingBloomFilter) Test(ctx context.Context, element string) (bool,
 		bitOffset := (hash % cbf.m) % 8
 		bucketKey := fmt.Sprintf("%s:%d", cbf.keyPrefix, bucketIndex)
 		
-		// Use individual operations instead of Lua script for cluster compatibility
-		script := `
-			local bucket_key = KEYS[1]
-			local bit_offset = tonumber(ARGV[1])
-			
-			-- Get current counter value (4 bits per counter)
-			local byte_val = redis.call('GETBIT', bucket_key, bit_offset * 4) * 8 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 1) * 4 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 2) * 2 +
-							redis.call('GETBIT', bucket_key, bit_offset * 4 + 3)
-			
-			-- If any counter is 0, element is definitely not in the set
-			if byte_val == 0 then
-				return 0
-			end
-			
-			return 1
-		`
-		
-		result, err := cbf.redis.Eval(ctx, script, []string{bucketKey}, bitOffset).Int()
+		result, err := testScript.Run(ctx, cbf.redis, []string{bucketKey}, bitOffset).Int()
 		if err != nil {
 			return false, err
 		}
@@ -189,15 +208,20 @@ func (cbf *CountingBloomFilter) Test(ctx context.Context, element string) (bool,
 
 // Clear clears all elements from the counting bloom filter
 func (cbf *CountingBloomFilter) Clear(ctx context.Context) error {
-	// Delete all keys with the prefix
+	// Delete all keys with the prefix, one DEL per key as buckets spread across cluster slots
 	pattern := cbf.keyPrefix + ":*"
-	keys, err := cbf.redis.Keys(ctx, pattern).Result()
+	keys, err := utils.ScanKeys(ctx, cbf.redis, pattern)
 	if err != nil {
 		return err
 	}
 	
 	if len(keys) > 0 {
-		return cbf.redis.Del(ctx, keys...).Err()
+		pipe := cbf.redis.Pipeline()
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		_, err = pipe.Exec(ctx)
+		return err
 	}
 	
 	return nil
@@ -243,7 +267,7 @@ func optimalK(m, n uint64) uint8 {
 func (cbf *CountingBloomFilter) Stats(ctx context.Context) (map[string]interface{}, error) {
 	// Get all keys with the prefix
 	pattern := cbf.keyPrefix + ":*"
-	keys, err := cbf.redis.Keys(ctx, pattern).Result()
+	keys, err := utils.ScanKeys(ctx, cbf.redis, pattern)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package breaker
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 )
@@ -48,11 +49,11 @@ type Config struct {
 
 // Counts holds the numbers of requests and their outcomes
 type Counts struct {
-	Requests       uint32
-	TotalSuccesses uint32
-	TotalFailures  uint32
-	ConsecutiveSuccesses uint32
-	ConsecutiveFailures  uint32
+	Requests             uint32 `json:"requests"`
+	TotalSuccesses       uint32 `json:"total_successes"`
+	TotalFailures        uint32 `json:"total_failures"`
+	ConsecutiveSuccesses uint32 `json:"consecutive_successes"`
+	ConsecutiveFailures  uint32 `json:"consecutive_failures"`
 }
 
 // CircuitBreaker circuit breaker implementation
@@ -322,6 +323,29 @@ func (m *Manager) State(name string) State {
 	return cb.State()
 }
 
+// Snapshot state and counts of a circuit breaker
+type Snapshot struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Counts Counts `json:"counts"`
+}
+
+// Snapshots returns the state of every circuit breaker created so far
+func (m *Manager) Snapshots() []Snapshot {
+	var snapshots []Snapshot
+	m.breakers.Range(func(key, value interface{}) bool {
+		cb := value.(*CircuitBreaker)
+		snapshots = append(snapshots, Snapshot{
+			Name:   key.(string),
+			State:  cb.State().String(),
+			Counts: cb.Counts(),
+		})
+		return true
+	})
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+	return snapshots
+}
+
 // DefaultManager default circuit breaker manager
 var DefaultManager = NewManager(Config{
 	MaxRequests:   5,
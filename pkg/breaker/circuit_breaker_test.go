@@ -257,6 +257,26 @@ func TestManager(t *testing.T) {
 		assert.Error(t, err)
 		assert.Equal(t, StateOpen, manager.State("test"))
 	})
+
+	t.Run("Snapshots", func(t *testing.T) {
+		manager := NewManager(Config{
+			ReadyToTrip: func(counts Counts) bool {
+				return counts.TotalFailures >= 1
+			},
+		})
+		ctx := context.Background()
+
+		manager.Execute(ctx, "b", func() error { return errors.New("test error") })
+		manager.Execute(ctx, "a", func() error { return nil })
+
+		snapshots := manager.Snapshots()
+		assert.Len(t, snapshots, 2)
+		assert.Equal(t, "a", snapshots[0].Name)
+		assert.Equal(t, "closed", snapshots[0].State)
+		assert.Equal(t, uint32(1), snapshots[0].Counts.TotalSuccesses)
+		assert.Equal(t, "b", snapshots[1].Name)
+		assert.Equal(t, "open", snapshots[1].State)
+	})
 }
 
 func TestDefaultManager(t *testing.T) {
@@ -0,0 +1,269 @@
+package degrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Automatic degradation states
+const (
+	AutoStateNormal   = "normal"
+	AutoStateDegraded = "degraded"
+)
+
+const (
+	// maxTransitions transitions kept per activity
+	maxTransitions = 100
+	// transitionsTTL how long the transition history is kept
+	transitionsTTL = 7 * 24 * time.Hour
+)
+
+// AutoPolicy thresholds driving automatic degradation of an activity
+type AutoPolicy struct {
+	ErrorRate    float64          // Degrade when the error rate reaches this ratio
+	P99Latency   time.Duration    // Degrade when p99 latency reaches this, 0 disables the signal
+	Backlog      int64            // Degrade when the queue backlog reaches this, 0 disables the signal
+	MinRequests  int64            // Error rate and latency are ignored below this many requests
+	RecoverRatio float64          // Recover once every signal is below RecoverRatio of its threshold
+	RecoverAfter int              // Consecutive healthy evaluations needed to recover
+	TTL          time.Duration    // Degrade TTL, refreshed on every evaluation so a dead controller cannot pin it
+	Strategy     *DegradeStrategy // Strategy applied while degraded
+}
+
+// Transition automatic degradation state change
+type Transition struct {
+	ActivityID uint64  `json:"activity_id"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Reason     string  `json:"reason"`
+	Strategy   string  `json:"strategy,omitempty"`
+	Signals    Signals `json:"signals"`
+	At         int64   `json:"at"`
+}
+
+// AutoState automatic degradation state of an activity on this instance
+type AutoState struct {
+	ActivityID uint64  `json:"activity_id"`
+	State      string  `json:"state"`
+	Since      int64   `json:"since"`
+	Healthy    int     `json:"healthy_evaluations"`
+	Signals    Signals `json:"signals"`
+}
+
+// AutoController degrades activities from live signals and recovers them with hysteresis
+//
+// Signals are local to the instance, the Redis degrade status is shared. The
+// controller only recovers activities it degraded itself and never touches a
+// degradation enabled manually.
+type AutoController struct {
+	manager *DegradeManager
+	backlog func() int64
+	mu      sync.Mutex
+	states  map[uint64]*AutoState
+	now     func() time.Time
+}
+
+// NewAutoController creates an auto degrade controller, backlog may be nil
+func NewAutoController(manager *DegradeManager, backlog func() int64) *AutoController {
+	return &AutoController{
+		manager: manager,
+		backlog: backlog,
+		states:  make(map[uint64]*AutoState),
+		now:     time.Now,
+	}
+}
+
+// Evaluate checks the activity signals against policy and degrades or recovers it
+// Returns the transition if the state changed
+func (c *AutoController) Evaluate(ctx context.Context, activityID uint64, policy *AutoPolicy) (*Transition, error) {
+	signals := c.manager.Signals(activityID)
+	if c.backlog != nil {
+		signals.Backlog = c.backlog()
+	}
+
+	c.mu.Lock()
+	state, ok := c.states[activityID]
+	if !ok {
+		state = &AutoState{ActivityID: activityID, State: AutoStateNormal, Since: c.now().Unix()}
+		c.states[activityID] = state
+	}
+	state.Signals = signals
+	current := *state
+	c.mu.Unlock()
+
+	reason := overloaded(signals, policy)
+
+	if current.State == AutoStateNormal {
+		if reason == "" {
+			return nil, nil
+		}
+		// Degraded manually or by another instance, leave it to its owner
+		if c.manager.IsDegrade(ctx, activityID) {
+			return nil, nil
+		}
+		if err := c.degrade(ctx, activityID, policy); err != nil {
+			return nil, err
+		}
+		return c.transition(ctx, activityID, AutoStateDegraded, reason, policy, signals), nil
+	}
+
+	// Marker gone: degradation expired, was disabled or taken over manually
+	if owned, err := c.manager.redis.Exists(ctx, autoKey(activityID)).Result(); err != nil {
+		return nil, err
+	} else if owned == 0 {
+		return c.transition(ctx, activityID, AutoStateNormal, "degradation cleared externally", policy, signals), nil
+	}
+
+	healthy := 0
+	if reason == "" && recovered(signals, policy) {
+		healthy = current.Healthy + 1
+	}
+	c.mu.Lock()
+	state.Healthy = healthy
+	c.mu.Unlock()
+
+	if healthy < policy.RecoverAfter {
+		return nil, c.degrade(ctx, activityID, policy)
+	}
+
+	if err := c.manager.DisableDegrade(ctx, activityID); err != nil {
+		return nil, err
+	}
+	return c.transition(ctx, activityID, AutoStateNormal, "signals recovered", policy, signals), nil
+}
+
+// States returns the automatic degradation state of every evaluated activity
+func (c *AutoController) States() []AutoState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	states := make([]AutoState, 0, len(c.states))
+	for _, state := range c.states {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ActivityID < states[j].ActivityID })
+	return states
+}
+
+// Transitions returns the most recent transitions of an activity across all instances, newest first
+func (c *AutoController) Transitions(ctx context.Context, activityID uint64, limit int) ([]*Transition, error) {
+	if limit <= 0 || limit > maxTransitions {
+		limit = maxTransitions
+	}
+
+	values, err := c.manager.redis.LRange(ctx, transitionsKey(activityID), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get degrade transitions: %w", err)
+	}
+
+	transitions := make([]*Transition, 0, len(values))
+	for _, value := range values {
+		var transition Transition
+		if err := json.Unmarshal([]byte(value), &transition); err != nil {
+			continue
+		}
+		transitions = append(transitions, &transition)
+	}
+	return transitions, nil
+}
+
+// Forget drops the local state of an activity, e.g. once it has ended
+func (c *AutoController) Forget(activityID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.states, activityID)
+}
+
+// degrade sets or refreshes the degradation and its ownership marker
+func (c *AutoController) degrade(ctx context.Context, activityID uint64, policy *AutoPolicy) error {
+	if err := c.manager.SetTemporaryDegrade(ctx, activityID, policy.Strategy, policy.TTL); err != nil {
+		return err
+	}
+	if err := c.manager.redis.Set(ctx, autoKey(activityID), "1", policy.TTL).Err(); err != nil {
+		return fmt.Errorf("failed to set auto degrade marker: %w", err)
+	}
+	return nil
+}
+
+// transition updates the local state and appends the change to the shared history
+func (c *AutoController) transition(ctx context.Context, activityID uint64, to, reason string, policy *AutoPolicy, signals Signals) *Transition {
+	now := c.now()
+
+	c.mu.Lock()
+	state := c.states[activityID]
+	from := state.State
+	state.State = to
+	state.Since = now.Unix()
+	state.Healthy = 0
+	c.mu.Unlock()
+
+	transition := &Transition{
+		ActivityID: activityID,
+		From:       from,
+		To:         to,
+		Reason:     reason,
+		Signals:    signals,
+		At:         now.Unix(),
+	}
+	if to == AutoStateDegraded && policy.Strategy != nil {
+		transition.Strategy = policy.Strategy.Type
+	}
+
+	// History is best effort, the transition itself already happened
+	if data, err := json.Marshal(transition); err == nil {
+		key := transitionsKey(activityID)
+		pipe := c.manager.redis.TxPipeline()
+		pipe.LPush(ctx, key, data)
+		pipe.LTrim(ctx, key, 0, maxTransitions-1)
+		pipe.Expire(ctx, key, transitionsTTL)
+		pipe.Exec(ctx)
+	}
+
+	return transition
+}
+
+// overloaded returns why the signals cross the policy thresholds, empty if they do not
+func overloaded(signals Signals, policy *AutoPolicy) string {
+	if policy.Backlog > 0 && signals.Backlog >= policy.Backlog {
+		return fmt.Sprintf("backlog %d >= %d", signals.Backlog, policy.Backlog)
+	}
+	if signals.Requests < policy.MinRequests {
+		return ""
+	}
+	if policy.ErrorRate > 0 && signals.ErrorRate >= policy.ErrorRate {
+		return fmt.Sprintf("error rate %.4f >= %.4f", signals.ErrorRate, policy.ErrorRate)
+	}
+	if policy.P99Latency > 0 && signals.P99Latency >= policy.P99Latency {
+		return fmt.Sprintf("p99 latency %s >= %s", signals.P99Latency, policy.P99Latency)
+	}
+	return ""
+}
+
+// recovered whether every signal is below RecoverRatio of its threshold
+func recovered(signals Signals, policy *AutoPolicy) bool {
+	if policy.Backlog > 0 && float64(signals.Backlog) >= float64(policy.Backlog)*policy.RecoverRatio {
+		return false
+	}
+	if signals.Requests < policy.MinRequests {
+		return true
+	}
+	if policy.ErrorRate > 0 && signals.ErrorRate >= policy.ErrorRate*policy.RecoverRatio {
+		return false
+	}
+	if policy.P99Latency > 0 && float64(signals.P99Latency) >= float64(policy.P99Latency)*policy.RecoverRatio {
+		return false
+	}
+	return true
+}
+
+func autoKey(activityID uint64) string {
+	return fmt.Sprintf("degrade:auto:%d", activityID)
+}
+
+func transitionsKey(activityID uint64) string {
+	return fmt.Sprintf("degrade:transitions:%d", activityID)
+}
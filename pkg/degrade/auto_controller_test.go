@@ -0,0 +1,146 @@
+package degrade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPolicy() *AutoPolicy {
+	return &AutoPolicy{
+		ErrorRate:    0.5,
+		P99Latency:   500 * time.Millisecond,
+		Backlog:      100,
+		MinRequests:  10,
+		RecoverRatio: 0.5,
+		RecoverAfter: 2,
+		TTL:          time.Minute,
+		Strategy:     &DegradeStrategy{Type: "lottery", Ratio: 0.3},
+	}
+}
+
+func TestAutoController_DegradeAndRecover(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	dm := NewDegradeManager(client)
+	controller := NewAutoController(dm, nil)
+	ctx := context.Background()
+	policy := testPolicy()
+
+	// Below MinRequests errors are ignored
+	for i := 0; i < 5; i++ {
+		dm.RecordRequest(1, time.Millisecond, true)
+	}
+	transition, err := controller.Evaluate(ctx, 1, policy)
+	require.NoError(t, err)
+	assert.Nil(t, transition)
+
+	for i := 0; i < 5; i++ {
+		dm.RecordRequest(1, time.Millisecond, true)
+	}
+	transition, err = controller.Evaluate(ctx, 1, policy)
+	require.NoError(t, err)
+	require.NotNil(t, transition)
+	assert.Equal(t, AutoStateNormal, transition.From)
+	assert.Equal(t, AutoStateDegraded, transition.To)
+	assert.Equal(t, "lottery", transition.Strategy)
+	assert.True(t, dm.IsDegrade(ctx, 1))
+	assert.Equal(t, 0.3, dm.GetStrategy(ctx, 1).Ratio)
+
+	// Error rate between the recover level and the threshold keeps it degraded
+	dm.signals = NewSignalRecorder()
+	for i := 0; i < 10; i++ {
+		dm.RecordRequest(1, time.Millisecond, i < 3)
+	}
+	for i := 0; i < 3; i++ {
+		transition, err = controller.Evaluate(ctx, 1, policy)
+		require.NoError(t, err)
+		assert.Nil(t, transition)
+	}
+	assert.True(t, dm.IsDegrade(ctx, 1))
+
+	// Recovers after RecoverAfter consecutive healthy evaluations
+	dm.signals = NewSignalRecorder()
+	transition, err = controller.Evaluate(ctx, 1, policy)
+	require.NoError(t, err)
+	assert.Nil(t, transition)
+	transition, err = controller.Evaluate(ctx, 1, policy)
+	require.NoError(t, err)
+	require.NotNil(t, transition)
+	assert.Equal(t, AutoStateNormal, transition.To)
+	assert.False(t, dm.IsDegrade(ctx, 1))
+
+	transitions, err := controller.Transitions(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, AutoStateNormal, transitions[0].To)
+	assert.Equal(t, AutoStateDegraded, transitions[1].To)
+}
+
+func TestAutoController_LatencyAndBacklog(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	policy := testPolicy()
+
+	t.Run("p99 latency", func(t *testing.T) {
+		dm := NewDegradeManager(client)
+		controller := NewAutoController(dm, nil)
+		for i := 0; i < 20; i++ {
+			dm.RecordRequest(1, time.Second, false)
+		}
+		transition, err := controller.Evaluate(ctx, 1, policy)
+		require.NoError(t, err)
+		require.NotNil(t, transition)
+		assert.Contains(t, transition.Reason, "p99 latency")
+	})
+
+	t.Run("backlog", func(t *testing.T) {
+		dm := NewDegradeManager(client)
+		controller := NewAutoController(dm, func() int64 { return 150 })
+		transition, err := controller.Evaluate(ctx, 2, policy)
+		require.NoError(t, err)
+		require.NotNil(t, transition)
+		assert.Contains(t, transition.Reason, "backlog")
+		assert.Equal(t, int64(150), controller.States()[0].Signals.Backlog)
+	})
+}
+
+func TestAutoController_LeavesManualDegradeAlone(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	dm := NewDegradeManager(client)
+	controller := NewAutoController(dm, nil)
+	ctx := context.Background()
+	policy := testPolicy()
+
+	// Manual degradation is never taken over nor recovered
+	require.NoError(t, dm.EnableDegrade(ctx, 1, &DegradeStrategy{Type: "queue_only"}))
+	for i := 0; i < 20; i++ {
+		dm.RecordRequest(1, time.Millisecond, true)
+	}
+	transition, err := controller.Evaluate(ctx, 1, policy)
+	require.NoError(t, err)
+	assert.Nil(t, transition)
+	assert.Equal(t, "queue_only", dm.GetStrategy(ctx, 1).Type)
+
+	// Manual disable of an auto degradation moves the controller back to normal
+	require.NoError(t, dm.DisableDegrade(ctx, 1))
+	transition, err = controller.Evaluate(ctx, 1, policy)
+	require.NoError(t, err)
+	require.NotNil(t, transition)
+	assert.Equal(t, AutoStateDegraded, transition.To)
+
+	require.NoError(t, dm.DisableDegrade(ctx, 1))
+	transition, err = controller.Evaluate(ctx, 1, policy)
+	require.NoError(t, err)
+	require.NotNil(t, transition)
+	assert.Equal(t, AutoStateNormal, transition.To)
+	assert.Equal(t, "degradation cleared externally", transition.Reason)
+}
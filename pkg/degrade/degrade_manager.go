@@ -4,20 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"seckill/pkg/log"
+	"seckill/pkg/utils"
 )
 
 // DegradeManager manages service degradation
 type DegradeManager struct {
-	redis redis.Cmdable
+	redis   redis.Cmdable
+	signals *SignalRecorder
 }
 
 // NewDegradeManager creates a new degrade manager
 func NewDegradeManager(redis redis.Cmdable) *DegradeManager {
 	return &DegradeManager{
-		redis: redis,
+		redis:   redis,
+		signals: NewSignalRecorder(),
 	}
 }
 
@@ -25,8 +31,9 @@ func NewDegradeManager(redis redis.Cmdable) *DegradeManager {
 type DegradeStrategy struct {
 	Type        string `json:"type"`         // queue_only/return_error/lottery
 	EstWaitTime int    `json:"est_wait_time"` // Estimated wait time (seconds)
-	Ratio       float64 `json:"ratio"`        // Degradation ratio (0-1)
+	Ratio       float64 `json:"ratio"`        // Degradation ratio (0-1), for lottery the fraction of users admitted
 	Message     string `json:"message"`       // Message to return
+	AdmitRate   int    `json:"admit_rate"`    // Waiting room admissions per second (queue_only)
 }
 
 // IsDegrade checks if service is degraded
@@ -83,6 +90,9 @@ func (dm *DegradeManager) EnableDegrade(ctx context.Context, activityID uint64,
 	if err := dm.redis.Set(ctx, strategyKey, data, 0).Err(); err != nil {
 		return fmt.Errorf("failed to set degrade strategy: %w", err)
 	}
+
+	// Manual degradation takes over from the auto controller
+	dm.redis.Del(ctx, autoKey(activityID))
 	
 	return nil
 }
@@ -92,7 +102,7 @@ func (dm *DegradeManager) DisableDegrade(ctx context.Context, activityID uint64)
 	statusKey := fmt.Sprintf("degrade:status:%d", activityID)
 	strategyKey := fmt.Sprintf("degrade:strategy:%d", activityID)
 	
-	if err := dm.redis.Del(ctx, statusKey, strategyKey).Err(); err != nil {
+	if err := dm.redis.Del(ctx, statusKey, strategyKey, autoKey(activityID)).Err(); err != nil {
 		return fmt.Errorf("failed to disable degrade: %w", err)
 	}
 	
@@ -127,10 +137,11 @@ func (dm *DegradeManager) GetDegradeStatus(ctx context.Context) (map[uint64]*Deg
 	result := make(map[uint64]*DegradeStrategy)
 	
 	// Scan for all degrade status keys
-	iter := dm.redis.Scan(ctx, 0, "degrade:status:*", 0).Iterator()
-	for iter.Next(ctx) {
-		key := iter.Val()
-		
+	keys, err := utils.ScanKeys(ctx, dm.redis, "degrade:status:*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan degrade keys: %w", err)
+	}
+	for _, key := range keys {
 		// Extract activity ID from key
 		var activityID uint64
 		if _, err := fmt.Sscanf(key, "degrade:status:%d", &activityID); err != nil {
@@ -142,10 +153,82 @@ func (dm *DegradeManager) GetDegradeStatus(ctx context.Context) (map[uint64]*Deg
 		result[activityID] = strategy
 	}
 	
-	if err := iter.Err(); err != nil {
-		return nil, fmt.Errorf("failed to scan degrade keys: %w", err)
-	}
-	
 	return result, nil
 }
 
+
+// RecordRequest records a request outcome for automatic degradation
+func (dm *DegradeManager) RecordRequest(activityID uint64, latency time.Duration, failed bool) {
+	dm.signals.Record(activityID, latency, failed)
+}
+
+// Signals gets the recent request signals of an activity on this instance
+func (dm *DegradeManager) Signals(activityID uint64) Signals {
+	return dm.signals.Snapshot(activityID)
+}
+
+// LotteryStats lottery admission counters of an activity
+type LotteryStats struct {
+	Admitted int64 `json:"admitted"`
+	Rejected int64 `json:"rejected"`
+}
+
+// lotteryBuckets resolution of the lottery ratio
+const lotteryBuckets = 10000
+
+// DrawLottery decides whether the user is admitted under the lottery strategy.
+// The draw only depends on activity and user, so retrying cannot change the outcome.
+func (dm *DegradeManager) DrawLottery(ctx context.Context, activityID, userID uint64, ratio float64) bool {
+	admitted := lotteryAdmit(activityID, userID, ratio)
+
+	field := "rejected"
+	if admitted {
+		field = "admitted"
+	}
+	key := fmt.Sprintf("degrade:lottery:%d", activityID)
+	_, err := dm.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, field, 1)
+		pipe.Expire(ctx, key, 7*24*time.Hour)
+		return nil
+	})
+	if err != nil {
+		// The draw stands, only the counters miss it
+		log.WithFields(map[string]interface{}{
+			"activity_id": activityID,
+			"error":       err.Error(),
+		}).Warn("Failed to count lottery draw")
+	}
+
+	return admitted
+}
+
+// GetLotteryStats gets lottery admission counters
+func (dm *DegradeManager) GetLotteryStats(ctx context.Context, activityID uint64) (*LotteryStats, error) {
+	key := fmt.Sprintf("degrade:lottery:%d", activityID)
+
+	values, err := dm.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lottery stats: %w", err)
+	}
+
+	stats := &LotteryStats{}
+	fmt.Sscanf(values["admitted"], "%d", &stats.Admitted)
+	fmt.Sscanf(values["rejected"], "%d", &stats.Rejected)
+	return stats, nil
+}
+
+// lotteryAdmit maps (activity, user) to a stable bucket and admits it if below ratio
+func lotteryAdmit(activityID, userID uint64, ratio float64) bool {
+	if ratio <= 0 {
+		return false
+	}
+	if ratio >= 1 {
+		return true
+	}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "lottery:%d:%d", activityID, userID)
+	bucket := h.Sum64() % lotteryBuckets
+
+	return float64(bucket) < ratio*lotteryBuckets
+}
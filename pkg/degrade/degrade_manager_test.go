@@ -125,3 +125,38 @@ func TestDegradeManager_DefaultStrategy(t *testing.T) {
 	assert.Equal(t, "System busy, please try again later", strategy.Message)
 }
 
+
+func TestDegradeManager_DrawLottery(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	dm := NewDegradeManager(client)
+	ctx := context.Background()
+	activityID := uint64(3)
+
+	// Same user always gets the same outcome
+	for userID := uint64(1); userID <= 50; userID++ {
+		first := dm.DrawLottery(ctx, activityID, userID, 0.3)
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, first, dm.DrawLottery(ctx, activityID, userID, 0.3))
+		}
+	}
+
+	// Admitted fraction roughly follows the ratio
+	admitted := 0
+	for userID := uint64(1000); userID < 11000; userID++ {
+		if lotteryAdmit(activityID, userID, 0.3) {
+			admitted++
+		}
+	}
+	assert.InDelta(t, 3000, admitted, 300)
+
+	// Boundary ratios
+	assert.False(t, lotteryAdmit(activityID, 1, 0))
+	assert.True(t, lotteryAdmit(activityID, 1, 1))
+
+	// Every draw is counted
+	stats, err := dm.GetLotteryStats(ctx, activityID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), stats.Admitted+stats.Rejected)
+}
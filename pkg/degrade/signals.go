@@ -0,0 +1,147 @@
+package degrade
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// signalSlots number of one second slots kept per activity
+	signalSlots = 10
+)
+
+// latencyBounds upper bounds of the latency histogram, the last bucket is unbounded
+var latencyBounds = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Signals live health signals of an activity over the recent window
+type Signals struct {
+	Requests   int64         `json:"requests"`
+	Errors     int64         `json:"errors"`
+	ErrorRate  float64       `json:"error_rate"`
+	P99Latency time.Duration `json:"p99_latency"`
+	Backlog    int64         `json:"backlog"`
+}
+
+// signalSlot counters of one second
+type signalSlot struct {
+	second    int64
+	requests  int64
+	errors    int64
+	latencies [len(latencyBounds) + 1]int64
+}
+
+// SignalRecorder per-activity sliding window of request outcomes, local to this instance
+type SignalRecorder struct {
+	mu    sync.Mutex
+	slots map[uint64]*[signalSlots]signalSlot
+	now   func() time.Time
+}
+
+// NewSignalRecorder creates a signal recorder
+func NewSignalRecorder() *SignalRecorder {
+	return &SignalRecorder{
+		slots: make(map[uint64]*[signalSlots]signalSlot),
+		now:   time.Now,
+	}
+}
+
+// Record records the outcome of one request
+func (r *SignalRecorder) Record(activityID uint64, latency time.Duration, failed bool) {
+	second := r.now().Unix()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ring, ok := r.slots[activityID]
+	if !ok {
+		ring = &[signalSlots]signalSlot{}
+		r.slots[activityID] = ring
+	}
+
+	slot := &ring[second%signalSlots]
+	if slot.second != second {
+		*slot = signalSlot{second: second}
+	}
+	slot.requests++
+	if failed {
+		slot.errors++
+	}
+	slot.latencies[latencyBucket(latency)]++
+}
+
+// Snapshot returns the signals of the last signalSlots seconds, backlog is left to the caller
+func (r *SignalRecorder) Snapshot(activityID uint64) Signals {
+	oldest := r.now().Unix() - signalSlots + 1
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var signals Signals
+	ring, ok := r.slots[activityID]
+	if !ok {
+		return signals
+	}
+
+	var latencies [len(latencyBounds) + 1]int64
+	for i := range ring {
+		slot := &ring[i]
+		if slot.second < oldest {
+			continue
+		}
+		signals.Requests += slot.requests
+		signals.Errors += slot.errors
+		for j, count := range slot.latencies {
+			latencies[j] += count
+		}
+	}
+
+	if signals.Requests == 0 {
+		return signals
+	}
+	signals.ErrorRate = float64(signals.Errors) / float64(signals.Requests)
+	signals.P99Latency = percentile(latencies[:], signals.Requests, 0.99)
+	return signals
+}
+
+// latencyBucket index of the histogram bucket holding latency
+func latencyBucket(latency time.Duration) int {
+	for i, bound := range latencyBounds {
+		if latency <= bound {
+			return i
+		}
+	}
+	return len(latencyBounds)
+}
+
+// percentile upper bound of the bucket holding the p-th request, overflow reports the last bound
+func percentile(latencies []int64, total int64, p float64) time.Duration {
+	rank := int64(math.Ceil(float64(total) * p))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i, count := range latencies {
+		seen += count
+		if seen >= rank {
+			if i < len(latencyBounds) {
+				return latencyBounds[i]
+			}
+			break
+		}
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
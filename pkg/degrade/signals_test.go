@@ -0,0 +1,31 @@
+package degrade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignalRecorder_Snapshot(t *testing.T) {
+	recorder := NewSignalRecorder()
+	now := time.Unix(1700000000, 0)
+	recorder.now = func() time.Time { return now }
+
+	for i := 0; i < 98; i++ {
+		recorder.Record(1, 20*time.Millisecond, false)
+	}
+	recorder.Record(1, 800*time.Millisecond, true)
+	recorder.Record(1, 3*time.Second, true)
+
+	signals := recorder.Snapshot(1)
+	assert.Equal(t, int64(100), signals.Requests)
+	assert.Equal(t, int64(2), signals.Errors)
+	assert.InDelta(t, 0.02, signals.ErrorRate, 0.0001)
+	assert.Equal(t, time.Second, signals.P99Latency)
+
+	// Slots older than the window are ignored
+	now = now.Add(signalSlots * time.Second)
+	assert.Equal(t, int64(0), recorder.Snapshot(1).Requests)
+	assert.Equal(t, int64(0), recorder.Snapshot(2).Requests)
+}
@@ -0,0 +1,111 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingScript counts the runs of a script before passing them on
+type countingScript struct {
+	Script
+	runs int
+}
+
+func (s *countingScript) Run(ctx context.Context, c redis.Scripter, keys []string, args ...interface{}) *redis.Cmd {
+	s.runs++
+	return s.Script.Run(ctx, c, keys, args...)
+}
+
+func TestSemaphore(t *testing.T) {
+	client := setupRedis(t)
+	ctx := context.Background()
+
+	t.Run("AcquireUpToLimit", func(t *testing.T) {
+		sem := NewSemaphore(client, time.Minute)
+
+		first, ok, err := sem.Acquire(ctx, "limit_test", 2)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		_, ok, err = sem.Acquire(ctx, "limit_test", 2)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		_, ok, err = sem.Acquire(ctx, "limit_test", 2)
+		require.NoError(t, err)
+		assert.False(t, ok, "3rd holder should be rejected")
+
+		// Released slot can be taken again
+		require.NoError(t, sem.Release(ctx, "limit_test", first))
+		_, ok, err = sem.Acquire(ctx, "limit_test", 2)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		inFlight, err := sem.InFlight(ctx, "limit_test")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), inFlight)
+	})
+
+	t.Run("ExpiredLeaseIsReclaimed", func(t *testing.T) {
+		sem := NewSemaphore(client, 50*time.Millisecond)
+
+		_, ok, err := sem.Acquire(ctx, "lease_test", 1)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		_, ok, err = sem.Acquire(ctx, "lease_test", 1)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		time.Sleep(100 * time.Millisecond)
+		_, ok, err = sem.Acquire(ctx, "lease_test", 1)
+		require.NoError(t, err)
+		assert.True(t, ok, "slot should be reclaimed after the lease expires")
+	})
+
+	t.Run("RunsInjectedScript", func(t *testing.T) {
+		script := &countingScript{Script: redis.NewScript(SemaphoreAcquireScript)}
+		UseSemaphoreScript(script)
+		t.Cleanup(func() { UseSemaphoreScript(redis.NewScript(SemaphoreAcquireScript)) })
+
+		_, ok, err := NewSemaphore(client, time.Minute).Acquire(ctx, "script_test", 1)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 1, script.runs)
+	})
+
+	t.Run("ZeroLimitIsUnlimited", func(t *testing.T) {
+		sem := NewSemaphore(client, time.Minute)
+
+		for i := 0; i < 10; i++ {
+			token, ok, err := sem.Acquire(ctx, "unlimited_test", 0)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.NoError(t, sem.Release(ctx, "unlimited_test", token))
+		}
+	})
+}
+
+func TestFixedWindowLimiter(t *testing.T) {
+	client := setupRedis(t)
+	ctx := context.Background()
+
+	limiter := NewFixedWindowLimiter(client, 3, time.Minute)
+	for i := 0; i < 3; i++ {
+		allowed, err := limiter.Allow(ctx, "fixed_key")
+		require.NoError(t, err)
+		assert.True(t, allowed, "Request %d should be allowed", i+1)
+	}
+
+	allowed, err := limiter.Allow(ctx, "fixed_key")
+	require.NoError(t, err)
+	assert.False(t, allowed, "4th request should be rejected")
+
+	allowed, err = limiter.Allow(ctx, "other_key")
+	require.NoError(t, err)
+	assert.True(t, allowed, "keys are limited independently")
+}
@@ -13,6 +13,40 @@ var (
 	ErrLockNotHeld     = errors.New("lock not held")
 )
 
+// Script runs a Lua script by hash, satisfied by *redis.Script and the shared script registry
+type Script interface {
+	Run(ctx context.Context, c redis.Scripter, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// UnlockScript deletes the lock if it still holds the caller's value
+const UnlockScript = `
+	if redis.call("get", KEYS[1]) == ARGV[1] then
+		return redis.call("del", KEYS[1])
+	else
+		return 0
+	end
+`
+
+// ExtendScript renews the lock's TTL if it still holds the caller's value
+const ExtendScript = `
+	if redis.call("get", KEYS[1]) == ARGV[1] then
+		return redis.call("pexpire", KEYS[1], ARGV[2])
+	else
+		return 0
+	end
+`
+
+var (
+	unlockScript Script = redis.NewScript(UnlockScript)
+	extendScript Script = redis.NewScript(ExtendScript)
+)
+
+// UseLockScripts runs unlock and extend through a shared script registry,
+// must be called before any lock is used
+func UseLockScripts(unlock, extend Script) {
+	unlockScript, extendScript = unlock, extend
+}
+
 // RedisLock represents a distributed lock using Redis
 type RedisLock struct {
 	client redis.Cmdable
@@ -70,15 +104,7 @@ func (l *RedisLock) TryLock(ctx context.Context, maxRetries int, retryDelay time
 
 // Unlock releases the lock
 func (l *RedisLock) Unlock(ctx context.Context) error {
-	script := `
-		if redis.call("get", KEYS[1]) == ARGV[1] then
-			return redis.call("del", KEYS[1])
-		else
-			return 0
-		end
-	`
-
-	result, err := l.client.Eval(ctx, script, []string{l.key}, l.value).Int()
+	result, err := unlockScript.Run(ctx, l.client, []string{l.key}, l.value).Int()
 	if err != nil {
 		return err
 	}
@@ -92,15 +118,7 @@ func (l *RedisLock) Unlock(ctx context.Context) error {
 
 // Extend extends the lock TTL
 func (l *RedisLock) Extend(ctx context.Context, ttl time.Duration) error {
-	script := `
-		if redis.call("get", KEYS[1]) == ARGV[1] then
-			return redis.call("pexpire", KEYS[1], ARGV[2])
-		else
-			return 0
-		end
-	`
-
-	result, err := l.client.Eval(ctx, script, []string{l.key}, l.value, int(ttl.Milliseconds())).Int()
+	result, err := extendScript.Run(ctx, l.client, []string{l.key}, l.value, int(ttl.Milliseconds())).Int()
 	if err != nil {
 		return err
 	}
@@ -18,7 +18,7 @@ var (
 type Config struct {
 	Level      string `json:"level"`      // debug, info, warn, error
 	Format     string `json:"format"`     // json, text
-	Output     string `json:"output"`     // stdout, file
+	Output     string `json:"output"`     // stdout, stderr, file
 	Filename   string `json:"filename"`   // log file path
 	MaxSize    int    `json:"max_size"`   // maximum size of a single file (MB)
 	MaxAge     int    `json:"max_age"`    // maximum number of days to keep files
@@ -51,6 +51,9 @@ func Init(cfg Config) error {
 
 	// Set output
 	var output io.Writer = os.Stdout
+	if cfg.Output == "stderr" {
+		output = os.Stderr
+	}
 	if cfg.Output == "file" && cfg.Filename != "" {
 		// Ensure log directory exists
 		if err := os.MkdirAll(filepath.Dir(cfg.Filename), 0755); err != nil {
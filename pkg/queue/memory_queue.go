@@ -187,6 +187,18 @@ func (mq *MemoryQueue) Health() error {
 	return nil
 }
 
+// Backlog returns the number of messages pending in the topic
+func (mq *MemoryQueue) Backlog(topic string) int {
+	mq.mu.RLock()
+	defer mq.mu.RUnlock()
+
+	t, exists := mq.topics[topic]
+	if !exists {
+		return 0
+	}
+	return len(t.messages)
+}
+
 // GetStats returns queue statistics
 func (mq *MemoryQueue) GetStats() *QueueStats {
 	mq.mu.RLock()
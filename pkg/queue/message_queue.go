@@ -108,3 +108,11 @@ func (q *MemoryMessageQueue) Close() error {
 	return nil
 }
 
+
+// Backlog returns the number of messages pending in the topic
+func (q *MemoryMessageQueue) Backlog(topic string) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return len(q.queues[topic])
+}
@@ -20,6 +20,12 @@ type Queue interface {
 	Health() error
 }
 
+// BacklogReporter reports messages waiting to be consumed
+type BacklogReporter interface {
+	// Backlog returns the number of messages pending in the topic
+	Backlog(topic string) int
+}
+
 // MessageHandler handles incoming messages
 type MessageHandler func(ctx context.Context, topic string, message []byte) error
 
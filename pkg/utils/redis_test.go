@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanKeys(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	for i, key := range []string{"bloom:1", "bloom:2", "bloom:3", "other"} {
+		require.NoError(t, mr.Set(key, string(rune('a'+i))))
+	}
+
+	keys, err := ScanKeys(context.Background(), client, "bloom:*")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"bloom:1", "bloom:2", "bloom:3"}, keys)
+}
@@ -67,3 +67,24 @@ func SuccessPageResponse(c *gin.Context, list interface{}, total int64, page, si
 		Timestamp: time.Now().Unix(),
 	})
 }
+
+// CursorResponse cursor page response structure
+type CursorResponse struct {
+	List       interface{} `json:"list"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+}
+
+// SuccessCursorResponse returns success cursor page response
+func SuccessCursorResponse(c *gin.Context, list interface{}, nextCursor string, hasMore bool) {
+	c.JSON(http.StatusOK, Response{
+		Code:    0,
+		Message: "success",
+		Data: CursorResponse{
+			List:       list,
+			NextCursor: nextCursor,
+			HasMore:    hasMore,
+		},
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -31,3 +31,18 @@ PREPARE stmt FROM @query;
 EXECUTE stmt;
 DEALLOCATE PREPARE stmt;
 
+
+-- Covering index for the units sold on Redis, summed per activity and user by the MySQL fallback
+SET @index_exists = 0;
+SELECT COUNT(*) INTO @index_exists 
+FROM INFORMATION_SCHEMA.STATISTICS 
+WHERE TABLE_SCHEMA = 'seckill' 
+  AND TABLE_NAME = 'orders' 
+  AND INDEX_NAME = 'idx_activity_user_status';
+
+SET @query = IF(@index_exists = 0,
+    'ALTER TABLE orders ADD INDEX idx_activity_user_status (activity_id, user_id, status, quantity, deduct_id)',
+    'SELECT ''Index idx_activity_user_status already exists'' AS message');
+PREPARE stmt FROM @query;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
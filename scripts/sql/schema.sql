@@ -182,7 +182,7 @@ CREATE TABLE `stock_logs` (
   `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Log ID',
   `activity_id` BIGINT UNSIGNED NOT NULL COMMENT 'Activity ID',
   `goods_id` BIGINT UNSIGNED NOT NULL COMMENT 'Goods ID',
-  `operation_type` TINYINT NOT NULL COMMENT 'Operation type: 1-deduct, 2-replenish, 3-sync',
+  `operation_type` TINYINT NOT NULL COMMENT 'Operation type: 1-deduct, 2-replenish, 3-sync, 4-confirm, 5-repair',
   `quantity` INT NOT NULL COMMENT 'Quantity (positive for increase, negative for decrease)',
   `before_stock` INT NOT NULL COMMENT 'Stock before operation',
   `after_stock` INT NOT NULL COMMENT 'Stock after operation',
@@ -263,6 +263,141 @@ CREATE TABLE `blacklist` (
   KEY `idx_expire_at` (`expire_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Blacklist table';
 
+-- ========================================
+-- 11. User blacklists table (global and per-activity seckill bans)
+-- ========================================
+CREATE TABLE `user_blacklists` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Blacklist ID',
+  `user_id` BIGINT UNSIGNED NOT NULL COMMENT 'User ID',
+  `activity_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Activity ID, 0 for all activities',
+  `reason` VARCHAR(255) NOT NULL COMMENT 'Reason',
+  `operator` VARCHAR(50) NOT NULL COMMENT 'Operator',
+  `expire_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Expiration time (NULL for permanent)',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created time',
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'Updated time',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_user_activity` (`user_id`, `activity_id`),
+  KEY `idx_activity_id` (`activity_id`),
+  KEY `idx_expire_at` (`expire_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='User blacklists table';
+
+-- ========================================
+-- 12. VIP grants table (tiers granted on top of level/points)
+-- ========================================
+CREATE TABLE `vip_grants` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Grant ID',
+  `user_id` BIGINT UNSIGNED NOT NULL COMMENT 'User ID',
+  `tier` TINYINT NOT NULL COMMENT 'VIP tier: 1-silver, 2-gold, 3-platinum',
+  `reason` VARCHAR(255) NOT NULL COMMENT 'Reason',
+  `operator` VARCHAR(50) NOT NULL COMMENT 'Operator',
+  `expire_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Expiration time (NULL for permanent)',
+  `revoked_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Revoked time',
+  `revoked_by` VARCHAR(50) DEFAULT NULL COMMENT 'Revoked by',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created time',
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'Updated time',
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`),
+  KEY `idx_expire_at` (`expire_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='VIP grants table';
+
+-- ========================================
+-- 13. Seckill purchases table (stock deducted from MySQL while Redis is unavailable)
+-- ========================================
+CREATE TABLE `seckill_purchases` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Purchase ID',
+  `deduct_id` VARCHAR(128) NOT NULL COMMENT 'Deduction ID',
+  `activity_id` BIGINT UNSIGNED NOT NULL COMMENT 'Activity ID',
+  `user_id` BIGINT UNSIGNED NOT NULL COMMENT 'User ID',
+  `quantity` INT NOT NULL COMMENT 'Quantity',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT 'Status: 0-try, 1-confirmed, 2-cancelled',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created time',
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'Updated time',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_deduct_id` (`deduct_id`),
+  KEY `idx_activity_user` (`activity_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Seckill purchases table';
+
+-- ========================================
+-- 14. TCC logs table (stock tries and how they were settled)
+-- ========================================
+CREATE TABLE `tcc_logs` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Log ID',
+  `deduct_id` VARCHAR(128) NOT NULL COMMENT 'Deduction ID',
+  `request_id` VARCHAR(64) NOT NULL COMMENT 'Request ID',
+  `activity_id` BIGINT UNSIGNED NOT NULL COMMENT 'Activity ID',
+  `user_id` BIGINT UNSIGNED NOT NULL COMMENT 'User ID',
+  `quantity` INT NOT NULL COMMENT 'Quantity',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT 'Status: 0-try, 1-confirmed, 2-cancelled',
+  `result` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Result of the last confirm/cancel',
+  `recovered` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Cancelled by the recovery sweeper',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created time',
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'Updated time',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_deduct_id` (`deduct_id`),
+  KEY `idx_request_id` (`request_id`),
+  KEY `idx_activity_status_user` (`activity_id`, `status`, `user_id`, `quantity`, `deduct_id`),
+  KEY `idx_status_created` (`status`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='TCC logs table';
+
+-- ========================================
+-- 15. TCC compensations table (failed confirm/cancel awaiting retry)
+-- ========================================
+CREATE TABLE `tcc_compensations` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Compensation ID',
+  `deduct_id` VARCHAR(128) NOT NULL COMMENT 'Deduction ID',
+  `operation` VARCHAR(16) NOT NULL COMMENT 'Operation: confirm/cancel',
+  `activity_id` BIGINT UNSIGNED NOT NULL COMMENT 'Activity ID',
+  `quantity` INT NOT NULL COMMENT 'Quantity',
+  `attempts` INT NOT NULL DEFAULT 0 COMMENT 'Attempts made',
+  `next_retry_at` TIMESTAMP NOT NULL COMMENT 'Next retry time',
+  `last_error` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Last error',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created time',
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'Updated time',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_deduct_operation` (`deduct_id`, `operation`),
+  KEY `idx_next_retry_at` (`next_retry_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='TCC compensations table';
+
+-- ========================================
+-- 16. TCC dead letters table (compensations needing manual attention)
+-- ========================================
+CREATE TABLE `tcc_dead_letters` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Dead letter ID',
+  `deduct_id` VARCHAR(128) NOT NULL COMMENT 'Deduction ID',
+  `operation` VARCHAR(16) NOT NULL COMMENT 'Operation: confirm/cancel',
+  `activity_id` BIGINT UNSIGNED NOT NULL COMMENT 'Activity ID',
+  `quantity` INT NOT NULL COMMENT 'Quantity',
+  `attempts` INT NOT NULL COMMENT 'Attempts made',
+  `last_error` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Last error',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT 'Status: 0-open, 1-retried, 2-resolved',
+  `resolved_by` VARCHAR(50) DEFAULT NULL COMMENT 'Resolved by',
+  `resolve_note` VARCHAR(255) DEFAULT NULL COMMENT 'Resolve note',
+  `resolved_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Resolved time',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created time',
+  `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'Updated time',
+  PRIMARY KEY (`id`),
+  KEY `idx_deduct_id` (`deduct_id`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='TCC dead letters table';
+
+-- ========================================
+-- 17. Outbox table (order events awaiting publication)
+-- ========================================
+CREATE TABLE `outbox` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Event ID',
+  `topic` VARCHAR(64) NOT NULL COMMENT 'Topic / event type',
+  `aggregate_id` VARCHAR(64) NOT NULL COMMENT 'Aggregate ID (order number)',
+  `payload` TEXT NOT NULL COMMENT 'Event payload (JSON)',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT 'Status: 0-pending, 1-delivered',
+  `attempts` INT NOT NULL DEFAULT 0 COMMENT 'Failed deliveries',
+  `last_error` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'Last error',
+  `delivered_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'Delivered time',
+  `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'Created time',
+  PRIMARY KEY (`id`),
+  KEY `idx_aggregate_id` (`aggregate_id`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='Outbox table';
+
 -- ========================================
 -- Create views (optional)
 -- ========================================
@@ -20,7 +20,10 @@ import (
 	"seckill/internal/model"
 	"seckill/internal/repository"
 	"seckill/internal/service/auth"
+	"seckill/internal/service/gray"
+	"seckill/internal/service/risk"
 	"seckill/internal/service/seckill"
+	"seckill/internal/service/vip"
 	"seckill/internal/utils"
 	"seckill/pkg/breaker"
 	"seckill/pkg/degrade"
@@ -145,8 +148,13 @@ func SetupIntegrationTestEnv(t *testing.T) *IntegrationTestEnv {
 		rateLimiter,
 		circuitBreaker,
 		degradeManager,
+		risk.NewEngine(redisClient, userRepo),
+		vip.NewVIPService(userRepo, repository.NewVIPGrantRepository(db), redisClient),
+		gray.NewController(redisClient, userRepo, activityRepo),
 		messageQueue,
 		redisClient,
+		nil,
+		nil,
 	)
 
 	// 初始化Handler
@@ -206,6 +214,13 @@ func setupTestDB(t *testing.T, cfg config.DatabaseConfig) *gorm.DB {
 		&model.Order{},
 		&model.OrderDetail{},
 		&model.StockLog{},
+		&model.Blacklist{},
+		&model.VIPGrant{},
+		&model.Purchase{},
+		&model.TCCLog{},
+		&model.TCCCompensation{},
+		&model.TCCDeadLetter{},
+		&model.OutboxEvent{},
 	)
 	require.NoError(t, err)
 